@@ -1,10 +1,310 @@
 package arrow
 
 import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	arrowmemory "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/memory"
 )
 
 func NewAllocator(a *memory.Allocator) arrowmemory.Allocator {
 	return a
 }
+
+// NewInstrumentedAllocator returns an allocator that allocates from a
+// and records the calls made to it in the returned AllocStats.
+// Use NewAllocator when the statistics are not needed.
+func NewInstrumentedAllocator(a *memory.Allocator) (arrowmemory.Allocator, *AllocStats) {
+	stats := &AllocStats{}
+	return &instrumentedAllocator{alloc: a, stats: stats}, stats
+}
+
+// AllocStats counts the calls made to an instrumented allocator.
+// The counters are updated atomically and may be read at any time.
+type AllocStats struct {
+	allocs         int64
+	reallocs       int64
+	frees          int64
+	bytesAllocated int64
+	bytesFreed     int64
+}
+
+// Allocs returns the number of calls to Allocate.
+func (s *AllocStats) Allocs() int64 {
+	return atomic.LoadInt64(&s.allocs)
+}
+
+// Reallocs returns the number of calls to Reallocate.
+func (s *AllocStats) Reallocs() int64 {
+	return atomic.LoadInt64(&s.reallocs)
+}
+
+// Frees returns the number of calls to Free.
+func (s *AllocStats) Frees() int64 {
+	return atomic.LoadInt64(&s.frees)
+}
+
+// BytesAllocated returns the total number of bytes requested
+// by Allocate and Reallocate.
+func (s *AllocStats) BytesAllocated() int64 {
+	return atomic.LoadInt64(&s.bytesAllocated)
+}
+
+// BytesFreed returns the total number of bytes released by
+// Free and by the buffers replaced by Reallocate.
+func (s *AllocStats) BytesFreed() int64 {
+	return atomic.LoadInt64(&s.bytesFreed)
+}
+
+type instrumentedAllocator struct {
+	alloc *memory.Allocator
+	stats *AllocStats
+}
+
+func (a *instrumentedAllocator) Allocate(size int) []byte {
+	b := a.alloc.Allocate(size)
+	atomic.AddInt64(&a.stats.allocs, 1)
+	atomic.AddInt64(&a.stats.bytesAllocated, int64(size))
+	return b
+}
+
+func (a *instrumentedAllocator) Reallocate(size int, b []byte) []byte {
+	freed := cap(b)
+	b = a.alloc.Reallocate(size, b)
+	atomic.AddInt64(&a.stats.reallocs, 1)
+	atomic.AddInt64(&a.stats.bytesAllocated, int64(size))
+	atomic.AddInt64(&a.stats.bytesFreed, int64(freed))
+	return b
+}
+
+func (a *instrumentedAllocator) Free(b []byte) {
+	a.alloc.Free(b)
+	atomic.AddInt64(&a.stats.frees, 1)
+	atomic.AddInt64(&a.stats.bytesFreed, int64(len(b)))
+}
+
+// NewBoundedAllocator returns an allocator that allocates from a and
+// applies backpressure when the memory in use approaches a quota.
+//
+// An allocation that would take the memory allocated by a above the
+// high water mark waits until enough memory is freed through the
+// returned allocator. If the memory is not freed before the timeout,
+// the allocation panics with a ResourceExhausted error in the same
+// way as an allocation that exceeds the limit of a.
+//
+// An allocation is never delayed when no memory is in use so an
+// allocation larger than the high water mark does not wait forever.
+func NewBoundedAllocator(a *memory.Allocator, highWater int64, timeout time.Duration) arrowmemory.Allocator {
+	b := &boundedAllocator{
+		alloc:     a,
+		highWater: highWater,
+		timeout:   timeout,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+type boundedAllocator struct {
+	alloc     *memory.Allocator
+	highWater int64
+	timeout   time.Duration
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+func (b *boundedAllocator) Allocate(size int) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.wait(size); err != nil {
+		panic(err)
+	}
+	return b.alloc.Allocate(size)
+}
+
+func (b *boundedAllocator) Reallocate(size int, buf []byte) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.wait(size - cap(buf)); err != nil {
+		panic(err)
+	}
+	buf = b.alloc.Reallocate(size, buf)
+	b.cond.Broadcast()
+	return buf
+}
+
+func (b *boundedAllocator) Free(buf []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.alloc.Free(buf)
+	b.cond.Broadcast()
+}
+
+// wait blocks until size bytes can be allocated without going
+// above the high water mark or until the timeout expires.
+// This must be called with mu held.
+func (b *boundedAllocator) wait(size int) error {
+	if b.available(size) {
+		return nil
+	}
+
+	timedOut := false
+	timer := time.AfterFunc(b.timeout, func() {
+		b.mu.Lock()
+		timedOut = true
+		b.mu.Unlock()
+		b.cond.Broadcast()
+	})
+	defer timer.Stop()
+
+	for !b.available(size) {
+		if timedOut {
+			return errors.Newf(codes.ResourceExhausted, "timed out after %v waiting for memory to be released: high water mark %d bytes, allocated: %d, wanted: %d", b.timeout, b.highWater, b.alloc.Allocated(), size)
+		}
+		b.cond.Wait()
+	}
+	return nil
+}
+
+// available reports whether size bytes can be allocated.
+func (b *boundedAllocator) available(size int) bool {
+	allocated := b.alloc.Allocated()
+	return size <= 0 || allocated == 0 || allocated+int64(size) <= b.highWater
+}
+
+const (
+	// minPoolSizeClass and maxPoolSizeClass are the smallest and
+	// largest buffers, as powers of two, kept by a PoolAllocator.
+	minPoolSizeClass = 6
+	maxPoolSizeClass = 20
+
+	// maxPooledBuffers is the number of buffers of
+	// each size class kept by a PoolAllocator.
+	maxPooledBuffers = 16
+)
+
+// PoolAllocator is an allocator that reuses the buffers that are
+// freed to it. Freed buffers are kept on a free list for their size
+// class and returned by the next allocation of the same size class.
+// The underlying allocator is used when there is no buffer to reuse.
+//
+// The buffers kept by the pool are still allocated from the
+// underlying allocator so they count against its limit until
+// the pool is closed.
+type PoolAllocator struct {
+	alloc *memory.Allocator
+
+	mu     sync.Mutex
+	pools  [maxPoolSizeClass + 1][][]byte
+	closed bool
+}
+
+// NewPoolAllocator returns a PoolAllocator that allocates from a.
+func NewPoolAllocator(a *memory.Allocator) *PoolAllocator {
+	return &PoolAllocator{alloc: a}
+}
+
+var _ arrowmemory.Allocator = (*PoolAllocator)(nil)
+
+func (p *PoolAllocator) Allocate(size int) []byte {
+	class, ok := sizeClass(size)
+	if !ok {
+		return p.alloc.Allocate(size)
+	}
+
+	p.mu.Lock()
+	pool := p.pools[class]
+	if n := len(pool); n > 0 {
+		b := pool[n-1]
+		pool[n-1] = nil
+		p.pools[class] = pool[:n-1]
+		p.mu.Unlock()
+
+		// The buffer is cleared because allocated
+		// memory is expected to be zeroed.
+		b = b[:size]
+		for i := range b {
+			b[i] = 0
+		}
+		return b
+	}
+	p.mu.Unlock()
+
+	// Allocate the whole size class so the
+	// buffer can be reused when it is freed.
+	return p.alloc.Allocate(1 << class)[:size]
+}
+
+func (p *PoolAllocator) Reallocate(size int, b []byte) []byte {
+	if size <= cap(b) {
+		if size > len(b) {
+			// Clear the memory that was not
+			// part of the original buffer.
+			ext := b[len(b):size]
+			for i := range ext {
+				ext[i] = 0
+			}
+		}
+		return b[:size]
+	}
+	nb := p.Allocate(size)
+	copy(nb, b)
+	p.Free(b)
+	return nb
+}
+
+func (p *PoolAllocator) Free(b []byte) {
+	b = b[:cap(b)]
+	class, ok := sizeClass(len(b))
+	if !ok || len(b) != 1<<class {
+		p.alloc.Free(b)
+		return
+	}
+
+	p.mu.Lock()
+	if !p.closed && len(p.pools[class]) < maxPooledBuffers {
+		p.pools[class] = append(p.pools[class], b)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.alloc.Free(b)
+}
+
+// Close releases the buffers kept by the pool to the underlying
+// allocator. Buffers freed after the pool is closed are released
+// to the underlying allocator immediately.
+func (p *PoolAllocator) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for class, pool := range p.pools {
+		for _, b := range pool {
+			p.alloc.Free(b)
+		}
+		p.pools[class] = nil
+	}
+	return nil
+}
+
+// sizeClass returns the size class of a buffer of the given size.
+// It returns false if buffers of the size are not pooled.
+func sizeClass(size int) (int, bool) {
+	if size <= 0 {
+		return 0, false
+	}
+	class := bits.Len(uint(size - 1))
+	if class < minPoolSizeClass {
+		class = minPoolSizeClass
+	}
+	return class, class <= maxPoolSizeClass
+}
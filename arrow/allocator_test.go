@@ -0,0 +1,301 @@
+package arrow_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	arrowmemory "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/memory"
+)
+
+func TestBoundedAllocator_WaitsForFree(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc := arrow.NewBoundedAllocator(mem, 64, time.Minute)
+
+	b0 := alloc.Allocate(48)
+
+	done := make(chan []byte)
+	go func() {
+		done <- alloc.Allocate(32)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected allocation to wait for memory to be released")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	alloc.Free(b0)
+	select {
+	case b1 := <-done:
+		if want, got := 32, len(b1); want != got {
+			t.Errorf("unexpected allocation size -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+		alloc.Free(b1)
+	case <-time.After(time.Second):
+		t.Fatal("allocation did not resume after memory was released")
+	}
+
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+func TestBoundedAllocator_Timeout(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc := arrow.NewBoundedAllocator(mem, 64, 10*time.Millisecond)
+
+	b := alloc.Allocate(64)
+	defer alloc.Free(b)
+
+	err := func() (err error) {
+		defer func() {
+			if e := recover(); e != nil {
+				err = e.(error)
+			}
+		}()
+		alloc.Allocate(1)
+		return nil
+	}()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want, got := codes.ResourceExhausted, flux.ErrorCode(err); want != got {
+		t.Errorf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if want, got := "timed out after 10ms waiting for memory to be released: high water mark 64 bytes, allocated: 64, wanted: 1", err.Error(); want != got {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+}
+
+func TestBoundedAllocator_LargeAllocation(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc := arrow.NewBoundedAllocator(mem, 64, time.Minute)
+
+	// An allocation above the high water mark does not
+	// wait when there is no memory to be released.
+	b := alloc.Allocate(128)
+	alloc.Free(b)
+}
+
+func TestBoundedAllocator_Concurrent(t *testing.T) {
+	const (
+		highWater = 256
+		workers   = 8
+		n         = 100
+	)
+	mem := &memory.Allocator{}
+	alloc := arrow.NewBoundedAllocator(mem, highWater, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				b := alloc.Allocate(32 + 8*i)
+				b = alloc.Reallocate(64+8*i, b)
+				alloc.Free(b)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+	if got := mem.MaxAllocated(); got > highWater {
+		t.Errorf("allocated memory exceeded the high water mark of %d bytes: %d", highWater, got)
+	}
+}
+
+func TestInstrumentedAllocator(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc, stats := arrow.NewInstrumentedAllocator(mem)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := alloc.Allocate(64)
+			b = alloc.Reallocate(128, b)
+			alloc.Free(b)
+		}()
+	}
+	wg.Wait()
+
+	for _, tc := range []struct {
+		name string
+		want int64
+		got  int64
+	}{
+		{name: "allocs", want: 4, got: stats.Allocs()},
+		{name: "reallocs", want: 4, got: stats.Reallocs()},
+		{name: "frees", want: 4, got: stats.Frees()},
+		{name: "bytes allocated", want: 4 * (64 + 128), got: stats.BytesAllocated()},
+		{name: "bytes freed", want: 4 * (64 + 128), got: stats.BytesFreed()},
+	} {
+		if tc.want != tc.got {
+			t.Errorf("unexpected %s -want/+got:\n\t- %d\n\t+ %d", tc.name, tc.want, tc.got)
+		}
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+func TestPoolAllocator_Reuse(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc := arrow.NewPoolAllocator(mem)
+
+	b0 := alloc.Allocate(100)
+	if want, got := int64(128), mem.Allocated(); want != got {
+		t.Errorf("unexpected allocated memory -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	for i := range b0 {
+		b0[i] = 0xff
+	}
+	alloc.Free(b0)
+
+	// The freed buffer is still allocated by the pool.
+	if want, got := int64(128), mem.Allocated(); want != got {
+		t.Errorf("unexpected allocated memory -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	b1 := alloc.Allocate(120)
+	if &b0[0] != &b1[0] {
+		t.Error("expected freed buffer to be reused")
+	}
+	if want, got := 120, len(b1); want != got {
+		t.Errorf("unexpected buffer length -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	for i, v := range b1 {
+		if v != 0 {
+			t.Fatalf("expected reused buffer to be cleared, got %d at %d", v, i)
+		}
+	}
+	if want, got := int64(1), mem.TotalAllocated()/128; want != got {
+		t.Errorf("unexpected number of allocations -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	b1 = alloc.Reallocate(1000, b1)
+	alloc.Free(b1)
+	if err := alloc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+func TestPoolAllocator_Limit(t *testing.T) {
+	limit := int64(256)
+	mem := &memory.Allocator{Limit: &limit}
+	alloc := arrow.NewPoolAllocator(mem)
+	defer func() { _ = alloc.Close() }()
+
+	b := alloc.Allocate(200)
+	alloc.Free(b)
+
+	// The pooled buffer counts against the limit.
+	err := func() (err error) {
+		defer func() {
+			if e := recover(); e != nil {
+				err = e.(error)
+			}
+		}()
+		alloc.Allocate(100)
+		return nil
+	}()
+	if want, got := codes.ResourceExhausted, flux.ErrorCode(err); want != got {
+		t.Errorf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+
+	// An allocation of the same size class reuses the buffer.
+	b = alloc.Allocate(250)
+	alloc.Free(b)
+}
+
+func TestPoolAllocator_Concurrent(t *testing.T) {
+	mem := &memory.Allocator{}
+	alloc := arrow.NewPoolAllocator(mem)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b := alloc.Allocate(64 << (j % 4))
+				b = alloc.Reallocate(512<<(i%2), b)
+				alloc.Free(b)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := alloc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+// BenchmarkPoolAllocator_CopyTable copies the value columns of a table
+// into new arrays and releases them in the same way as diff.
+func BenchmarkPoolAllocator_CopyTable(b *testing.B) {
+	const n = 1024
+	vs := make([]float64, n)
+	for i := range vs {
+		vs[i] = float64(i)
+	}
+	for _, tc := range []struct {
+		name  string
+		alloc func(mem *memory.Allocator) (arrowmemory.Allocator, func() error)
+	}{
+		{
+			name: "default",
+			alloc: func(mem *memory.Allocator) (arrowmemory.Allocator, func() error) {
+				return arrow.NewAllocator(mem), func() error { return nil }
+			},
+		},
+		{
+			name: "pool",
+			alloc: func(mem *memory.Allocator) (arrowmemory.Allocator, func() error) {
+				pool := arrow.NewPoolAllocator(mem)
+				return pool, pool.Close
+			},
+		},
+	} {
+		b.Run(fmt.Sprintf("alloc=%s", tc.name), func(b *testing.B) {
+			alloc, closeFn := tc.alloc(&memory.Allocator{})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var arrs []array.Array
+				for col := 0; col < 4; col++ {
+					fb := array.NewFloatBuilder(alloc)
+					fb.Reserve(n)
+					for _, v := range vs {
+						fb.Append(v)
+					}
+					arrs = append(arrs, fb.NewArray())
+					fb.Release()
+				}
+				for _, arr := range arrs {
+					arr.Release()
+				}
+			}
+			if err := closeFn(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
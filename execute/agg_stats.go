@@ -0,0 +1,46 @@
+package execute
+
+import (
+	"context"
+	"sync"
+
+	"github.com/influxdata/flux/metadata"
+)
+
+// AggStats collects the statistics that aggregates report about
+// their internal state, such as the size of a sketch. The statistics
+// are added to the metadata of the query once all of the transformations
+// have finished if the EmitAggStats execution option is set.
+type AggStats struct {
+	mu sync.Mutex
+	md metadata.Metadata
+}
+
+func newAggStats() *AggStats {
+	return &AggStats{md: make(metadata.Metadata)}
+}
+
+// GetAggStats returns the statistics of the query executed with the
+// context. It returns nil if aggregate statistics are not emitted.
+func GetAggStats(ctx context.Context) *AggStats {
+	s, _ := ctx.Value(aggStatsKey).(*AggStats)
+	return s
+}
+
+// Add adds the statistics of an aggregate to the metadata key.
+// It does nothing if s is nil.
+func (s *AggStats) Add(key string, stats interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.md.Add(key, stats)
+	s.mu.Unlock()
+}
+
+// metadata returns the statistics that have been added.
+func (s *AggStats) metadata() metadata.Metadata {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.md
+}
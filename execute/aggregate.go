@@ -1,7 +1,10 @@
 package execute
 
 import (
+	"bytes"
 	"context"
+	"encoding"
+	"encoding/gob"
 
 	"github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
@@ -13,6 +16,7 @@ import (
 	"github.com/influxdata/flux/internal/feature"
 	fluxmemory "github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/values"
 )
 
 // AggregateTransformation implements a transformation that aggregates
@@ -73,6 +77,8 @@ func (t *aggregateTransformation) ProcessMessage(m Message) error {
 		return t.processChunk(m.TableChunk())
 	case FlushKeyMsg:
 		return t.flushKey(m.Key())
+	case CheckpointMsg:
+		return t.checkpoint(m.Barrier())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -115,6 +121,33 @@ func (t *aggregateTransformation) flushKey(key flux.GroupKey) error {
 	return nil
 }
 
+// checkpoint records the state of every group key
+// and then forwards the barrier downstream.
+func (t *aggregateTransformation) checkpoint(b *CheckpointBarrier) error {
+	s, ok := t.t.(StateSnapshotter)
+	if !ok {
+		return errors.Newf(codes.Unimplemented, "checkpointing is not supported by %s", OperationType(t.t))
+	}
+	data, err := snapshotGroupStates(t.d, s)
+	if err != nil {
+		return err
+	}
+	if err := b.Record(t.d.id, data); err != nil {
+		return err
+	}
+	return t.d.Checkpoint(b)
+}
+
+// checkpointSupport implements checkpointParticipant.
+func (t *aggregateTransformation) checkpointSupport() (supported, stateful bool) {
+	_, ok := t.t.(StateSnapshotter)
+	return ok, true
+}
+
+func (t *aggregateTransformation) restoreCheckpoint(data []byte) error {
+	return restoreGroupStates(t.d, t.t.(StateSnapshotter), data)
+}
+
 // Finish is implemented to remain compatible with legacy upstreams.
 func (t *aggregateTransformation) Finish(id DatasetID, err error) {
 	if err == nil {
@@ -130,6 +163,10 @@ func (t *aggregateTransformation) OperationType() string {
 	return OperationType(t.t)
 }
 
+func (t *aggregateTransformation) SchemaContract() *SchemaContract {
+	return SchemaContractOf(t.t)
+}
+
 type SimpleAggregateConfig struct {
 	plan.DefaultCost
 	Columns []string `json:"columns"`
@@ -208,7 +245,13 @@ func (t *simpleAggregateTransformation) Process(id DatasetID, tbl flux.Table) er
 		return err
 	}
 
+	agg, err := aggregateForKey(t.agg, tbl.Key())
+	if err != nil {
+		return err
+	}
+
 	builderColMap := make([]int, len(t.config.Columns))
+	auxColMap := make([][]int, len(t.config.Columns))
 	tableColMap := make([]int, len(t.config.Columns))
 	aggregates := make([]ValueFunc, len(t.config.Columns))
 	cols := tbl.Cols()
@@ -230,15 +273,15 @@ func (t *simpleAggregateTransformation) Process(id DatasetID, tbl flux.Table) er
 		var vf ValueFunc
 		switch c.Type {
 		case flux.TBool:
-			vf = t.agg.NewBoolAgg()
+			vf = agg.NewBoolAgg()
 		case flux.TInt:
-			vf = t.agg.NewIntAgg()
+			vf = agg.NewIntAgg()
 		case flux.TUInt:
-			vf = t.agg.NewUIntAgg()
+			vf = agg.NewUIntAgg()
 		case flux.TFloat:
-			vf = t.agg.NewFloatAgg()
+			vf = agg.NewFloatAgg()
 		case flux.TString:
-			vf = t.agg.NewStringAgg()
+			vf = agg.NewStringAgg()
 		}
 		if vf == nil {
 			return errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", c.Type)
@@ -253,6 +296,15 @@ func (t *simpleAggregateTransformation) Process(id DatasetID, tbl flux.Table) er
 		if err != nil {
 			return err
 		}
+		if aux, ok := vf.(AuxiliaryValueFunc); ok {
+			for _, col := range aux.AuxiliaryColumns() {
+				bj, err := builder.AddCol(col)
+				if err != nil {
+					return err
+				}
+				auxColMap[j] = append(auxColMap[j], bj)
+			}
+		}
 		tableColMap[j] = idx
 	}
 
@@ -277,6 +329,11 @@ func (t *simpleAggregateTransformation) Process(id DatasetID, tbl flux.Table) er
 			default:
 				return errors.Newf(codes.Invalid, "unsupported aggregate type %v", c.Type)
 			}
+			if ef, ok := vf.(ErrorValueFunc); ok {
+				if err := ef.Err(); err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	}); err != nil {
@@ -285,6 +342,14 @@ func (t *simpleAggregateTransformation) Process(id DatasetID, tbl flux.Table) er
 	for j, vf := range aggregates {
 		bj := builderColMap[j]
 
+		if aux, ok := vf.(AuxiliaryValueFunc); ok {
+			for i, v := range aux.AuxiliaryValues() {
+				if err := builder.AppendValue(auxColMap[j][i], v); err != nil {
+					return err
+				}
+			}
+		}
+
 		// If the value is null, append a null to the column.
 		if vf.IsNull() {
 			if err := builder.AppendNil(bj); err != nil {
@@ -380,6 +445,11 @@ func (t *simpleAggregateTransformation2) initializeState(chunk table.Chunk, curr
 		return current.(aggregateStateList), nil
 	}
 
+	agg, err := aggregateForKey(t.agg, chunk.Key())
+	if err != nil {
+		return nil, err
+	}
+
 	state := make(aggregateStateList, len(t.config.Columns))
 	for i, label := range t.config.Columns {
 		j := chunk.Index(label)
@@ -389,27 +459,100 @@ func (t *simpleAggregateTransformation2) initializeState(chunk table.Chunk, curr
 			return nil, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
 		}
 
-		var vf ValueFunc
 		col := chunk.Col(j)
-		switch col.Type {
-		case flux.TBool:
-			vf = t.agg.NewBoolAgg()
-		case flux.TInt:
-			vf = t.agg.NewIntAgg()
-		case flux.TUInt:
-			vf = t.agg.NewUIntAgg()
-		case flux.TFloat:
-			vf = t.agg.NewFloatAgg()
-		case flux.TString:
-			vf = t.agg.NewStringAgg()
-		default:
-			return nil, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", col.Type)
+		vf, err := newAggregateValueFunc(agg, col.Type)
+		if err != nil {
+			return nil, err
 		}
+		state[i].agg, state[i].inType = vf, col.Type
+	}
+	return state, nil
+}
 
-		if vf == nil {
-			return nil, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", col.Type)
+// newAggregateValueFunc creates the aggregate for an input column of the given type.
+func newAggregateValueFunc(agg SimpleAggregate, typ flux.ColType) (ValueFunc, error) {
+	var vf ValueFunc
+	switch typ {
+	case flux.TBool:
+		vf = agg.NewBoolAgg()
+	case flux.TInt:
+		vf = agg.NewIntAgg()
+	case flux.TUInt:
+		vf = agg.NewUIntAgg()
+	case flux.TFloat:
+		vf = agg.NewFloatAgg()
+	case flux.TString:
+		vf = agg.NewStringAgg()
+	default:
+		return nil, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+	}
+
+	if vf == nil {
+		return nil, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+	}
+	return vf, nil
+}
+
+// aggregateStateSnapshot is the encoded form of an aggregateState.
+type aggregateStateSnapshot struct {
+	InType flux.ColType
+	State  []byte
+}
+
+// SnapshotState implements StateSnapshotter. Each aggregate must
+// implement encoding.BinaryMarshaler to be recorded in a checkpoint.
+func (t *simpleAggregateTransformation2) SnapshotState(key flux.GroupKey, state interface{}) ([]byte, error) {
+	aggregates := state.(aggregateStateList)
+	snapshots := make([]aggregateStateSnapshot, len(aggregates))
+	for i, s := range aggregates {
+		m, ok := s.agg.(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, errors.Newf(codes.Unimplemented, "aggregate state %T cannot be checkpointed", s.agg)
 		}
-		state[i].agg, state[i].inType = vf, col.Type
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i] = aggregateStateSnapshot{InType: s.inType, State: data}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshots); err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to encode aggregate state")
+	}
+	return buf.Bytes(), nil
+}
+
+// RestoreState implements StateSnapshotter. Each aggregate must
+// implement encoding.BinaryUnmarshaler to be restored from a checkpoint.
+func (t *simpleAggregateTransformation2) RestoreState(key flux.GroupKey, data []byte) (interface{}, error) {
+	var snapshots []aggregateStateSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshots); err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to decode aggregate state")
+	}
+	if len(snapshots) != len(t.config.Columns) {
+		return nil, errors.Newf(codes.Internal, "checkpoint has %d aggregates, expected %d", len(snapshots), len(t.config.Columns))
+	}
+
+	agg, err := aggregateForKey(t.agg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(aggregateStateList, len(snapshots))
+	for i, s := range snapshots {
+		vf, err := newAggregateValueFunc(agg, s.InType)
+		if err != nil {
+			return nil, err
+		}
+		u, ok := vf.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return nil, errors.Newf(codes.Unimplemented, "aggregate state %T cannot be restored from a checkpoint", vf)
+		}
+		if err := u.UnmarshalBinary(s.State); err != nil {
+			return nil, err
+		}
+		state[i].agg, state[i].inType = vf, s.InType
 	}
 	return state, nil
 }
@@ -449,6 +592,11 @@ func (t *simpleAggregateTransformation2) Aggregate(chunk table.Chunk, state inte
 			// that the input type matches the type for this chunk.
 			return nil, false, errors.Newf(codes.Internal, "aggregate of type %s not supported", c.Type)
 		}
+		if ef, ok := agg.(ErrorValueFunc); ok {
+			if err := ef.Err(); err != nil {
+				return nil, false, err
+			}
+		}
 	}
 	return aggregates, true, nil
 }
@@ -466,6 +614,11 @@ func (t *simpleAggregateTransformation2) Compute(key flux.GroupKey, state interf
 			Type:  s.agg.Type(),
 		})
 	}
+	for _, s := range aggregates {
+		if aux, ok := s.agg.(AuxiliaryValueFunc); ok {
+			buffer.Columns = append(buffer.Columns, aux.AuxiliaryColumns()...)
+		}
+	}
 
 	buffer.Values = make([]array.Array, len(key.Cols()), len(buffer.Columns))
 	for j := range key.Cols() {
@@ -494,6 +647,14 @@ func (t *simpleAggregateTransformation2) Compute(key flux.GroupKey, state interf
 		}
 		buffer.Values = append(buffer.Values, arr)
 	}
+	for _, s := range aggregates {
+		if aux, ok := s.agg.(AuxiliaryValueFunc); ok {
+			for i, col := range aux.AuxiliaryColumns() {
+				v := aux.AuxiliaryValues()[i]
+				buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, v, 1, mem))
+			}
+		}
+	}
 
 	if err := buffer.Validate(); err != nil {
 		return err
@@ -518,6 +679,26 @@ type SimpleAggregate interface {
 	NewStringAgg() DoStringAgg
 }
 
+// GroupKeySimpleAggregate is a SimpleAggregate whose
+// aggregates depend on the group key of the table
+// being aggregated.
+type GroupKeySimpleAggregate interface {
+	SimpleAggregate
+
+	// ForKey returns the SimpleAggregate that will be used
+	// to aggregate the table with the given group key.
+	ForKey(key flux.GroupKey) (SimpleAggregate, error)
+}
+
+// aggregateForKey returns the SimpleAggregate to use for the
+// given group key.
+func aggregateForKey(agg SimpleAggregate, key flux.GroupKey) (SimpleAggregate, error) {
+	if a, ok := agg.(GroupKeySimpleAggregate); ok {
+		return a.ForKey(key)
+	}
+	return agg, nil
+}
+
 type ValueFunc interface {
 	Type() flux.ColType
 	IsNull() bool
@@ -543,6 +724,26 @@ type DoStringAgg interface {
 	DoString(*array.String)
 }
 
+// AuxiliaryValueFunc is implemented by a ValueFunc that reports
+// additional columns alongside its aggregated value.
+type AuxiliaryValueFunc interface {
+	// AuxiliaryColumns returns the additional columns
+	// reported by the aggregate.
+	AuxiliaryColumns() []flux.ColMeta
+
+	// AuxiliaryValues returns the value for each
+	// of the additional columns.
+	AuxiliaryValues() []values.Value
+}
+
+// ErrorValueFunc is implemented by a ValueFunc that can
+// fail while aggregating values.
+type ErrorValueFunc interface {
+	// Err returns the error encountered while
+	// aggregating values, if any.
+	Err() error
+}
+
 type BoolValueFunc interface {
 	ValueBool() bool
 }
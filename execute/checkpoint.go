@@ -0,0 +1,436 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/values"
+)
+
+// DefaultCheckpointInterval is the interval between checkpoints
+// when CheckpointOptions does not specify one.
+const DefaultCheckpointInterval = time.Minute
+
+// CheckpointOptions configures checkpointing for a query.
+//
+// Checkpoints are taken by sending a barrier through the query.
+// Each source emits the barrier between two chunks of data and
+// records its position, and each stateful transformation records
+// its state when the barrier reaches it before forwarding the
+// barrier downstream. A checkpoint is complete once every source
+// and stateful transformation has recorded its part.
+//
+// The consistency model of a completed checkpoint is as follows:
+//
+//   - Every row a source emitted before the barrier is reflected in
+//     the recorded state exactly once. When the query is resumed, each
+//     source restarts at its recorded position so these rows are not
+//     read again and every row after the barrier is processed once.
+//   - Tables that were sent to the results before the barrier are not
+//     produced again when the query is resumed. A consumer that needs
+//     every table must finish reading the results it received before
+//     the checkpoint completes.
+//   - Checkpoints that did not complete are discarded and the query
+//     resumes from the most recent completed checkpoint.
+//
+// Checkpointing is only supported for queries whose sources implement
+// CheckpointSource and whose transformations each have a single input
+// and either are narrow transformations or are aggregate transformations
+// implementing StateSnapshotter. Parallel execution is not supported.
+type CheckpointOptions struct {
+	// Store holds the checkpoints for the query.
+	// If the store already contains a checkpoint,
+	// the query is resumed from it.
+	Store CheckpointStore
+
+	// Interval is the time between checkpoints.
+	Interval time.Duration
+}
+
+// Checkpoint is the recorded state of a query at a barrier.
+type Checkpoint struct {
+	// ID identifies the barrier for this checkpoint.
+	// IDs increase with each checkpoint of a query.
+	ID int64
+
+	// States holds the state recorded by each source
+	// and transformation keyed by their dataset id.
+	States map[string][]byte
+}
+
+// CheckpointStore persists the checkpoints of a single query.
+type CheckpointStore interface {
+	// Save stores a completed checkpoint.
+	Save(ctx context.Context, cp *Checkpoint) error
+
+	// Load returns the most recent checkpoint or nil
+	// if no checkpoint has been saved.
+	Load(ctx context.Context) (*Checkpoint, error)
+}
+
+// InMemoryCheckpointStore is a CheckpointStore that
+// keeps the most recent checkpoint in memory.
+type InMemoryCheckpointStore struct {
+	mu sync.Mutex
+	cp *Checkpoint
+}
+
+// NewInMemoryCheckpointStore constructs an empty InMemoryCheckpointStore.
+func NewInMemoryCheckpointStore() *InMemoryCheckpointStore {
+	return &InMemoryCheckpointStore{}
+}
+
+func (s *InMemoryCheckpointStore) Save(ctx context.Context, cp *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cp == nil || cp.ID > s.cp.ID {
+		s.cp = cp
+	}
+	return nil
+}
+
+func (s *InMemoryCheckpointStore) Load(ctx context.Context) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cp, nil
+}
+
+// CheckpointSource is a Source that can take part in a checkpoint.
+type CheckpointSource interface {
+	Source
+
+	// RestoreCheckpoint positions the source at the state it
+	// recorded for a checkpoint. It is called before Run.
+	RestoreCheckpoint(data []byte) error
+
+	// Checkpoint requests that the source emit the barrier.
+	//
+	// The source records its position with the barrier and then
+	// sends the barrier to its transformations after the data
+	// it has already sent and before any further data.
+	// A source that has finished ignores the request.
+	// Checkpoint is called concurrently with Run.
+	Checkpoint(b *CheckpointBarrier)
+}
+
+// CheckpointBarrier marks the point in the stream of a
+// query at which a checkpoint is taken.
+type CheckpointBarrier struct {
+	ID int64
+	c  *checkpointCoordinator
+}
+
+// Record records the state of a source or transformation
+// for the checkpoint of this barrier.
+func (b *CheckpointBarrier) Record(id DatasetID, data []byte) error {
+	return b.c.record(b.ID, id, data)
+}
+
+// StateSnapshotter is implemented by an AggregateTransformation
+// whose state can be recorded in a checkpoint.
+type StateSnapshotter interface {
+	// SnapshotState encodes the state for the given group key.
+	SnapshotState(key flux.GroupKey, state interface{}) ([]byte, error)
+
+	// RestoreState decodes the state for the given group key
+	// from data that was produced by SnapshotState.
+	RestoreState(key flux.GroupKey, data []byte) (interface{}, error)
+}
+
+// checkpointParticipant is implemented by transports
+// that know how to handle checkpoint barriers.
+type checkpointParticipant interface {
+	// checkpointSupport reports whether the transport supports
+	// checkpoints and whether it records state for them.
+	checkpointSupport() (supported, stateful bool)
+
+	// restoreCheckpoint restores the state that
+	// was recorded for a checkpoint.
+	restoreCheckpoint(data []byte) error
+}
+
+// checkpointParticipantOf returns the checkpointParticipant
+// for a transformation if it has one.
+func checkpointParticipantOf(t Transformation) (checkpointParticipant, bool) {
+	var tr interface{} = t
+	if a, ok := t.(*transportTransformationAdapter); ok {
+		tr = a.Transport
+	}
+	p, ok := tr.(checkpointParticipant)
+	return p, ok
+}
+
+// checkpointCoordinator assigns barriers to checkpoints and
+// saves each checkpoint once every participant has recorded
+// its state.
+type checkpointCoordinator struct {
+	ctx      context.Context
+	store    CheckpointStore
+	interval time.Duration
+
+	sources  []CheckpointSource
+	restores map[DatasetID]func(data []byte) error
+
+	mu      sync.Mutex
+	lastID  int64
+	pending map[int64]*Checkpoint
+}
+
+func newCheckpointCoordinator(ctx context.Context, opts *CheckpointOptions) *checkpointCoordinator {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	return &checkpointCoordinator{
+		ctx:      ctx,
+		store:    opts.Store,
+		interval: interval,
+		restores: make(map[DatasetID]func(data []byte) error),
+		pending:  make(map[int64]*Checkpoint),
+	}
+}
+
+// addSource registers a source that records its position.
+func (c *checkpointCoordinator) addSource(id DatasetID, src Source) error {
+	cs, ok := src.(CheckpointSource)
+	if !ok {
+		return errors.Newf(codes.Unimplemented, "checkpointing is not supported by source %s", src.Label())
+	}
+	c.sources = append(c.sources, cs)
+	c.restores[id] = cs.RestoreCheckpoint
+	return nil
+}
+
+// addTransformation registers a transformation that forwards
+// barriers and, if it is stateful, records its state.
+func (c *checkpointCoordinator) addTransformation(id DatasetID, t Transformation) error {
+	p, ok := checkpointParticipantOf(t)
+	if !ok {
+		return errors.Newf(codes.Unimplemented, "checkpointing is not supported by %s", OperationType(t))
+	}
+	supported, stateful := p.checkpointSupport()
+	if !supported {
+		return errors.Newf(codes.Unimplemented, "checkpointing is not supported by %s", OperationType(t))
+	}
+	if stateful {
+		c.restores[id] = p.restoreCheckpoint
+	}
+	return nil
+}
+
+// restore loads the most recent checkpoint and restores the
+// state of each participant from it.
+func (c *checkpointCoordinator) restore() error {
+	cp, err := c.store.Load(c.ctx)
+	if err != nil {
+		return errors.Wrap(err, codes.Inherit, "failed to load checkpoint")
+	} else if cp == nil {
+		return nil
+	}
+
+	c.lastID = cp.ID
+	for id, restore := range c.restores {
+		data, ok := cp.States[id.String()]
+		if !ok {
+			continue
+		}
+		if err := restore(data); err != nil {
+			return errors.Wrapf(err, codes.Inherit, "failed to restore checkpoint %d", cp.ID)
+		}
+	}
+	return nil
+}
+
+// run requests a checkpoint from the sources at each interval
+// until done is closed or the query is canceled. The checkpoints
+// that are still pending when it returns will never complete,
+// such as when a source fails before it records its part, so
+// they are discarded.
+func (c *checkpointCoordinator) run(done <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	defer c.discardPending()
+	for {
+		select {
+		case <-ticker.C:
+			c.trigger()
+		case <-done:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// trigger starts a new checkpoint by sending
+// a barrier request to each source.
+func (c *checkpointCoordinator) trigger() {
+	c.mu.Lock()
+	c.lastID++
+	b := &CheckpointBarrier{ID: c.lastID, c: c}
+	c.pending[b.ID] = &Checkpoint{
+		ID:     b.ID,
+		States: make(map[string][]byte, len(c.restores)),
+	}
+	c.mu.Unlock()
+
+	for _, src := range c.sources {
+		src.Checkpoint(b)
+	}
+}
+
+// discardPending discards the checkpoints that have not completed.
+// A barrier of a discarded checkpoint that is recorded later is ignored.
+func (c *checkpointCoordinator) discardPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.pending {
+		delete(c.pending, id)
+	}
+}
+
+func (c *checkpointCoordinator) record(barrier int64, id DatasetID, data []byte) error {
+	c.mu.Lock()
+	cp, ok := c.pending[barrier]
+	if !ok {
+		// The checkpoint was superseded by a more recent one.
+		c.mu.Unlock()
+		return nil
+	}
+	cp.States[id.String()] = data
+	if len(cp.States) < len(c.restores) {
+		c.mu.Unlock()
+		return nil
+	}
+
+	// The checkpoint is complete so any older
+	// checkpoints will never be needed.
+	for pid := range c.pending {
+		if pid <= barrier {
+			delete(c.pending, pid)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.store.Save(c.ctx, cp); err != nil {
+		return errors.Wrapf(err, codes.Inherit, "failed to save checkpoint %d", cp.ID)
+	}
+	return nil
+}
+
+// checkpointKeyColumn is the encoded form of
+// a single column of a group key.
+type checkpointKeyColumn struct {
+	Label string
+	Type  flux.ColType
+	Null  bool
+
+	Int    int64
+	UInt   uint64
+	Float  float64
+	String string
+	Bool   bool
+}
+
+// checkpointGroupState is the encoded state
+// of a single group key.
+type checkpointGroupState struct {
+	Key   []checkpointKeyColumn
+	State []byte
+}
+
+// snapshotGroupStates encodes the state of every
+// group key that is held by the dataset.
+func snapshotGroupStates(d *TransportDataset, s StateSnapshotter) ([]byte, error) {
+	var states []checkpointGroupState
+	if err := d.Range(func(key flux.GroupKey, state interface{}) error {
+		data, err := s.SnapshotState(key, state)
+		if err != nil {
+			return err
+		}
+		cols := make([]checkpointKeyColumn, len(key.Cols()))
+		for j, c := range key.Cols() {
+			cols[j] = checkpointKeyColumn{Label: c.Label, Type: c.Type}
+			v := key.Value(j)
+			if v.IsNull() {
+				cols[j].Null = true
+				continue
+			}
+			switch c.Type {
+			case flux.TInt:
+				cols[j].Int = v.Int()
+			case flux.TTime:
+				cols[j].Int = int64(v.Time())
+			case flux.TUInt:
+				cols[j].UInt = v.UInt()
+			case flux.TFloat:
+				cols[j].Float = v.Float()
+			case flux.TString:
+				cols[j].String = v.Str()
+			case flux.TBool:
+				cols[j].Bool = v.Bool()
+			default:
+				return errors.Newf(codes.Internal, "unsupported group key column type %v", c.Type)
+			}
+		}
+		states = append(states, checkpointGroupState{Key: cols, State: data})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(states); err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to encode checkpoint state")
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreGroupStates decodes the state of each group key
+// that was encoded by snapshotGroupStates into the dataset.
+func restoreGroupStates(d *TransportDataset, s StateSnapshotter, data []byte) error {
+	var states []checkpointGroupState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&states); err != nil {
+		return errors.Wrap(err, codes.Internal, "failed to decode checkpoint state")
+	}
+
+	for _, gs := range states {
+		cols := make([]flux.ColMeta, len(gs.Key))
+		vs := make([]values.Value, len(gs.Key))
+		for j, c := range gs.Key {
+			cols[j] = flux.ColMeta{Label: c.Label, Type: c.Type}
+			switch {
+			case c.Null:
+				vs[j] = values.NewNull(flux.SemanticType(c.Type))
+			case c.Type == flux.TInt:
+				vs[j] = values.NewInt(c.Int)
+			case c.Type == flux.TTime:
+				vs[j] = values.NewTime(values.Time(c.Int))
+			case c.Type == flux.TUInt:
+				vs[j] = values.NewUInt(c.UInt)
+			case c.Type == flux.TFloat:
+				vs[j] = values.NewFloat(c.Float)
+			case c.Type == flux.TString:
+				vs[j] = values.NewString(c.String)
+			case c.Type == flux.TBool:
+				vs[j] = values.NewBool(c.Bool)
+			default:
+				return errors.Newf(codes.Internal, "unsupported group key column type %v", c.Type)
+			}
+		}
+		key := NewGroupKey(cols, vs)
+
+		state, err := s.RestoreState(key, gs.State)
+		if err != nil {
+			return err
+		}
+		d.Set(key, state)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package execute
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// checkpointIgnoringSource is a source that never
+// records its part of a checkpoint.
+type checkpointIgnoringSource struct {
+	ExecutionNode
+}
+
+func (s *checkpointIgnoringSource) AddTransformation(t Transformation) {}
+func (s *checkpointIgnoringSource) Run(ctx context.Context)            {}
+func (s *checkpointIgnoringSource) RestoreCheckpoint(data []byte) error {
+	return nil
+}
+func (s *checkpointIgnoringSource) Checkpoint(b *CheckpointBarrier) {}
+
+func TestCheckpointCoordinator_DiscardPending(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stop func(done chan struct{}, cancel context.CancelFunc)
+	}{
+		{
+			name: "finished",
+			stop: func(done chan struct{}, cancel context.CancelFunc) {
+				close(done)
+			},
+		},
+		{
+			name: "canceled",
+			stop: func(done chan struct{}, cancel context.CancelFunc) {
+				cancel()
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			c := newCheckpointCoordinator(ctx, &CheckpointOptions{
+				Store:    NewInMemoryCheckpointStore(),
+				Interval: time.Hour,
+			})
+			if err := c.addSource(DatasetID{}, &checkpointIgnoringSource{}); err != nil {
+				t.Fatal(err)
+			}
+			c.trigger()
+			c.trigger()
+			if got, want := len(c.pending), 2; got != want {
+				t.Fatalf("unexpected pending checkpoints -want/+got:\n\t- %d\n\t+ %d", want, got)
+			}
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				c.run(done)
+			}()
+			tc.stop(done, cancel)
+			<-stopped
+
+			if got := len(c.pending); got != 0 {
+				t.Errorf("expected the pending checkpoints to be discarded, got %d", got)
+			}
+
+			// A barrier of a discarded checkpoint is ignored.
+			if err := c.record(1, DatasetID{}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if cp, _ := c.store.Load(ctx); cp != nil {
+				t.Errorf("expected no checkpoint to be saved, got %d", cp.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package execute_test
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/universe"
+	"go.uber.org/zap/zaptest"
+)
+
+const checkpointTestSourceKind = "checkpoint-test-source"
+
+func init() {
+	execute.RegisterSource(checkpointTestSourceKind, createCheckpointTestSource)
+}
+
+type checkpointTestProcedureSpec struct {
+	plan.DefaultCost
+	Values []float64
+	// CrashAt is the index of the row at which the source
+	// emits a barrier and then fails or -1 to never fail.
+	CrashAt int
+}
+
+func (s *checkpointTestProcedureSpec) Kind() plan.ProcedureKind {
+	return checkpointTestSourceKind
+}
+
+func (s *checkpointTestProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createCheckpointTestSource(spec plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	s := spec.(*checkpointTestProcedureSpec)
+	return &checkpointTestSource{
+		id:       id,
+		d:        execute.NewTransportDataset(id, a.Allocator()),
+		values:   s.Values,
+		crashAt:  s.CrashAt,
+		barriers: make(chan *execute.CheckpointBarrier, 1),
+	}, nil
+}
+
+// checkpointTestSource emits each value as its own chunk
+// in a single table and records the index of the next
+// value when a checkpoint is taken.
+type checkpointTestSource struct {
+	execute.ExecutionNode
+	id       execute.DatasetID
+	d        *execute.TransportDataset
+	values   []float64
+	crashAt  int
+	pos      int
+	barriers chan *execute.CheckpointBarrier
+}
+
+func (s *checkpointTestSource) AddTransformation(t execute.Transformation) {
+	s.d.AddTransformation(t)
+}
+
+func (s *checkpointTestSource) RestoreCheckpoint(data []byte) error {
+	s.pos = int(binary.LittleEndian.Uint64(data))
+	return nil
+}
+
+func (s *checkpointTestSource) Checkpoint(b *execute.CheckpointBarrier) {
+	select {
+	case s.barriers <- b:
+	default:
+	}
+}
+
+func (s *checkpointTestSource) Run(ctx context.Context) {
+	s.d.Finish(s.run(ctx))
+}
+
+func (s *checkpointTestSource) run(ctx context.Context) error {
+	var key flux.GroupKey
+	for ; s.pos < len(s.values); s.pos++ {
+		if s.pos == s.crashAt {
+			select {
+			case b := <-s.barriers:
+				data := make([]byte, 8)
+				binary.LittleEndian.PutUint64(data, uint64(s.pos))
+				if err := b.Record(s.id, data); err != nil {
+					return err
+				}
+				if err := s.d.Checkpoint(b); err != nil {
+					return err
+				}
+				return errors.New(codes.Internal, "crash")
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{"a", s.values[s.pos]}},
+		}
+		key = tbl.Key()
+		if err := tbl.Do(func(cr flux.ColReader) error {
+			chunk := table.ChunkFromReader(cr)
+			chunk.Retain()
+			return s.d.Process(chunk)
+		}); err != nil {
+			return err
+		}
+	}
+	if key == nil {
+		return nil
+	}
+	return s.d.FlushKey(key)
+}
+
+func checkpointTestPlan(crashAt int) *plan.Spec {
+	return plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("source", &checkpointTestProcedureSpec{
+				Values:  []float64{1, 2, 3, 4},
+				CrashAt: crashAt,
+			}),
+			plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+}
+
+func runCheckpointTest(t *testing.T, spec *plan.Spec, store execute.CheckpointStore) ([]*executetest.Table, error) {
+	t.Helper()
+
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.Checkpoint = &execute.CheckpointOptions{
+		Store:    store,
+		Interval: time.Millisecond,
+	}
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		return nil, err
+	}
+
+	var got []*executetest.Table
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			cpy, err := executetest.ConvertTable(tbl)
+			if err != nil {
+				return err
+			}
+			got = append(got, cpy)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return got, nil
+}
+
+func TestExecutor_CheckpointResume(t *testing.T) {
+	store := execute.NewInMemoryCheckpointStore()
+
+	// The first run takes a checkpoint after the
+	// first two values and then fails.
+	if _, err := runCheckpointTest(t, checkpointTestPlan(2), store); err == nil {
+		t.Fatal("expected error")
+	}
+	if cp, err := store.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if cp == nil {
+		t.Fatal("expected a checkpoint to be saved")
+	}
+
+	// Resuming only reads the last two values from the source
+	// but includes the first two values from the checkpoint.
+	got, err := runCheckpointTest(t, checkpointTestPlan(-1), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*executetest.Table{{
+		KeyCols: []string{"t0"},
+		ColMeta: []flux.ColMeta{
+			{Label: "t0", Type: flux.TString},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{{"a", 10.0}},
+	}}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, got) {
+		t.Fatalf("unexpected tables -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestExecutor_CheckpointUnsupportedSource(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(nil)),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	_, err := runCheckpointTest(t, spec, execute.NewInMemoryCheckpointStore())
+	if err == nil {
+		t.Fatal("expected error")
+	} else if got, want := errors.Code(err), codes.Unimplemented; got != want {
+		t.Fatalf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
@@ -0,0 +1,86 @@
+package execute
+
+import (
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+)
+
+// SchemaContract declares the schema that every table
+// produced by a transformation must satisfy.
+type SchemaContract struct {
+	// Columns lists the columns that must be present in every
+	// output table. A column with an invalid type only requires
+	// the label to be present.
+	Columns []flux.ColMeta
+
+	// KeyColumns lists the columns that must be part of the
+	// group key of every output table.
+	KeyColumns []string
+}
+
+// SchemaContractDeclarer is implemented by transformations that declare
+// a contract for the tables they produce.
+//
+// Contracts are only checked when the ValidateSchemaContracts
+// execution option is enabled.
+type SchemaContractDeclarer interface {
+	// SchemaContract returns the contract for the output tables
+	// or nil if the transformation does not declare one.
+	SchemaContract() *SchemaContract
+}
+
+// SchemaContractOf returns the schema contract declared by
+// the transformation or nil if it does not declare one.
+func SchemaContractOf(t interface{}) *SchemaContract {
+	if t, ok := t.(SchemaContractDeclarer); ok {
+		return t.SchemaContract()
+	}
+	return nil
+}
+
+// Validate returns an error if a table with the given
+// group key and columns does not satisfy the contract.
+func (c *SchemaContract) Validate(key flux.GroupKey, cols []flux.ColMeta) error {
+	for _, want := range c.Columns {
+		idx := ColIdx(want.Label, cols)
+		if idx < 0 {
+			return errors.Newf(codes.Internal, "missing column %q", want.Label)
+		}
+		if want.Type != flux.TInvalid && cols[idx].Type != want.Type {
+			return errors.Newf(codes.Internal, "column %q has type %s, expected %s", want.Label, cols[idx].Type, want.Type)
+		}
+	}
+	for _, label := range c.KeyColumns {
+		if !key.HasCol(label) {
+			return errors.Newf(codes.Internal, "column %q is not part of the group key", label)
+		}
+	}
+	return nil
+}
+
+// schemaContractChecker validates each chunk that the TransportDataset
+// of a transformation that declares a SchemaContract sends downstream.
+// It only inspects the schema of the chunk, so the validation does not
+// copy or retain the data of the chunk.
+type schemaContractChecker struct {
+	contract SchemaContract
+	label    string
+	op       string
+}
+
+func newSchemaContractChecker(contract SchemaContract, id plan.NodeID, op string) *schemaContractChecker {
+	return &schemaContractChecker{
+		contract: contract,
+		label:    string(id),
+		op:       op,
+	}
+}
+
+func (c *schemaContractChecker) validate(key flux.GroupKey, cols []flux.ColMeta) error {
+	if err := c.contract.Validate(key, cols); err != nil {
+		return errors.Wrapf(err, codes.Inherit, "output of %s (%s) violates its schema contract", c.label, c.op)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package execute_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"go.uber.org/zap/zaptest"
+)
+
+const contractTestKind = "contract-test"
+
+func init() {
+	execute.RegisterTransformation(contractTestKind, createContractTestTransformation)
+}
+
+type contractTestProcedureSpec struct {
+	plan.DefaultCost
+	Contract execute.SchemaContract
+}
+
+func (s *contractTestProcedureSpec) Kind() plan.ProcedureKind {
+	return contractTestKind
+}
+
+func (s *contractTestProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createContractTestTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s := spec.(*contractTestProcedureSpec)
+	return execute.NewNarrowTransformation(id, &contractTestTransformation{contract: s.Contract}, a.Allocator())
+}
+
+// contractTestTransformation passes its input through unchanged
+// and declares the configured contract for its output.
+type contractTestTransformation struct {
+	contract execute.SchemaContract
+}
+
+func (t *contractTestTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem memory.Allocator) error {
+	chunk.Retain()
+	return d.Process(chunk)
+}
+
+func (t *contractTestTransformation) SchemaContract() *execute.SchemaContract {
+	return &t.contract
+}
+
+func (t *contractTestTransformation) Close() error {
+	return nil
+}
+
+func TestExecutor_ValidateSchemaContracts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contract execute.SchemaContract
+		validate bool
+		wantErr  string
+	}{
+		{
+			name: "satisfied",
+			contract: execute.SchemaContract{
+				Columns: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value"},
+				},
+				KeyColumns: []string{"_start", "_stop"},
+			},
+			validate: true,
+		},
+		{
+			name: "missing column",
+			contract: execute.SchemaContract{
+				Columns: []flux.ColMeta{{Label: "_field", Type: flux.TString}},
+			},
+			validate: true,
+			wantErr:  `output of contract (*execute_test.contractTestTransformation) violates its schema contract: missing column "_field"`,
+		},
+		{
+			name: "wrong type",
+			contract: execute.SchemaContract{
+				Columns: []flux.ColMeta{{Label: "_value", Type: flux.TInt}},
+			},
+			validate: true,
+			wantErr:  `column "_value" has type float, expected int`,
+		},
+		{
+			name: "missing key column",
+			contract: execute.SchemaContract{
+				KeyColumns: []string{"_measurement"},
+			},
+			validate: true,
+			wantErr:  `column "_measurement" is not part of the group key`,
+		},
+		{
+			name: "disabled",
+			contract: execute.SchemaContract{
+				Columns: []flux.ColMeta{{Label: "_field", Type: flux.TString}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+						[]*executetest.Table{{
+							KeyCols: []string{"_start", "_stop"},
+							ColMeta: []flux.ColMeta{
+								{Label: "_start", Type: flux.TTime},
+								{Label: "_stop", Type: flux.TTime},
+								{Label: "_time", Type: flux.TTime},
+								{Label: "_value", Type: flux.TFloat},
+							},
+							Data: [][]interface{}{
+								{execute.Time(0), execute.Time(5), execute.Time(0), 1.0},
+								{execute.Time(0), execute.Time(5), execute.Time(1), 2.0},
+							},
+						}},
+					)),
+					plan.CreatePhysicalNode("contract", &contractTestProcedureSpec{Contract: tc.contract}),
+					plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+				Resources: flux.ResourceManagement{
+					ConcurrencyQuota: 1,
+					MemoryBytesQuota: math.MaxInt64,
+				},
+				Now: time.Now(),
+			})
+
+			deps := execute.DefaultExecutionDependencies()
+			deps.ExecutionOptions.ValidateSchemaContracts = tc.validate
+			ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+			ctx = deps.Inject(ctx)
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, r := range results {
+				err = r.Tables().Do(func(tbl flux.Table) error {
+					return tbl.Do(func(flux.ColReader) error { return nil })
+				})
+			}
+
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", tc.wantErr)
+			} else if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %q", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -242,6 +242,10 @@ type TransportDataset struct {
 	transports []Transport
 	cache      *RandomAccessGroupLookup
 	mem        memory.Allocator
+
+	// contract validates each chunk before it is sent
+	// downstream when schema contracts are validated.
+	contract *schemaContractChecker
 }
 
 // NewTransportDataset constructs a TransportDataset.
@@ -279,6 +283,12 @@ func (d *TransportDataset) sendMessage(m Message) error {
 
 // Process sends the given Chunk to be processed by the downstream transports.
 func (d *TransportDataset) Process(chunk table.Chunk) error {
+	if d.contract != nil {
+		if err := d.contract.validate(chunk.Key(), chunk.Cols()); err != nil {
+			chunk.Release()
+			return err
+		}
+	}
 	m := &processChunkMsg{
 		srcMessage: srcMessage(d.id),
 		chunk:      chunk,
@@ -295,6 +305,15 @@ func (d *TransportDataset) FlushKey(key flux.GroupKey) error {
 	return d.sendMessage(m)
 }
 
+// Checkpoint sends the checkpoint barrier to the downstream transports.
+func (d *TransportDataset) Checkpoint(b *CheckpointBarrier) error {
+	m := &checkpointMsg{
+		srcMessage: srcMessage(d.id),
+		barrier:    b,
+	}
+	return d.sendMessage(m)
+}
+
 func (d *TransportDataset) Lookup(key flux.GroupKey) (interface{}, bool) {
 	return d.cache.Lookup(key)
 }
@@ -17,13 +17,115 @@ import (
 
 type key int
 
-const executionDependenciesKey key = iota
+const (
+	executionDependenciesKey key = iota
+	aggStatsKey
+)
+
+// DefaultDispatcherThroughput is the number of times the dispatcher
+// runs work for a transformation before yielding to another one
+// when the execution options do not specify a throughput.
+const DefaultDispatcherThroughput = 10
 
 type ExecutionOptions struct {
 	OperatorProfiler   *OperatorProfiler
 	Profilers          []Profiler
 	DefaultMemoryLimit int64
 	ConcurrencyLimit   int
+
+	// AutoConcurrency sets the concurrency quota of a query without
+	// a ConcurrencyLimit to the number of transformations in its plan,
+	// limited to the number of CPUs. It has no effect when the plan
+	// specifies a concurrency quota or a ConcurrencyLimit is set.
+	AutoConcurrency bool
+
+	// ValidateSchemaContracts enables validation of the tables
+	// produced by each transformation that declares a SchemaContract.
+	ValidateSchemaContracts bool
+
+	// Checkpoint enables checkpointing of the query
+	// and resuming it from a previous checkpoint.
+	Checkpoint *CheckpointOptions
+
+	// ResultPriorities maps result names to a scheduling priority.
+	// Work feeding a result with a higher priority is dispatched
+	// before work feeding results with a lower priority so that
+	// the result completes sooner. Results that are not present
+	// have a priority of zero.
+	//
+	// This is a best-effort hint. It does not guarantee the order
+	// in which results complete or produce their tables.
+	ResultPriorities map[string]int
+
+	// OnFirstResult is called the first time any result of
+	// the query receives a table with the name of the result
+	// and the time elapsed since execution started.
+	OnFirstResult func(name string, elapsed time.Duration)
+
+	// MaxPlanNodes is the maximum number of nodes a plan may have.
+	// MaxPlanDepth is the maximum length of a chain of nodes in a plan,
+	// counting from the node that produces a result.
+	// Plans that exceed either limit are rejected before they are executed.
+	//
+	// A limit of zero, the default, means the plan size is unlimited.
+	// Servers that execute queries submitted by users should set both
+	// limits well above the size of their expected queries, such as
+	// 10000 nodes and a depth of 1000, to reject generated plans that
+	// could exhaust the stack while the plan is being walked.
+	MaxPlanNodes int
+	MaxPlanDepth int
+
+	// DispatcherThroughput is the number of times the dispatcher
+	// runs work for a transformation before yielding to another one.
+	// A higher throughput reduces the scheduling overhead of queries
+	// with many small tables while a lower throughput spreads the work
+	// of a few large tables more evenly. It must not be negative.
+	// A throughput of zero uses the throughput of the executor which
+	// is DefaultDispatcherThroughput unless its ExecutorOptions set it.
+	DispatcherThroughput int
+
+	// ProfileNodes enables the collection of execution statistics
+	// for each transformation in the plan. The statistics are reported
+	// in the metadata of the query as a NodeProfile for each node.
+	ProfileNodes bool
+
+	// ExactQuantileSpillThreshold is the number of values the exact
+	// quantile aggregate buffers in memory for each table before the
+	// values are sorted and written to a temporary file. The quantile
+	// is then found by merging the sorted runs of the file. A threshold
+	// of zero, the default, keeps every value in memory.
+	ExactQuantileSpillThreshold int
+
+	// TDigestPoolFraction is the fraction of the memory limit of the
+	// query that the t-digest quantile aggregate may hold in digests it
+	// keeps for reuse once their table has been aggregated. The budget
+	// is measured in bytes so fewer digests are pooled at a higher
+	// compression. A fraction of zero, the default, or a query without
+	// a memory limit pools one digest for each aggregated column.
+	// It must be between zero and one.
+	TDigestPoolFraction float64
+
+	// EmitAggStats enables the collection of statistics that aggregates
+	// report about their internal state, such as the number of centroids
+	// of a t-digest. The statistics are reported in the metadata of the
+	// query once all of the transformations have finished.
+	EmitAggStats bool
+
+	// MaxDuration is the maximum amount of time the query may execute.
+	// The query is aborted with a deadline exceeded error if it has not
+	// finished by then, even if the caller did not set a deadline on its
+	// context. A duration of zero, the default, means there is no limit.
+	// It must not be negative.
+	MaxDuration time.Duration
+
+	// Deterministic makes the tables of each result arrive in the same
+	// order every time the query is executed. The sources of the query
+	// run one at a time in the order of their plan node ids instead of
+	// concurrently, and each result is buffered until it finishes and
+	// then delivers its tables ordered by their group keys. This trades
+	// throughput and memory for output that can be compared with a
+	// golden file. The rows within a table are not reordered.
+	Deterministic bool
 }
 
 // ExecutionDependencies represents the dependencies that a function call
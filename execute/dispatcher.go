@@ -2,6 +2,7 @@ package execute
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"go.uber.org/zap"
@@ -19,9 +20,34 @@ type Dispatcher interface {
 // The throughput is the maximum number of messages to process for this scheduling.
 type ScheduleFunc func(ctx context.Context, throughput int)
 
+// prioritizedDispatcher is implemented by a Dispatcher
+// that can run some work ahead of other work.
+type prioritizedDispatcher interface {
+	// SchedulePriority schedules fn to be executed before any
+	// work that was scheduled with a lower priority.
+	// Work scheduled with Schedule has a priority of zero.
+	SchedulePriority(fn ScheduleFunc, priority int)
+}
+
+// errorDispatcher is implemented by a Dispatcher that can
+// report an error from work that it ran.
+type errorDispatcher interface {
+	// setErr reports the error on the error channel
+	// of the dispatcher if no error has been reported.
+	setErr(err error)
+}
+
+// priorityRing holds the work scheduled with the same priority.
+type priorityRing struct {
+	priority int
+	work     *ring
+}
+
 // poolDispatcher implements Dispatcher using a pool of goroutines.
 type poolDispatcher struct {
-	work   *ring
+	// work holds the scheduled work ordered from the
+	// highest priority to the lowest priority.
+	work   []priorityRing
 	ready  chan struct{}
 	workMu sync.Mutex
 
@@ -35,12 +61,25 @@ type poolDispatcher struct {
 	errC    chan error
 
 	logger *zap.Logger
+
+	// pool runs the scheduled work when the dispatcher shares the
+	// workers of its executor. It is nil when the dispatcher starts
+	// its own workers. The fields below are only used with a pool
+	// and are guarded by workMu.
+	pool *workerPool
+	ctx  context.Context
+	// workers is the maximum number of workers of the pool
+	// that may run the work of the dispatcher at once.
+	workers int
+	// slots is the number of times the dispatcher is queued
+	// on the pool or is running work on one of its workers.
+	slots int
 }
 
 func newPoolDispatcher(throughput int, logger *zap.Logger) *poolDispatcher {
 	return &poolDispatcher{
 		throughput: throughput,
-		work:       newRing(100),
+		work:       []priorityRing{{work: newRing(100)}},
 		ready:      make(chan struct{}, 1),
 		closing:    make(chan struct{}),
 		errC:       make(chan error, 1),
@@ -49,12 +88,23 @@ func newPoolDispatcher(throughput int, logger *zap.Logger) *poolDispatcher {
 }
 
 func (d *poolDispatcher) Schedule(fn ScheduleFunc) {
+	d.SchedulePriority(fn, 0)
+}
+
+// SchedulePriority schedules fn to be executed before any work with
+// a lower priority that has not started yet. Priorities are a hint
+// and do not preempt work that is already running.
+func (d *poolDispatcher) SchedulePriority(fn ScheduleFunc, priority int) {
 	d.workMu.Lock()
 	defer d.workMu.Unlock()
 
 	// Schedule the work and then report to the channel that there
 	// is available work to unblock the worker scheduler thread.
-	d.work.Append(fn)
+	d.ringFor(priority).Append(fn)
+	if d.pool != nil {
+		d.queue()
+		return
+	}
 	select {
 	case d.ready <- struct{}{}:
 		// The ready channel should have a buffer of 1.
@@ -65,7 +115,53 @@ func (d *poolDispatcher) Schedule(fn ScheduleFunc) {
 	}
 }
 
+// ringFor returns the ring for the given priority
+// and creates it if it does not exist.
+// This must be called with workMu held.
+func (d *poolDispatcher) ringFor(priority int) *ring {
+	i := sort.Search(len(d.work), func(i int) bool {
+		return d.work[i].priority <= priority
+	})
+	if i < len(d.work) && d.work[i].priority == priority {
+		return d.work[i].work
+	}
+	d.work = append(d.work, priorityRing{})
+	copy(d.work[i+1:], d.work[i:])
+	d.work[i] = priorityRing{priority: priority, work: newRing(100)}
+	return d.work[i].work
+}
+
+// next returns the scheduled work with the highest priority.
+// This must be called with workMu held.
+func (d *poolDispatcher) next() ScheduleFunc {
+	for _, pr := range d.work {
+		if next := pr.work.Next(); next != nil {
+			return next.(ScheduleFunc)
+		}
+	}
+	return nil
+}
+
+// pending returns the number of scheduled work functions
+// that have not started yet.
+// This must be called with workMu held.
+func (d *poolDispatcher) pending() int {
+	n := 0
+	for _, pr := range d.work {
+		n += pr.work.Len()
+	}
+	return n
+}
+
 func (d *poolDispatcher) Start(n int, ctx context.Context) {
+	if d.pool != nil {
+		d.workMu.Lock()
+		defer d.workMu.Unlock()
+		d.ctx, d.workers = ctx, n
+		d.queue()
+		return
+	}
+
 	d.wg.Add(n)
 	for i := 0; i < n; i++ {
 		go func() {
@@ -142,11 +238,8 @@ func (d *poolDispatcher) run(ctx context.Context) {
 // the dispatcher is closed, or there is no more work in the queue.
 func (d *poolDispatcher) doWork(ctx context.Context) {
 	for {
-		var fn ScheduleFunc
 		d.workMu.Lock()
-		if next := d.work.Next(); next != nil {
-			fn = next.(ScheduleFunc)
-		}
+		fn := d.next()
 		d.workMu.Unlock()
 
 		if fn == nil {
@@ -168,3 +261,152 @@ func (d *poolDispatcher) doWork(ctx context.Context) {
 		}
 	}
 }
+
+// queue queues the dispatcher on its pool once for each pending
+// work function until it has as many slots as the workers it may use.
+// This must be called with workMu held.
+func (d *poolDispatcher) queue() {
+	if d.ctx == nil {
+		// The work is queued when the dispatcher is started.
+		return
+	}
+	for d.slots < d.workers && d.slots < d.pending() {
+		// The slot is added within the lock used by Stop
+		// so it is never added while Stop is waiting
+		// for the slots that remain.
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			return
+		}
+		d.wg.Add(1)
+		d.mu.Unlock()
+
+		d.slots++
+		d.pool.push(d)
+	}
+}
+
+// runPooled runs the next scheduled work function on a worker of the pool.
+// The dispatcher is queued again when there is more work so the workers
+// take turns between the queries that share them. Otherwise, the slot
+// the worker was running is released.
+func (d *poolDispatcher) runPooled() {
+	d.workMu.Lock()
+	var fn ScheduleFunc
+	if !d.stopped() {
+		fn = d.next()
+	}
+	d.workMu.Unlock()
+
+	if fn != nil {
+		d.runPooledWork(fn)
+	}
+
+	d.workMu.Lock()
+	defer d.workMu.Unlock()
+	if fn != nil && !d.stopped() && d.pending() > 0 {
+		d.pool.push(d)
+		return
+	}
+	d.slots--
+	d.wg.Done()
+}
+
+// runPooledWork runs fn and reports a panic as the error of the dispatcher
+// so it does not stop the worker or affect the other queries of the pool.
+func (d *poolDispatcher) runPooledWork(fn ScheduleFunc) {
+	defer d.recover()
+	fn(d.ctx, d.throughput)
+}
+
+// stopped reports whether the context of the dispatcher
+// was canceled or the dispatcher was closed.
+func (d *poolDispatcher) stopped() bool {
+	select {
+	case <-d.ctx.Done():
+		return true
+	case <-d.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// workerPool is a long-lived pool of goroutines that runs the work
+// scheduled on the dispatchers of many queries. A dispatcher is queued
+// once for each worker it may use so the work of a query never takes
+// more workers than its concurrency quota.
+type workerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  *ring
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(n int) *workerPool {
+	p := &workerPool{
+		queue: newRing(100),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer p.wg.Done()
+			p.run()
+		}()
+	}
+	return p
+}
+
+// push queues a dispatcher that has work to run.
+func (p *workerPool) push(d *poolDispatcher) {
+	p.mu.Lock()
+	p.queue.Append(d)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// pop waits for a dispatcher with work to run.
+// It returns nil when the pool is closed and
+// the queued dispatchers have all been run.
+func (p *workerPool) pop() *poolDispatcher {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.queue.Len() == 0 {
+		if p.closed {
+			return nil
+		}
+		p.cond.Wait()
+	}
+	return p.queue.Next().(*poolDispatcher)
+}
+
+// run is the logic executed by each worker goroutine in the pool.
+func (p *workerPool) run() {
+	for {
+		d := p.pop()
+		if d == nil {
+			return
+		}
+		d.runPooled()
+	}
+}
+
+// isClosed reports whether Close was called.
+func (p *workerPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close stops the workers once the work that is
+// queued has been run and waits for them to finish.
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	p.wg.Wait()
+}
@@ -3,6 +3,7 @@ package execute
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -81,3 +82,106 @@ func TestDispatcher_ScheduleMany(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestDispatcher_SchedulePriority(t *testing.T) {
+	d := newPoolDispatcher(10, zaptest.NewLogger(t))
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	schedule := func(priority int) {
+		wg.Add(1)
+		d.SchedulePriority(func(ctx context.Context, throughput int) {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}, priority)
+	}
+
+	// Schedule the work before starting the dispatcher
+	// so a single worker observes all of it at once.
+	for _, priority := range []int{0, -1, 2, 0, 1, 2} {
+		schedule(priority)
+	}
+	d.Start(1, context.Background())
+	wg.Wait()
+	if err := d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{2, 2, 1, 0, 0, -1}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected number of calls -want/+got:\n\t- %d\n\t+ %d", len(want), len(order))
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected order -want/+got:\n\t- %v\n\t+ %v", want, order)
+		}
+	}
+}
+
+func TestDispatcher_Pool(t *testing.T) {
+	pool := newWorkerPool(4)
+	defer pool.Close()
+
+	newDispatcher := func() *poolDispatcher {
+		d := newPoolDispatcher(10, zaptest.NewLogger(t))
+		d.pool = pool
+		return d
+	}
+
+	// A panic is reported by the dispatcher that ran the work.
+	failed := newDispatcher()
+	failed.Start(2, context.Background())
+	failed.Schedule(func(ctx context.Context, throughput int) {
+		panic("expected")
+	})
+
+	// Canceling a query does not cancel the work of the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := newDispatcher()
+	canceled.Start(2, ctx)
+	canceled.Schedule(func(ctx context.Context, throughput int) {
+		t.Error("work was run after the context was canceled")
+	})
+
+	// Work scheduled before the dispatcher is started is run
+	// by no more workers than the dispatcher may use.
+	d := newDispatcher()
+	var (
+		running, calls int32
+		wg             sync.WaitGroup
+	)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		d.Schedule(func(ctx context.Context, throughput int) {
+			defer wg.Done()
+			if n := atomic.AddInt32(&running, 1); n > 1 {
+				t.Errorf("unexpected number of workers: %d", n)
+			}
+			atomic.AddInt32(&calls, 1)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	d.Start(1, context.Background())
+	wg.Wait()
+
+	if err := d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(200); got != want {
+		t.Fatalf("unexpected number of calls -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if err := canceled.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Stop(); err == nil {
+		t.Fatal("expected error")
+	} else if got, want := err.Error(), "panic: expected"; got != want {
+		t.Fatalf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,18 +33,99 @@ type Executor interface {
 
 type executor struct {
 	logger *zap.Logger
+
+	// pool holds the workers shared by the dispatchers of every
+	// query. It is nil when each query starts its own workers.
+	pool *workerPool
+
+	// opts holds the defaults for the queries of the executor.
+	opts ExecutorOptions
+}
+
+// ExecutorOptions are the defaults an executor uses for each query
+// it executes. The ExecutionOptions of a query take precedence over
+// them, so they only apply to the options a query leaves unset.
+type ExecutorOptions struct {
+	// DispatcherThroughput is the dispatcher throughput of queries
+	// that do not specify one. A throughput that is not positive
+	// uses DefaultDispatcherThroughput.
+	DispatcherThroughput int
+
+	// ConcurrencyLimit is the concurrency limit of queries that
+	// do not specify one and AutoConcurrency enables the automatic
+	// concurrency quota for every query. See ExecutionOptions for
+	// how they choose the concurrency quota of a query.
+	ConcurrencyLimit int
+	AutoConcurrency  bool
+
+	// ProfileNodes enables the collection of execution statistics
+	// for the transformations of every query as if each query had
+	// set the ProfileNodes execution option.
+	ProfileNodes bool
 }
 
+// NewExecutor creates an executor with the default ExecutorOptions.
 func NewExecutor(logger *zap.Logger) Executor {
+	return NewExecutorWithOptions(logger, ExecutorOptions{})
+}
+
+// NewExecutorWithOptions creates an executor that uses
+// the given defaults for each query it executes.
+func NewExecutorWithOptions(logger *zap.Logger, opts ExecutorOptions) Executor {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if opts.DispatcherThroughput <= 0 {
+		opts.DispatcherThroughput = DefaultDispatcherThroughput
+	}
 	e := &executor{
 		logger: logger,
+		opts:   opts,
 	}
 	return e
 }
 
+// PooledExecutor is an Executor that runs the work of its queries
+// on a pool of workers that outlives each query.
+type PooledExecutor interface {
+	Executor
+
+	// Close stops the workers of the pool.
+	// It must be called once the queries that were executed
+	// have finished and no more queries will be executed.
+	Close()
+}
+
+// NewPooledExecutor creates an executor that starts the given number
+// of workers once and shares them between the queries it executes,
+// instead of starting new workers for each query.
+// This avoids the cost of starting the workers when many small
+// queries are executed.
+//
+// Each query still uses at most its concurrency quota of workers
+// and the errors and cancellation of a query do not affect the others.
+// If workers is not positive, GOMAXPROCS workers are started.
+func NewPooledExecutor(logger *zap.Logger, workers int) PooledExecutor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	e := &executor{
+		logger: logger,
+		pool:   newWorkerPool(workers),
+		opts:   ExecutorOptions{DispatcherThroughput: DefaultDispatcherThroughput},
+	}
+	return e
+}
+
+func (e *executor) Close() {
+	if e.pool != nil {
+		e.pool.Close()
+	}
+}
+
 type streamContext struct {
 	bounds *Bounds
 }
@@ -67,6 +151,40 @@ type executionState struct {
 
 	dispatcher *poolDispatcher
 	logger     *zap.Logger
+
+	validateContracts bool
+	checkpoints       *checkpointCoordinator
+
+	// priorities holds the scheduling priority for
+	// the transports of each node in the plan.
+	priorities map[plan.Node]int
+
+	// firstResult is notified when the first table is
+	// received by any result. It may be nil.
+	firstResult *firstResultHook
+
+	// nodeProfiles holds the statistics of each transformation
+	// when nodes are profiled. It is nil otherwise.
+	nodeProfiles map[plan.NodeID]*nodeProfile
+
+	// aggStats collects the statistics reported by aggregates
+	// when they are emitted. It is nil otherwise.
+	aggStats *AggStats
+
+	// maxDuration is the maximum duration of the query if it
+	// determines the deadline of the context. It is zero otherwise.
+	maxDuration time.Duration
+
+	// resultNodes holds the plan node that produces each result.
+	resultNodes map[string]plan.Node
+
+	// deterministic runs the sources one at a time and
+	// sorts the tables of each result before they are delivered.
+	deterministic bool
+
+	// defaults holds the options of the executor
+	// for the options the query does not set.
+	defaults ExecutorOptions
 }
 
 func (e *executor) Execute(ctx context.Context, p *plan.Spec, a *memory.Allocator) (map[string]flux.Result, <-chan metadata.Metadata, error) {
@@ -78,18 +196,95 @@ func (e *executor) Execute(ctx context.Context, p *plan.Spec, a *memory.Allocato
 	return es.results, es.metaCh, nil
 }
 
+// newDispatcher creates the dispatcher for a query which
+// runs its work on the workers of the pool if there is one.
+func (e *executor) newDispatcher(throughput int) *poolDispatcher {
+	d := newPoolDispatcher(throughput, e.logger)
+	d.pool = e.pool
+	return d
+}
+
 func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a *memory.Allocator) (*executionState, error) {
-	ctx, cancel := context.WithCancel(ctx)
+	if e.pool != nil && e.pool.isClosed() {
+		return nil, errors.New(codes.Canceled, "executor is closed")
+	}
+	if HaveExecutionDependencies(ctx) {
+		if opts := GetExecutionDependencies(ctx).ExecutionOptions; opts != nil {
+			if err := checkPlanLimits(p, opts.MaxPlanNodes, opts.MaxPlanDepth); err != nil {
+				return nil, err
+			}
+		}
+	}
+	throughput, err := getDispatcherThroughput(ctx, e.opts.DispatcherThroughput)
+	if err != nil {
+		return nil, err
+	}
+	maxDuration, err := getMaxDuration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCycles(p); err != nil {
+		return nil, err
+	}
+
+	var cancel context.CancelFunc
+	if maxDuration > 0 {
+		deadline := time.Now().Add(maxDuration)
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		// The caller may have set an earlier deadline
+		// that aborts the query before the maximum duration.
+		if d, _ := ctx.Deadline(); !d.Equal(deadline) {
+			maxDuration = 0
+		}
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	var aggStats *AggStats
+	if HaveExecutionDependencies(ctx) {
+		if opts := GetExecutionDependencies(ctx).ExecutionOptions; opts != nil && opts.EmitAggStats {
+			// The statistics are found through the context
+			// that is passed to each transformation.
+			aggStats = newAggStats()
+			ctx = context.WithValue(ctx, aggStatsKey, aggStats)
+		}
+	}
 	es := &executionState{
-		p:         p,
-		ctx:       ctx,
-		cancel:    cancel,
-		alloc:     a,
-		resources: p.Resources,
-		results:   make(map[string]flux.Result),
-		// TODO(nathanielc): Have the planner specify the dispatcher throughput
-		dispatcher: newPoolDispatcher(10, e.logger),
-		logger:     e.logger,
+		p:           p,
+		ctx:         ctx,
+		cancel:      cancel,
+		alloc:       a,
+		resources:   p.Resources,
+		results:     make(map[string]flux.Result),
+		resultNodes: make(map[string]plan.Node),
+		dispatcher:  e.newDispatcher(throughput),
+		logger:      e.logger,
+		aggStats:    aggStats,
+		defaults:    e.opts,
+	}
+	es.maxDuration = maxDuration
+	profileNodes := e.opts.ProfileNodes
+	if HaveExecutionDependencies(ctx) {
+		if opts := GetExecutionDependencies(ctx).ExecutionOptions; opts != nil {
+			es.validateContracts = opts.ValidateSchemaContracts
+			if opts.Checkpoint != nil && opts.Checkpoint.Store != nil {
+				es.checkpoints = newCheckpointCoordinator(ctx, opts.Checkpoint)
+			}
+			if len(opts.ResultPriorities) > 0 {
+				priorities, err := nodePriorities(p, opts.ResultPriorities)
+				if err != nil {
+					return nil, err
+				}
+				es.priorities = priorities
+			}
+			if opts.OnFirstResult != nil {
+				es.firstResult = newFirstResultHook(opts.OnFirstResult)
+			}
+			profileNodes = profileNodes || opts.ProfileNodes
+			es.deterministic = opts.Deterministic
+		}
+	}
+	if profileNodes {
+		es.nodeProfiles = make(map[plan.NodeID]*nodeProfile)
 	}
 	v := &createExecutionNodeVisitor{
 		es:    es,
@@ -100,10 +295,33 @@ func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a *me
 		return nil, err
 	}
 
+	if es.checkpoints != nil {
+		if err := es.checkpoints.restore(); err != nil {
+			return nil, err
+		}
+	}
+
+	if es.deterministic {
+		// The plan is walked from its roots which are not in a stable
+		// order so the sources are ordered by their node ids. The
+		// copies of a parallel source keep the order they were added in.
+		sort.SliceStable(es.sources, func(i, j int) bool {
+			return es.sources[i].Label() < es.sources[j].Label()
+		})
+	}
+
 	// Only sources can be a MetadataNode at the moment so allocate enough
 	// space for all of them to report metadata. Not all of them will necessarily
-	// report metadata.
-	es.metaCh = make(chan metadata.Metadata, len(es.sources))
+	// report metadata. The node profiles, aggregate statistics, and
+	// memory usage are reported once all of the transports have finished.
+	metaSize := len(es.sources) + 1
+	if es.nodeProfiles != nil {
+		metaSize++
+	}
+	if es.aggStats != nil {
+		metaSize++
+	}
+	es.metaCh = make(chan metadata.Metadata, metaSize)
 
 	// Choose some default resource limits based on execution options, if necessary.
 	es.chooseDefaultResources(ctx, p)
@@ -156,7 +374,7 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 	// N.b. yields become results here, but other terminal nodes are handled
 	// further below.
 	if yieldSpec, ok := spec.(plan.YieldProcedureSpec); ok {
-		if err := v.generateResult(yieldSpec.YieldName(), node, 0); err != nil {
+		if err := v.generateResult(yieldSpec.YieldName(), node); err != nil {
 			return err
 		}
 		return nil
@@ -216,6 +434,14 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 
 	v.nodes[node] = make([]Node, copies)
 
+	if v.es.checkpoints != nil {
+		if copies > 1 || predCopies > 1 {
+			return errors.Newf(codes.Unimplemented, "checkpointing does not support parallel execution of %s", node.ID())
+		} else if len(node.Predecessors()) > 1 {
+			return errors.Newf(codes.Unimplemented, "checkpointing does not support %s with multiple inputs", node.ID())
+		}
+	}
+
 	// If node is a leaf, create a source
 	if len(node.Predecessors()) == 0 {
 		createSourceFn, ok := procedureToSource[kind]
@@ -233,6 +459,11 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			}
 
 			source.SetLabel(string(node.ID()))
+			if v.es.checkpoints != nil {
+				if err := v.es.checkpoints.addSource(id, source); err != nil {
+					return err
+				}
+			}
 			v.es.sources = append(v.es.sources, source)
 			v.nodes[node][i] = source
 		}
@@ -245,6 +476,21 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			return fmt.Errorf("unsupported procedure %v", kind)
 		}
 
+		// Transformations of a node with a memory limit or that is
+		// profiled allocate from their own allocator.
+		mem := v.es.alloc
+		nodeAlloc, err := v.es.nodeAllocator(ppn)
+		if err != nil {
+			return err
+		}
+		if nodeAlloc != nil {
+			mem = nodeAlloc
+			for i := range ec {
+				ec[i].alloc = nodeAlloc
+			}
+		}
+		profile := v.es.profileNode(node, nodeAlloc)
+
 		for i := 0; i < copies; i++ {
 			id := datasetIDFromNodeID(node.ID(), i)
 
@@ -253,6 +499,9 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			if err != nil {
 				return err
 			}
+			if profile != nil && profile.op == "" {
+				profile.op = OperationType(tr)
+			}
 
 			if ds, ok := ds.(DatasetContext); ok {
 				ds.WithContext(v.es.ctx)
@@ -264,6 +513,24 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 			ds.SetTriggerSpec(ppn.TriggerSpec)
 			v.nodes[node][i] = ds
 
+			if v.es.checkpoints != nil {
+				if err := v.es.checkpoints.addTransformation(id, tr); err != nil {
+					return err
+				}
+			}
+
+			if v.es.validateContracts {
+				if contract := SchemaContractOf(tr); contract != nil {
+					// The contract is validated by the dataset on the chunks
+					// it sends so the validation does not consume the output.
+					d, ok := ds.(*TransportDataset)
+					if !ok {
+						return errors.Newf(codes.Unimplemented, "schema contract of %s (%s) cannot be validated for dataset %T", node.ID(), OperationType(tr), ds)
+					}
+					d.contract = newSchemaContractChecker(*contract, node.ID(), OperationType(tr))
+				}
+			}
+
 			for _, p := range nonYieldPredecessors(node) {
 				// In case (1) above, both copies and predCopies are 1. We link
 				// forward from the only copy of the predecessor node.
@@ -280,7 +547,12 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 				for j := 0; j < predCopies; j++ {
 					// Either i == 0 && j == 0: we are either iterating i, or we are iterating j.
 					executionNode := v.nodes[p][i+j]
-					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, v.es.logger, v.es.alloc)
+					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, v.es.logger, mem)
+					transport.profile = profile
+					transport.priority = v.es.priorities[node]
+					transport.abort = func(err error) {
+						v.es.abortNode(node, err)
+					}
 					v.es.transports = append(v.es.transports, transport)
 					executionNode.AddTransformation(transport)
 				}
@@ -306,7 +578,7 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 		if err != nil {
 			return err
 		}
-		if err := v.generateResult(resultName, node, 0); err != nil {
+		if err := v.generateResult(resultName, node); err != nil {
 			return err
 		}
 	}
@@ -314,7 +586,9 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 }
 
 // generateResult will attach a result to the query for the specified node.
-func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan.Node, idx int) error {
+// If the node runs in parallel, the copies of the node are merged into the
+// result so that each result name is produced once.
+func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan.Node) error {
 	// if the result name is already present in the result set, that's an error.
 	if _, ok := v.es.results[resultName]; ok {
 		// XXX: we produce an error like this in the planner for duplicate yield
@@ -322,9 +596,18 @@ func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan
 		// yields, we need a similar check here.
 		return errors.Newf(codes.Invalid, "tried to produce more than one result with the name %q", resultName)
 	}
-	r := newResult(resultName)
+	copies := v.nodes[skipYields(node)]
+	if len(copies) == 0 {
+		return errors.Newf(codes.Internal, "cannot produce result %q before node %q is created", resultName, skipYields(node).ID())
+	}
+	r := newResult(resultName, skipYields(node).ID(), len(copies))
+	r.firstTable = v.es.firstResult
+	r.sorted = v.es.deterministic
 	v.es.results[resultName] = r
-	v.nodes[skipYields(node)][idx].AddTransformation(r)
+	v.es.resultNodes[resultName] = node
+	for _, n := range copies {
+		n.AddTransformation(r)
+	}
 	return nil
 }
 
@@ -354,6 +637,175 @@ func getResultName(node plan.Node, spec plan.ProcedureSpec, isParallelMerge bool
 	return name, nil
 }
 
+// checkPlanLimits returns an error if the plan has more than maxNodes nodes
+// or is deeper than maxDepth. A limit of zero or less is not enforced.
+//
+// The depth of a root is one and the depth of any other node is one more
+// than the deepest successor from which the walk reaches it. The walk stops
+// as soon as a limit is exceeded so that the recursive walks of a
+// pathologically deep plan are never started. It uses an explicit stack
+// instead of recursion so the depth of the plan does not matter to it.
+func checkPlanLimits(p *plan.Spec, maxNodes, maxDepth int) error {
+	if maxNodes <= 0 && maxDepth <= 0 {
+		return nil
+	}
+
+	// The nodes are visited in the same order as a top down walk
+	// so each root and predecessor is pushed in reverse order.
+	roots := sortedRoots(p)
+	stack := make([]plan.Node, 0, len(roots))
+	for i := len(roots) - 1; i >= 0; i-- {
+		stack = append(stack, roots[i])
+	}
+	depths := make(map[plan.Node]int)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := depths[node]; ok {
+			continue
+		}
+
+		depth := 1
+		for _, succ := range node.Successors() {
+			if d, ok := depths[succ]; ok && d >= depth {
+				depth = d + 1
+			}
+		}
+		depths[node] = depth
+
+		if maxNodes > 0 && len(depths) > maxNodes {
+			return errors.Newf(codes.Invalid, "plan exceeds the maximum of %d nodes", maxNodes)
+		}
+		if maxDepth > 0 && depth > maxDepth {
+			return errors.Newf(codes.Invalid, "plan exceeds the maximum depth of %d at node %q", maxDepth, node.ID())
+		}
+
+		preds := node.Predecessors()
+		for i := len(preds) - 1; i >= 0; i-- {
+			stack = append(stack, preds[i])
+		}
+	}
+	return nil
+}
+
+// checkCycles returns an error naming the nodes of a cycle if the
+// plan contains one. The walks used to build the execution state
+// would otherwise visit a node in a cycle before its predecessors.
+//
+// The walk keeps the path from the root to the current node on an
+// explicit stack instead of recursing so a deep plan cannot exhaust
+// the stack of the goroutine.
+func checkCycles(p *plan.Spec) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	marks := make(map[plan.Node]int)
+
+	// frame is a node on the path and the
+	// index of the next predecessor to visit.
+	type frame struct {
+		node plan.Node
+		next int
+	}
+	var path []frame
+
+	for _, root := range sortedRoots(p) {
+		if marks[root] == visited {
+			continue
+		}
+		marks[root] = visiting
+		path = append(path[:0], frame{node: root})
+		for len(path) > 0 {
+			top := &path[len(path)-1]
+			preds := top.node.Predecessors()
+			if top.next == len(preds) {
+				marks[top.node] = visited
+				path = path[:len(path)-1]
+				continue
+			}
+			pred := preds[top.next]
+			top.next++
+
+			switch marks[pred] {
+			case visited:
+				continue
+			case visiting:
+				// The path follows predecessors so walk it backwards
+				// to name the nodes in the order that data flows.
+				ids := []string{string(pred.ID())}
+				for i := len(path) - 1; i >= 0; i-- {
+					ids = append(ids, string(path[i].node.ID()))
+					if path[i].node == pred {
+						break
+					}
+				}
+				return errors.Newf(codes.Internal, "plan contains a cycle: %s", strings.Join(ids, " -> "))
+			}
+			marks[pred] = visiting
+			path = append(path, frame{node: pred})
+		}
+	}
+	return nil
+}
+
+// sortedRoots returns the roots of the plan sorted by their id
+// so the plan is always walked in the same order.
+func sortedRoots(p *plan.Spec) []plan.Node {
+	roots := make([]plan.Node, 0, len(p.Roots))
+	for root := range p.Roots {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].ID() < roots[j].ID()
+	})
+	return roots
+}
+
+// nodePriorities determines the scheduling priority of each node in the plan
+// from the priorities of the results it feeds. A node that feeds multiple
+// results uses the highest priority of those results.
+func nodePriorities(p *plan.Spec, resultPriorities map[string]int) (map[plan.Node]int, error) {
+	priorities := make(map[plan.Node]int)
+
+	var prioritize func(node plan.Node, priority int)
+	prioritize = func(node plan.Node, priority int) {
+		if p, ok := priorities[node]; ok && p >= priority {
+			return
+		}
+		priorities[node] = priority
+		for _, pred := range node.Predecessors() {
+			prioritize(pred, priority)
+		}
+	}
+
+	err := p.TopDownWalk(func(node plan.Node) error {
+		spec := node.ProcedureSpec()
+		name := ""
+		if yieldSpec, ok := spec.(plan.YieldProcedureSpec); ok {
+			name = yieldSpec.YieldName()
+		} else if len(node.Successors()) == 0 {
+			_, isParallelMerge := node.(*plan.PhysicalPlanNode).OutputAttrs[plan.ParallelMergeKey]
+			resultName, err := getResultName(node, spec, isParallelMerge)
+			if err != nil {
+				return err
+			}
+			name = resultName
+		}
+
+		if name != "" {
+			// Results without a priority still need to be recorded
+			// so a node feeding them is not given a lower priority.
+			prioritize(node, resultPriorities[name])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return priorities, nil
+}
+
 func (es *executionState) validate() error {
 	if es.resources.ConcurrencyQuota == 0 {
 		return errors.New(codes.Invalid, "execution state must have a non-zero concurrency quota")
@@ -373,8 +825,51 @@ func getResourceLimits(ctx context.Context) (int64, int) {
 	return execOptions.DefaultMemoryLimit, execOptions.ConcurrencyLimit
 }
 
+// getAutoConcurrency returns whether the concurrency quota
+// should scale with the number of CPUs, if exec options are present.
+func getAutoConcurrency(ctx context.Context) bool {
+	if !HaveExecutionDependencies(ctx) {
+		return false
+	}
+	execOptions := GetExecutionDependencies(ctx).ExecutionOptions
+	return execOptions != nil && execOptions.AutoConcurrency
+}
+
+// getDispatcherThroughput returns the dispatcher throughput
+// from exec options, if present, or the given default throughput.
+func getDispatcherThroughput(ctx context.Context, defaultThroughput int) (int, error) {
+	if !HaveExecutionDependencies(ctx) {
+		return defaultThroughput, nil
+	}
+	execOptions := GetExecutionDependencies(ctx).ExecutionOptions
+	if execOptions == nil || execOptions.DispatcherThroughput == 0 {
+		return defaultThroughput, nil
+	} else if execOptions.DispatcherThroughput < 0 {
+		return 0, errors.Newf(codes.Invalid, "dispatcher throughput must be positive, got %d", execOptions.DispatcherThroughput)
+	}
+	return execOptions.DispatcherThroughput, nil
+}
+
+// getMaxDuration returns the maximum duration of the query
+// from exec options, if present, or zero if there is no limit.
+func getMaxDuration(ctx context.Context) (time.Duration, error) {
+	if !HaveExecutionDependencies(ctx) {
+		return 0, nil
+	}
+	execOptions := GetExecutionDependencies(ctx).ExecutionOptions
+	if execOptions == nil {
+		return 0, nil
+	} else if execOptions.MaxDuration < 0 {
+		return 0, errors.Newf(codes.Invalid, "max duration must not be negative, got %v", execOptions.MaxDuration)
+	}
+	return execOptions.MaxDuration, nil
+}
+
 func (es *executionState) chooseDefaultResources(ctx context.Context, p *plan.Spec) {
 	defaultMemoryLimit, concurrencyLimit := getResourceLimits(ctx)
+	if concurrencyLimit == 0 {
+		concurrencyLimit = es.defaults.ConcurrencyLimit
+	}
 
 	// Update memory quota
 	if es.resources.MemoryBytesQuota == 0 {
@@ -390,31 +885,69 @@ func (es *executionState) chooseDefaultResources(ctx context.Context, p *plan.Sp
 		// quota equal to the number of transformations and limits
 		// it to the value specified.
 		if concurrencyLimit > 0 {
-			concurrencyQuota := 0
-			_ = p.TopDownWalk(func(node plan.Node) error {
-				// Do not include source nodes in the node list as
-				// they do not use the dispatcher.
-				if len(node.Predecessors()) > 0 {
-					addend := 1
-					ppn := node.(*plan.PhysicalPlanNode)
-					if attr, ok := ppn.OutputAttrs[plan.ParallelRunKey]; ok {
-						addend = attr.(plan.ParallelRunAttribute).Factor
-					}
-					concurrencyQuota += addend
-				}
-				return nil
-			})
+			es.resources.ConcurrencyQuota = transformationConcurrency(p, concurrencyLimit)
+		} else if getAutoConcurrency(ctx) || es.defaults.AutoConcurrency {
+			// Without a limit, auto concurrency limits the quota
+			// to the number of CPUs that are available instead.
+			es.resources.ConcurrencyQuota = transformationConcurrency(p, runtime.NumCPU())
+		}
+	}
+}
 
-			if concurrencyQuota > int(concurrencyLimit) {
-				concurrencyQuota = int(concurrencyLimit)
-			} else if concurrencyQuota == 0 {
-				concurrencyQuota = 1
+// transformationConcurrency returns the number of transformations in the plan,
+// counting each parallel instance, limited to the given value.
+// It is at least one.
+func transformationConcurrency(p *plan.Spec, limit int) int {
+	concurrencyQuota := 0
+	_ = p.TopDownWalk(func(node plan.Node) error {
+		// Do not include source nodes in the node list as
+		// they do not use the dispatcher.
+		if len(node.Predecessors()) > 0 {
+			addend := 1
+			ppn := node.(*plan.PhysicalPlanNode)
+			if attr, ok := ppn.OutputAttrs[plan.ParallelRunKey]; ok {
+				addend = attr.(plan.ParallelRunAttribute).Factor
 			}
-			es.resources.ConcurrencyQuota = concurrencyQuota
+			concurrencyQuota += addend
+		}
+		return nil
+	})
+
+	if concurrencyQuota > limit {
+		concurrencyQuota = limit
+	} else if concurrencyQuota == 0 {
+		concurrencyQuota = 1
+	}
+	return concurrencyQuota
+}
+
+// nodeAllocator returns the allocator for the transformations of a node
+// if it has a memory limit or nodes are profiled. The allocator allocates
+// from the allocator of the query so the memory is counted against both.
+// It returns nil if the node uses the allocator of the query.
+func (es *executionState) nodeAllocator(ppn *plan.PhysicalPlanNode) (*memory.Allocator, error) {
+	attr, hasLimit := ppn.OutputAttrs[plan.MemoryLimitKey]
+	if !hasLimit && es.nodeProfiles == nil {
+		return nil, nil
+	}
+
+	alloc := &memory.Allocator{
+		Name: fmt.Sprintf("node %q", ppn.ID()),
+	}
+	if es.alloc != nil {
+		alloc.Allocator = es.alloc
+	}
+	if hasLimit {
+		limit := attr.(plan.MemoryLimitAttribute).Bytes
+		if limit <= 0 {
+			return nil, errors.Newf(codes.Invalid, "memory limit of node %q must be positive, got %d", ppn.ID(), limit)
 		}
+		alloc.Limit = &limit
 	}
+	return alloc, nil
 }
 
+// abort aborts every result of the query and cancels it.
 func (es *executionState) abort(err error) {
 	for _, r := range es.results {
 		r.(*result).abort(err)
@@ -422,26 +955,69 @@ func (es *executionState) abort(err error) {
 	es.cancel()
 }
 
+// abortNode aborts the results that are downstream of the node
+// that failed. Results on independent parts of the plan are not
+// aborted so they can complete. The query is canceled when all
+// of the results are aborted.
+func (es *executionState) abortNode(node plan.Node, err error) {
+	downstream := make(map[plan.Node]bool)
+	var walk func(n plan.Node)
+	walk = func(n plan.Node) {
+		if downstream[n] {
+			return
+		}
+		downstream[n] = true
+		for _, succ := range n.Successors() {
+			walk(succ)
+		}
+	}
+	walk(node)
+
+	aborted := 0
+	for name, r := range es.results {
+		r := r.(*result)
+		if downstream[es.resultNodes[name]] {
+			r.abort(err)
+		}
+		if r.isAborted() {
+			aborted++
+		}
+	}
+	if aborted == len(es.results) {
+		es.cancel()
+	}
+}
+
+// ctxErr returns the error that the query is aborted with
+// when its context is done.
+func (es *executionState) ctxErr() error {
+	err := es.ctx.Err()
+	if es.maxDuration > 0 && err == context.DeadlineExceeded {
+		return errors.Newf(codes.DeadlineExceeded, "query exceeded the maximum duration of %v", es.maxDuration)
+	}
+	return err
+}
+
 func (es *executionState) do() {
 	var wg sync.WaitGroup
-	for _, src := range es.sources {
+	if es.deterministic {
+		// Each source runs after the previous one has
+		// finished so its tables are produced after them.
 		wg.Add(1)
-		go func(src Source) {
-			ctx := es.ctx
-			if ctxWithSpan, span := StartSpanFromContext(ctx, reflect.TypeOf(src).String(), src.Label()); span != nil {
-				ctx = ctxWithSpan
-				defer span.Finish()
-			}
+		go func() {
 			defer wg.Done()
-
-			// Setup panic handling on the source goroutines
-			defer es.recover()
-			src.Run(ctx)
-
-			if mdn, ok := src.(MetadataNode); ok {
-				es.metaCh <- mdn.Metadata()
+			for _, src := range es.sources {
+				es.runSource(src)
 			}
-		}(src)
+		}()
+	} else {
+		for _, src := range es.sources {
+			wg.Add(1)
+			go func(src Source) {
+				defer wg.Done()
+				es.runSource(src)
+			}(src)
+		}
 	}
 
 	wg.Add(1)
@@ -454,7 +1030,7 @@ func (es *executionState) do() {
 			select {
 			case <-t.Finished():
 			case <-es.ctx.Done():
-				es.abort(es.ctx.Err())
+				es.abort(es.ctxErr())
 			case err := <-es.dispatcher.Err():
 				if err != nil {
 					es.abort(err)
@@ -466,14 +1042,58 @@ func (es *executionState) do() {
 		if err != nil {
 			es.abort(err)
 		}
+
+		if es.nodeProfiles != nil {
+			es.metaCh <- es.nodeMetadata()
+		}
+		if es.aggStats != nil {
+			es.metaCh <- es.aggStats.metadata()
+		}
+		es.metaCh <- es.memoryMetadata()
 	}()
 
+	done := make(chan struct{})
+	if es.checkpoints != nil {
+		go es.checkpoints.run(done)
+	}
+	if es.maxDuration > 0 {
+		// Abort the query when it exceeds the maximum duration
+		// even if no transport is waiting on the context.
+		go func() {
+			select {
+			case <-es.ctx.Done():
+				if es.ctx.Err() == context.DeadlineExceeded {
+					es.abort(es.ctxErr())
+				}
+			case <-done:
+			}
+		}()
+	}
+
 	go func() {
 		defer close(es.metaCh)
+		defer close(done)
 		wg.Wait()
 	}()
 }
 
+// runSource runs the source and reports its metadata.
+func (es *executionState) runSource(src Source) {
+	ctx := es.ctx
+	if ctxWithSpan, span := StartSpanFromContext(ctx, reflect.TypeOf(src).String(), src.Label()); span != nil {
+		ctx = ctxWithSpan
+		defer span.Finish()
+	}
+
+	// Setup panic handling on the source goroutines
+	defer es.recover()
+	src.Run(ctx)
+
+	if mdn, ok := src.(MetadataNode); ok {
+		es.metaCh <- mdn.Metadata()
+	}
+}
+
 type ParallelOpts struct {
 	Group  int
 	Factor int
@@ -485,6 +1105,10 @@ type executionContext struct {
 	parents       []DatasetID
 	streamContext streamContext
 	parallelOpts  ParallelOpts
+
+	// alloc is the allocator of a node with a memory limit or that
+	// is profiled. The allocator of the query is used if it is nil.
+	alloc *memory.Allocator
 }
 
 func resolveTime(qt flux.Time, now time.Time) Time {
@@ -504,6 +1128,9 @@ func (ec executionContext) StreamContext() StreamContext {
 }
 
 func (ec executionContext) Allocator() *memory.Allocator {
+	if ec.alloc != nil {
+		return ec.alloc
+	}
 	return ec.es.alloc
 }
 
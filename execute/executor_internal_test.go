@@ -0,0 +1,275 @@
+package execute
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest/spec"
+	"go.uber.org/zap"
+)
+
+type priorityTestYieldSpec struct {
+	spec.MockProcedureSpec
+	name string
+}
+
+func (s *priorityTestYieldSpec) YieldName() string {
+	return s.name
+}
+
+func TestNodePriorities(t *testing.T) {
+	ps := spec.CreatePlanSpec(&spec.PlanSpec{
+		Nodes: []plan.Node{
+			spec.CreatePhysicalMockNode("from0"),
+			spec.CreatePhysicalMockNode("a"),
+			plan.CreatePhysicalNode("yield-dash", &priorityTestYieldSpec{name: "dash"}),
+			spec.CreatePhysicalMockNode("b"),
+			spec.CreatePhysicalMockNode("from1"),
+			spec.CreatePhysicalMockNode("c"),
+			plan.CreatePhysicalNode("yield-low", &priorityTestYieldSpec{name: "low"}),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{0, 3},
+			{4, 5},
+			{5, 6},
+		},
+	})
+
+	priorities, err := nodePriorities(ps, map[string]int{
+		"dash": 5,
+		"low":  -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[plan.NodeID]int, len(priorities))
+	for node, priority := range priorities {
+		got[node.ID()] = priority
+	}
+	want := map[plan.NodeID]int{
+		"from0":      5,
+		"a":          5,
+		"yield-dash": 5,
+		// b produces the default result which has no priority.
+		"b":         0,
+		"from1":     -1,
+		"c":         -1,
+		"yield-low": -1,
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatalf("unexpected priorities -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestCreateExecutionState_Cycle(t *testing.T) {
+	ps := spec.CreatePlanSpec(&spec.PlanSpec{
+		Nodes: []plan.Node{
+			spec.CreatePhysicalMockNode("from"),
+			spec.CreatePhysicalMockNode("a"),
+			spec.CreatePhysicalMockNode("b"),
+			spec.CreatePhysicalMockNode("c"),
+			spec.CreatePhysicalMockNode("yield"),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{2, 3},
+			{3, 1},
+			{3, 4},
+		},
+	})
+
+	e := &executor{logger: zap.NewNop()}
+	_, err := e.createExecutionState(context.Background(), ps, &memory.Allocator{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := errors.New(codes.Internal, "plan contains a cycle: c -> a -> b -> c")
+	if !cmp.Equal(want, err) {
+		t.Fatalf("unexpected error -want/+got:\n%s", cmp.Diff(want, err))
+	}
+}
+
+func TestCheckPlanLimits(t *testing.T) {
+	//  from0   from1
+	//    |       |
+	//    a       |
+	//    |       |
+	//    b ----- c
+	//            |
+	//          yield
+	ps := spec.CreatePlanSpec(&spec.PlanSpec{
+		Nodes: []plan.Node{
+			spec.CreatePhysicalMockNode("from0"),
+			spec.CreatePhysicalMockNode("a"),
+			spec.CreatePhysicalMockNode("b"),
+			spec.CreatePhysicalMockNode("from1"),
+			spec.CreatePhysicalMockNode("c"),
+			spec.CreatePhysicalMockNode("yield"),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{2, 4},
+			{3, 4},
+			{4, 5},
+		},
+	})
+
+	testCases := []struct {
+		name     string
+		maxNodes int
+		maxDepth int
+		wantErr  error
+	}{
+		{
+			name: "unlimited",
+		},
+		{
+			name:     "within limits",
+			maxNodes: 6,
+			maxDepth: 5,
+		},
+		{
+			name:     "too many nodes",
+			maxNodes: 5,
+			wantErr:  errors.New(codes.Invalid, "plan exceeds the maximum of 5 nodes"),
+		},
+		{
+			name:     "too deep",
+			maxDepth: 4,
+			wantErr:  errors.New(codes.Invalid, `plan exceeds the maximum depth of 4 at node "from0"`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPlanLimits(ps, tc.maxNodes, tc.maxDepth)
+			if !cmp.Equal(tc.wantErr, err) {
+				t.Fatalf("unexpected error -want/+got:\n%s", cmp.Diff(tc.wantErr, err))
+			}
+		})
+	}
+}
+
+func TestCheckPlanLimits_Deep(t *testing.T) {
+	// A chain of nodes that is much deeper than the limit.
+	const n = 100000
+	nodes := make([]plan.Node, n)
+	edges := make([][2]int, n-1)
+	for i := range nodes {
+		nodes[i] = spec.CreatePhysicalMockNode(fmt.Sprintf("n%d", i))
+		if i > 0 {
+			edges[i-1] = [2]int{i - 1, i}
+		}
+	}
+	ps := spec.CreatePlanSpec(&spec.PlanSpec{
+		Nodes: nodes,
+		Edges: edges,
+	})
+
+	if err := checkCycles(ps); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPlanLimits(ps, 0, n); err != nil {
+		t.Fatal(err)
+	}
+	err := checkPlanLimits(ps, 0, 1000)
+	want := errors.Newf(codes.Invalid, `plan exceeds the maximum depth of 1000 at node "n%d"`, n-1001)
+	if !cmp.Equal(want, err) {
+		t.Fatalf("unexpected error -want/+got:\n%s", cmp.Diff(want, err))
+	}
+}
+
+func TestCreateExecutionState_PlanLimits(t *testing.T) {
+	ps := spec.CreatePlanSpec(&spec.PlanSpec{
+		Nodes: []plan.Node{
+			spec.CreatePhysicalMockNode("from"),
+			spec.CreatePhysicalMockNode("a"),
+			spec.CreatePhysicalMockNode("yield"),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+	})
+
+	deps := DefaultExecutionDependencies()
+	deps.ExecutionOptions.MaxPlanDepth = 2
+	ctx := deps.Inject(context.Background())
+
+	e := &executor{logger: zap.NewNop()}
+	_, err := e.createExecutionState(ctx, ps, &memory.Allocator{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := errors.New(codes.Invalid, `plan exceeds the maximum depth of 2 at node "from"`)
+	if !cmp.Equal(want, err) {
+		t.Fatalf("unexpected error -want/+got:\n%s", cmp.Diff(want, err))
+	}
+}
+
+func TestGetDispatcherThroughput(t *testing.T) {
+	testCases := []struct {
+		name       string
+		throughput int
+		executor   int
+		want       int
+		wantErr    error
+	}{
+		{
+			name: "default",
+			want: DefaultDispatcherThroughput,
+		},
+		{
+			name:     "executor default",
+			executor: 50,
+			want:     50,
+		},
+		{
+			name:       "set with executor default",
+			throughput: 100,
+			executor:   50,
+			want:       100,
+		},
+		{
+			name:       "set",
+			throughput: 100,
+			want:       100,
+		},
+		{
+			name:       "negative",
+			throughput: -1,
+			wantErr:    errors.New(codes.Invalid, "dispatcher throughput must be positive, got -1"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			deps := DefaultExecutionDependencies()
+			deps.ExecutionOptions.DispatcherThroughput = tc.throughput
+			ctx := deps.Inject(context.Background())
+
+			executor := tc.executor
+			if executor == 0 {
+				executor = DefaultDispatcherThroughput
+			}
+			got, err := getDispatcherThroughput(ctx, executor)
+			if !cmp.Equal(tc.wantErr, err) {
+				t.Fatalf("unexpected error -want/+got:\n%s", cmp.Diff(tc.wantErr, err))
+			}
+			if got != tc.want {
+				t.Errorf("unexpected dispatcher throughput -want/+got:\n\t- %d\n\t+ %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package execute_test
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	"math"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/metadata"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/plan/plantest"
 	"github.com/influxdata/flux/runtime"
@@ -25,6 +29,8 @@ import (
 func init() {
 	execute.RegisterSource(executetest.FromTestKind, executetest.CreateFromSource)
 	execute.RegisterSource(executetest.AllocatingFromTestKind, executetest.CreateAllocatingFromSource)
+	execute.RegisterSource(blockingTestSourceKind, createBlockingTestSource)
+	execute.RegisterTransformation(failingTestKind, createFailingTestTransformation)
 	execute.RegisterTransformation(executetest.ToTestKind, executetest.CreateToTransformation)
 	plan.RegisterProcedureSpecWithSideEffect(executetest.ToTestKind, executetest.NewToProcedure, executetest.ToTestKind)
 }
@@ -799,3 +805,847 @@ func TestExecutor_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestExecutor_OnFirstResult(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{
+					{
+						KeyCols: []string{"t0"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t0", Type: flux.TString},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{{"a", 1.0}},
+					},
+					{
+						KeyCols: []string{"t0"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t0", Type: flux.TString},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{{"b", 2.0}},
+					},
+				},
+			)),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("first")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	var names []string
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.OnFirstResult = func(name string, elapsed time.Duration) {
+		if elapsed < 0 {
+			t.Errorf("unexpected negative elapsed time %v", elapsed)
+		}
+		names = append(names, name)
+	}
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"first"}; !cmp.Equal(want, names) {
+		t.Fatalf("unexpected callbacks -want/+got:\n%s", cmp.Diff(want, names))
+	}
+}
+
+func TestExecutor_Deterministic(t *testing.T) {
+	newTable := func(t0 string, v float64) *executetest.Table {
+		return &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{t0, v}},
+		}
+	}
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from0", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{newTable("c", 3.0), newTable("a", 1.0)},
+			)),
+			plan.CreatePhysicalNode("from1", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{newTable("d", 4.0), newTable("b", 2.0)},
+			)),
+			plan.CreatePhysicalNode("union", &universe.UnionProcedureSpec{}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 2},
+			{1, 2},
+			{2, 3},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 2,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.Deterministic = true
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if err := results["_result"].Tables().Do(func(tbl flux.Table) error {
+		got = append(got, tbl.Key().ValueString(0))
+		return tbl.Do(func(flux.ColReader) error { return nil })
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b", "c", "d"}; !cmp.Equal(want, got) {
+		t.Fatalf("unexpected table order -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestExecutor_ResultNodeID(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{{1.0}},
+				}},
+			)),
+			plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("sum")),
+			plan.CreatePhysicalNode("count", &universe.CountProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{0, 3},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]plan.NodeID)
+	for name, r := range results {
+		nr, ok := r.(execute.NodeResult)
+		if !ok {
+			t.Fatalf("result %q does not implement execute.NodeResult", name)
+		}
+		got[name] = nr.NodeID()
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]plan.NodeID{
+		"sum":                 "sum",
+		plan.DefaultYieldName: "count",
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatalf("unexpected result nodes -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestExecutor_Pooled(t *testing.T) {
+	newSpec := func() *plan.Spec {
+		return plantest.CreatePlanSpec(&plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+					[]*executetest.Table{{
+						ColMeta: []flux.ColMeta{
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{{1.0}, {2.0}, {3.0}},
+					}},
+				)),
+				plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+					SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+				}),
+				plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+			},
+			Edges: [][2]int{
+				{0, 1},
+				{1, 2},
+			},
+			Resources: flux.ResourceManagement{
+				ConcurrencyQuota: 2,
+				MemoryBytesQuota: math.MaxInt64,
+			},
+			Now: time.Now(),
+		})
+	}
+
+	exe := execute.NewPooledExecutor(zaptest.NewLogger(t), 2)
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+
+	// The queries share the workers of the executor.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, _, err := exe.Execute(ctx, newSpec(), executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var got []float64
+			if err := results["_result"].Tables().Do(func(tbl flux.Table) error {
+				return tbl.Do(func(cr flux.ColReader) error {
+					vs := cr.Floats(0)
+					for i := 0; i < vs.Len(); i++ {
+						got = append(got, vs.Value(i))
+					}
+					return nil
+				})
+			}); err != nil {
+				t.Error(err)
+				return
+			}
+			if want := []float64{6.0}; !cmp.Equal(want, got) {
+				t.Errorf("unexpected result -want/+got:\n%s", cmp.Diff(want, got))
+			}
+		}()
+	}
+	wg.Wait()
+
+	exe.Close()
+	if _, _, err := exe.Execute(ctx, newSpec(), executetest.UnlimitedAllocator); err == nil {
+		t.Fatal("expected an error after the executor was closed")
+	} else if got, want := errors.Code(err), codes.Canceled; got != want {
+		t.Fatalf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
+
+func TestExecutor_ProfileNodes(t *testing.T) {
+	// The spec is created for each execution
+	// because its source can only be run once.
+	newSpec := func() *plan.Spec {
+		return plantest.CreatePlanSpec(&plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+					[]*executetest.Table{{
+						KeyCols: []string{"t0"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t0", Type: flux.TString},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{"a", 1.0},
+							{"a", 2.0},
+						},
+					}},
+				)),
+				plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+					SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+				}),
+				plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+			},
+			Edges: [][2]int{
+				{0, 1},
+				{1, 2},
+			},
+			Resources: flux.ResourceManagement{
+				ConcurrencyQuota: 1,
+				MemoryBytesQuota: math.MaxInt64,
+			},
+			Now: time.Now(),
+		})
+	}
+
+	// The nodes are profiled if either the query
+	// or the executor enables the option.
+	for _, executorOption := range []bool{false, true} {
+		executorOption := executorOption
+		t.Run(fmt.Sprintf("executor option %v", executorOption), func(t *testing.T) {
+			deps := execute.DefaultExecutionDependencies()
+			deps.ExecutionOptions.ProfileNodes = !executorOption
+			ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+			ctx = deps.Inject(ctx)
+
+			exe := execute.NewExecutorWithOptions(zaptest.NewLogger(t), execute.ExecutorOptions{
+				ProfileNodes: executorOption,
+			})
+			results, metaCh, err := exe.Execute(ctx, newSpec(), executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if err := r.Tables().Do(func(tbl flux.Table) error {
+					return tbl.Do(func(flux.ColReader) error { return nil })
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			md := make(metadata.Metadata)
+			for m := range metaCh {
+				md.AddAll(m)
+			}
+			v, err := md.Get(execute.NodeProfileKeyPrefix + "sum")
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile, ok := v.(execute.NodeProfile)
+			if !ok {
+				t.Fatalf("unexpected metadata value %T", v)
+			}
+			if want, got := "sum", profile.Label; want != got {
+				t.Errorf("unexpected label -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+			if profile.Duration <= 0 {
+				t.Errorf("expected a positive duration, got %v", profile.Duration)
+			}
+			if profile.MaxAllocated <= 0 {
+				t.Errorf("expected memory to be allocated, got %d bytes", profile.MaxAllocated)
+			}
+			if _, err := md.Get(execute.NodeProfileKeyPrefix + "from-test"); err == nil {
+				t.Error("unexpected profile for a source")
+			}
+		})
+	}
+}
+
+func TestExecutor_MemoryUsage(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", 1.0},
+						{"a", 2.0},
+					},
+				}},
+			)),
+			plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: 1 << 20,
+		},
+		Now: time.Now(),
+	})
+
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	alloc := &memory.Allocator{}
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, metaCh, err := exe.Execute(ctx, spec, alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	md := make(metadata.Metadata)
+	for m := range metaCh {
+		md.AddAll(m)
+	}
+	v, err := md.Get(execute.MemoryUsageKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, ok := v.(execute.MemoryUsage)
+	if !ok {
+		t.Fatalf("unexpected metadata value %T", v)
+	}
+	if want, got := int64(1<<20), usage.MemoryBytesQuota; want != got {
+		t.Errorf("unexpected memory quota -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := alloc.MaxAllocated(), usage.MaxAllocated; want != got {
+		t.Errorf("unexpected max allocated -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if usage.MaxAllocated <= 0 {
+		t.Errorf("expected memory to be allocated, got %d bytes", usage.MaxAllocated)
+	}
+}
+
+func TestExecutor_EmitAggStats(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", 1.0},
+						{"a", 2.0},
+						{"a", 2.0},
+					},
+				}},
+			)),
+			plan.CreatePhysicalNode("quantile", &universe.TDigestQuantileProcedureSpec{
+				Quantile:              0.5,
+				Compression:           1000,
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.EmitAggStats = true
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, metaCh, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	md := make(metadata.Metadata)
+	for m := range metaCh {
+		md.AddAll(m)
+	}
+	want := []interface{}{universe.TDigestStats{
+		Compression: 1000,
+		Centroids:   3,
+		Weight:      3,
+	}}
+	if got := md[universe.TDigestStatsKey]; !cmp.Equal(want, got) {
+		t.Fatalf("unexpected digest statistics -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+const blockingTestSourceKind = "blocking-test-source"
+
+// blockingTestProcedureSpec is a source that waits for its context
+// to be done, reports the error of the context, and then waits to
+// be released before it finishes.
+type blockingTestProcedureSpec struct {
+	plan.DefaultCost
+	Err     chan error
+	Release chan struct{}
+}
+
+func (s *blockingTestProcedureSpec) Kind() plan.ProcedureKind {
+	return blockingTestSourceKind
+}
+
+func (s *blockingTestProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createBlockingTestSource(spec plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	return &blockingTestSource{
+		spec: spec.(*blockingTestProcedureSpec),
+		d:    execute.NewTransportDataset(id, a.Allocator()),
+	}, nil
+}
+
+type blockingTestSource struct {
+	execute.ExecutionNode
+	spec *blockingTestProcedureSpec
+	d    *execute.TransportDataset
+}
+
+func (s *blockingTestSource) AddTransformation(t execute.Transformation) {
+	s.d.AddTransformation(t)
+}
+
+func (s *blockingTestSource) Run(ctx context.Context) {
+	<-ctx.Done()
+	s.spec.Err <- ctx.Err()
+	<-s.spec.Release
+	s.d.Finish(ctx.Err())
+}
+
+func TestExecutor_MaxDuration(t *testing.T) {
+	src := &blockingTestProcedureSpec{
+		Err:     make(chan error, 1),
+		Release: make(chan struct{}),
+	}
+	defer close(src.Release)
+
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("blocking", src),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.MaxDuration = 10 * time.Millisecond
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The source sees the context of the query is cancelled
+	// when the maximum duration is exceeded.
+	select {
+	case err := <-src.Err:
+		if want := context.DeadlineExceeded; err != want {
+			t.Errorf("unexpected source context error -want/+got:\n\t- %v\n\t+ %v", want, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the source context to be done")
+	}
+
+	for _, r := range results {
+		err := r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if want, got := codes.DeadlineExceeded, flux.ErrorCode(err); want != got {
+			t.Errorf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+		}
+	}
+}
+
+func TestExecutor_NodeMemoryLimit(t *testing.T) {
+	sum := plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	})
+	sum.SetOutputAttr(plan.MemoryLimitKey, plan.MemoryLimitAttribute{Bytes: 1})
+
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", 1.0},
+						{"a", 2.0},
+					},
+				}},
+			)),
+			sum,
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, &memory.Allocator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		err = r.Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		})
+	}
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want, got := codes.ResourceExhausted, flux.ErrorCode(err); want != got {
+		t.Errorf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	var limitErr memory.LimitExceededError
+	if !stderrors.As(err, &limitErr) {
+		t.Fatalf("expected a memory limit error, got %v", err)
+	}
+	if want, got := `node "sum"`, limitErr.Name; want != got {
+		t.Errorf("unexpected allocator name -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+	if want, got := int64(1), limitErr.Limit; want != got {
+		t.Errorf("unexpected limit -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
+const failingTestKind = "failing-test"
+
+// failingTestProcedureSpec is a transformation that fails
+// when it processes a table by either returning an error
+// or panicking.
+type failingTestProcedureSpec struct {
+	plan.DefaultCost
+	Panic bool
+}
+
+func (s *failingTestProcedureSpec) Kind() plan.ProcedureKind {
+	return failingTestKind
+}
+
+func (s *failingTestProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createFailingTestTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	d := execute.NewPassthroughDataset(id)
+	return &failingTestTransformation{
+		d:     d,
+		panic: spec.(*failingTestProcedureSpec).Panic,
+	}, d, nil
+}
+
+type failingTestTransformation struct {
+	execute.ExecutionNode
+	d     *execute.PassthroughDataset
+	panic bool
+}
+
+func (t *failingTestTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *failingTestTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	tbl.Done()
+	if t.panic {
+		panic(errors.New(codes.Internal, "expected"))
+	}
+	return errors.New(codes.Invalid, "expected")
+}
+
+func (t *failingTestTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+
+func (t *failingTestTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+
+func (t *failingTestTransformation) Finish(id execute.DatasetID, err error) {
+	t.d.Finish(err)
+}
+
+func TestExecutor_TransformationFailure(t *testing.T) {
+	testCases := []struct {
+		name     string
+		panic    bool
+		wantCode codes.Code
+		wantErr  string
+	}{
+		{
+			name:     "error",
+			wantCode: codes.Invalid,
+			wantErr:  "runtime error: expected",
+		},
+		{
+			name:     "panic",
+			panic:    true,
+			wantCode: codes.Internal,
+			wantErr:  "panic: expected",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+						[]*executetest.Table{{
+							KeyCols: []string{"t0"},
+							ColMeta: []flux.ColMeta{
+								{Label: "t0", Type: flux.TString},
+								{Label: "_value", Type: flux.TFloat},
+							},
+							Data: [][]interface{}{{"a", 1.0}},
+						}},
+					)),
+					plan.CreatePhysicalNode("fail", &failingTestProcedureSpec{Panic: tc.panic}),
+					plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+				Resources: flux.ResourceManagement{
+					ConcurrencyQuota: 1,
+					MemoryBytesQuota: math.MaxInt64,
+				},
+				Now: time.Now(),
+			})
+
+			ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				err = r.Tables().Do(func(tbl flux.Table) error {
+					return tbl.Do(func(flux.ColReader) error { return nil })
+				})
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if want, got := tc.wantCode, flux.ErrorCode(err); want != got {
+				t.Errorf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+			if want, got := tc.wantErr, err.Error(); want != got {
+				t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+			var nodeErr *execute.NodeError
+			if !stderrors.As(err, &nodeErr) {
+				t.Fatalf("expected a node error, got %v", err)
+			}
+			if want, got := plan.NodeID("fail"), nodeErr.NodeID; want != got {
+				t.Errorf("unexpected node -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+			if want, got := plan.ProcedureKind(failingTestKind), nodeErr.Kind; want != got {
+				t.Errorf("unexpected node kind -want/+got:\n\t- %s\n\t+ %s", want, got)
+			}
+		})
+	}
+}
+
+func TestExecutor_PartialResults(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from-test", executetest.NewFromProcedureSpec(
+				[]*executetest.Table{{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", 1.0},
+						{"a", 2.0},
+					},
+				}},
+			)),
+			plan.CreatePhysicalNode("fail", &failingTestProcedureSpec{Panic: true}),
+			plan.CreatePhysicalNode("yield0", executetest.NewYieldProcedureSpec("failed")),
+			plan.CreatePhysicalNode("yield1", executetest.NewYieldProcedureSpec("passed")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{0, 3},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, _, err := exe.Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = results["failed"].Tables().Do(func(tbl flux.Table) error {
+		return tbl.Do(func(flux.ColReader) error { return nil })
+	})
+	if want, got := "panic: expected", fmt.Sprint(err); want != got {
+		t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, got)
+	}
+
+	n := 0
+	if err := results["passed"].Tables().Do(func(tbl flux.Table) error {
+		return tbl.Do(func(cr flux.ColReader) error {
+			n += cr.Len()
+			return nil
+		})
+	}); err != nil {
+		t.Fatalf("unexpected error for independent result: %s", err)
+	}
+	if want, got := 2, n; want != got {
+		t.Errorf("unexpected number of rows -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+}
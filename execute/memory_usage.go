@@ -0,0 +1,33 @@
+package execute
+
+import (
+	"github.com/influxdata/flux/metadata"
+)
+
+// MemoryUsageKey is the metadata key of the MemoryUsage of a query.
+const MemoryUsageKey = "flux/memory-usage"
+
+// MemoryUsage reports the peak memory of a query along with its
+// memory quota so the headroom of the query can be measured.
+type MemoryUsage struct {
+	// MaxAllocated is the peak number of bytes allocated
+	// by the allocator of the query.
+	MaxAllocated int64
+	// MemoryBytesQuota is the memory quota of the query.
+	MemoryBytesQuota int64
+}
+
+// memoryMetadata returns the metadata that reports the memory usage
+// of the query. It is reported once the transports have finished
+// so the peak includes the memory of every transformation.
+func (es *executionState) memoryMetadata() metadata.Metadata {
+	usage := MemoryUsage{
+		MemoryBytesQuota: es.resources.MemoryBytesQuota,
+	}
+	if es.alloc != nil {
+		usage.MaxAllocated = es.alloc.MaxAllocated()
+	}
+	md := make(metadata.Metadata, 1)
+	md.Add(MemoryUsageKey, usage)
+	return md
+}
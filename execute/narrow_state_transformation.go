@@ -82,3 +82,7 @@ func (n *narrowStateTransformation) Finish(id DatasetID, err error) {
 func (n *narrowStateTransformation) OperationType() string {
 	return OperationType(n.t)
 }
+
+func (n *narrowStateTransformation) SchemaContract() *SchemaContract {
+	return SchemaContractOf(n.t)
+}
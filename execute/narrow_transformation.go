@@ -43,12 +43,25 @@ func (n *narrowTransformation) ProcessMessage(m Message) error {
 		return n.t.Process(m.TableChunk(), n.d, n.d.mem)
 	case FlushKeyMsg:
 		return n.d.FlushKey(m.Key())
+	case CheckpointMsg:
+		return n.d.Checkpoint(m.Barrier())
 	case ProcessMsg:
 		panic("unreachable")
 	}
 	return nil
 }
 
+// checkpointSupport implements checkpointParticipant.
+// A narrow transformation has no state so it only
+// forwards the barrier.
+func (n *narrowTransformation) checkpointSupport() (supported, stateful bool) {
+	return true, false
+}
+
+func (n *narrowTransformation) restoreCheckpoint(data []byte) error {
+	return nil
+}
+
 // Finish is implemented to remain compatible with legacy upstreams.
 func (n *narrowTransformation) Finish(id DatasetID, err error) {
 	err = Close(err, n.t)
@@ -58,3 +71,7 @@ func (n *narrowTransformation) Finish(id DatasetID, err error) {
 func (n *narrowTransformation) OperationType() string {
 	return OperationType(n.t)
 }
+
+func (n *narrowTransformation) SchemaContract() *SchemaContract {
+	return SchemaContractOf(n.t)
+}
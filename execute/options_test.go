@@ -3,6 +3,7 @@ package execute
 import (
 	"context"
 	"math"
+	"runtime"
 	"testing"
 
 	"github.com/influxdata/flux"
@@ -21,7 +22,9 @@ func TestExecuteOptions(t *testing.T) {
 		name               string
 		spec               *planspec.PlanSpec
 		concurrencyLimit   int
+		autoConcurrency    bool
 		defaultMemoryLimit int64
+		executor           ExecutorOptions
 		want               runWith
 	}{
 		{
@@ -176,6 +179,116 @@ func TestExecuteOptions(t *testing.T) {
 				concurrencyQuota: 4,
 			},
 		},
+		{
+			// Auto concurrency sets the concurrency quota based on the
+			// number of non-source nodes (5), limited to the number of CPUs.
+			name: "via-options-auto-concurrency",
+			spec: &planspec.PlanSpec{
+				Nodes: []plan.Node{
+					planspec.CreatePhysicalMockNode("0"),
+					planspec.CreatePhysicalMockNode("1"),
+					planspec.CreatePhysicalMockNode("2"),
+					planspec.CreatePhysicalMockNode("3"),
+					planspec.CreatePhysicalMockNode("root-0"),
+					planspec.CreatePhysicalMockNode("root-1"),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{3, 5},
+				},
+			},
+			autoConcurrency: true,
+			want: runWith{
+				memoryBytesQuota: math.MaxInt64,
+				concurrencyQuota: 5,
+			},
+		},
+		{
+			// An explicit concurrency limit takes precedence
+			// over auto concurrency.
+			name: "via-options-auto-concurrency-limited",
+			spec: &planspec.PlanSpec{
+				Nodes: []plan.Node{
+					planspec.CreatePhysicalMockNode("0"),
+					planspec.CreatePhysicalMockNode("1"),
+					planspec.CreatePhysicalMockNode("2"),
+					planspec.CreatePhysicalMockNode("3"),
+					planspec.CreatePhysicalMockNode("root-0"),
+					planspec.CreatePhysicalMockNode("root-1"),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{3, 5},
+				},
+			},
+			concurrencyLimit: 1,
+			autoConcurrency:  true,
+			want: runWith{
+				memoryBytesQuota: math.MaxInt64,
+				concurrencyQuota: 1,
+			},
+		},
+		{
+			// The concurrency limit of the executor is
+			// used when the options do not set one.
+			name: "via-executor-concurrency-limit",
+			spec: &planspec.PlanSpec{
+				Nodes: []plan.Node{
+					planspec.CreatePhysicalMockNode("0"),
+					planspec.CreatePhysicalMockNode("1"),
+					planspec.CreatePhysicalMockNode("2"),
+					planspec.CreatePhysicalMockNode("3"),
+					planspec.CreatePhysicalMockNode("root-0"),
+					planspec.CreatePhysicalMockNode("root-1"),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{3, 5},
+				},
+			},
+			executor: ExecutorOptions{ConcurrencyLimit: 3},
+			want: runWith{
+				memoryBytesQuota: math.MaxInt64,
+				concurrencyQuota: 3,
+			},
+		},
+		{
+			// The concurrency limit of the options
+			// takes precedence over the executor.
+			name: "via-options-over-executor",
+			spec: &planspec.PlanSpec{
+				Nodes: []plan.Node{
+					planspec.CreatePhysicalMockNode("0"),
+					planspec.CreatePhysicalMockNode("1"),
+					planspec.CreatePhysicalMockNode("2"),
+					planspec.CreatePhysicalMockNode("3"),
+					planspec.CreatePhysicalMockNode("root-0"),
+					planspec.CreatePhysicalMockNode("root-1"),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{3, 5},
+				},
+			},
+			concurrencyLimit: 2,
+			executor:         ExecutorOptions{ConcurrencyLimit: 3},
+			want: runWith{
+				memoryBytesQuota: math.MaxInt64,
+				concurrencyQuota: 2,
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -200,6 +313,7 @@ func TestExecuteOptions(t *testing.T) {
 		if tc.concurrencyLimit != 0 {
 			execDeps.ExecutionOptions.ConcurrencyLimit = tc.concurrencyLimit
 		}
+		execDeps.ExecutionOptions.AutoConcurrency = tc.autoConcurrency
 
 		// Construct a basic execution state and choose the default resources.
 		es := &executionState{
@@ -207,6 +321,7 @@ func TestExecuteOptions(t *testing.T) {
 			ctx:       ctx,
 			resources: outputPlan.Resources,
 			logger:    zaptest.NewLogger(t),
+			defaults:  tc.executor,
 		}
 		es.chooseDefaultResources(ctx, outputPlan)
 
@@ -219,6 +334,9 @@ func TestExecuteOptions(t *testing.T) {
 				tc.want.memoryBytesQuota, es.resources.MemoryBytesQuota)
 		}
 
+		if tc.autoConcurrency && tc.concurrencyLimit == 0 && tc.want.concurrencyQuota > runtime.NumCPU() {
+			tc.want.concurrencyQuota = runtime.NumCPU()
+		}
 		if es.resources.ConcurrencyQuota != tc.want.concurrencyQuota {
 			t.Errorf("Expected concurrency quota of %v, but execution state has %v",
 				tc.want.concurrencyQuota, es.resources.ConcurrencyQuota)
@@ -346,6 +346,99 @@ func TestParallel_Execute(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Each parallel copy sorts the values of its table into the
+			// state of an exact quantile and the sorted states of the
+			// tables with the same group key are merged after the merge.
+			name: `parallel-from-exact-quantile-merge`,
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("parallel-from-test",
+						executetest.NewParallelFromProcedureSpec(
+							[]*executetest.ParallelTable{
+								{
+									Table: &executetest.Table{
+										KeyCols: []string{"_start", "_stop"},
+										ColMeta: []flux.ColMeta{
+											{Label: "_start", Type: flux.TTime},
+											{Label: "_stop", Type: flux.TTime},
+											{Label: "_time", Type: flux.TTime},
+											{Label: "_value", Type: flux.TFloat},
+											{Label: executetest.ParallelGroupColName, Type: flux.TInt},
+										},
+										Data: [][]interface{}{
+											{execute.Time(0), execute.Time(10), execute.Time(0), 5.0, -1},
+											{execute.Time(0), execute.Time(10), execute.Time(2), 1.0, -1},
+											{execute.Time(0), execute.Time(10), execute.Time(4), 9.0, -1},
+											{execute.Time(0), execute.Time(10), execute.Time(6), 3.0, -1},
+										},
+									},
+									ResidesOnPartition: 0,
+								},
+								{
+									Table: &executetest.Table{
+										KeyCols: []string{"_start", "_stop"},
+										ColMeta: []flux.ColMeta{
+											{Label: "_start", Type: flux.TTime},
+											{Label: "_stop", Type: flux.TTime},
+											{Label: "_time", Type: flux.TTime},
+											{Label: "_value", Type: flux.TFloat},
+											{Label: executetest.ParallelGroupColName, Type: flux.TInt},
+										},
+										Data: [][]interface{}{
+											{execute.Time(0), execute.Time(10), execute.Time(1), 2.0, -1},
+											{execute.Time(0), execute.Time(10), execute.Time(3), 8.0, -1},
+											{execute.Time(0), execute.Time(10), execute.Time(5), 4.0, -1},
+										},
+									},
+									ResidesOnPartition: 1,
+								},
+							}),
+						plantest.WithOutputAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2})),
+					plantest.CreatePhysicalNode("state", &universe.ExactQuantileStateProcedureSpec{
+						ExactQuantileAggProcedureSpec: &universe.ExactQuantileAggProcedureSpec{
+							Quantile:              0.75,
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+						plantest.WithRequiredAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2}),
+						plantest.WithOutputAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2})),
+					plantest.CreatePhysicalNode("merge", &universe.PartitionMergeProcedureSpec{},
+						plantest.WithRequiredAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2}),
+						plantest.WithOutputAttr(plan.ParallelMergeKey, plan.ParallelMergeAttribute{Factor: 2})),
+					plantest.CreatePhysicalNode("quantile", &universe.MergeExactQuantileProcedureSpec{
+						ExactQuantileAggProcedureSpec: &universe.ExactQuantileAggProcedureSpec{
+							Quantile:              0.75,
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					}),
+					plantest.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+				},
+			},
+			want: map[string][]*executetest.Table{
+				"_result": []*executetest.Table{
+					{
+						KeyCols: []string{"_start", "_stop"},
+						ColMeta: []flux.ColMeta{
+							{Label: "_start", Type: flux.TTime},
+							{Label: "_stop", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							// The values are 1, 2, 3, 4, 5, 8, 9 so the
+							// quantile is between 5 and 8.
+							{execute.Time(0), execute.Time(10), 6.5},
+						},
+					},
+				},
+			},
+		},
 		{
 			// Error: the from node does not specify the parallel-run
 			// attribute. It is required its successor, filter.
@@ -541,3 +634,108 @@ func TestParallel_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestParallel_TerminalNode(t *testing.T) {
+	from := func() *executetest.ParallelFromProcedureSpec {
+		tables := make([]*executetest.ParallelTable, 2)
+		for i := range tables {
+			tables[i] = &executetest.ParallelTable{
+				Table: &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: executetest.ParallelGroupColName, Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{fmt.Sprintf("t%d", i), float64(i), -1},
+					},
+				},
+				ResidesOnPartition: i,
+			}
+		}
+		return executetest.NewParallelFromProcedureSpec(tables)
+	}
+	want := make([]*executetest.Table, 2)
+	for i := range want {
+		want[i] = &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: executetest.ParallelGroupColName, Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{fmt.Sprintf("t%d", i), float64(i), int64(i)},
+			},
+		}
+	}
+
+	// The copies of a parallel node that produces a result
+	// are merged into a single result with the tables of each.
+	testcases := []struct {
+		name string
+		spec *plantest.PlanSpec
+	}{
+		{
+			name: "terminal",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("parallel-from-test", from(),
+						plantest.WithOutputAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2})),
+				},
+			},
+		},
+		{
+			name: "yield",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("parallel-from-test", from(),
+						plantest.WithOutputAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: 2})),
+					plantest.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Resources = flux.ResourceManagement{
+				ConcurrencyQuota: 1,
+				MemoryBytesQuota: math.MaxInt64,
+			}
+			tc.spec.Now = time.Now()
+			ps := plantest.CreatePlanSpec(tc.spec)
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+			results, _, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("expected one result, got %d", len(results))
+			}
+			var got []*executetest.Table
+			if err := results[plan.DefaultYieldName].Tables().Do(func(tbl flux.Table) error {
+				cb, err := executetest.ConvertTable(tbl)
+				if err != nil {
+					return err
+				}
+				got = append(got, cb)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			executetest.NormalizeTables(got)
+			executetest.NormalizeTables(want)
+			if !cmp.Equal(want, got) {
+				t.Error("unexpected result -want/+got", cmp.Diff(want, got))
+			}
+		})
+	}
+}
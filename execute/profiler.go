@@ -3,11 +3,15 @@ package execute
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/metadata"
+	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/values"
 	"github.com/opentracing/opentracing-go"
 )
@@ -33,6 +37,7 @@ func init() {
 	RegisterProfilerFactories(
 		createQueryProfiler,
 		createOperatorProfiler,
+		createNodeProfiler,
 	)
 }
 
@@ -399,6 +404,14 @@ func (s *QueryProfiler) getTableBuilder(q flux.Query, alloc *memory.Allocator) (
 		strings.Join(stats.RuntimeErrors, "\n"),
 	}
 	for key, values := range stats.Metadata {
+		switch values[0].(type) {
+		case NodeProfile:
+			// Node profiles are reported by the node profiler.
+			continue
+		case MemoryUsage:
+			// The peak memory is reported in MaxAllocated.
+			continue
+		}
 		var ty flux.ColType
 		if intValue, ok := values[0].(int); ok {
 			ty = flux.TInt
@@ -431,3 +444,149 @@ func (s *QueryProfiler) getTableBuilder(q flux.Query, alloc *memory.Allocator) (
 	}
 	return b, nil
 }
+
+// NodeProfileKeyPrefix is the prefix of the metadata key for the
+// NodeProfile of each node. The key ends with the ID of the node.
+const NodeProfileKeyPrefix = "profiler/node/"
+
+// NodeProfile holds the execution statistics of a transformation.
+type NodeProfile struct {
+	// Type is the operation type of the transformation.
+	Type string
+	// Label is the ID of the node in the plan.
+	Label string
+	// Duration is the wall time spent processing messages.
+	Duration time.Duration
+	// MaxAllocated is the peak number of bytes allocated.
+	MaxAllocated int64
+}
+
+// nodeProfile collects the statistics of a node while it executes.
+// Every copy of a node that runs in parallel shares the same profile.
+type nodeProfile struct {
+	op       string
+	alloc    *memory.Allocator
+	duration int64
+}
+
+func (p *nodeProfile) addDuration(d time.Duration) {
+	atomic.AddInt64(&p.duration, int64(d))
+}
+
+// profileNode creates the profile of a node if nodes are profiled.
+// The memory of the node is measured with the allocator of the node.
+func (es *executionState) profileNode(node plan.Node, alloc *memory.Allocator) *nodeProfile {
+	if es.nodeProfiles == nil {
+		return nil
+	}
+	profile := &nodeProfile{alloc: alloc}
+	es.nodeProfiles[node.ID()] = profile
+	return profile
+}
+
+// nodeMetadata returns the metadata that reports the profile of each node.
+func (es *executionState) nodeMetadata() metadata.Metadata {
+	md := make(metadata.Metadata, len(es.nodeProfiles))
+	for id, profile := range es.nodeProfiles {
+		md.Add(NodeProfileKeyPrefix+string(id), NodeProfile{
+			Type:         profile.op,
+			Label:        string(id),
+			Duration:     time.Duration(atomic.LoadInt64(&profile.duration)),
+			MaxAllocated: profile.alloc.MaxAllocated(),
+		})
+	}
+	return md
+}
+
+// NodeProfiler reports the wall time and peak memory of each
+// transformation from the NodeProfile metadata of the query.
+type NodeProfiler struct{}
+
+func createNodeProfiler() Profiler {
+	return &NodeProfiler{}
+}
+
+func (s *NodeProfiler) Name() string {
+	return "node"
+}
+
+func (s *NodeProfiler) GetResult(q flux.Query, alloc *memory.Allocator) (flux.Table, error) {
+	b, err := s.getTableBuilder(q, alloc)
+	if err != nil {
+		return nil, err
+	}
+	return b.Table()
+}
+
+// GetSortedResult is identical to GetResult, except it calls Sort()
+// on the ColListTableBuilder to make testing easier.
+// sortKeys and desc are passed directly into the Sort() call
+func (s *NodeProfiler) GetSortedResult(q flux.Query, alloc *memory.Allocator, desc bool, sortKeys ...string) (flux.Table, error) {
+	b, err := s.getTableBuilder(q, alloc)
+	if err != nil {
+		return nil, err
+	}
+	b.Sort(sortKeys, desc)
+	return b.Table()
+}
+
+func (s *NodeProfiler) getTableBuilder(q flux.Query, alloc *memory.Allocator) (*ColListTableBuilder, error) {
+	groupKey := NewGroupKey(
+		[]flux.ColMeta{
+			{
+				Label: "_measurement",
+				Type:  flux.TString,
+			},
+		},
+		[]values.Value{
+			values.NewString("profiler/node"),
+		},
+	)
+	b := NewColListTableBuilder(groupKey, alloc)
+	colMeta := []flux.ColMeta{
+		{
+			Label: "_measurement",
+			Type:  flux.TString,
+		},
+		{
+			Label: "Type",
+			Type:  flux.TString,
+		},
+		{
+			Label: "Label",
+			Type:  flux.TString,
+		},
+		{
+			Label: "Duration",
+			Type:  flux.TInt,
+		},
+		{
+			Label: "MaxAllocated",
+			Type:  flux.TInt,
+		},
+	}
+	for _, col := range colMeta {
+		if _, err := b.AddCol(col); err != nil {
+			return nil, err
+		}
+	}
+
+	var profiles []NodeProfile
+	q.Statistics().Metadata.Range(func(key string, value interface{}) bool {
+		if profile, ok := value.(NodeProfile); ok && strings.HasPrefix(key, NodeProfileKeyPrefix) {
+			profiles = append(profiles, profile)
+		}
+		return true
+	})
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Label < profiles[j].Label
+	})
+	for _, profile := range profiles {
+		b.AppendString(0, "profiler/node")
+		b.AppendString(1, profile.Type)
+		b.AppendString(2, profile.Label)
+		b.AppendInt(3, profile.Duration.Nanoseconds())
+		b.AppendInt(4, profile.MaxAllocated)
+	}
+	return b, nil
+}
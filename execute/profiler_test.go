@@ -115,6 +115,11 @@ func TestQueryProfiler_GetResult(t *testing.T) {
 			"influxdb/scanned-bytes":  []interface{}{10},
 			"influxdb/scanned-values": []interface{}{11},
 			"flux/query-plan":         []interface{}{"query plan"},
+			// The memory usage is not reported in its own column.
+			execute.MemoryUsageKey: []interface{}{execute.MemoryUsage{
+				MaxAllocated:     8,
+				MemoryBytesQuota: 100,
+			}},
 		},
 	})
 	wantStr := `
@@ -142,3 +147,48 @@ func TestQueryProfiler_GetResult(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestNodeProfiler_GetResult(t *testing.T) {
+	p := &execute.NodeProfiler{}
+	q := &mock.Query{}
+	q.SetStatistics(flux.Statistics{
+		Metadata: metadata.Metadata{
+			"flux/query-plan": []interface{}{"query plan"},
+			execute.NodeProfileKeyPrefix + "sum2": []interface{}{execute.NodeProfile{
+				Type:         "*universe.sumTransformation",
+				Label:        "sum2",
+				Duration:     2000,
+				MaxAllocated: 64,
+			}},
+			execute.NodeProfileKeyPrefix + "filter1": []interface{}{execute.NodeProfile{
+				Type:         "*universe.filterTransformation",
+				Label:        "filter1",
+				Duration:     1000,
+				MaxAllocated: 128,
+			}},
+		},
+	})
+	wantStr := `
+#datatype,string,long,string,string,string,long,long
+#group,false,false,true,false,false,false,false
+#default,_profiler,,,,,,
+,result,table,_measurement,Type,Label,Duration,MaxAllocated
+,,0,profiler/node,*universe.filterTransformation,filter1,1000,128
+,,0,profiler/node,*universe.sumTransformation,sum2,2000,64
+`
+	q.Done()
+	tbl, err := p.GetResult(q, &memory.Allocator{})
+	if err != nil {
+		t.Error(err)
+	}
+	result := table.NewProfilerResult(tbl)
+	got := flux.NewSliceResultIterator([]flux.Result{&result})
+	dec := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{})
+	want, e := dec.Decode(ioutil.NopCloser(strings.NewReader(wantStr)))
+	if e != nil {
+		t.Error(err)
+	}
+	if err := executetest.EqualResultIterators(want, got); err != nil {
+		t.Fatal(err)
+	}
+}
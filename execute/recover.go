@@ -55,3 +55,53 @@ func (d *poolDispatcher) recover() {
 		}
 	}
 }
+
+func (t *consecutiveTransport) recover() {
+	if e := recover(); e != nil {
+		// We had a panic, report it with the node that failed.
+		err, ok := e.(error)
+		if !ok {
+			err = fmt.Errorf("%v", e)
+		}
+
+		if errors.Code(err) != codes.ResourceExhausted {
+			err = errors.Wrap(err, codes.Internal, "panic")
+			if entry := t.logger.Check(zapcore.InfoLevel, "Transformation panic"); entry != nil {
+				entry.Stack = string(debug.Stack())
+				entry.Write(zap.Error(err), zap.String("node", t.label))
+			}
+		}
+		err = t.nodeError(err)
+
+		if t.abort != nil {
+			// Only abort the results that depend on this node
+			// so the rest of the query can complete.
+			t.abort(err)
+			t.fail(err)
+			return
+		}
+		if d, ok := t.dispatcher.(errorDispatcher); ok {
+			d.setErr(err)
+			return
+		}
+		// Let the dispatcher recover from the panic
+		// if it cannot report the error directly.
+		panic(err)
+	}
+}
+
+// recoverFinish recovers from a panic of a transformation that
+// is being finished after it already panicked. The error of the
+// first panic has been reported, so the second one is only logged.
+func (t *consecutiveTransport) recoverFinish() {
+	if e := recover(); e != nil {
+		err, ok := e.(error)
+		if !ok {
+			err = fmt.Errorf("%v", e)
+		}
+		if entry := t.logger.Check(zapcore.InfoLevel, "Transformation panic while finishing"); entry != nil {
+			entry.Stack = string(debug.Stack())
+			entry.Write(zap.Error(err), zap.String("node", t.label))
+		}
+	}
+}
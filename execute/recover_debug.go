@@ -5,3 +5,6 @@ package execute
 
 func (es *executionState) recover() {}
 func (d *poolDispatcher) recover()  {}
+
+func (t *consecutiveTransport) recover()       {}
+func (t *consecutiveTransport) recoverFinish() {}
@@ -0,0 +1,68 @@
+//go:build !debug
+// +build !debug
+
+package execute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan/plantest/spec"
+	"go.uber.org/zap/zaptest"
+)
+
+// finishPanicTransformation panics when it is finished.
+type finishPanicTransformation struct {
+	ExecutionNode
+}
+
+func (t *finishPanicTransformation) RetractTable(id DatasetID, key flux.GroupKey) error {
+	return nil
+}
+
+func (t *finishPanicTransformation) Process(id DatasetID, tbl flux.Table) error {
+	tbl.Done()
+	return nil
+}
+
+func (t *finishPanicTransformation) UpdateWatermark(id DatasetID, mark Time) error {
+	return nil
+}
+
+func (t *finishPanicTransformation) UpdateProcessingTime(id DatasetID, pt Time) error {
+	return nil
+}
+
+func (t *finishPanicTransformation) Finish(id DatasetID, err error) {
+	panic(errors.New(codes.Internal, "expected on finish"))
+}
+
+func TestConsecutiveTransport_FailPanic(t *testing.T) {
+	tr := newConsecutiveTransport(
+		context.Background(),
+		nil,
+		&finishPanicTransformation{},
+		spec.CreatePhysicalMockNode("fail"),
+		zaptest.NewLogger(t),
+		memory.DefaultAllocator,
+	)
+
+	// The transformation panics again when the transport
+	// finishes it with the error of its first panic.
+	tr.transition(running)
+	err := errors.New(codes.Internal, "expected")
+	tr.fail(err)
+
+	select {
+	case <-tr.Finished():
+	default:
+		t.Fatal("expected the transport to be finished")
+	}
+	if want, got := err, tr.err(); want != got {
+		t.Errorf("unexpected error -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
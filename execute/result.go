@@ -1,22 +1,49 @@
 package execute
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/plan"
 )
 
+// NodeResult is a result that knows the plan node that produced it.
+// The results returned by the executor implement this interface.
+type NodeResult interface {
+	flux.Result
+	// NodeID returns the id of the plan node whose output is the result.
+	// A yield is not reported; the node that it yields is reported instead.
+	NodeID() plan.NodeID
+}
+
 // result implements both the Transformation and Result interfaces,
 // mapping the pushed based Transformation API to the pull based Result interface.
 type result struct {
 	ExecutionNode
-	name string
+	name   string
+	nodeID plan.NodeID
 
 	mu     sync.Mutex
 	tables chan resultMessage
 
 	abortErr chan error
 	aborted  chan struct{}
+
+	// firstTable is notified when the result
+	// receives a table. It may be nil.
+	firstTable *firstResultHook
+
+	// sorted buffers the tables of the result until it
+	// finishes and delivers them in group key order.
+	sorted bool
+
+	// pending is the number of datasets that feed the result
+	// and have not finished. The result finishes with the last
+	// one so the copies of a parallel node share one result.
+	pending int32
 }
 
 type resultMessage struct {
@@ -24,9 +51,13 @@ type resultMessage struct {
 	err   error
 }
 
-func newResult(name string) *result {
+// newResult creates a result that receives the tables of the
+// given number of datasets and finishes once all of them have.
+func newResult(name string, nodeID plan.NodeID, parents int) *result {
 	return &result{
-		name: name,
+		name:    name,
+		nodeID:  nodeID,
+		pending: int32(parents),
 		// TODO(nathanielc): Currently this buffer needs to be big enough hold all result tables :(
 		tables:   make(chan resultMessage, 1000),
 		abortErr: make(chan error, 1),
@@ -37,12 +68,20 @@ func newResult(name string) *result {
 func (s *result) Name() string {
 	return s.name
 }
+
+func (s *result) NodeID() plan.NodeID {
+	return s.nodeID
+}
+
 func (s *result) RetractTable(DatasetID, flux.GroupKey) error {
 	//TODO implement
 	return nil
 }
 
 func (s *result) Process(id DatasetID, tbl flux.Table) error {
+	if s.firstTable != nil {
+		s.firstTable.notify(s.name)
+	}
 	select {
 	case s.tables <- resultMessage{
 		table: tbl,
@@ -57,6 +96,14 @@ func (s *result) Tables() flux.TableIterator {
 }
 
 func (s *result) Do(f func(flux.Table) error) error {
+	if s.sorted {
+		return s.doSorted(f)
+	}
+	return s.do(f)
+}
+
+// do calls f with each table in the order it is received.
+func (s *result) do(f func(flux.Table) error) error {
 	for {
 		select {
 		case err := <-s.abortErr:
@@ -75,6 +122,38 @@ func (s *result) Do(f func(flux.Table) error) error {
 	}
 }
 
+// doSorted buffers each table of the result and calls f with
+// the tables ordered by their group keys once the result has finished.
+func (s *result) doSorted(f func(flux.Table) error) error {
+	var tables []flux.BufferedTable
+	defer func() {
+		for _, tbl := range tables {
+			tbl.Done()
+		}
+	}()
+
+	if err := s.do(func(tbl flux.Table) error {
+		cpy, err := CopyTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, cpy)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Key().Less(tables[j].Key())
+	})
+	for _, tbl := range tables {
+		if err := f(tbl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *result) UpdateWatermark(id DatasetID, mark Time) error {
 	//Nothing to do
 	return nil
@@ -93,7 +172,9 @@ func (s *result) Finish(id DatasetID, err error) {
 		case <-s.aborted:
 		}
 	}
-	close(s.tables)
+	if atomic.AddInt32(&s.pending, -1) == 0 {
+		close(s.tables)
+	}
 }
 
 // Abort the result with the given error
@@ -115,3 +196,34 @@ func (s *result) abort(err error) {
 	s.abortErr <- err
 	close(s.aborted)
 }
+
+// isAborted reports whether the result has been aborted.
+func (s *result) isAborted() bool {
+	select {
+	case <-s.aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// firstResultHook calls a function the first
+// time any result of a query receives a table.
+type firstResultHook struct {
+	once  sync.Once
+	start time.Time
+	fn    func(name string, elapsed time.Duration)
+}
+
+func newFirstResultHook(fn func(name string, elapsed time.Duration)) *firstResultHook {
+	return &firstResultHook{
+		start: time.Now(),
+		fn:    fn,
+	}
+}
+
+func (h *firstResultHook) notify(name string) {
+	h.once.Do(func() {
+		h.fn(name, time.Since(h.start))
+	})
+}
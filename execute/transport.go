@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
@@ -44,6 +45,7 @@ type consecutiveTransport struct {
 	t         Transport
 	messages  MessageQueue
 	op, label string
+	kind      plan.ProcedureKind
 	stack     []interpreter.StackEntry
 
 	finished chan struct{}
@@ -52,6 +54,21 @@ type consecutiveTransport struct {
 
 	schedulerState int32
 	inflight       int32
+
+	// priority is the scheduling priority of this transport
+	// when the dispatcher supports prioritized work.
+	priority int
+
+	// profile records the time spent processing messages
+	// when the node is profiled. It may be nil.
+	profile *nodeProfile
+
+	// abort aborts the results downstream of the node
+	// when the transformation panics. It may be nil.
+	abort func(err error)
+
+	// src is the dataset of the message being processed.
+	src DatasetID
 }
 
 func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Transformation, n plan.Node, logger *zap.Logger, mem memory.Allocator) *consecutiveTransport {
@@ -64,6 +81,7 @@ func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Trans
 		messages: newMessageQueue(64),
 		op:       OperationType(t),
 		label:    string(n.ID()),
+		kind:     n.ProcedureSpec().Kind(),
 		stack:    n.CallStack(),
 		finished: make(chan struct{}),
 	}
@@ -96,10 +114,67 @@ func (t *consecutiveTransport) setErr(err error) {
 	if srcInfo := t.sourceInfo(); srcInfo != "" {
 		msg += " " + srcInfo
 	}
-	err = errors.Wrap(err, codes.Inherit, msg)
+	err = t.nodeError(errors.Wrap(err, codes.Inherit, msg))
+	t.errValue = err
+	t.errMu.Unlock()
+}
+
+// NodeError is the error of a transformation along with
+// the plan node that failed. Its message is the message of
+// the underlying error, so the node is only reported to
+// callers that look for it with errors.As.
+type NodeError struct {
+	// NodeID is the id of the node that failed
+	// and Kind is the kind of its procedure.
+	NodeID plan.NodeID
+	Kind   plan.ProcedureKind
+	Err    error
+}
+
+func (e *NodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// nodeError tags an error with the node of the transformation that failed.
+// The code and documentation link are kept on the outer error since they
+// are not looked up through a NodeError.
+func (t *consecutiveTransport) nodeError(err error) error {
+	return &errors.Error{
+		Code:   errors.Code(err),
+		DocURL: errors.DocURL(err),
+		Err: &NodeError{
+			NodeID: plan.NodeID(t.label),
+			Kind:   t.kind,
+			Err:    err,
+		},
+	}
+}
+
+// fail finishes the transport with the error of a transformation
+// that panicked so the transformations downstream of it receive
+// the error instead of waiting for more messages.
+func (t *consecutiveTransport) fail(err error) {
+	t.errMu.Lock()
 	t.errValue = err
 	t.errMu.Unlock()
+
+	if t.tryTransition(running, finished) {
+		defer close(t.finished)
+		// The transformation may panic again while it finishes
+		// since its state is left as it was by the first panic.
+		defer t.recoverFinish()
+		m := &finishMsg{
+			srcMessage: srcMessage(t.src),
+			err:        err,
+		}
+		_ = t.t.ProcessMessage(m)
+	}
 }
+
 func (t *consecutiveTransport) err() error {
 	t.errMu.Lock()
 	err := t.errValue
@@ -196,6 +271,10 @@ const (
 // schedule indicates that there is work available to schedule.
 func (t *consecutiveTransport) schedule() {
 	if t.tryTransition(idle, running) {
+		if pd, ok := t.dispatcher.(prioritizedDispatcher); ok && t.priority != 0 {
+			pd.SchedulePriority(t.processMessages, t.priority)
+			return
+		}
 		t.dispatcher.Schedule(t.processMessages)
 	}
 }
@@ -211,10 +290,13 @@ func (t *consecutiveTransport) transition(new int32) {
 }
 
 func (t *consecutiveTransport) processMessages(ctx context.Context, throughput int) {
+	// Setup panic handling so the error names the transformation
+	defer t.recover()
 PROCESS:
 	i := 0
 	for m := t.messages.Pop(); m != nil; m = t.messages.Pop() {
 		atomic.AddInt32(&t.inflight, -1)
+		t.src = m.SrcDatasetID()
 		if f, err := t.processMessage(ctx, m); err != nil || f {
 			// Set the error if there was any
 			t.setErr(err)
@@ -260,6 +342,12 @@ func (t *consecutiveTransport) processMessage(ctx context.Context, m Message) (f
 	if _, span := StartSpanFromContext(ctx, t.op, t.label); span != nil {
 		defer span.Finish()
 	}
+	if t.profile != nil {
+		start := time.Now()
+		defer func() {
+			t.profile.addDuration(time.Since(start))
+		}()
+	}
 	if err := t.t.ProcessMessage(m); err != nil {
 		return false, err
 	}
@@ -319,6 +407,10 @@ const (
 	// to flush the data associated with a key presently stored
 	// in the Dataset.
 	FlushKeyType
+
+	// CheckpointType is sent when a checkpoint barrier
+	// passes through the upstream Dataset.
+	CheckpointType
 )
 
 type srcMessage DatasetID
@@ -494,6 +586,26 @@ func (m *flushKeyMsg) Dup() Message {
 	return m
 }
 
+type CheckpointMsg interface {
+	Message
+	Barrier() *CheckpointBarrier
+}
+
+type checkpointMsg struct {
+	srcMessage
+	barrier *CheckpointBarrier
+}
+
+func (m *checkpointMsg) Type() MessageType {
+	return CheckpointType
+}
+func (m *checkpointMsg) Barrier() *CheckpointBarrier {
+	return m.barrier
+}
+func (m *checkpointMsg) Dup() Message {
+	return m
+}
+
 // consecutiveTransportTable is a flux.Table that is being processed
 // within a consecutiveTransport.
 type consecutiveTransportTable struct {
@@ -746,6 +858,10 @@ func (t *transportTransformationAdapter) Finish(id DatasetID, err error) {
 func (t *transportTransformationAdapter) OperationType() string {
 	return OperationType(t.Transport)
 }
+
+func (t *transportTransformationAdapter) SchemaContract() *SchemaContract {
+	return SchemaContractOf(t.Transport)
+}
 func (t *transportTransformationAdapter) RetractTable(_ DatasetID, _ flux.GroupKey) error {
 	return nil
 }
@@ -387,6 +387,7 @@ type ExecOptsConfig struct {
 
 func (eoc *ExecOptsConfig) ConfigureProfiler(ctx context.Context, profilerNames []string) {
 	var tfProfiler *execute.OperatorProfiler
+	profileNodes := false
 	dedupeMap := make(map[string]bool)
 	profilers := make([]execute.Profiler, 0)
 	for _, profilerName := range profilerNames {
@@ -407,6 +408,10 @@ func (eoc *ExecOptsConfig) ConfigureProfiler(ctx context.Context, profilerNames
 				// array to avoid the array look-up.
 
 				tfProfiler = tfp
+			} else if _, ok := profiler.(*execute.NodeProfiler); ok {
+				// The node profiler reads the statistics that the
+				// executor collects when nodes are profiled.
+				profileNodes = true
 			}
 			profilers = append(profilers, profiler)
 		}
@@ -416,6 +421,7 @@ func (eoc *ExecOptsConfig) ConfigureProfiler(ctx context.Context, profilerNames
 		deps := execute.GetExecutionDependencies(ctx)
 		deps.ExecutionOptions.OperatorProfiler = tfProfiler
 		deps.ExecutionOptions.Profilers = profilers
+		deps.ExecutionOptions.ProfileNodes = profileNodes
 	}
 }
 
@@ -42,6 +42,10 @@ type Allocator struct {
 	// allocate and free memory.
 	// If this is unset, the DefaultAllocator is used.
 	Allocator memory.Allocator
+
+	// Name describes what this Allocator is used for.
+	// It is included in the error when the limit is exceeded.
+	Name string
 }
 
 // Allocate will ensure that the requested memory is available and
@@ -75,8 +79,10 @@ func (a *Allocator) Reallocate(size int, b []byte) []byte {
 		return DefaultAllocator.Reallocate(size, b)
 	}
 
+	// The underlying allocator accounts for the difference itself
+	// if it is another Allocator so it is only counted here.
 	sizediff := size - cap(b)
-	if err := a.Account(sizediff); err != nil {
+	if err := a.count(sizediff); err != nil {
 		panic(err)
 	}
 
@@ -87,10 +93,25 @@ func (a *Allocator) Reallocate(size int, b []byte) []byte {
 // Account will manually account for the amount of memory being used.
 // This is typically used for memory that is allocated outside of the
 // Allocator that must be recorded in some way.
+//
+// If the underlying allocator is another Allocator, the memory
+// is also accounted for by that Allocator. This allows an Allocator
+// to track the memory used by part of a query while the memory is
+// still counted against the limit of the query.
 func (a *Allocator) Account(size int) error {
 	if size == 0 {
 		return nil
 	}
+	if parent, ok := a.Allocator.(*Allocator); ok {
+		if err := parent.Account(size); err != nil {
+			return err
+		}
+		if err := a.count(size); err != nil {
+			_ = parent.Account(-size)
+			return err
+		}
+		return nil
+	}
 	return a.count(size)
 }
 
@@ -202,6 +223,7 @@ func (a *Allocator) requestMemory(allocated, want int64) error {
 		// needed to know it failed.
 	}
 	return errors.Wrap(LimitExceededError{
+		Name:      a.Name,
 		Limit:     *a.Limit,
 		Allocated: allocated,
 		Wanted:    want - allocated,
@@ -241,11 +263,16 @@ type Manager interface {
 
 // LimitExceededError is an error when the allocation limit is exceeded.
 type LimitExceededError struct {
+	// Name is the name of the Allocator if it has one.
+	Name      string
 	Limit     int64
 	Allocated int64
 	Wanted    int64
 }
 
 func (a LimitExceededError) Error() string {
+	if a.Name != "" {
+		return fmt.Sprintf("memory allocation limit reached for %s: limit %d bytes, allocated: %d, wanted: %d", a.Name, a.Limit, a.Allocated, a.Wanted)
+	}
 	return fmt.Sprintf("memory allocation limit reached: limit %d bytes, allocated: %d, wanted: %d", a.Limit, a.Allocated, a.Wanted)
 }
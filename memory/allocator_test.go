@@ -183,6 +183,56 @@ func TestAllocator_Limit(t *testing.T) {
 	}
 }
 
+func TestAllocator_Parent(t *testing.T) {
+	mem := arrowmemory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	maxLimit := int64(128)
+	parent := &memory.Allocator{Limit: &maxLimit, Allocator: mem}
+	child := &memory.Allocator{Allocator: parent}
+
+	b := child.Allocate(64)
+	b = child.Reallocate(96, b)
+	if err := child.Account(32); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, a := range []*memory.Allocator{parent, child} {
+		if want, got := int64(128), a.Allocated(); want != got {
+			t.Fatalf("unexpected allocated count -want/+got\n\t- %d\n\t+ %d", want, got)
+		}
+	}
+
+	// The limit of the parent applies to the child.
+	if err := child.Account(1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	child.Free(b)
+	_ = child.Account(-32)
+
+	mem.AssertSize(t, 0)
+	for _, a := range []*memory.Allocator{parent, child} {
+		if want, got := int64(0), a.Allocated(); want != got {
+			t.Fatalf("unexpected allocated count -want/+got\n\t- %d\n\t+ %d", want, got)
+		}
+		if want, got := int64(128), a.MaxAllocated(); want != got {
+			t.Fatalf("unexpected max allocated count -want/+got\n\t- %d\n\t+ %d", want, got)
+		}
+	}
+}
+
+func TestAllocator_Name(t *testing.T) {
+	maxLimit := int64(64)
+	allocator := &memory.Allocator{Limit: &maxLimit, Name: "test"}
+	err := allocator.Account(65)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want, got := "memory allocation limit reached for test: limit 64 bytes, allocated: 0, wanted: 65", err.Error(); want != got {
+		t.Fatalf("unexpected error -want/+got\n\t- %s\n\t+ %s", want, got)
+	}
+}
+
 func TestAllocator_Free(t *testing.T) {
 	allocator := &memory.Allocator{}
 	if err := allocator.Account(64); err != nil {
@@ -25,3 +25,21 @@ type ParallelMergeAttribute struct {
 func (ParallelMergeAttribute) SuccessorsMustRequire() bool {
 	return false
 }
+
+// MemoryLimitKey is the key of the attribute that limits the memory
+// used by the transformations of a node. The memory is still counted
+// against the memory quota of the query, but a node that exceeds its
+// own limit fails without using up the memory of the other nodes.
+// The limit is shared by every copy of a node that runs in parallel.
+const MemoryLimitKey = "memory-limit"
+
+type MemoryLimitAttribute struct {
+	// Bytes is the maximum number of bytes the node may allocate.
+	Bytes int64
+}
+
+// The memory limit only applies to the node itself
+// so successors are not required to have it.
+func (MemoryLimitAttribute) SuccessorsMustRequire() bool {
+	return false
+}
@@ -2,8 +2,10 @@ package array
 
 import (
 	"context"
+	"encoding/binary"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
 	"github.com/influxdata/flux/arrow"
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
@@ -82,9 +84,11 @@ func (s *FromProcedureSpec) Copy() plan.ProcedureSpec {
 func createFromSource(ps plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	spec := ps.(*FromProcedureSpec)
 	return &tableSource{
-		id:   id,
-		mem:  a.Allocator(),
-		rows: spec.Rows,
+		id:       id,
+		mem:      a.Allocator(),
+		rows:     spec.Rows,
+		d:        execute.NewTransportDataset(id, a.Allocator()),
+		barriers: make(chan *execute.CheckpointBarrier, 1),
 	}, nil
 }
 
@@ -93,47 +97,125 @@ type tableSource struct {
 	id   execute.DatasetID
 	mem  *memory.Allocator
 	rows values.Array
-	ts   execute.TransformationSet
+	d    *execute.TransportDataset
+
+	// offset is the number of rows that were already sent
+	// when the checkpoint this source was restored from was taken.
+	offset   int
+	barriers chan *execute.CheckpointBarrier
 }
 
+var _ execute.CheckpointSource = (*tableSource)(nil)
+
 func (s *tableSource) AddTransformation(t execute.Transformation) {
-	s.ts = append(s.ts, t)
+	s.d.AddTransformation(t)
+}
+
+// RestoreCheckpoint implements execute.CheckpointSource.
+// The checkpoint data is the number of rows that had been sent.
+func (s *tableSource) RestoreCheckpoint(data []byte) error {
+	if len(data) != 8 {
+		return errors.Newf(codes.Internal, "invalid %s checkpoint of %d bytes", FromKind, len(data))
+	}
+	offset := binary.LittleEndian.Uint64(data)
+	if offset > uint64(s.rows.Len()) {
+		return errors.Newf(codes.Internal, "%s checkpoint offset %d is past the end of %d rows", FromKind, offset, s.rows.Len())
+	}
+	s.offset = int(offset)
+	return nil
+}
+
+// Checkpoint implements execute.CheckpointSource.
+// The barrier is handled between two chunks by Run.
+// If a barrier is already waiting, this one is dropped.
+func (s *tableSource) Checkpoint(b *execute.CheckpointBarrier) {
+	select {
+	case s.barriers <- b:
+	default:
+	}
 }
 
 func (s *tableSource) Run(ctx context.Context) {
-	tbl, err := buildTable(s.rows, s.mem)
-	if err == nil {
-		err = s.ts.Process(s.id, tbl)
+	s.d.Finish(s.run(ctx))
+}
+
+func (s *tableSource) run(ctx context.Context) error {
+	buf, err := buildTable(s.rows, s.mem)
+	if err != nil {
+		return err
+	}
+	defer buf.Release()
+
+	// Send the rows in chunks so a checkpoint can be taken
+	// between any two of them.
+	for i, n := s.offset, buf.Len(); i < n; i += table.BufferSize {
+		if err := s.checkpoint(i); err != nil {
+			return err
+		}
+		end := i + table.BufferSize
+		if end > n {
+			end = n
+		}
+		vs := make([]array.Array, len(buf.Values))
+		for j, arr := range buf.Values {
+			vs[j] = arrow.Slice(arr, int64(i), int64(end))
+		}
+		chunk := table.ChunkFromBuffer(arrow.TableBuffer{
+			GroupKey: buf.GroupKey,
+			Columns:  buf.Columns,
+			Values:   vs,
+		})
+		if err := s.d.Process(chunk); err != nil {
+			return err
+		}
+	}
+	if err := s.checkpoint(buf.Len()); err != nil {
+		return err
 	}
+	return s.d.FlushKey(buf.GroupKey)
+}
 
-	s.ts.Finish(s.id, err)
+// checkpoint records the number of rows sent so far
+// if a checkpoint barrier is waiting and forwards the barrier.
+func (s *tableSource) checkpoint(sent int) error {
+	select {
+	case b := <-s.barriers:
+		var data [8]byte
+		binary.LittleEndian.PutUint64(data[:], uint64(sent))
+		if err := b.Record(s.id, data[:]); err != nil {
+			return err
+		}
+		return s.d.Checkpoint(b)
+	default:
+		return nil
+	}
 }
 
-func buildTable(rows values.Array, mem *memory.Allocator) (flux.Table, error) {
+func buildTable(rows values.Array, mem *memory.Allocator) (arrow.TableBuffer, error) {
 	typ, err := rows.Type().ElemType()
 	if err != nil {
-		return nil, err
+		return arrow.TableBuffer{}, err
 	} else if typ.Nature() != semantic.Object {
-		return nil, errors.New(codes.Internal, "rows should have been a list of records")
+		return arrow.TableBuffer{}, errors.New(codes.Internal, "rows should have been a list of records")
 	}
 	l, err := typ.NumProperties()
 	if err != nil {
-		return nil, err
+		return arrow.TableBuffer{}, err
 	}
 	cols := make([]flux.ColMeta, 0, l)
 	for i := 0; i < l; i++ {
 		rp, err := typ.RecordProperty(i)
 		if err != nil {
-			return nil, err
+			return arrow.TableBuffer{}, err
 		}
 
 		pt, err := rp.TypeOf()
 		if err != nil {
-			return nil, err
+			return arrow.TableBuffer{}, err
 		}
 		ctyp := flux.ColumnType(pt)
 		if ctyp == flux.TInvalid {
-			return nil, errors.Newf(codes.Invalid, "cannot represent the type %v as column data", pt)
+			return arrow.TableBuffer{}, errors.Newf(codes.Invalid, "cannot represent the type %v as column data", pt)
 		}
 		cols = append(cols, flux.ColMeta{
 			Label: rp.Name(),
@@ -146,15 +228,15 @@ func buildTable(rows values.Array, mem *memory.Allocator) (flux.Table, error) {
 	for _, col := range cols {
 		i, err := builder.AddCol(col)
 		if err != nil {
-			return nil, err
+			return arrow.TableBuffer{}, err
 		}
 		builder.Builders[i].Resize(rows.Len())
 	}
 
 	if err := appendRows(builder, rows); err != nil {
-		return nil, err
+		return arrow.TableBuffer{}, err
 	}
-	return builder.Table()
+	return builder.Buffer()
 }
 
 func appendRows(builder *table.ArrowBuilder, rows values.Array) (err error) {
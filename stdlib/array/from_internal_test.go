@@ -0,0 +1,85 @@
+package array
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/mock"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+func TestFrom_RestoreCheckpoint(t *testing.T) {
+	const n, offset = 2500, 2000
+
+	rows := make([]values.Value, n)
+	for i := range rows {
+		rows[i] = values.NewObjectWithValues(map[string]values.Value{
+			"_value": values.NewInt(int64(i)),
+		})
+	}
+	spec := &FromProcedureSpec{
+		Rows: values.NewArrayWithBacking(semantic.NewArrayType(rows[0].Type()), rows),
+	}
+
+	a := mock.AdministrationWithContext(context.Background())
+	s, err := createFromSource(spec, executetest.RandomDatasetID(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := executetest.NewDataStore()
+	s.AddTransformation(store)
+
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, offset)
+	if err := s.(execute.CheckpointSource).RestoreCheckpoint(data); err != nil {
+		t.Fatal(err)
+	}
+	s.Run(context.Background())
+	if err := store.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*executetest.Table{{
+		ColMeta: []flux.ColMeta{{Label: "_value", Type: flux.TInt}},
+	}}
+	for i := offset; i < n; i++ {
+		want[0].Data = append(want[0].Data, []interface{}{int64(i)})
+	}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestFrom_RestoreCheckpointInvalid(t *testing.T) {
+	spec := &FromProcedureSpec{
+		Rows: values.NewArrayWithBacking(
+			semantic.NewArrayType(semantic.NewObjectType(nil)),
+			[]values.Value{values.NewObjectWithValues(nil)},
+		),
+	}
+	a := mock.AdministrationWithContext(context.Background())
+	s, err := createFromSource(spec, executetest.RandomDatasetID(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, 2)
+	for _, data := range [][]byte{data, {1}} {
+		if err := s.(execute.CheckpointSource).RestoreCheckpoint(data); err == nil {
+			t.Errorf("expected error for checkpoint %v", data)
+		}
+	}
+}
@@ -2,10 +2,22 @@ package array_test
 
 import (
 	"context"
+	"math"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
 	_ "github.com/influxdata/flux/fluxinit/static"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
 	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/array"
+	"github.com/influxdata/flux/values"
+	"go.uber.org/zap/zaptest"
 )
 
 func TestArrayFrom_ReceiveTableObjectIsError(t *testing.T) {
@@ -20,3 +32,65 @@ func TestArrayFrom_ReceiveTableObjectIsError(t *testing.T) {
 		t.Errorf("wanted error %q, got %q", want, got)
 	}
 }
+
+func TestFrom_Checkpoint(t *testing.T) {
+	const n = 2500
+
+	rows := make([]values.Value, n)
+	want := &executetest.Table{
+		ColMeta: []flux.ColMeta{{Label: "_value", Type: flux.TInt}},
+	}
+	for i := range rows {
+		rows[i] = values.NewObjectWithValues(map[string]values.Value{
+			"_value": values.NewInt(int64(i)),
+		})
+		want.Data = append(want.Data, []interface{}{int64(i)})
+	}
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from", &array.FromProcedureSpec{
+				Rows: values.NewArrayWithBacking(semantic.NewArrayType(rows[0].Type()), rows),
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{{0, 1}},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+
+	store := execute.NewInMemoryCheckpointStore()
+	deps := execute.DefaultExecutionDependencies()
+	deps.ExecutionOptions.Checkpoint = &execute.CheckpointOptions{
+		Store:    store,
+		Interval: time.Millisecond,
+	}
+	ctx := executetest.NewTestExecuteDependencies().Inject(context.Background())
+	ctx = deps.Inject(ctx)
+
+	results, _, err := execute.NewExecutor(zaptest.NewLogger(t)).Execute(ctx, spec, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*executetest.Table
+	for _, r := range results {
+		if err := r.Tables().Do(func(tbl flux.Table) error {
+			cpy, err := executetest.ConvertTable(tbl)
+			if err != nil {
+				return err
+			}
+			got = append(got, cpy)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables([]*executetest.Table{want})
+	if !cmp.Equal([]*executetest.Table{want}, got) {
+		t.Errorf("unexpected tables -want/+got:\n%s", cmp.Diff([]*executetest.Table{want}, got))
+	}
+}
@@ -87,7 +87,7 @@ func AddLimitToNode(queryNode plan.Node, limitNode plan.Node) (plan.Node, bool)
 	querySpec := queryNode.ProcedureSpec().(*FromBigtableProcedureSpec)
 	limitSpec := limitNode.ProcedureSpec().(*universe.LimitProcedureSpec)
 
-	if limitSpec.Offset != 0 {
+	if limitSpec.Offset != 0 || len(limitSpec.ResetOn) > 0 || limitSpec.Global || limitSpec.Method == universe.LimitMethodSample || limitSpec.Stride > 1 {
 		return limitNode, false
 	}
 
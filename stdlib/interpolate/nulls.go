@@ -0,0 +1,368 @@
+package interpolate
+
+import (
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const NullsInterpolateKind = "nullsInterpolateKind"
+
+const methodLinear = "linear"
+
+type NullsInterpolateOpSpec struct {
+	Method      string `json:"method"`
+	Column      string `json:"column"`
+	TimeColumn  string `json:"timeColumn"`
+	ByIndex     bool   `json:"byIndex"`
+	Extrapolate bool   `json:"extrapolate"`
+}
+
+func init() {
+	runtime.RegisterPackageValue("interpolate", "nulls",
+		flux.MustValue(flux.FunctionValue("nulls",
+			createNullsInterpolateOpSpec,
+			runtime.MustLookupBuiltinType("interpolate", "nulls"),
+		)),
+	)
+	flux.RegisterOpSpec(NullsInterpolateKind,
+		func() flux.OperationSpec {
+			return new(NullsInterpolateOpSpec)
+		},
+	)
+	plan.RegisterProcedureSpec(
+		NullsInterpolateKind,
+		newNullsInterpolateProcedure,
+		NullsInterpolateKind,
+	)
+	execute.RegisterTransformation(
+		NullsInterpolateKind,
+		createNullsInterpolateTransformation,
+	)
+}
+
+func createNullsInterpolateOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &NullsInterpolateOpSpec{
+		Method:     methodLinear,
+		Column:     execute.DefaultValueColLabel,
+		TimeColumn: execute.DefaultTimeColLabel,
+	}
+	if m, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Method = m
+	}
+	if spec.Method != methodLinear {
+		return nil, errors.Newf(codes.Invalid, "unknown interpolation method %q", spec.Method)
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	}
+	if b, ok, err := args.GetBool("byIndex"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.ByIndex = b
+	}
+	if b, ok, err := args.GetBool("extrapolate"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Extrapolate = b
+	}
+	return spec, nil
+}
+
+func (s *NullsInterpolateOpSpec) Kind() flux.OperationKind {
+	return NullsInterpolateKind
+}
+
+type NullsInterpolateProcedureSpec struct {
+	plan.DefaultCost
+	Method      string `json:"method"`
+	Column      string `json:"column"`
+	TimeColumn  string `json:"timeColumn"`
+	ByIndex     bool   `json:"byIndex"`
+	Extrapolate bool   `json:"extrapolate"`
+}
+
+func newNullsInterpolateProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*NullsInterpolateOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &NullsInterpolateProcedureSpec{
+		Method:      spec.Method,
+		Column:      spec.Column,
+		TimeColumn:  spec.TimeColumn,
+		ByIndex:     spec.ByIndex,
+		Extrapolate: spec.Extrapolate,
+	}, nil
+}
+
+func (s *NullsInterpolateProcedureSpec) Kind() plan.ProcedureKind {
+	return NullsInterpolateKind
+}
+func (s *NullsInterpolateProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *NullsInterpolateProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createNullsInterpolateTransformation(
+	id execute.DatasetID,
+	mode execute.AccumulationMode,
+	spec plan.ProcedureSpec,
+	a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*NullsInterpolateProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewNullsInterpolateTransformation(id, s, a.Allocator())
+}
+
+type nullsInterpolateTransformation struct {
+	spec NullsInterpolateProcedureSpec
+}
+
+// NewNullsInterpolateTransformation creates a transformation that
+// replaces null values in a column using linear interpolation
+// between the nearest non-null values in each table.
+//
+// Interpolation requires the next non-null value so every
+// chunk of a table is buffered until the table is complete.
+func NewNullsInterpolateTransformation(id execute.DatasetID, spec *NullsInterpolateProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &nullsInterpolateTransformation{spec: *spec}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+// nullsInterpolateState holds the buffered chunks of a table
+// along with the position and value of every row.
+type nullsInterpolateState struct {
+	chunks []table.Chunk
+
+	// xs holds the position of each row and ys holds its value.
+	// A row is only valid if both its position and value are non-null.
+	xs, ys []float64
+	xValid []bool
+	yValid []bool
+}
+
+func (s *nullsInterpolateState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *nullsInterpolateTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *nullsInterpolateState
+	if state != nil {
+		s = state.(*nullsInterpolateState)
+	} else {
+		s = &nullsInterpolateState{}
+	}
+
+	idx := chunk.Index(t.spec.Column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.spec.Column)
+	}
+	if chunk.Key().HasCol(t.spec.Column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot interpolate a column that is part of the group key")
+	}
+
+	// Record the position of each row.
+	if t.spec.ByIndex {
+		for i, n := 0, chunk.Len(); i < n; i++ {
+			s.xs = append(s.xs, float64(len(s.xs)))
+			s.xValid = append(s.xValid, true)
+		}
+	} else {
+		tIdx := chunk.Index(t.spec.TimeColumn)
+		if tIdx < 0 {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.spec.TimeColumn)
+		} else if typ := chunk.Col(tIdx).Type; typ != flux.TTime {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q is of type %s, expected time", t.spec.TimeColumn, typ)
+		}
+		ts := chunk.Ints(tIdx)
+		for i := 0; i < ts.Len(); i++ {
+			s.xs = append(s.xs, float64(ts.Value(i)))
+			s.xValid = append(s.xValid, ts.IsValid(i))
+		}
+	}
+
+	// Record the value of each row.
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.ys = append(s.ys, float64(vs.Value(i)))
+			s.yValid = append(s.yValid, vs.IsValid(i))
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.ys = append(s.ys, float64(vs.Value(i)))
+			s.yValid = append(s.yValid, vs.IsValid(i))
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.ys = append(s.ys, vs.Value(i))
+			s.yValid = append(s.yValid, vs.IsValid(i))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot interpolate column %q of type %s", t.spec.Column, typ)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	return s, true, nil
+}
+
+func (t *nullsInterpolateTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*nullsInterpolateState)
+	values, valid := t.interpolate(s)
+
+	offset := 0
+	for _, chunk := range s.chunks {
+		idx := chunk.Index(t.spec.Column)
+		n := chunk.Len()
+
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for i := offset; i < offset+n; i++ {
+			if valid[i] {
+				b.Append(values[i])
+			} else {
+				b.AppendNull()
+			}
+		}
+		offset += n
+
+		buffer := chunk.Buffer()
+		cols := make([]flux.ColMeta, len(buffer.Columns))
+		copy(cols, buffer.Columns)
+		vs := make([]array.Array, len(buffer.Values))
+		for j := range vs {
+			if j == idx {
+				continue
+			}
+			vs[j] = buffer.Values[j]
+			vs[j].Retain()
+		}
+		cols[idx].Type, vs[idx] = flux.TFloat, b.NewFloatArray()
+
+		buffer.Columns, buffer.Values = cols, vs
+		if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interpolate computes the output value of every row. Non-null values
+// are left unchanged and null values are linearly interpolated from the
+// nearest non-null values before and after them. Leading and trailing
+// null values remain null unless extrapolation is enabled.
+func (t *nullsInterpolateTransformation) interpolate(s *nullsInterpolateState) ([]float64, []bool) {
+	n := len(s.ys)
+	values := make([]float64, n)
+	valid := make([]bool, n)
+
+	// anchor reports whether a row can be used to interpolate other rows.
+	anchor := func(i int) bool {
+		return s.xValid[i] && s.yValid[i]
+	}
+
+	// Find the nearest anchor before each row.
+	prev := make([]int, n)
+	last := -1
+	for i := 0; i < n; i++ {
+		prev[i] = last
+		if anchor(i) {
+			last = i
+		}
+	}
+
+	// Walk backwards and interpolate each null value
+	// using the previous and next anchors.
+	next := -1
+	for i := n - 1; i >= 0; i-- {
+		if s.yValid[i] {
+			values[i], valid[i] = s.ys[i], true
+			if anchor(i) {
+				next = i
+			}
+			continue
+		}
+		if !s.xValid[i] {
+			continue
+		}
+
+		p := prev[i]
+		switch {
+		case p >= 0 && next >= 0:
+			values[i], valid[i] = lerp(s, p, next, s.xs[i]), true
+		case !t.spec.Extrapolate:
+		case p >= 0 && prev[p] >= 0:
+			// Trailing null, extrapolate from the last two anchors.
+			values[i], valid[i] = lerp(s, prev[p], p, s.xs[i]), true
+		case next >= 0:
+			// Leading null, extrapolate from the first two anchors.
+			if nn := nextAnchor(s, next); nn >= 0 {
+				values[i], valid[i] = lerp(s, next, nn, s.xs[i]), true
+			}
+		}
+	}
+	return values, valid
+}
+
+// nextAnchor returns the index of the next row after i
+// that has a non-null position and value or -1.
+func nextAnchor(s *nullsInterpolateState, i int) int {
+	for j := i + 1; j < len(s.ys); j++ {
+		if s.xValid[j] && s.yValid[j] {
+			return j
+		}
+	}
+	return -1
+}
+
+// lerp computes the value at x on the line
+// through the rows at indices i and j.
+func lerp(s *nullsInterpolateState, i, j int, x float64) float64 {
+	x0, x1 := s.xs[i], s.xs[j]
+	y0, y1 := s.ys[i], s.ys[j]
+	if x0 == x1 {
+		return y0
+	}
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
+
+func (t *nullsInterpolateTransformation) Close() error {
+	return nil
+}
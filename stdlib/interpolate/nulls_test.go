@@ -0,0 +1,177 @@
+package interpolate_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/interpolate"
+)
+
+func TestNullsInterpolate(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec *interpolate.NullsInterpolateProcedureSpec
+		data []flux.Table
+		want []*executetest.Table
+	}{
+		{
+			name: "by time",
+			spec: &interpolate.NullsInterpolateProcedureSpec{
+				Method:     "linear",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), nil},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), nil},
+					{execute.Time(40), 4.0},
+					{execute.Time(50), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), nil},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), 2.0},
+					{execute.Time(40), 4.0},
+					{execute.Time(50), nil},
+				},
+			}},
+		},
+		{
+			name: "by index across chunks",
+			spec: &interpolate.NullsInterpolateProcedureSpec{
+				Method:  "linear",
+				Column:  "_value",
+				ByIndex: true,
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{int64(0), "a"},
+						{nil, "a"},
+						{nil, "a"},
+						{int64(3), "a"},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{0.0, "a"},
+					{1.0, "a"},
+					{2.0, "a"},
+					{3.0, "a"},
+				},
+			}},
+		},
+		{
+			name: "extrapolate",
+			spec: &interpolate.NullsInterpolateProcedureSpec{
+				Method:      "linear",
+				Column:      "_value",
+				TimeColumn:  "_time",
+				Extrapolate: true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), nil},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), 2.0},
+					{execute.Time(30), nil},
+					{execute.Time(40), 4.0},
+					{execute.Time(50), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 0.0},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), 2.0},
+					{execute.Time(30), 3.0},
+					{execute.Time(40), 4.0},
+					{execute.Time(50), 5.0},
+				},
+			}},
+		},
+		{
+			name: "extrapolate single value",
+			spec: &interpolate.NullsInterpolateProcedureSpec{
+				Method:      "linear",
+				Column:      "_value",
+				TimeColumn:  "_time",
+				Extrapolate: true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), nil},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), nil},
+					{execute.Time(10), 1.0},
+					{execute.Time(20), nil},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := interpolate.NewNullsInterpolateTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
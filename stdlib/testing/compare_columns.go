@@ -0,0 +1,294 @@
+package testing
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const CompareColumnsKind = "compareColumns"
+
+const (
+	// DiffersLabel is the column that reports whether two columns differ.
+	DiffersLabel = "_differs"
+	// DeltaLabel is the column that contains the difference between two columns.
+	DeltaLabel = "_delta"
+)
+
+const (
+	// ToleranceAbsolute compares floats by their absolute difference.
+	ToleranceAbsolute = "absolute"
+	// ToleranceRelative compares floats by their difference relative
+	// to the larger magnitude of the two values.
+	ToleranceRelative = "relative"
+	// ToleranceULP compares floats by the number of representable
+	// floats between them.
+	ToleranceULP = "ulp"
+)
+
+// floatsDiffer reports whether two floats differ by more than epsilon
+// using the tolerance method. NaN values never differ so callers that
+// care about NaN must check for it first.
+func floatsDiffer(want, got, epsilon float64, tolerance string) bool {
+	switch tolerance {
+	case ToleranceRelative:
+		if math.IsInf(want, 0) || math.IsInf(got, 0) {
+			// An infinite value is only within any
+			// relative distance of the same infinity.
+			return want != got && !math.IsNaN(want) && !math.IsNaN(got)
+		}
+		return math.Abs(want-got) > epsilon*math.Max(math.Abs(want), math.Abs(got))
+	case ToleranceULP:
+		if math.IsNaN(want) || math.IsNaN(got) {
+			return false
+		}
+		return math.Abs(float64(orderedFloatBits(want)-orderedFloatBits(got))) > epsilon
+	default:
+		return math.Abs(want-got) > epsilon
+	}
+}
+
+// orderedFloatBits maps the bits of a float to an integer so that
+// adjacent floats map to adjacent integers.
+func orderedFloatBits(f float64) int64 {
+	i := int64(math.Float64bits(f))
+	if i < 0 {
+		return math.MinInt64 - i
+	}
+	return i
+}
+
+type CompareColumnsOpSpec struct {
+	A         string  `json:"a"`
+	B         string  `json:"b"`
+	Epsilon   float64 `json:"epsilon"`
+	Tolerance string  `json:"tolerance"`
+	NaNsEqual bool    `json:"nansEqual,omitempty"`
+}
+
+func (s *CompareColumnsOpSpec) Kind() flux.OperationKind {
+	return CompareColumnsKind
+}
+
+func init() {
+	compareColumnsSignature := runtime.MustLookupBuiltinType("testing", "compareColumns")
+
+	runtime.RegisterPackageValue("testing", "compareColumns", flux.MustValue(flux.FunctionValue(CompareColumnsKind, createCompareColumnsOpSpec, compareColumnsSignature)))
+	flux.RegisterOpSpec(CompareColumnsKind, newCompareColumnsOp)
+	plan.RegisterProcedureSpec(CompareColumnsKind, newCompareColumnsProcedure, CompareColumnsKind)
+	execute.RegisterTransformation(CompareColumnsKind, createCompareColumnsTransformation)
+}
+
+func createCompareColumnsOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(CompareColumnsOpSpec)
+	var err error
+	if spec.A, err = args.GetRequiredString("a"); err != nil {
+		return nil, err
+	}
+	if spec.B, err = args.GetRequiredString("b"); err != nil {
+		return nil, err
+	}
+
+	if epsilon, ok, err := args.GetFloat("epsilon"); err != nil {
+		return nil, err
+	} else if ok {
+		if epsilon < 0 {
+			return nil, errors.New(codes.Invalid, "epsilon must not be negative")
+		}
+		spec.Epsilon = epsilon
+	} else {
+		spec.Epsilon = DefaultEpsilon
+	}
+
+	if tolerance, ok, err := args.GetString("tolerance"); err != nil {
+		return nil, err
+	} else if ok {
+		switch tolerance {
+		case ToleranceAbsolute, ToleranceRelative, ToleranceULP:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown tolerance %q, expected %q, %q, or %q", tolerance, ToleranceAbsolute, ToleranceRelative, ToleranceULP)
+		}
+		spec.Tolerance = tolerance
+	} else {
+		spec.Tolerance = ToleranceAbsolute
+	}
+
+	if nansEqual, ok, err := args.GetBool("nansEqual"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.NaNsEqual = nansEqual
+	}
+	return spec, nil
+}
+
+func newCompareColumnsOp() flux.OperationSpec {
+	return new(CompareColumnsOpSpec)
+}
+
+type CompareColumnsProcedureSpec struct {
+	plan.DefaultCost
+	A, B      string
+	Epsilon   float64
+	Tolerance string
+	NaNsEqual bool
+}
+
+func (s *CompareColumnsProcedureSpec) Kind() plan.ProcedureKind {
+	return CompareColumnsKind
+}
+
+func (s *CompareColumnsProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *CompareColumnsProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func newCompareColumnsProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*CompareColumnsOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &CompareColumnsProcedureSpec{
+		A:         spec.A,
+		B:         spec.B,
+		Epsilon:   spec.Epsilon,
+		Tolerance: spec.Tolerance,
+		NaNsEqual: spec.NaNsEqual,
+	}, nil
+}
+
+func createCompareColumnsTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*CompareColumnsProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewCompareColumnsTransformation(id, s, a.Allocator())
+}
+
+type compareColumnsTransformation struct {
+	a, b      string
+	epsilon   float64
+	tolerance string
+	nansEqual bool
+}
+
+// NewCompareColumnsTransformation creates a transformation that compares
+// two numeric columns of each row. It adds the difference of the columns
+// to the _delta column and whether they differ by more than the tolerance
+// to the _differs column.
+//
+// A null value only equals another null value and the delta is null if
+// either value is null. A NaN value differs from every value, including
+// other NaN values unless nansEqual is set.
+func NewCompareColumnsTransformation(id execute.DatasetID, spec *CompareColumnsProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &compareColumnsTransformation{
+		a:         spec.A,
+		b:         spec.B,
+		epsilon:   spec.Epsilon,
+		tolerance: spec.Tolerance,
+		nansEqual: spec.NaNsEqual,
+	}
+	return execute.NewNarrowTransformation(id, t, mem)
+}
+
+// floatColumn returns a function that reads the
+// values of a numeric column as floats.
+func floatColumn(chunk table.Chunk, label string) (func(i int) (float64, bool), error) {
+	idx := chunk.Index(label)
+	if idx < 0 {
+		return nil, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+	}
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		return func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}, nil
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		return func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}, nil
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		return func(i int) (float64, bool) {
+			return vs.Value(i), vs.IsValid(i)
+		}, nil
+	default:
+		return nil, errors.Newf(codes.FailedPrecondition, "cannot compare column %q of type %s", label, typ)
+	}
+}
+
+func (t *compareColumnsTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	a, err := floatColumn(chunk, t.a)
+	if err != nil {
+		return err
+	}
+	b, err := floatColumn(chunk, t.b)
+	if err != nil {
+		return err
+	}
+
+	n := chunk.Len()
+	differs := arrowutil.NewBooleanBuilder(mem)
+	differs.Resize(n)
+	deltas := arrowutil.NewFloatBuilder(mem)
+	deltas.Resize(n)
+	for i := 0; i < n; i++ {
+		av, aok := a(i)
+		bv, bok := b(i)
+		if !aok || !bok {
+			differs.Append(aok != bok)
+			deltas.AppendNull()
+			continue
+		}
+
+		deltas.Append(av - bv)
+		if math.IsNaN(av) || math.IsNaN(bv) {
+			differs.Append(!t.nansEqual || !math.IsNaN(av) || !math.IsNaN(bv))
+			continue
+		}
+		differs.Append(floatsDiffer(av, bv, t.epsilon, t.tolerance))
+	}
+
+	buffer := chunk.Buffer()
+	cols := make([]flux.ColMeta, 0, len(buffer.Columns)+2)
+	vs := make([]array.Array, 0, len(buffer.Values)+2)
+	for j, col := range buffer.Columns {
+		if col.Label == DiffersLabel || col.Label == DeltaLabel {
+			continue
+		}
+		cols = append(cols, col)
+		vs = append(vs, buffer.Values[j])
+		buffer.Values[j].Retain()
+	}
+	cols = append(cols,
+		flux.ColMeta{Label: DiffersLabel, Type: flux.TBool},
+		flux.ColMeta{Label: DeltaLabel, Type: flux.TFloat},
+	)
+	vs = append(vs, differs.NewArray(), deltas.NewArray())
+
+	buffer.Columns, buffer.Values = cols, vs
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *compareColumnsTransformation) Close() error {
+	return nil
+}
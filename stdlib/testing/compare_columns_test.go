@@ -0,0 +1,163 @@
+package testing_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	fluxtesting "github.com/influxdata/flux/stdlib/testing"
+)
+
+func TestCompareColumns_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *fluxtesting.CompareColumnsProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "absolute",
+			spec: &fluxtesting.CompareColumnsProcedureSpec{
+				A:         "a",
+				B:         "b",
+				Epsilon:   0.5,
+				Tolerance: fluxtesting.ToleranceAbsolute,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.25, int64(1)},
+					{execute.Time(2), 3.0, int64(2)},
+					{execute.Time(3), nil, int64(2)},
+					{execute.Time(4), nil, nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TInt},
+					{Label: "_differs", Type: flux.TBool},
+					{Label: "_delta", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.25, int64(1), false, 0.25},
+					{execute.Time(2), 3.0, int64(2), true, 1.0},
+					{execute.Time(3), nil, int64(2), true, nil},
+					{execute.Time(4), nil, nil, false, nil},
+				},
+			}},
+		},
+		{
+			name: "relative",
+			spec: &fluxtesting.CompareColumnsProcedureSpec{
+				A:         "a",
+				B:         "b",
+				Epsilon:   0.01,
+				Tolerance: fluxtesting.ToleranceRelative,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1000.0, 1005.0},
+					{1.0, 1.5},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+					{Label: "_differs", Type: flux.TBool},
+					{Label: "_delta", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1000.0, 1005.0, false, -5.0},
+					{1.0, 1.5, true, -0.5},
+				},
+			}},
+		},
+		{
+			name: "ulp",
+			spec: &fluxtesting.CompareColumnsProcedureSpec{
+				A:         "a",
+				B:         "b",
+				Epsilon:   1,
+				Tolerance: fluxtesting.ToleranceULP,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.0, math.Nextafter(1, 2)},
+					{1.0, math.Nextafter(math.Nextafter(1, 2), 2)},
+					{0.0, math.Copysign(0, -1)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+					{Label: "_differs", Type: flux.TBool},
+					{Label: "_delta", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.0, math.Nextafter(1, 2), false, 1 - math.Nextafter(1, 2)},
+					{1.0, math.Nextafter(math.Nextafter(1, 2), 2), true, 1 - math.Nextafter(math.Nextafter(1, 2), 2)},
+					{0.0, math.Copysign(0, -1), false, 0.0},
+				},
+			}},
+		},
+		{
+			name: "missing column",
+			spec: &fluxtesting.CompareColumnsProcedureSpec{
+				A:         "a",
+				B:         "c",
+				Epsilon:   fluxtesting.DefaultEpsilon,
+				Tolerance: fluxtesting.ToleranceAbsolute,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.0, 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "c" does not exist`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := fluxtesting.NewCompareColumnsTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
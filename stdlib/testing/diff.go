@@ -3,6 +3,8 @@ package testing
 import (
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/influxdata/flux"
@@ -11,19 +13,141 @@ import (
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
 )
 
 const DiffKind = "diff"
 const DefaultEpsilon = 1e-6
 const DefaultNaNsEqual = false
 
+// DefaultMaxAlignRows is the default maximum number of rows
+// in a table for the rows of each table to be aligned.
+const DefaultMaxAlignRows = 1000
+
+const (
+	// DiffTypeLabel is the group key column that contains the kind
+	// of difference when the output of diff is partitioned.
+	DiffTypeLabel = "_diffType"
+
+	// DiffTypeAdded marks rows that are only in got.
+	DiffTypeAdded = "added"
+	// DiffTypeRemoved marks rows that are only in want.
+	DiffTypeRemoved = "removed"
+	// DiffTypeChanged marks rows that are in both want and got
+	// at the same position but with different values.
+	// When rows are aligned, the position is relative to
+	// the rows that are equal in each table.
+	DiffTypeChanged = "changed"
+	// DiffTypeSchema marks rows that report a column
+	// that is only in one of want or got.
+	DiffTypeSchema = "schema"
+	// DiffTypeTruncated marks the row that reports the number
+	// of differing rows that were left out of the output.
+	DiffTypeTruncated = "truncated"
+	// DiffTypeKey marks the row that reports a group key
+	// that is only in one of want or got.
+	DiffTypeKey = "key"
+	// DiffTypeContext marks the equal rows that are
+	// written around the rows that differ.
+	DiffTypeContext = "context"
+)
+
+// DiffDetailLabel is the column that describes why a changed row
+// differs when the diff is verbose.
+const DiffDetailLabel = "_diff_detail"
+
+// DiffRemainingLabel is the column with the number of differing rows
+// that were left out of the output when the number of rows is limited.
+const DiffRemainingLabel = "_diff_remaining"
+
+// DiffTruncatedMarker is the _diff marker of the row that
+// reports the number of differing rows that were left out.
+const DiffTruncatedMarker = "..."
+
+// DiffContextMarker is the _diff marker of an equal row
+// that is written around the rows that differ.
+const DiffContextMarker = " "
+
+const (
+	// DiffSummaryAddedLabel is the summary column with
+	// the number of rows that are only in got.
+	DiffSummaryAddedLabel = "_added"
+	// DiffSummaryRemovedLabel is the summary column with
+	// the number of rows that are only in want.
+	DiffSummaryRemovedLabel = "_removed"
+	// DiffSummaryChangedLabel is the summary column with
+	// the number of pairs of rows that differ.
+	DiffSummaryChangedLabel = "_changed"
+	// DiffSummaryColumnLabel is the summary column with the
+	// column that differs in the most changed rows.
+	DiffSummaryColumnLabel = "_column"
+)
+
+const (
+	// DiffModeOrdered compares the rows of each table in order.
+	DiffModeOrdered = "ordered"
+	// DiffModeMultiset compares the rows of each table as a
+	// multiset and ignores the order of the rows.
+	DiffModeMultiset = "multiset"
+)
+
 type DiffOpSpec struct {
 	Verbose   bool    `json:"verbose,omitempty"`
 	Epsilon   float64 `json:"epsilon"`
 	NaNsEqual bool    `json:"nansEqual,omitempty"`
+	Mode      string  `json:"mode,omitempty"`
+	// NumericLoose compares int and uint columns with float
+	// columns by value instead of reporting a type mismatch.
+	NumericLoose bool `json:"numericLoose,omitempty"`
+	// Partition splits the output into separate tables
+	// for each kind of difference.
+	Partition bool `json:"partition,omitempty"`
+	// DiffType restricts the partitioned output
+	// to a single kind of difference.
+	DiffType string `json:"diffType,omitempty"`
+	// On are the columns used to match the rows of each table.
+	// Rows are matched by position if there are no columns.
+	On []string `json:"on,omitempty"`
+	// MaxAlignRows is the maximum number of rows in a table
+	// for the rows of each table to be aligned before they
+	// are compared. Larger tables are compared by position.
+	MaxAlignRows int64 `json:"maxAlignRows,omitempty"`
+	// Epsilons are the epsilons of individual float columns.
+	// Other float columns use Epsilon.
+	Epsilons map[string]float64 `json:"epsilons,omitempty"`
+	// Relative compares float values by their difference relative
+	// to the larger magnitude of the two values instead of
+	// their absolute difference.
+	Relative bool `json:"relative,omitempty"`
+	// Summary outputs a table for each group key with the
+	// number of rows of each kind of difference instead of
+	// the rows that differ.
+	Summary bool `json:"summary,omitempty"`
+	// TimeEpsilon is the largest difference, in nanoseconds,
+	// between two time values that are considered equal.
+	TimeEpsilon int64 `json:"timeEpsilon,omitempty"`
+	// Ignore are the columns that are not compared
+	// and are left out of the output.
+	Ignore []string `json:"ignore,omitempty"`
+	// MaxDiffs is the maximum number of differing rows in the
+	// output for each group key. Zero does not limit the rows.
+	MaxDiffs int64 `json:"maxDiffs,omitempty"`
+	// UnmatchedKeys reports each group key that is only in
+	// want or got with a row before the rows of its table.
+	UnmatchedKeys bool `json:"unmatchedKeys,omitempty"`
+	// SortBy are the columns used to sort the rows of each
+	// table before they are compared. The rows are compared
+	// in the order they are received if there are no columns.
+	SortBy []string `json:"sortBy,omitempty"`
+	// Context is the number of equal rows written before and
+	// after each run of differing rows when rows are compared
+	// by their position.
+	Context int64 `json:"context,omitempty"`
 }
 
 func (s *DiffOpSpec) Kind() flux.OperationKind {
@@ -31,9 +155,11 @@ func (s *DiffOpSpec) Kind() flux.OperationKind {
 }
 
 func init() {
-	diffSignature := runtime.MustLookupBuiltinType("testing", "diff")
+	diffSignature := runtime.MustLookupBuiltinType("testing", "_diff")
+	diffSummarySignature := runtime.MustLookupBuiltinType("testing", "_diffSummary")
 
-	runtime.RegisterPackageValue("testing", "diff", flux.MustValue(flux.FunctionValue(DiffKind, createDiffOpSpec, diffSignature)))
+	runtime.RegisterPackageValue("testing", "_diff", flux.MustValue(flux.FunctionValue(DiffKind, createDiffOpSpec, diffSignature)))
+	runtime.RegisterPackageValue("testing", "_diffSummary", flux.MustValue(flux.FunctionValue(DiffKind, createDiffSummaryOpSpec, diffSummarySignature)))
 	flux.RegisterOpSpec(DiffKind, newDiffOp)
 	plan.RegisterProcedureSpec(DiffKind, newDiffProcedure, DiffKind)
 	execute.RegisterTransformation(DiffKind, createDiffTransformation)
@@ -80,7 +206,186 @@ func createDiffOpSpec(args flux.Arguments, a *flux.Administration) (flux.Operati
 		nansEqual = DefaultNaNsEqual
 	}
 
-	return &DiffOpSpec{Verbose: verbose, Epsilon: epsilon, NaNsEqual: nansEqual}, nil
+	mode, ok, err := args.GetString("mode")
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		mode = DiffModeOrdered
+	}
+	switch mode {
+	case DiffModeOrdered, DiffModeMultiset:
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown diff mode %q, expected %q or %q", mode, DiffModeOrdered, DiffModeMultiset)
+	}
+
+	numericLoose, _, err := args.GetBool("numericLoose")
+	if err != nil {
+		return nil, err
+	}
+	partition, _, err := args.GetBool("partition")
+	if err != nil {
+		return nil, err
+	}
+	diffType, ok, err := args.GetString("diffType")
+	if err != nil {
+		return nil, err
+	} else if ok {
+		if !partition {
+			return nil, errors.New(codes.Invalid, "diffType is only valid with partition")
+		}
+		switch diffType {
+		case DiffTypeAdded, DiffTypeRemoved, DiffTypeChanged, DiffTypeSchema,
+			DiffTypeTruncated, DiffTypeKey, DiffTypeContext:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown diff type %q", diffType)
+		}
+	}
+
+	var on []string
+	if arr, ok, err := args.GetArrayAllowEmpty("on", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		on, err = interpreter.ToStringArray(arr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(on) > 0 && mode != DiffModeOrdered {
+		return nil, errors.Newf(codes.Invalid, "on is only valid with mode %q", DiffModeOrdered)
+	}
+
+	maxAlignRows, ok, err := args.GetInt("maxAlignRows")
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		maxAlignRows = DefaultMaxAlignRows
+	} else if maxAlignRows < 0 {
+		return nil, errors.New(codes.Invalid, "maxAlignRows must not be negative")
+	}
+
+	relative, _, err := args.GetBool("relative")
+	if err != nil {
+		return nil, err
+	}
+
+	var epsilons map[string]float64
+	if o, ok, err := args.GetObject("epsilons"); err != nil {
+		return nil, err
+	} else if ok && o.Len() > 0 {
+		epsilons = make(map[string]float64, o.Len())
+		o.Range(func(label string, v values.Value) {
+			if err != nil {
+				return
+			}
+			if v.Type().Nature() != semantic.Float {
+				err = errors.Newf(codes.Invalid, "epsilon for column %q must be a float, got %s", label, v.Type())
+				return
+			}
+			epsilons[label] = v.Float()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var timeEpsilon int64
+	if d, ok, err := args.GetDuration("timeEpsilon"); err != nil {
+		return nil, err
+	} else if ok {
+		if d.IsNegative() {
+			return nil, errors.New(codes.Invalid, "timeEpsilon must not be negative")
+		} else if d.Months() != 0 {
+			return nil, errors.New(codes.Invalid, "timeEpsilon cannot contain month units")
+		}
+		timeEpsilon = d.Nanoseconds()
+	}
+
+	maxDiffs, _, err := args.GetInt("maxDiffs")
+	if err != nil {
+		return nil, err
+	} else if maxDiffs < 0 {
+		return nil, errors.New(codes.Invalid, "maxDiffs must not be negative")
+	}
+
+	unmatchedKeys, _, err := args.GetBool("unmatchedKeys")
+	if err != nil {
+		return nil, err
+	}
+
+	context, _, err := args.GetInt("context")
+	if err != nil {
+		return nil, err
+	} else if context < 0 {
+		return nil, errors.New(codes.Invalid, "context must not be negative")
+	} else if context > 0 && (mode != DiffModeOrdered || len(on) > 0) {
+		return nil, errors.Newf(codes.Invalid, "context is only valid with mode %q without on", DiffModeOrdered)
+	}
+
+	var ignore []string
+	if arr, ok, err := args.GetArrayAllowEmpty("ignore", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		ignore, err = interpreter.ToStringArray(arr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var sortBy []string
+	if arr, ok, err := args.GetArrayAllowEmpty("sortBy", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		sortBy, err = interpreter.ToStringArray(arr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, label := range ignore {
+		for _, o := range on {
+			if o == label {
+				return nil, errors.Newf(codes.Invalid, "diff column %q cannot be both in on and ignore", label)
+			}
+		}
+		for _, o := range sortBy {
+			if o == label {
+				return nil, errors.Newf(codes.Invalid, "diff column %q cannot be both in sortBy and ignore", label)
+			}
+		}
+		if _, ok := epsilons[label]; ok {
+			return nil, errors.Newf(codes.Invalid, "diff epsilon column %q is ignored", label)
+		}
+	}
+
+	return &DiffOpSpec{
+		Verbose:       verbose,
+		Epsilon:       epsilon,
+		NaNsEqual:     nansEqual,
+		Mode:          mode,
+		NumericLoose:  numericLoose,
+		Partition:     partition,
+		DiffType:      diffType,
+		On:            on,
+		MaxAlignRows:  maxAlignRows,
+		Epsilons:      epsilons,
+		Relative:      relative,
+		TimeEpsilon:   timeEpsilon,
+		Ignore:        ignore,
+		MaxDiffs:      maxDiffs,
+		UnmatchedKeys: unmatchedKeys,
+		SortBy:        sortBy,
+		Context:       context,
+	}, nil
+}
+
+// createDiffSummaryOpSpec creates a diff that outputs
+// a summary of the differences instead of the rows.
+func createDiffSummaryOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec, err := createDiffOpSpec(args, a)
+	if err != nil {
+		return nil, err
+	}
+	spec.(*DiffOpSpec).Summary = true
+	return spec, nil
 }
 
 func newDiffOp() flux.OperationSpec {
@@ -89,8 +394,24 @@ func newDiffOp() flux.OperationSpec {
 
 type DiffProcedureSpec struct {
 	plan.DefaultCost
-	Verbose bool
-	Epsilon float64
+	Verbose       bool
+	Epsilon       float64
+	NaNsEqual     bool
+	Mode          string
+	NumericLoose  bool
+	Partition     bool
+	DiffType      string
+	On            []string
+	MaxAlignRows  int64
+	Epsilons      map[string]float64
+	Relative      bool
+	Summary       bool
+	TimeEpsilon   int64
+	Ignore        []string
+	MaxDiffs      int64
+	UnmatchedKeys bool
+	SortBy        []string
+	Context       int64
 }
 
 func (s *DiffProcedureSpec) Kind() plan.ProcedureKind {
@@ -99,15 +420,59 @@ func (s *DiffProcedureSpec) Kind() plan.ProcedureKind {
 
 func (s *DiffProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := *s
+	if len(s.On) > 0 {
+		ns.On = make([]string, len(s.On))
+		copy(ns.On, s.On)
+	}
+	if len(s.Ignore) > 0 {
+		ns.Ignore = make([]string, len(s.Ignore))
+		copy(ns.Ignore, s.Ignore)
+	}
+	if len(s.SortBy) > 0 {
+		ns.SortBy = make([]string, len(s.SortBy))
+		copy(ns.SortBy, s.SortBy)
+	}
+	if len(s.Epsilons) > 0 {
+		ns.Epsilons = make(map[string]float64, len(s.Epsilons))
+		for label, epsilon := range s.Epsilons {
+			ns.Epsilons[label] = epsilon
+		}
+	}
 	return &ns
 }
 
+// TriggerSpec implements plan.TriggerAwareProcedureSpec so the diff of
+// each table is sent as soon as it is complete instead of when the
+// transformation is finished.
+func (s *DiffProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
 func newDiffProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
 	spec, ok := qs.(*DiffOpSpec)
 	if !ok {
 		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
 	}
-	return &DiffProcedureSpec{Verbose: spec.Verbose, Epsilon: spec.Epsilon}, nil
+	return &DiffProcedureSpec{
+		Verbose:       spec.Verbose,
+		Epsilon:       spec.Epsilon,
+		NaNsEqual:     spec.NaNsEqual,
+		Mode:          spec.Mode,
+		NumericLoose:  spec.NumericLoose,
+		Partition:     spec.Partition,
+		DiffType:      spec.DiffType,
+		On:            spec.On,
+		MaxAlignRows:  spec.MaxAlignRows,
+		Epsilons:      spec.Epsilons,
+		Relative:      spec.Relative,
+		Summary:       spec.Summary,
+		TimeEpsilon:   spec.TimeEpsilon,
+		Ignore:        spec.Ignore,
+		MaxDiffs:      spec.MaxDiffs,
+		UnmatchedKeys: spec.UnmatchedKeys,
+		SortBy:        spec.SortBy,
+		Context:       spec.Context,
+	}, nil
 }
 
 type DiffTransformation struct {
@@ -122,15 +487,36 @@ type DiffTransformation struct {
 	alloc *memory.Allocator
 
 	inputCache *execute.RandomAccessGroupLookup
-
-	epsilon   float64
-	nansEqual bool
+	// finished reports whether the dataset has been finished.
+	finished bool
+
+	verbose       bool
+	epsilon       float64
+	nansEqual     bool
+	mode          string
+	numericLoose  bool
+	partition     bool
+	diffType      string
+	on            []string
+	maxAlignRows  int
+	epsilons      map[string]float64
+	relative      bool
+	summary       bool
+	timeEpsilon   int64
+	ignore        []string
+	maxDiffs      int64
+	unmatchedKeys bool
+	sortBy        []string
+	context       int
 }
 
 type diffParentState struct {
 	mark       execute.Time
 	processing execute.Time
 	finished   bool
+	// keys holds the group key of each table
+	// that has been read from the parent.
+	keys *execute.RandomAccessGroupLookup
 }
 
 type tableBuffer struct {
@@ -150,16 +536,16 @@ type tableColumn struct {
 	Values array.Array
 }
 
-func copyTable(id execute.DatasetID, tbl flux.Table, alloc *memory.Allocator) (*tableBuffer, error) {
+func copyTable(id execute.DatasetID, tbl flux.Table, ignore []string, alloc *memory.Allocator) (*tableBuffer, error) {
 	// Find the value columns for the table and save them.
-	// We do not care about the group key.
+	// We do not care about the group key or the ignored columns.
 	type tableBuilderColumn struct {
 		Type    flux.ColType
 		Builder array.Builder
 	}
 	builders := make(map[string]tableBuilderColumn)
 	for _, col := range tbl.Cols() {
-		if tbl.Key().HasCol(col.Label) {
+		if tbl.Key().HasCol(col.Label) || isIgnored(col.Label, ignore) {
 			continue
 		}
 
@@ -187,7 +573,7 @@ func copyTable(id execute.DatasetID, tbl flux.Table, alloc *memory.Allocator) (*
 	if err := tbl.Do(func(cr flux.ColReader) error {
 		sz += cr.Len()
 		for j, col := range cr.Cols() {
-			if tbl.Key().HasCol(col.Label) {
+			if _, ok := builders[col.Label]; !ok {
 				continue
 			}
 
@@ -289,6 +675,173 @@ func copyTable(id execute.DatasetID, tbl flux.Table, alloc *memory.Allocator) (*
 	}, nil
 }
 
+// sort reorders the rows of the table by the values of the columns.
+// Null values are sorted first and rows with equal values keep their
+// order. Group key columns have the same value in every row so they
+// are not part of the buffer and do not change the order.
+func (tb *tableBuffer) sort(key flux.GroupKey, labels []string, alloc *memory.Allocator) error {
+	cols := make([]*tableColumn, 0, len(labels))
+	for _, label := range labels {
+		if key.HasCol(label) {
+			continue
+		}
+		col, ok := tb.columns[label]
+		if !ok {
+			return errors.Newf(codes.FailedPrecondition, "diff sortBy column %q does not exist", label)
+		}
+		cols = append(cols, col)
+	}
+
+	indices := make([]int, tb.sz)
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		for _, col := range cols {
+			if c := compareValues(col.Values, indices[i], indices[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	for _, col := range tb.columns {
+		vs, err := takeValues(col.Values, indices, alloc)
+		if err != nil {
+			return err
+		}
+		col.Values.Release()
+		col.Values = vs
+	}
+	return nil
+}
+
+// compareValues compares the values of the array at i and j.
+// It returns a negative number if the value at i is sorted first,
+// a positive number if the value at j is sorted first, and zero
+// if they are equal. Null values are sorted before other values.
+func compareValues(vs array.Array, i, j int) int {
+	if vs.IsNull(i) || vs.IsNull(j) {
+		switch {
+		case vs.IsValid(i):
+			return 1
+		case vs.IsValid(j):
+			return -1
+		default:
+			return 0
+		}
+	}
+
+	switch vs := vs.(type) {
+	case *array.Float:
+		if a, b := vs.Value(i), vs.Value(j); a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case *array.Int:
+		if a, b := vs.Value(i), vs.Value(j); a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case *array.Uint:
+		if a, b := vs.Value(i), vs.Value(j); a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case *array.String:
+		return strings.Compare(vs.Value(i), vs.Value(j))
+	case *array.Boolean:
+		if a, b := vs.Value(i), vs.Value(j); !a && b {
+			return -1
+		} else if a && !b {
+			return 1
+		}
+	}
+	return 0
+}
+
+// takeValues returns an array with the values
+// of the array at each of the indices in order.
+func takeValues(vs array.Array, indices []int, alloc *memory.Allocator) (array.Array, error) {
+	switch vs := vs.(type) {
+	case *array.Float:
+		b := arrow.NewFloatBuilder(alloc)
+		defer b.Release()
+		b.Reserve(len(indices))
+		for _, i := range indices {
+			if vs.IsValid(i) {
+				b.Append(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Int:
+		b := arrow.NewIntBuilder(alloc)
+		defer b.Release()
+		b.Reserve(len(indices))
+		for _, i := range indices {
+			if vs.IsValid(i) {
+				b.Append(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Uint:
+		b := arrow.NewUintBuilder(alloc)
+		defer b.Release()
+		b.Reserve(len(indices))
+		for _, i := range indices {
+			if vs.IsValid(i) {
+				b.Append(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray(), nil
+	case *array.String:
+		b := arrow.NewStringBuilder(alloc)
+		defer b.Release()
+		b.Reserve(len(indices))
+		for _, i := range indices {
+			if vs.IsValid(i) {
+				b.Append(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Boolean:
+		b := arrow.NewBoolBuilder(alloc)
+		defer b.Release()
+		b.Reserve(len(indices))
+		for _, i := range indices {
+			if vs.IsValid(i) {
+				b.Append(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray(), nil
+	default:
+		return nil, errors.New(codes.Unimplemented)
+	}
+}
+
+// isIgnored reports whether the column is one of the ignored columns.
+func isIgnored(label string, ignore []string) bool {
+	for _, l := range ignore {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
 func createDiffTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
 	if len(a.Parents()) != 2 {
 		return nil, nil, errors.New(codes.Internal, "diff should have exactly 2 parents")
@@ -308,17 +861,34 @@ func createDiffTransformation(id execute.DatasetID, mode execute.AccumulationMod
 
 func NewDiffTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *DiffProcedureSpec, wantID, gotID execute.DatasetID, a *memory.Allocator) *DiffTransformation {
 	parentState := make(map[execute.DatasetID]*diffParentState)
-	parentState[wantID] = new(diffParentState)
-	parentState[gotID] = new(diffParentState)
+	parentState[wantID] = &diffParentState{keys: execute.NewRandomAccessGroupLookup()}
+	parentState[gotID] = &diffParentState{keys: execute.NewRandomAccessGroupLookup()}
 	return &DiffTransformation{
-		wantID:      wantID,
-		gotID:       gotID,
-		d:           d,
-		cache:       cache,
-		inputCache:  execute.NewRandomAccessGroupLookup(),
-		parentState: parentState,
-		alloc:       a,
-		epsilon:     spec.Epsilon,
+		wantID:        wantID,
+		gotID:         gotID,
+		d:             d,
+		cache:         cache,
+		inputCache:    execute.NewRandomAccessGroupLookup(),
+		parentState:   parentState,
+		alloc:         a,
+		verbose:       spec.Verbose,
+		epsilon:       spec.Epsilon,
+		nansEqual:     spec.NaNsEqual,
+		mode:          spec.Mode,
+		numericLoose:  spec.NumericLoose,
+		partition:     spec.Partition,
+		diffType:      spec.DiffType,
+		on:            spec.On,
+		maxAlignRows:  int(spec.MaxAlignRows),
+		epsilons:      spec.Epsilons,
+		relative:      spec.Relative,
+		summary:       spec.Summary,
+		timeEpsilon:   spec.TimeEpsilon,
+		ignore:        spec.Ignore,
+		maxDiffs:      spec.MaxDiffs,
+		unmatchedKeys: spec.UnmatchedKeys,
+		sortBy:        spec.SortBy,
+		context:       int(spec.Context),
 	}
 }
 
@@ -338,12 +908,39 @@ func (t *DiffTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 		return nil
 	}
 
+	// Each parent must send at most one table for each group key.
+	// A second table would replace the cached table or be diffed
+	// against a table that was already diffed.
+	keys := t.parentState[id].keys
+	if _, ok := keys.Lookup(tbl.Key()); ok {
+		side := "got"
+		if id == t.wantID {
+			side = "want"
+		}
+		return errors.Newf(codes.FailedPrecondition, "diff found more than one %s table with key %v", side, tbl.Key())
+	}
+	keys.Set(tbl.Key(), true)
+
+	// The values of group key columns are the same in both
+	// tables so ignoring one of them is likely a mistake.
+	for _, label := range t.ignore {
+		if tbl.Key().HasCol(label) {
+			return errors.Newf(codes.Invalid, "diff cannot ignore group key column %q", label)
+		}
+	}
+
 	// Copy the table we are processing into a buffer.
 	// This may or may not be the want table. We fix that later.
-	want, err := copyTable(id, tbl, t.alloc)
+	want, err := copyTable(id, tbl, t.ignore, t.alloc)
 	if err != nil {
 		return err
 	}
+	if len(t.sortBy) > 0 {
+		if err := want.sort(tbl.Key(), t.sortBy, t.alloc); err != nil {
+			want.Release()
+			return err
+		}
+	}
 
 	// Look in the input cache for a table buffer.
 	var got *tableBuffer
@@ -351,7 +948,7 @@ func (t *DiffTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 		// We did not find an entry. If the other table has
 		// not been finished, we need to store this table
 		// for later usage.
-		if !t.parentState[id].finished {
+		if !t.parentState[t.otherID(id)].finished {
 			t.inputCache.Set(tbl.Key(), want)
 			return nil
 		}
@@ -375,20 +972,42 @@ func (t *DiffTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 	return t.diff(tbl.Key(), want, got)
 }
 
-func (t *DiffTransformation) createSchema(builder execute.TableBuilder, want, got *tableBuffer) (diffIdx int, colMap map[string]int, err error) {
+func (t *DiffTransformation) createSchema(builder execute.TableBuilder, want, got *tableBuffer) (*diffOutputTable, error) {
 	// Construct the table schema by adding columns for the table key
 	// (which, by definition, cannot be different at this point),
-	// a _diff column for the marker, and then the columns  for each
-	// of the value types in alphabetical order.
+	// a _diff column for the marker, a _diff_detail column if the
+	// diff is verbose, a _diff_remaining column if the number of
+	// rows is limited, and then the columns  for each of the value
+	// types in alphabetical order.
 	if err := execute.AddTableKeyCols(builder.Key(), builder); err != nil {
-		return 0, nil, err
+		return nil, err
 	}
-	diffIdx, err = builder.AddCol(flux.ColMeta{
+	diffIdx, err := builder.AddCol(flux.ColMeta{
 		Label: "_diff",
 		Type:  flux.TString,
 	})
 	if err != nil {
-		return 0, nil, err
+		return nil, err
+	}
+	detailIdx := -1
+	if t.verbose {
+		detailIdx, err = builder.AddCol(flux.ColMeta{
+			Label: DiffDetailLabel,
+			Type:  flux.TString,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	remainingIdx := -1
+	if t.maxDiffs > 0 {
+		remainingIdx, err = builder.AddCol(flux.ColMeta{
+			Label: DiffRemainingLabel,
+			Type:  flux.TInt,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Determine all of the column names and their types.
@@ -398,7 +1017,11 @@ func (t *DiffTransformation) createSchema(builder execute.TableBuilder, want, go
 	}
 	for label, col := range got.columns {
 		if typ, ok := colTypes[label]; ok && typ != col.Type {
-			return 0, nil, errors.Newf(codes.FailedPrecondition, "column types differ: want=%s got=%s", typ, col.Type)
+			if !t.looseNumeric(typ, col.Type) {
+				return nil, errors.Newf(codes.FailedPrecondition, "column types differ: want=%s got=%s", typ, col.Type)
+			}
+			// Report both values as floats.
+			colTypes[label] = flux.TFloat
 		} else if !ok {
 			colTypes[label] = col.Type
 		}
@@ -411,24 +1034,65 @@ func (t *DiffTransformation) createSchema(builder execute.TableBuilder, want, go
 	sort.Strings(labels)
 
 	// Now construct the schema and mark the column ids.
-	colMap = make(map[string]int)
+	colMap := make(map[string]int)
 	for _, label := range labels {
 		idx, err := builder.AddCol(flux.ColMeta{
 			Label: label,
 			Type:  colTypes[label],
 		})
 		if err != nil {
-			return 0, nil, err
+			return nil, err
 		}
 		colMap[label] = idx
 	}
-	return diffIdx, colMap, nil
+	return &diffOutputTable{
+		builder:      builder,
+		diffIdx:      diffIdx,
+		detailIdx:    detailIdx,
+		remainingIdx: remainingIdx,
+		colMap:       colMap,
+	}, nil
 }
 
 func (t *DiffTransformation) diff(key flux.GroupKey, want, got *tableBuffer) error {
 	defer want.Release()
 	defer got.Release()
 
+	if err := t.validateEpsilons(key, want, got); err != nil {
+		return err
+	}
+
+	out := t.newDiffOutput(key, want, got)
+	if err := out.appendKeyDiff(); err != nil {
+		return err
+	}
+	if err := out.appendSchemaDiff(); err != nil {
+		return err
+	}
+
+	var err error
+	if t.mode == DiffModeMultiset {
+		err = t.diffMultiset(out, want, got)
+	} else if len(t.on) > 0 {
+		err = t.diffOn(out, want, got)
+	} else if want.sz <= t.maxAlignRows && got.sz <= t.maxAlignRows {
+		err = t.diffAligned(out, want, got)
+	} else {
+		err = t.diffOrdered(out, want, got)
+	}
+	if err != nil {
+		return err
+	}
+
+	if t.summary {
+		return out.writeSummary()
+	}
+	return out.appendTruncated()
+}
+
+// diffOrdered compares the row at each position of want
+// with the row at the same position of got.
+func (t *DiffTransformation) diffOrdered(out *diffOutput, want, got *tableBuffer) error {
 	// Find the smallest size for the tables. We will only iterate
 	// over these rows.
 	sz := want.sz
@@ -441,7 +1105,7 @@ func (t *DiffTransformation) diff(key flux.GroupKey, want, got *tableBuffer) err
 	i := 0
 	if want.sz == got.sz {
 		for ; i < sz; i++ {
-			if eq := t.rowEqual(want, got, i); !eq {
+			if eq := t.rowEqual(want, got, i, i); !eq {
 				break
 			}
 		}
@@ -450,117 +1114,1039 @@ func (t *DiffTransformation) diff(key flux.GroupKey, want, got *tableBuffer) err
 		if i == sz {
 			return nil
 		}
-	}
-
-	// This diff algorithm is not really a smart diff. We may want to
-	// fix that in the future and we reserve the right to do that, but
-	// this will just check the first row of one table with the first
-	// row of the other.
-	// First, construct an output table.
-	builder, created := t.cache.TableBuilder(key)
-	if !created {
-		return errors.New(codes.FailedPrecondition, "duplicate table key")
-	}
 
-	diffIdx, columnIdxs, err := t.createSchema(builder, want, got)
-	if err != nil {
-		return err
+		// The equal rows before the first unequal
+		// row are only needed for their context.
+		if i -= t.context; i < 0 {
+			i = 0
+		}
 	}
 
+	// The tables are too large to align so this will just check
+	// the first row of one table with the first row of the other.
+	// This is also used when there is not enough memory to align them.
 	for ; i < sz; i++ {
-		if eq := t.rowEqual(want, got, i); !eq {
-			if err := t.appendRow(builder, i, diffIdx, "-", want, columnIdxs); err != nil {
-				return err
-			}
-			if err := t.appendRow(builder, i, diffIdx, "+", got, columnIdxs); err != nil {
+		if eq := t.rowEqual(want, got, i, i); !eq {
+			if err := out.appendChanged(i, i); err != nil {
 				return err
 			}
+		} else if err := out.appendEqual(i); err != nil {
+			return err
 		}
 	}
 
 	// Append the remainder of the rows.
 	for i := sz; i < want.sz; i++ {
-		if err := t.appendRow(builder, i, diffIdx, "-", want, columnIdxs); err != nil {
+		if err := out.appendRow(DiffTypeRemoved, i, "-", want, ""); err != nil {
 			return err
 		}
 	}
 	for i := sz; i < got.sz; i++ {
-		if err := t.appendRow(builder, i, diffIdx, "+", got, columnIdxs); err != nil {
+		if err := out.appendRow(DiffTypeAdded, i, "+", got, ""); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *DiffTransformation) rowEqual(want, got *tableBuffer, i int) bool {
-	if len(want.columns) != len(got.columns) {
-		return false
+// validateEpsilons checks that each column with its own
+// epsilon exists and is a float column.
+func (t *DiffTransformation) validateEpsilons(key flux.GroupKey, want, got *tableBuffer) error {
+	for label := range t.epsilons {
+		exists := false
+		for _, tbl := range []*tableBuffer{want, got} {
+			if col, ok := tbl.columns[label]; ok {
+				if col.Type != flux.TFloat {
+					return errors.Newf(codes.Invalid, "diff epsilon column %q must be of type %s, got %s", label, flux.TFloat, col.Type)
+				}
+				exists = true
+			}
+		}
+		if idx := execute.ColIdx(label, key.Cols()); idx >= 0 {
+			if typ := key.Cols()[idx].Type; typ != flux.TFloat {
+				return errors.Newf(codes.Invalid, "diff epsilon column %q must be of type %s, got %s", label, flux.TFloat, typ)
+			}
+			exists = true
+		}
+		if !exists {
+			return errors.Newf(codes.FailedPrecondition, "diff epsilon column %q does not exist", label)
+		}
 	}
+	return nil
+}
 
-	for label, wantCol := range want.columns {
-		gotCol, ok := got.columns[label]
-		if !ok {
-			return false
-		}
+// epsilonFor returns the epsilon used to compare the values of the column.
+func (t *DiffTransformation) epsilonFor(label string) float64 {
+	if epsilon, ok := t.epsilons[label]; ok {
+		return epsilon
+	}
+	return t.epsilon
+}
 
-		if wantCol.Values.IsValid(i) != gotCol.Values.IsValid(i) {
-			return false
-		} else if wantCol.Values.IsNull(i) {
-			continue
-		}
+// tolerance returns how the epsilon is applied to float values.
+func (t *DiffTransformation) tolerance() string {
+	if t.relative {
+		return ToleranceRelative
+	}
+	return ToleranceAbsolute
+}
 
-		switch wantCol.Type {
-		case flux.TFloat:
-			want, got := wantCol.Values.(*array.Float).Value(i), gotCol.Values.(*array.Float).Value(i)
-			if t.nansEqual && math.IsNaN(want) && math.IsNaN(got) {
-				// treat NaNs as equal so go to next column
-				continue
-			}
-			if math.Abs(want-got) > t.epsilon {
-				return false
-			}
-		case flux.TInt:
-			want, got := wantCol.Values.(*array.Int), gotCol.Values.(*array.Int)
-			if want.Value(i) != got.Value(i) {
-				return false
-			}
-		case flux.TUInt:
-			want, got := wantCol.Values.(*array.Uint), gotCol.Values.(*array.Uint)
-			if want.Value(i) != got.Value(i) {
-				return false
-			}
-		case flux.TString:
-			want, got := wantCol.Values.(*array.String), gotCol.Values.(*array.String)
-			if want.Value(i) != got.Value(i) {
-				return false
-			}
-		case flux.TBool:
-			want, got := wantCol.Values.(*array.Boolean), gotCol.Values.(*array.Boolean)
-			if want.Value(i) != got.Value(i) {
-				return false
-			}
-		case flux.TTime:
-			want, got := wantCol.Values.(*array.Int), gotCol.Values.(*array.Int)
-			if want.Value(i) != got.Value(i) {
-				return false
-			}
+// diffDetail describes the first shared column, in alphabetical order,
+// whose value differs between row i of want and row j of got. The
+// difference of the values is included for float columns.
+func (t *DiffTransformation) diffDetail(want, got *tableBuffer, i, j int) string {
+	for _, label := range sharedColumns(want, got) {
+		wantCol, gotCol := want.columns[label], got.columns[label]
+		switch {
+		case t.valueEqual(label, wantCol, gotCol, i, j):
+			continue
+		case wantCol.Values.IsNull(i):
+			return strconv.Quote(label) + " is null in want"
+		case gotCol.Values.IsNull(j):
+			return strconv.Quote(label) + " is null in got"
+		case wantCol.Type == flux.TFloat && gotCol.Type == flux.TFloat:
+			delta := gotCol.Values.(*array.Float).Value(j) - wantCol.Values.(*array.Float).Value(i)
+			return strconv.Quote(label) + " differs by " + strconv.FormatFloat(delta, 'g', -1, 64)
 		default:
-			return false
+			return strconv.Quote(label) + " differs"
 		}
 	}
-	return true
+	return ""
 }
 
-func (t *DiffTransformation) appendRow(builder execute.TableBuilder, i, diffIdx int, diff string, tbl *tableBuffer, colMap map[string]int) error {
-	// Add the want column first.
-	if err := execute.AppendKeyValues(builder.Key(), builder); err != nil {
-		return err
+// diffOutput creates the output tables of a diff as rows are appended.
+// If the diff is partitioned, there is a table for each kind of difference
+// with the kind added to the group key. Otherwise, there is a single table.
+// When the diff is restricted to one kind of difference, the rows of every
+// other kind are left out but still counted by the limit.
+//
+// If the diff is a summary, the rows are counted instead of appended.
+type diffOutput struct {
+	t         *DiffTransformation
+	key       flux.GroupKey
+	want, got *tableBuffer
+	tables    map[string]*diffOutputTable
+
+	added, removed, changed int64
+	// columns counts the changed rows in which each column differs.
+	columns map[string]int64
+
+	// rows is the number of differing rows that were appended
+	// and remaining is the number of differing rows that were
+	// left out because the number of rows is limited.
+	rows, remaining int64
+
+	// context holds the last equal rows of want that are written
+	// if a differing row follows them, and after is the number of
+	// equal rows that are still written after a differing row.
+	context []int
+	after   int
+}
+
+type diffOutputTable struct {
+	builder      execute.TableBuilder
+	diffIdx      int
+	detailIdx    int
+	remainingIdx int
+	colMap       map[string]int
+}
+
+func (t *DiffTransformation) newDiffOutput(key flux.GroupKey, want, got *tableBuffer) *diffOutput {
+	return &diffOutput{
+		t:      t,
+		key:    key,
+		want:   want,
+		got:    got,
+		tables: make(map[string]*diffOutputTable),
 	}
-	// Add the diff column.
-	if err := builder.AppendString(diffIdx, diff); err != nil {
+}
+
+// appendRow appends a row of the table to the output table for the
+// kind of difference. The detail is only reported if the diff is
+// verbose and an empty detail is reported as null.
+func (o *diffOutput) appendRow(diffType string, i int, diff string, tbl *tableBuffer, detail string) error {
+	if o.t.summary {
+		switch diffType {
+		case DiffTypeAdded:
+			o.added++
+		case DiffTypeRemoved:
+			o.removed++
+		}
+		return nil
+	}
+
+	if o.truncate(1) {
+		return nil
+	}
+	if err := o.appendContext(); err != nil {
 		return err
 	}
-	// Add all of the values.
+	return o.writeRow(diffType, i, diff, tbl, detail)
+}
+
+// appendEqual appends row i of want as a context row if it closely
+// follows a differing row. Otherwise, the row is kept in case it
+// closely precedes the next differing row. Context rows are not
+// counted by the limit, but none are appended once a differing
+// row has been left out so the output remains a prefix of the diff.
+func (o *diffOutput) appendEqual(i int) error {
+	if o.t.context == 0 || o.t.summary || o.remaining > 0 {
+		return nil
+	}
+	if o.after > 0 {
+		o.after--
+		return o.writeRow(DiffTypeContext, i, DiffContextMarker, o.want, "")
+	}
+	if len(o.context) == o.t.context {
+		copy(o.context, o.context[1:])
+		o.context = o.context[:len(o.context)-1]
+	}
+	o.context = append(o.context, i)
+	return nil
+}
+
+// appendContext appends the context rows that precede
+// the differing row that is about to be appended.
+func (o *diffOutput) appendContext() error {
+	for _, i := range o.context {
+		if err := o.writeRow(DiffTypeContext, i, DiffContextMarker, o.want, ""); err != nil {
+			return err
+		}
+	}
+	o.context = o.context[:0]
+	o.after = o.t.context
+	return nil
+}
+
+// writeRow writes a row of the table to the output table
+// for the kind of difference without checking the limit.
+func (o *diffOutput) writeRow(diffType string, i int, diff string, tbl *tableBuffer, detail string) error {
+	out, err := o.table(diffType)
+	if err != nil || out == nil {
+		return err
+	}
+	if err := o.t.appendRow(out.builder, i, out.diffIdx, diff, tbl, out.colMap); err != nil {
+		return err
+	}
+	if err := out.appendDetail(detail); err != nil {
+		return err
+	}
+	return out.appendRemaining(0)
+}
+
+// truncate reports whether the next n differing rows are left out
+// of the output and counts them. Once a row is left out, every row
+// after it is left out too so the output is a prefix of the diff.
+// A changed pair of rows is counted together so it is never split.
+func (o *diffOutput) truncate(n int64) bool {
+	if o.t.maxDiffs > 0 && (o.remaining > 0 || o.rows+n > o.t.maxDiffs) {
+		o.remaining += n
+		return true
+	}
+	o.rows += n
+	return false
+}
+
+// appendTruncated appends a row with the number of differing rows
+// that were left out of the output. There is no such row if every
+// differing row was appended.
+func (o *diffOutput) appendTruncated() error {
+	if o.remaining == 0 {
+		return nil
+	}
+	out, err := o.table(DiffTypeTruncated)
+	if err != nil || out == nil {
+		return err
+	}
+	return out.appendNullRow(DiffTruncatedMarker, "", o.remaining)
+}
+
+// table returns the output table for the kind of difference
+// and creates it if it does not exist. There is no table if the
+// diff is restricted to a different kind of difference.
+func (o *diffOutput) table(diffType string) (*diffOutputTable, error) {
+	if !o.t.partition {
+		diffType = ""
+	} else if o.t.diffType != "" && diffType != o.t.diffType {
+		return nil, nil
+	}
+	out, ok := o.tables[diffType]
+	if !ok {
+		key := o.key
+		var err error
+		if diffType != "" {
+			key, err = execute.NewGroupKeyBuilder(o.key).
+				SetKeyValue(DiffTypeLabel, values.NewString(diffType)).
+				Build()
+			if err != nil {
+				return nil, err
+			}
+		}
+		builder, created := o.t.cache.TableBuilder(key)
+		if !created {
+			return nil, errors.New(codes.FailedPrecondition, "duplicate table key")
+		}
+		out, err = o.t.createSchema(builder, o.want, o.got)
+		if err != nil {
+			return nil, err
+		}
+		o.tables[diffType] = out
+	}
+	return out, nil
+}
+
+// appendDetail appends the detail of the last row if the diff
+// is verbose. An empty detail is appended as null.
+func (out *diffOutputTable) appendDetail(detail string) error {
+	if out.detailIdx < 0 {
+		return nil
+	} else if detail == "" {
+		return out.builder.AppendNil(out.detailIdx)
+	}
+	return out.builder.AppendString(out.detailIdx, detail)
+}
+
+// appendNullRow appends a row with the _diff marker, the detail and
+// the number of rows left out that holds the values of the group key
+// and is null in every other column.
+func (out *diffOutputTable) appendNullRow(diff, detail string, remaining int64) error {
+	if err := execute.AppendKeyValues(out.builder.Key(), out.builder); err != nil {
+		return err
+	}
+	if err := out.builder.AppendString(out.diffIdx, diff); err != nil {
+		return err
+	}
+	for _, j := range out.colMap {
+		if err := out.builder.AppendNil(j); err != nil {
+			return err
+		}
+	}
+	if err := out.appendDetail(detail); err != nil {
+		return err
+	}
+	return out.appendRemaining(remaining)
+}
+
+// appendRemaining appends the number of differing rows that were
+// left out if the number of rows is limited. Zero is appended as null.
+func (out *diffOutputTable) appendRemaining(n int64) error {
+	if out.remainingIdx < 0 {
+		return nil
+	} else if n == 0 {
+		return out.builder.AppendNil(out.remainingIdx)
+	}
+	return out.builder.AppendInt(out.remainingIdx, n)
+}
+
+// appendSchemaDiff appends a row for each column that is only in one
+// of the tables before the rows that differ. The _diff marker of the
+// row is the name of the column prefixed with - if the column is only
+// in want or + if it is only in got, and every value is null.
+// There are no such rows if a table is missing entirely since each of
+// its rows is reported instead, or if the diff is a summary.
+func (o *diffOutput) appendSchemaDiff() error {
+	if o.t.summary || o.want.columns == nil || o.got.columns == nil {
+		return nil
+	}
+
+	var diffs []string
+	for label := range o.want.columns {
+		if _, ok := o.got.columns[label]; !ok {
+			diffs = append(diffs, "-"+label)
+		}
+	}
+	for label := range o.got.columns {
+		if _, ok := o.want.columns[label]; !ok {
+			diffs = append(diffs, "+"+label)
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i][1:] < diffs[j][1:]
+	})
+
+	out, err := o.table(DiffTypeSchema)
+	if err != nil || out == nil {
+		return err
+	}
+	for _, diff := range diffs {
+		table := "want"
+		if diff[0] == '+' {
+			table = "got"
+		}
+		if err := out.appendNullRow(diff, strconv.Quote(diff[1:])+" is only in "+table, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendKeyDiff appends a row that reports the group key if it is only
+// in one of the tables and unmatched keys are reported. The _diff marker
+// of the row is - if the group key is only in want or + if it is only
+// in got, and every value that is not part of the group key is null.
+// The row is added even if the table has no rows so the group key is
+// never left out of the output. It is not counted by maxDiffs.
+func (o *diffOutput) appendKeyDiff() error {
+	if !o.t.unmatchedKeys || o.t.summary {
+		return nil
+	}
+
+	diff, table := "-", "want"
+	if o.want.columns == nil {
+		diff, table = "+", "got"
+	} else if o.got.columns != nil {
+		return nil
+	}
+
+	out, err := o.table(DiffTypeKey)
+	if err != nil || out == nil {
+		return err
+	}
+	return out.appendNullRow(diff, "group key is only in "+table, 0)
+}
+
+// appendChanged appends row i of want and row j of got as a pair of
+// changed rows. The detail of the pair is computed if the diff is verbose.
+func (o *diffOutput) appendChanged(i, j int) error {
+	if o.t.summary {
+		o.changed++
+		o.countColumns(i, j)
+		return nil
+	}
+
+	if o.truncate(2) {
+		return nil
+	}
+	if err := o.appendContext(); err != nil {
+		return err
+	}
+	var detail string
+	if o.t.verbose {
+		detail = o.t.diffDetail(o.want, o.got, i, j)
+	}
+	if err := o.writeRow(DiffTypeChanged, i, "-", o.want, detail); err != nil {
+		return err
+	}
+	return o.writeRow(DiffTypeChanged, j, "+", o.got, detail)
+}
+
+// countColumns counts each column that differs
+// between row i of want and row j of got.
+func (o *diffOutput) countColumns(i, j int) {
+	if o.columns == nil {
+		o.columns = make(map[string]int64)
+	}
+	for _, label := range sharedColumns(o.want, o.got) {
+		if !o.t.valueEqual(label, o.want.columns[label], o.got.columns[label], i, j) {
+			o.columns[label]++
+		}
+	}
+}
+
+// writeSummary writes a table with the group key, the number of
+// rows of each kind of difference and the column that differs in
+// the most changed rows. Ties are broken by the column name.
+func (o *diffOutput) writeSummary() error {
+	builder, created := o.t.cache.TableBuilder(o.key)
+	if !created {
+		return errors.New(codes.FailedPrecondition, "duplicate table key")
+	}
+	if err := execute.AddTableKeyCols(o.key, builder); err != nil {
+		return err
+	}
+	cols := []flux.ColMeta{
+		{Label: DiffSummaryAddedLabel, Type: flux.TInt},
+		{Label: DiffSummaryRemovedLabel, Type: flux.TInt},
+		{Label: DiffSummaryChangedLabel, Type: flux.TInt},
+		{Label: DiffSummaryColumnLabel, Type: flux.TString},
+	}
+	idx := make([]int, len(cols))
+	for k, col := range cols {
+		j, err := builder.AddCol(col)
+		if err != nil {
+			return err
+		}
+		idx[k] = j
+	}
+
+	if err := execute.AppendKeyValues(o.key, builder); err != nil {
+		return err
+	}
+	for k, n := range []int64{o.added, o.removed, o.changed} {
+		if err := builder.AppendInt(idx[k], n); err != nil {
+			return err
+		}
+	}
+
+	var column string
+	for label, n := range o.columns {
+		if most := o.columns[column]; n > most || (n == most && label < column) {
+			column = label
+		}
+	}
+	if column == "" {
+		return builder.AppendNil(idx[3])
+	}
+	return builder.AppendString(idx[3], column)
+}
+
+// diffMultiset compares the tables as multisets of rows.
+// Each row is converted to a key using the columns in both tables
+// and the number of times each key occurs in each table is counted.
+// When a key occurs more often in one table, the excess rows are
+// reported as missing from the other table in the order they appear.
+//
+// Float values are rounded to the nearest multiple of epsilon
+// when the key is computed so two values only compare as equal
+// if they round to the same multiple. This means two values that
+// are within epsilon of each other may still be reported as
+// different if they are on either side of a rounding boundary.
+// When the tolerance is relative, the fraction of each float value
+// is rounded instead so the rounding is relative to its exponent.
+// Time values are rounded to the nearest multiple of the time epsilon
+// in the same way.
+func (t *DiffTransformation) diffMultiset(out *diffOutput, want, got *tableBuffer) error {
+	labels := sharedColumns(want, got)
+	loose := t.looseColumns(want, got)
+	wantKeys := t.rowKeys(want, labels, loose, "-")
+	gotKeys := t.rowKeys(got, labels, loose, "+")
+
+	// Count the number of times each key occurs in want
+	// and subtract the number of times it occurs in got.
+	counts := make(map[string]int, len(wantKeys))
+	for _, k := range wantKeys {
+		counts[k]++
+	}
+	for _, k := range gotKeys {
+		counts[k]--
+	}
+
+	equal := true
+	for _, n := range counts {
+		if n != 0 {
+			equal = false
+			break
+		}
+	}
+	if equal {
+		return nil
+	}
+
+	for i, k := range wantKeys {
+		if counts[k] > 0 {
+			counts[k]--
+			if err := out.appendRow(DiffTypeRemoved, i, "-", want, ""); err != nil {
+				return err
+			}
+		}
+	}
+	for i, k := range gotKeys {
+		if counts[k] < 0 {
+			counts[k]++
+			if err := out.appendRow(DiffTypeAdded, i, "+", got, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffAligned compares the tables using the shortest edit script
+// between their rows. A row that is only in one table is reported by
+// itself instead of shifting every row that follows it. Within each run
+// of rows that are not in the script as equal, removed and added rows
+// are paired in order and reported as changed, and the remaining rows
+// are reported as removed or added. If the memory limit is reached
+// while the script is computed, the rows are compared by position.
+func (t *DiffTransformation) diffAligned(out *diffOutput, want, got *tableBuffer) error {
+	edits, err := t.editScript(want, got)
+	if err != nil {
+		// There is not enough memory to align the rows,
+		// so they are compared by position instead.
+		if errors.Code(err) == codes.ResourceExhausted {
+			return t.diffOrdered(out, want, got)
+		}
+		return err
+	} else if len(edits) == 0 {
+		return nil
+	}
+
+	var removed, added []int
+	flush := func() error {
+		n := len(removed)
+		if len(added) < n {
+			n = len(added)
+		}
+		for k := 0; k < n; k++ {
+			if err := out.appendChanged(removed[k], added[k]); err != nil {
+				return err
+			}
+		}
+		for _, i := range removed[n:] {
+			if err := out.appendRow(DiffTypeRemoved, i, "-", want, ""); err != nil {
+				return err
+			}
+		}
+		for _, j := range added[n:] {
+			if err := out.appendRow(DiffTypeAdded, j, "+", got, ""); err != nil {
+				return err
+			}
+		}
+		removed, added = removed[:0], added[:0]
+		return nil
+	}
+
+	// The script leaves out the equal rows at the start and end of
+	// the tables so the rows around it are added for their context.
+	start := edits[0].i
+	if edits[0].op == editAdd {
+		start = edits[0].j
+	}
+	for i := start - t.context; i < start; i++ {
+		if i < 0 {
+			continue
+		}
+		if err := out.appendEqual(i); err != nil {
+			return err
+		}
+	}
+	end := start
+	for _, e := range edits {
+		switch e.op {
+		case editRemove:
+			removed = append(removed, e.i)
+			end = e.i + 1
+		case editAdd:
+			added = append(added, e.j)
+		default:
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := out.appendEqual(e.i); err != nil {
+				return err
+			}
+			end = e.i + 1
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	for i := end; i < want.sz && i < end+t.context; i++ {
+		if err := out.appendEqual(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	editEqual = iota
+	editRemove
+	editAdd
+)
+
+// diffEdit is an operation of an edit script. Row i of want is
+// removed, row j of got is added, or row i is equal to row j.
+type diffEdit struct {
+	op   int
+	i, j int
+}
+
+// editScript computes the shortest edit script that transforms the
+// rows of want into the rows of got using the algorithm described in
+// "An O(ND) Difference Algorithm and Its Variations" by Eugene W. Myers.
+// The equal rows at the start and end of the tables are matched before
+// the search and are not included in the script, so the script is empty
+// if the tables are equal.
+//
+// The search records the furthest point of each diagonal for every
+// edit so the script can be recovered. The memory for these points grows
+// with the square of the number of edits and is accounted for with the
+// allocator of the transformation, which is why the size of the tables
+// that are aligned is limited.
+func (t *DiffTransformation) editScript(want, got *tableBuffer) ([]diffEdit, error) {
+	start := 0
+	for start < want.sz && start < got.sz && t.rowEqual(want, got, start, start) {
+		start++
+	}
+	wantEnd, gotEnd := want.sz, got.sz
+	for wantEnd > start && gotEnd > start && t.rowEqual(want, got, wantEnd-1, gotEnd-1) {
+		wantEnd--
+		gotEnd--
+	}
+
+	n, m := wantEnd-start, gotEnd-start
+	if n == 0 && m == 0 {
+		return nil, nil
+	}
+
+	// The points are indexed by diagonal k = x - y,
+	// offset by max so the index is never negative.
+	max := n + m
+	accounted := 0
+	defer func() {
+		t.alloc.Account(-accounted)
+	}()
+	account := func(points int) error {
+		size := points * 4
+		if err := t.alloc.Account(size); err != nil {
+			return err
+		}
+		accounted += size
+		return nil
+	}
+	if err := account(2*max + 2); err != nil {
+		return nil, err
+	}
+	v := make([]int32, 2*max+2)
+	var trace [][]int32
+	for d, found := 0, false; d <= max && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = int(v[max+k+1])
+			} else {
+				x = int(v[max+k-1]) + 1
+			}
+			y := x - k
+			for x < n && y < m && t.rowEqual(want, got, start+x, start+y) {
+				x++
+				y++
+			}
+			v[max+k] = int32(x)
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		if err := account(2*d + 1); err != nil {
+			return nil, err
+		}
+		trace = append(trace, append([]int32(nil), v[max-d:max+d+1]...))
+	}
+
+	// Walk back from the end of the tables to recover the edits.
+	// The points of edit d are indexed by k + d.
+	var edits []diffEdit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev, k := trace[d-1], x-y
+		var prevX, midX int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevX = int(prev[k+1+d-1])
+			midX = prevX
+		} else {
+			prevX = int(prev[k-1+d-1])
+			midX = prevX + 1
+		}
+		for x > midX {
+			x, y = x-1, y-1
+			edits = append(edits, diffEdit{op: editEqual, i: start + x, j: start + y})
+		}
+		if midX == prevX {
+			y--
+			edits = append(edits, diffEdit{op: editAdd, j: start + y})
+		} else {
+			x--
+			edits = append(edits, diffEdit{op: editRemove, i: start + x})
+		}
+	}
+	for x > 0 {
+		x, y = x-1, y-1
+		edits = append(edits, diffEdit{op: editEqual, i: start + x, j: start + y})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, nil
+}
+
+// diffOn matches the rows of the tables by the values of the on columns.
+// Rows with the same values are paired in the order they appear in each
+// table and a pair is reported as changed if any other value differs.
+// Rows whose values are only in want are reported as removed and rows
+// whose values are only in got are reported as added.
+func (t *DiffTransformation) diffOn(out *diffOutput, want, got *tableBuffer) error {
+	// Group key columns are not buffered, but they are
+	// the same for every row so they always match.
+	for _, label := range t.on {
+		_, inWant := want.columns[label]
+		_, inGot := got.columns[label]
+		if !inWant && !inGot && !out.key.HasCol(label) {
+			return errors.Newf(codes.FailedPrecondition, "diff column %q does not exist", label)
+		}
+	}
+
+	loose := t.looseColumns(want, got)
+	wantKeys := t.rowKeys(want, t.on, loose, "-")
+	gotKeys := t.rowKeys(got, t.on, loose, "+")
+
+	// Index the rows of got by key in the order they appear.
+	gotRows := make(map[string][]int, len(gotKeys))
+	for j, k := range gotKeys {
+		gotRows[k] = append(gotRows[k], j)
+	}
+
+	// Pair each row of want with the next unpaired row
+	// of got that has the same key.
+	pairs := make([]int, len(wantKeys))
+	paired := make([]bool, len(gotKeys))
+	equal := true
+	for i, k := range wantKeys {
+		rows := gotRows[k]
+		if len(rows) == 0 {
+			pairs[i], equal = -1, false
+			continue
+		}
+		pairs[i], gotRows[k] = rows[0], rows[1:]
+		paired[rows[0]] = true
+		if !t.rowEqual(want, got, i, rows[0]) {
+			equal = false
+		}
+	}
+	for _, ok := range paired {
+		if !ok {
+			equal = false
+		}
+	}
+	if equal {
+		return nil
+	}
+
+	for i, j := range pairs {
+		if j < 0 {
+			if err := out.appendRow(DiffTypeRemoved, i, "-", want, ""); err != nil {
+				return err
+			}
+			continue
+		} else if t.rowEqual(want, got, i, j) {
+			continue
+		}
+
+		if err := out.appendChanged(i, j); err != nil {
+			return err
+		}
+	}
+	for j, ok := range paired {
+		if !ok {
+			if err := out.appendRow(DiffTypeAdded, j, "+", got, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// looseColumns returns the columns that are compared loosely. These
+// columns must use the same key for an integer and a float with the
+// same value.
+func (t *DiffTransformation) looseColumns(want, got *tableBuffer) map[string]bool {
+	var loose map[string]bool
+	for label, wantCol := range want.columns {
+		if gotCol, ok := got.columns[label]; ok && wantCol.Type != gotCol.Type && t.looseNumeric(wantCol.Type, gotCol.Type) {
+			if loose == nil {
+				loose = make(map[string]bool)
+			}
+			loose[label] = true
+		}
+	}
+	return loose
+}
+
+// rowKeys computes a key for each row of the table using the given
+// columns. Two rows have the same key if they are considered equal.
+// The side is used to make keys that must not match any other row unique.
+// Values of loose columns are keyed by their exact decimal value so an
+// integer and a whole float with the same value have the same key.
+func (t *DiffTransformation) rowKeys(tbl *tableBuffer, labels []string, loose map[string]bool, side string) []string {
+	keys := make([]string, tbl.sz)
+	var buf []byte
+	for i := 0; i < tbl.sz; i++ {
+		buf = buf[:0]
+		for _, label := range labels {
+			buf = append(buf, label...)
+			buf = append(buf, '=')
+
+			col, ok := tbl.columns[label]
+			if !ok {
+				// Missing columns never match a column that exists.
+				buf = append(buf, "missing;"...)
+				continue
+			} else if col.Values.IsNull(i) {
+				buf = append(buf, "null;"...)
+				continue
+			}
+
+			switch col.Type {
+			case flux.TFloat:
+				v := col.Values.(*array.Float).Value(i)
+				if math.IsNaN(v) {
+					if !t.nansEqual {
+						// NaN is not equal to any value so make the key unique.
+						buf = append(buf, "NaN#"+side...)
+						buf = strconv.AppendInt(buf, int64(i), 10)
+					} else {
+						buf = append(buf, "NaN"...)
+					}
+				} else if loose[label] && !math.IsInf(v, 0) {
+					if v == 0 {
+						// Negative zero is equal to the integer zero.
+						v = 0
+					}
+					buf = strconv.AppendFloat(buf, v, 'f', -1, 64)
+				} else if epsilon := t.epsilonFor(label); epsilon > 0 && !math.IsInf(v, 0) && t.relative {
+					// Round the fraction so values with the same
+					// exponent are rounded relative to their magnitude.
+					frac, exp := math.Frexp(v)
+					buf = strconv.AppendInt(buf, int64(exp), 10)
+					buf = append(buf, 'e')
+					buf = strconv.AppendFloat(buf, math.Round(frac/epsilon), 'g', -1, 64)
+				} else if epsilon > 0 && !math.IsInf(v, 0) {
+					buf = strconv.AppendFloat(buf, math.Round(v/epsilon), 'g', -1, 64)
+				} else {
+					buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+				}
+			case flux.TInt:
+				buf = strconv.AppendInt(buf, col.Values.(*array.Int).Value(i), 10)
+			case flux.TTime:
+				v := col.Values.(*array.Int).Value(i)
+				if t.timeEpsilon > 0 {
+					// Round the time to the nearest multiple of the time epsilon.
+					v = int64(math.Round(float64(v) / float64(t.timeEpsilon)))
+				}
+				buf = strconv.AppendInt(buf, v, 10)
+			case flux.TUInt:
+				buf = strconv.AppendUint(buf, col.Values.(*array.Uint).Value(i), 10)
+			case flux.TString:
+				buf = strconv.AppendQuote(buf, col.Values.(*array.String).Value(i))
+			case flux.TBool:
+				buf = strconv.AppendBool(buf, col.Values.(*array.Boolean).Value(i))
+			}
+			buf = append(buf, ';')
+		}
+		keys[i] = string(buf)
+	}
+	return keys
+}
+
+// rowEqual reports whether row i of want is equal to row j of got.
+// Only the columns that are in both tables are compared. Columns that
+// are only in one table are reported by appendSchemaDiff instead.
+func (t *DiffTransformation) rowEqual(want, got *tableBuffer, i, j int) bool {
+	for label, wantCol := range want.columns {
+		gotCol, ok := got.columns[label]
+		if !ok {
+			continue
+		}
+		if !t.valueEqual(label, wantCol, gotCol, i, j) {
+			return false
+		}
+	}
+	return true
+}
+
+// sharedColumns returns the columns that are
+// in both tables in alphabetical order.
+func sharedColumns(want, got *tableBuffer) []string {
+	labels := make([]string, 0, len(want.columns))
+	for label := range want.columns {
+		if _, ok := got.columns[label]; ok {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// valueEqual reports whether row i of the want column
+// is equal to row j of the got column.
+func (t *DiffTransformation) valueEqual(label string, wantCol, gotCol *tableColumn, i, j int) bool {
+	if wantCol.Values.IsValid(i) != gotCol.Values.IsValid(j) {
+		return false
+	} else if wantCol.Values.IsNull(i) {
+		return true
+	}
+
+	if wantCol.Type != gotCol.Type {
+		return t.looseNumeric(wantCol.Type, gotCol.Type) && numericValuesEqual(wantCol, gotCol, i, j)
+	}
+
+	switch wantCol.Type {
+	case flux.TFloat:
+		want, got := wantCol.Values.(*array.Float).Value(i), gotCol.Values.(*array.Float).Value(j)
+		if t.nansEqual && math.IsNaN(want) && math.IsNaN(got) {
+			// treat NaNs as equal
+			return true
+		}
+		return !floatsDiffer(want, got, t.epsilonFor(label), t.tolerance())
+	case flux.TInt:
+		want, got := wantCol.Values.(*array.Int), gotCol.Values.(*array.Int)
+		return want.Value(i) == got.Value(j)
+	case flux.TUInt:
+		want, got := wantCol.Values.(*array.Uint), gotCol.Values.(*array.Uint)
+		return want.Value(i) == got.Value(j)
+	case flux.TString:
+		want, got := wantCol.Values.(*array.String), gotCol.Values.(*array.String)
+		return want.Value(i) == got.Value(j)
+	case flux.TBool:
+		want, got := wantCol.Values.(*array.Boolean), gotCol.Values.(*array.Boolean)
+		return want.Value(i) == got.Value(j)
+	case flux.TTime:
+		want, got := wantCol.Values.(*array.Int), gotCol.Values.(*array.Int)
+		return math.Abs(float64(want.Value(i)-got.Value(j))) <= float64(t.timeEpsilon)
+	default:
+		return false
+	}
+}
+
+// looseNumeric reports whether columns of the two types
+// are compared by value because numericLoose is set.
+func (t *DiffTransformation) looseNumeric(a, b flux.ColType) bool {
+	if !t.numericLoose {
+		return false
+	}
+	isInteger := func(typ flux.ColType) bool {
+		return typ == flux.TInt || typ == flux.TUInt
+	}
+	return (isInteger(a) && b == flux.TFloat) || (a == flux.TFloat && isInteger(b))
+}
+
+// numericValuesEqual reports whether the integer value in one column
+// has exactly the same value as the float value in the other column,
+// comparing row i of the first column with row j of the second.
+func numericValuesEqual(a, b *tableColumn, i, j int) bool {
+	if a.Type != flux.TFloat {
+		a, b = b, a
+		i, j = j, i
+	}
+	f := a.Values.(*array.Float).Value(i)
+	if f != math.Trunc(f) {
+		return false
+	}
+	switch b.Type {
+	case flux.TInt:
+		return f >= math.MinInt64 && f < -math.MinInt64 && int64(f) == b.Values.(*array.Int).Value(j)
+	case flux.TUInt:
+		return f >= 0 && f < math.MaxUint64 && uint64(f) == b.Values.(*array.Uint).Value(j)
+	default:
+		return false
+	}
+}
+
+func (t *DiffTransformation) appendRow(builder execute.TableBuilder, i, diffIdx int, diff string, tbl *tableBuffer, colMap map[string]int) error {
+	// Add the want column first.
+	if err := execute.AppendKeyValues(builder.Key(), builder); err != nil {
+		return err
+	}
+	// Add the diff column.
+	if err := builder.AppendString(diffIdx, diff); err != nil {
+		return err
+	}
+	// Add all of the values.
 	for label, j := range colMap {
 		col, ok := tbl.columns[label]
 		if !ok || col.Values.IsNull(i) {
@@ -570,6 +2156,21 @@ func (t *DiffTransformation) appendRow(builder execute.TableBuilder, i, diffIdx
 			continue
 		}
 
+		if col.Type != flux.TFloat && builder.Cols()[j].Type == flux.TFloat {
+			// The column is compared loosely so
+			// integers are reported as floats.
+			var v float64
+			if col.Type == flux.TUInt {
+				v = float64(col.Values.(*array.Uint).Value(i))
+			} else {
+				v = float64(col.Values.(*array.Int).Value(i))
+			}
+			if err := builder.AppendFloat(j, v); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch col.Type {
 		case flux.TFloat:
 			vs := col.Values.(*array.Float)
@@ -611,15 +2212,18 @@ func (t *DiffTransformation) UpdateWatermark(id execute.DatasetID, mark execute.
 	defer t.mu.Unlock()
 
 	t.parentState[id].mark = mark
+	return t.d.UpdateWatermark(t.watermark())
+}
 
+// watermark returns the smallest watermark of the parents.
+func (t *DiffTransformation) watermark() execute.Time {
 	min := execute.Time(math.MaxInt64)
 	for _, state := range t.parentState {
 		if state.mark < min {
 			min = state.mark
 		}
 	}
-
-	return t.d.UpdateWatermark(min)
+	return min
 }
 
 func (t *DiffTransformation) UpdateProcessingTime(id execute.DatasetID, mark execute.Time) error {
@@ -644,8 +2248,15 @@ func (t *DiffTransformation) Finish(id execute.DatasetID, err error) {
 
 	t.parentState[id].finished = true
 
+	// The dataset is finished as soon as either parent
+	// fails and it must not be finished a second time.
+	if t.finished {
+		return
+	}
 	if err != nil {
+		t.finished = true
 		t.d.Finish(err)
+		return
 	}
 
 	finished := true
@@ -666,6 +2277,48 @@ func (t *DiffTransformation) Finish(id execute.DatasetID, err error) {
 			}
 			return t.diff(key, want, got)
 		})
+		t.finished = true
 		t.d.Finish(err)
+	} else {
+		// The tables of the other parent that are waiting for a table
+		// from this parent will never be paired so they are diffed now
+		// rather than when the other parent finishes.
+		if err := t.diffUnpaired(t.otherID(id)); err != nil {
+			t.finished = true
+			t.d.Finish(err)
+		}
+	}
+}
+
+// diffUnpaired diffs the cached tables of the parent with the given id
+// against an empty table and sends them.
+// This must be called with the lock held.
+func (t *DiffTransformation) diffUnpaired(id execute.DatasetID) error {
+	if err := t.inputCache.Range(func(key flux.GroupKey, value interface{}) error {
+		obj := value.(*tableBuffer)
+		if obj.id != id {
+			return nil
+		}
+		t.inputCache.Delete(key)
+
+		want, got := obj, &tableBuffer{}
+		if id != t.wantID {
+			want, got = got, want
+		}
+		return t.diff(key, want, got)
+	}); err != nil {
+		return err
+	}
+	// Updating the watermark evaluates the triggers
+	// so the diffs are sent without waiting for the
+	// next update from the other parent.
+	return t.d.UpdateWatermark(t.watermark())
+}
+
+// otherID returns the id of the parent that is not id.
+func (t *DiffTransformation) otherID(id execute.DatasetID) execute.DatasetID {
+	if id == t.wantID {
+		return t.gotID
 	}
+	return t.wantID
 }
@@ -5,11 +5,13 @@ import (
 	"math"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	fluxtesting "github.com/influxdata/flux/stdlib/testing"
 )
@@ -57,6 +59,40 @@ func TestDiff_Process(t *testing.T) {
 			},
 			want: []*executetest.Table(nil),
 		},
+		{
+			name: "nans equal",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				NaNsEqual:   true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), math.NaN()},
+						{execute.Time(3), 3.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), math.NaN()},
+						{execute.Time(3), 3.0},
+					},
+				},
+			},
+			want: []*executetest.Table(nil),
+		},
 		{
 			name: "different time same value",
 			spec: &fluxtesting.DiffProcedureSpec{
@@ -102,6 +138,57 @@ func TestDiff_Process(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "verbose",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Verbose:     true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.5},
+						{execute.Time(4), 3.0},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_diff_detail", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", `"_value" differs by 0.5`, execute.Time(2), 2.0},
+						{"+", `"_value" differs by 0.5`, execute.Time(2), 2.5},
+						{"-", `"_time" differs`, execute.Time(3), 3.0},
+						{"+", `"_time" differs`, execute.Time(4), 3.0},
+						{"+", nil, execute.Time(5), 5.0},
+					},
+				},
+			},
+		},
 		{
 			name: "different values",
 			spec: &fluxtesting.DiffProcedureSpec{
@@ -260,6 +347,98 @@ func TestDiff_Process(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "column only in want",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "extra", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "a"},
+						{execute.Time(2), 2.0, "b"},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "extra", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"-extra", nil, nil, nil},
+					},
+				},
+			},
+		},
+		{
+			name: "column only in got verbose",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Verbose:     true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "extra", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "a"},
+						{execute.Time(2), 2.5, "b"},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_diff_detail", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "extra", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"+extra", `"extra" is only in got`, nil, nil, nil},
+						{"-", `"_value" differs by 0.5`, execute.Time(2), 2.0, nil},
+						{"+", `"_value" differs by 0.5`, execute.Time(2), 2.5, "b"},
+					},
+				},
+			},
+		},
 		{
 			name: "float64 comparison large epsilon",
 			spec: &fluxtesting.DiffProcedureSpec{
@@ -340,10 +519,127 @@ func TestDiff_Process(t *testing.T) {
 			},
 		},
 		{
-			name: "float64 comparison Inf",
+			name: "float64 comparison column epsilon",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Epsilon:     1e-6,
+				Epsilons:    map[string]float64{"bytes": 10},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1000.0, 0.1},
+						{execute.Time(2), 2000.0, 0.5},
+						{execute.Time(3), 3000.0, 0.25},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1005.0, 0.1},
+						{execute.Time(2), 2020.0, 0.5},
+						{execute.Time(3), 3000.0, 0.26},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(2), 2000.0, 0.5},
+						{"+", execute.Time(2), 2020.0, 0.5},
+						{"-", execute.Time(3), 3000.0, 0.25},
+						{"+", execute.Time(3), 3000.0, 0.26},
+					},
+				},
+			},
+		},
+		{
+			name: "float64 comparison column epsilon missing column",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Epsilons:    map[string]float64{"packets": 10},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1000.0, 0.1},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1005.0, 0.1},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "float64 comparison column epsilon not float",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Epsilons:    map[string]float64{"_time": 10},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1000.0, 0.1},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "bytes", Type: flux.TFloat},
+						{Label: "ratio", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1005.0, 0.1},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "float64 comparison relative",
 			spec: &fluxtesting.DiffProcedureSpec{
 				DefaultCost: plan.DefaultCost{},
 				Epsilon:     1e-6,
+				Relative:    true,
 			},
 			data0: []*executetest.Table{
 				{
@@ -352,9 +648,10 @@ func TestDiff_Process(t *testing.T) {
 						{Label: "_value", Type: flux.TFloat},
 					},
 					Data: [][]interface{}{
-						{execute.Time(1), math.Inf(1)},
-						{execute.Time(2), math.Inf(-1)},
-						{execute.Time(3), math.Inf(1)},
+						{execute.Time(1), 1e12},
+						{execute.Time(2), 100.0},
+						{execute.Time(3), 0.0},
+						{execute.Time(4), math.Inf(1)},
 					},
 				},
 			},
@@ -365,9 +662,10 @@ func TestDiff_Process(t *testing.T) {
 						{Label: "_value", Type: flux.TFloat},
 					},
 					Data: [][]interface{}{
-						{execute.Time(1), math.Inf(-1)},
-						{execute.Time(2), math.Inf(-1)},
-						{execute.Time(3), math.Inf(2)},
+						{execute.Time(1), 1e12 + 1e5},
+						{execute.Time(2), 100.01},
+						{execute.Time(3), 0.0},
+						{execute.Time(4), math.MaxFloat64},
 					},
 				},
 			},
@@ -379,32 +677,1247 @@ func TestDiff_Process(t *testing.T) {
 						{Label: "_value", Type: flux.TFloat},
 					},
 					Data: [][]interface{}{
-						{"-", execute.Time(1), math.Inf(1)},
-						{"+", execute.Time(1), math.Inf(-1)},
+						{"-", execute.Time(2), 100.0},
+						{"+", execute.Time(2), 100.01},
+						{"-", execute.Time(4), math.Inf(1)},
+						{"+", execute.Time(4), math.MaxFloat64},
 					},
 				},
 			},
 		},
-	}
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			id0 := executetest.RandomDatasetID()
-			id1 := executetest.RandomDatasetID()
-
-			parents := []execute.DatasetID{
-				execute.DatasetID(id0),
-				execute.DatasetID(id1),
-			}
-
-			d := executetest.NewDataset(executetest.RandomDatasetID())
-			c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
-			c.SetTriggerSpec(plan.DefaultTriggerSpec)
-			jt := fluxtesting.NewDiffTransformation(d, c, tc.spec, parents[0], parents[1], executetest.UnlimitedAllocator)
-
-			executetest.NormalizeTables(tc.data0)
-			executetest.NormalizeTables(tc.data1)
-			l := len(tc.data0)
+		{
+			name: "float64 comparison Inf",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Epsilon:     1e-6,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), math.Inf(1)},
+						{execute.Time(2), math.Inf(-1)},
+						{execute.Time(3), math.Inf(1)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), math.Inf(-1)},
+						{execute.Time(2), math.Inf(-1)},
+						{execute.Time(3), math.Inf(2)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(1), math.Inf(1)},
+						{"+", execute.Time(1), math.Inf(-1)},
+					},
+				},
+			},
+		},
+		{
+			name: "time epsilon",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				TimeEpsilon: int64(time.Millisecond),
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1 * time.Second), 1.0},
+						{execute.Time(2 * time.Second), 2.0},
+						{execute.Time(3 * time.Second), 3.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1*time.Second + 400*time.Microsecond), 1.0},
+						{execute.Time(2*time.Second - time.Millisecond), 2.0},
+						{execute.Time(3*time.Second + 2*time.Millisecond), 3.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(3 * time.Second), 3.0},
+						{"+", execute.Time(3*time.Second + 2*time.Millisecond), 3.0},
+					},
+				},
+			},
+		},
+		{
+			name: "multiset ignores row order",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Mode:        fluxtesting.DiffModeMultiset,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(1), 2.0},
+						{execute.Time(2), nil},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(2), nil},
+						{execute.Time(1), 2.0},
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			want: []*executetest.Table(nil),
+		},
+		{
+			name: "multiset duplicates",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Mode:        fluxtesting.DiffModeMultiset,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(1)},
+						{execute.Time(1), int64(1)},
+						{execute.Time(2), int64(2)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(2), int64(2)},
+						{execute.Time(1), int64(1)},
+						{execute.Time(2), int64(2)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(1), int64(1)},
+						{"+", execute.Time(2), int64(2)},
+					},
+				},
+			},
+		},
+		{
+			name: "multiset rounds floats to epsilon",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Epsilon:     0.1,
+				Mode:        fluxtesting.DiffModeMultiset,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{1.01},
+						{2.0},
+						{3.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{2.02},
+						{0.99},
+						{3.2},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", 3.0},
+						{"+", 3.2},
+					},
+				},
+			},
+		},
+		{
+			name: "numeric loose",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:  plan.DefaultCost{},
+				Epsilon:      1e-6,
+				NumericLoose: true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(5)},
+						{execute.Time(2), int64(-3)},
+						{execute.Time(3), int64(7)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 5.0},
+						{execute.Time(2), -3.0},
+						{execute.Time(3), 7.0000001},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(3), 7.0},
+						{"+", execute.Time(3), 7.0000001},
+					},
+				},
+			},
+		},
+		{
+			name: "numeric loose multiset",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:  plan.DefaultCost{},
+				Epsilon:      1e-6,
+				Mode:         fluxtesting.DiffModeMultiset,
+				NumericLoose: true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{2.0},
+						{-0.0},
+						{1.5},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TUInt},
+					},
+					Data: [][]interface{}{
+						{uint64(0)},
+						{uint64(1)},
+						{uint64(2)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", 1.5},
+						{"+", 1.0},
+					},
+				},
+			},
+		},
+		{
+			name: "aligned insertion",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:  plan.DefaultCost{},
+				MaxAlignRows: 10,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(10), 9.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.5},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"+", execute.Time(10), 9.0},
+						{"-", execute.Time(5), 5.0},
+						{"+", execute.Time(5), 5.5},
+					},
+				},
+			},
+		},
+		{
+			name: "aligned too many rows",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:  plan.DefaultCost{},
+				MaxAlignRows: 5,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(10), 9.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.5},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(3), 3.0},
+						{"+", execute.Time(10), 9.0},
+						{"-", execute.Time(4), 4.0},
+						{"+", execute.Time(3), 3.0},
+						{"-", execute.Time(5), 5.0},
+						{"+", execute.Time(4), 4.0},
+						{"+", execute.Time(5), 5.5},
+					},
+				},
+			},
+		},
+		{
+			name: "on",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				On:          []string{"_time"},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.5},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(4), 4.0},
+						{"+", execute.Time(4), 4.5},
+						{"-", execute.Time(5), 5.0},
+						{"+", execute.Time(2), 2.0},
+					},
+				},
+			},
+		},
+		{
+			name: "on missing column",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				On:          []string{"host"},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 2.0},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate want key after diff",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(2), 2.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate got key before want",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ignore",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Ignore:      []string{"id", "ingest"},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "id", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "a"},
+						{execute.Time(2), 2.0, "b"},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "id", Type: flux.TString},
+						{Label: "ingest", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "x", execute.Time(10)},
+						{execute.Time(2), 5.0, "y", execute.Time(11)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", execute.Time(2), 2.0},
+						{"+", execute.Time(2), 5.0},
+					},
+				},
+			},
+		},
+		{
+			name: "ignore group key column",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Ignore:      []string{"host"},
+			},
+			data0: []*executetest.Table{
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "host", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "a"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "max diffs",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				MaxDiffs:    3,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 5.0},
+						{execute.Time(2), 6.0},
+						{execute.Time(3), 7.0},
+						{execute.Time(4), 8.0},
+						{execute.Time(5), 9.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_diff_remaining", Type: flux.TInt},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", nil, execute.Time(1), 1.0},
+						{"+", nil, execute.Time(1), 5.0},
+						{"...", int64(7), nil, nil},
+					},
+				},
+			},
+		},
+		{
+			name: "max diffs not reached",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				MaxDiffs:    2,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 3.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_diff_remaining", Type: flux.TInt},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"-", nil, execute.Time(2), 2.0},
+						{"+", nil, execute.Time(2), 3.0},
+					},
+				},
+			},
+		},
+		{
+			name: "unmatched keys",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:   plan.DefaultCost{},
+				UnmatchedKeys: true,
+			},
+			data0: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", 1.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					KeyCols:   []string{"t0"},
+					KeyValues: []interface{}{"b"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", "-", nil},
+						{"a", "-", 1.0},
+					},
+				},
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"b", "+", nil},
+					},
+				},
+			},
+		},
+		{
+			name: "sort by",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				SortBy:      []string{"host", "_time"},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), "b", 1.0},
+						{execute.Time(1), nil, 2.0},
+						{execute.Time(2), "a", 3.0},
+						{execute.Time(1), "a", 4.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), "a", 4.0},
+						{execute.Time(2), "a", 3.0},
+						{execute.Time(1), "b", 1.0},
+						{execute.Time(1), nil, 2.0},
+					},
+				},
+			},
+			want: []*executetest.Table(nil),
+		},
+		{
+			name: "sort by missing column",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				SortBy:      []string{"host"},
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "context",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Context:     2,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+						{execute.Time(6), 6.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.5},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+						{execute.Time(6), 6.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{" ", execute.Time(1), 1.0},
+						{" ", execute.Time(2), 2.0},
+						{"-", execute.Time(3), 3.0},
+						{"+", execute.Time(3), 3.5},
+						{" ", execute.Time(4), 4.0},
+						{" ", execute.Time(5), 5.0},
+					},
+				},
+			},
+		},
+		{
+			name: "aligned context",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost:  plan.DefaultCost{},
+				MaxAlignRows: 10,
+				Context:      1,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(10), 9.0},
+						{execute.Time(3), 3.0},
+						{execute.Time(4), 4.0},
+						{execute.Time(5), 5.5},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{" ", execute.Time(2), 2.0},
+						{"+", execute.Time(10), 9.0},
+						{" ", execute.Time(3), 3.0},
+						{" ", execute.Time(4), 4.0},
+						{"-", execute.Time(5), 5.0},
+						{"+", execute.Time(5), 5.5},
+					},
+				},
+			},
+		},
+		{
+			name: "summary",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Summary:     true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.0},
+						{execute.Time(3), 3.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+						{execute.Time(2), 2.5},
+						{execute.Time(4), 3.5},
+						{execute.Time(5), 5.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_added", Type: flux.TInt},
+						{Label: "_removed", Type: flux.TInt},
+						{Label: "_changed", Type: flux.TInt},
+						{Label: "_column", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{int64(1), int64(0), int64(2), "_value"},
+					},
+				},
+			},
+		},
+		{
+			name: "summary equal",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Summary:     true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_added", Type: flux.TInt},
+						{Label: "_removed", Type: flux.TInt},
+						{Label: "_changed", Type: flux.TInt},
+						{Label: "_column", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{int64(0), int64(0), int64(0), nil},
+					},
+				},
+			},
+		},
+		{
+			name: "partition",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Partition:   true,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(1)},
+						{int64(2)},
+						{int64(3)},
+						{int64(4)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(1)},
+						{int64(5)},
+						{int64(3)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_diffType"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_diffType", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"changed", "-", int64(2)},
+						{"changed", "+", int64(5)},
+					},
+				},
+				{
+					KeyCols: []string{"_diffType"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_diffType", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"removed", "-", int64(4)},
+					},
+				},
+			},
+		},
+		{
+			name: "partition diff type",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Partition:   true,
+				DiffType:    fluxtesting.DiffTypeRemoved,
+			},
+			data0: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(1)},
+						{int64(2)},
+						{int64(3)},
+						{int64(4)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(1)},
+						{int64(5)},
+						{int64(3)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_diffType"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_diffType", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"removed", "-", int64(4)},
+					},
+				},
+			},
+		},
+		{
+			name: "partition multiset",
+			spec: &fluxtesting.DiffProcedureSpec{
+				DefaultCost: plan.DefaultCost{},
+				Mode:        fluxtesting.DiffModeMultiset,
+				Partition:   true,
+			},
+			data0: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"a", int64(1)},
+						{"a", int64(2)},
+						{"a", int64(3)},
+					},
+				},
+			},
+			data1: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"a", int64(3)},
+						{"a", int64(4)},
+						{"a", int64(1)},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0", "_diffType"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_diffType", Type: flux.TString},
+						{Label: "t0", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"added", "a", "+", int64(4)},
+					},
+				},
+				{
+					KeyCols: []string{"t0", "_diffType"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_diffType", Type: flux.TString},
+						{Label: "t0", Type: flux.TString},
+						{Label: "_diff", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"removed", "a", "-", int64(2)},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			id0 := executetest.RandomDatasetID()
+			id1 := executetest.RandomDatasetID()
+
+			parents := []execute.DatasetID{
+				execute.DatasetID(id0),
+				execute.DatasetID(id1),
+			}
+
+			d := executetest.NewDataset(executetest.RandomDatasetID())
+			c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
+			c.SetTriggerSpec(plan.DefaultTriggerSpec)
+			jt := fluxtesting.NewDiffTransformation(d, c, tc.spec, parents[0], parents[1], executetest.UnlimitedAllocator)
+
+			executetest.NormalizeTables(tc.data0)
+			executetest.NormalizeTables(tc.data1)
+			l := len(tc.data0)
 			if len(tc.data1) > l {
 				l = len(tc.data1)
 			}
@@ -449,3 +1962,149 @@ func TestDiff_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestDiff_FinishedParent(t *testing.T) {
+	newTable := func(t0 string, v int64) *executetest.Table {
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TInt},
+			},
+			Data: [][]interface{}{{t0, v}},
+		}
+		tbl.Normalize()
+		return tbl
+	}
+
+	wantID := executetest.RandomDatasetID()
+	gotID := executetest.RandomDatasetID()
+	d := executetest.NewDataset(executetest.RandomDatasetID())
+	c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
+	c.SetTriggerSpec(plan.DefaultTriggerSpec)
+	spec := &fluxtesting.DiffProcedureSpec{}
+	dt := fluxtesting.NewDiffTransformation(d, c, spec, wantID, gotID, executetest.UnlimitedAllocator)
+
+	// keys returns the t0 values of the tables that have been diffed.
+	keys := func() []string {
+		tables, err := executetest.TablesFromCache(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, tbl := range tables {
+			keys = append(keys, tbl.GroupKey.LabelValue("t0").Str())
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	if err := dt.Process(wantID, newTable("a", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dt.Process(gotID, newTable("b", 3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(); len(got) != 0 {
+		t.Fatalf("unexpected diffs before a parent finished: %v", got)
+	}
+
+	// The want table without a pair is diffed once the got parent
+	// finishes while the got table still waits for its pair.
+	dt.Finish(gotID, nil)
+	if want, got := []string{"a"}, keys(); !cmp.Equal(want, got) {
+		t.Fatalf("unexpected diffs -want/+got:\n%s", cmp.Diff(want, got))
+	}
+
+	// New want tables are diffed as soon as they are processed.
+	if err := dt.Process(wantID, newTable("c", 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dt.Process(wantID, newTable("b", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"b", "c"}, keys(); !cmp.Equal(want, got) {
+		t.Fatalf("unexpected diffs -want/+got:\n%s", cmp.Diff(want, got))
+	}
+
+	dt.Finish(wantID, nil)
+	if got := keys(); len(got) != 0 {
+		t.Fatalf("unexpected diffs after both parents finished: %v", got)
+	}
+}
+
+func TestDiff_AlignMemoryLimit(t *testing.T) {
+	// The first row of want is removed and the remaining rows are
+	// equal before the last rows of each table, which all differ.
+	// The edit script for these tables is too large for the limit,
+	// so the rows are compared by position instead.
+	newTable := func(values []int64) *executetest.Table {
+		tbl := &executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_value", Type: flux.TInt},
+			},
+		}
+		for _, v := range values {
+			tbl.Data = append(tbl.Data, []interface{}{v})
+		}
+		tbl.Normalize()
+		return tbl
+	}
+	var wantValues, gotValues []int64
+	for i := int64(0); i <= 50; i++ {
+		wantValues = append(wantValues, i)
+	}
+	for i := int64(1); i <= 50; i++ {
+		gotValues = append(gotValues, i)
+	}
+	for i := int64(0); i < 100; i++ {
+		wantValues = append(wantValues, 1000+i)
+		gotValues = append(gotValues, 2000+i)
+	}
+
+	wantID := executetest.RandomDatasetID()
+	gotID := executetest.RandomDatasetID()
+	d := executetest.NewDataset(executetest.RandomDatasetID())
+	c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
+	c.SetTriggerSpec(plan.DefaultTriggerSpec)
+	spec := &fluxtesting.DiffProcedureSpec{MaxAlignRows: fluxtesting.DefaultMaxAlignRows}
+	limit := int64(16 * 1024)
+	mem := &memory.Allocator{Limit: &limit}
+	dt := fluxtesting.NewDiffTransformation(d, c, spec, wantID, gotID, mem)
+
+	if err := dt.Process(wantID, newTable(wantValues)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dt.Process(gotID, newTable(gotValues)); err != nil {
+		t.Fatal(err)
+	}
+	dt.Finish(wantID, nil)
+	dt.Finish(gotID, nil)
+
+	want := &executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_diff", Type: flux.TString},
+			{Label: "_value", Type: flux.TInt},
+		},
+	}
+	for i := range gotValues {
+		want.Data = append(want.Data,
+			[]interface{}{"-", wantValues[i]},
+			[]interface{}{"+", gotValues[i]},
+		)
+	}
+	want.Data = append(want.Data, []interface{}{"-", wantValues[len(wantValues)-1]})
+
+	got, err := executetest.TablesFromCache(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables([]*executetest.Table{want})
+	if !cmp.Equal([]*executetest.Table{want}, got) {
+		t.Errorf("unexpected tables -want/+got\n%s", cmp.Diff([]*executetest.Table{want}, got))
+	}
+	if n := mem.Allocated(); n != 0 {
+		t.Errorf("memory is still allocated: %d", n)
+	}
+}
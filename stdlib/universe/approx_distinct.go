@@ -0,0 +1,304 @@
+package universe
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ApproxDistinctKind = "approxDistinct"
+
+const (
+	// defaultApproxDistinctPrecision uses 2^14 registers
+	// for a standard error of about 0.8%.
+	defaultApproxDistinctPrecision = 14
+	minApproxDistinctPrecision     = 4
+	maxApproxDistinctPrecision     = 18
+)
+
+// ApproxDistinctOpSpec estimates the number of distinct
+// values in a column with a HyperLogLog sketch.
+type ApproxDistinctOpSpec struct {
+	Precision int64 `json:"precision"`
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	approxDistinctSignature := runtime.MustLookupBuiltinType("universe", "approxDistinct")
+
+	runtime.RegisterPackageValue("universe", ApproxDistinctKind, flux.MustValue(flux.FunctionValue(ApproxDistinctKind, createApproxDistinctOpSpec, approxDistinctSignature)))
+	flux.RegisterOpSpec(ApproxDistinctKind, newApproxDistinctOp)
+	plan.RegisterProcedureSpec(ApproxDistinctKind, newApproxDistinctProcedure, ApproxDistinctKind)
+	execute.RegisterTransformation(ApproxDistinctKind, createApproxDistinctTransformation)
+}
+
+func createApproxDistinctOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(ApproxDistinctOpSpec)
+	if p, ok, err := args.GetInt("precision"); err != nil {
+		return nil, err
+	} else if ok {
+		if p < minApproxDistinctPrecision || p > maxApproxDistinctPrecision {
+			return nil, errors.Newf(codes.Invalid, "precision must be between %d and %d, got %d", minApproxDistinctPrecision, maxApproxDistinctPrecision, p)
+		}
+		spec.Precision = p
+	} else {
+		spec.Precision = defaultApproxDistinctPrecision
+	}
+
+	if err := spec.SimpleAggregateConfig.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func newApproxDistinctOp() flux.OperationSpec {
+	return new(ApproxDistinctOpSpec)
+}
+
+func (s *ApproxDistinctOpSpec) Kind() flux.OperationKind {
+	return ApproxDistinctKind
+}
+
+type ApproxDistinctProcedureSpec struct {
+	Precision int64 `json:"precision"`
+	execute.SimpleAggregateConfig
+}
+
+func newApproxDistinctProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ApproxDistinctOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ApproxDistinctProcedureSpec{
+		Precision:             spec.Precision,
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *ApproxDistinctProcedureSpec) Kind() plan.ProcedureKind {
+	return ApproxDistinctKind
+}
+
+func (s *ApproxDistinctProcedureSpec) Copy() plan.ProcedureSpec {
+	return &ApproxDistinctProcedureSpec{
+		Precision:             s.Precision,
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ApproxDistinctProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createApproxDistinctTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ApproxDistinctProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	agg := NewApproxDistinctAgg(int(s.Precision), a.Allocator())
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, s.SimpleAggregateConfig, a.Allocator())
+}
+
+// ApproxDistinctAgg estimates the number of distinct non-null values
+// of a column with a HyperLogLog sketch. The sketch has 2^Precision
+// one byte registers that are accounted for with the allocator.
+type ApproxDistinctAgg struct {
+	Precision int
+	mem       *memory.Allocator
+}
+
+func NewApproxDistinctAgg(precision int, mem *memory.Allocator) *ApproxDistinctAgg {
+	return &ApproxDistinctAgg{
+		Precision: precision,
+		mem:       mem,
+	}
+}
+
+func (a *ApproxDistinctAgg) newState() *ApproxDistinctState {
+	s := &ApproxDistinctState{
+		precision: uint(a.Precision),
+		mem:       a.mem,
+	}
+	size := 1 << a.Precision
+	if err := a.mem.Account(size); err != nil {
+		s.err = err
+		return s
+	}
+	s.registers = make([]uint8, size)
+	return s
+}
+
+func (a *ApproxDistinctAgg) NewBoolAgg() execute.DoBoolAgg {
+	return a.newState()
+}
+
+func (a *ApproxDistinctAgg) NewIntAgg() execute.DoIntAgg {
+	return a.newState()
+}
+
+func (a *ApproxDistinctAgg) NewUIntAgg() execute.DoUIntAgg {
+	return a.newState()
+}
+
+func (a *ApproxDistinctAgg) NewFloatAgg() execute.DoFloatAgg {
+	return a.newState()
+}
+
+func (a *ApproxDistinctAgg) NewStringAgg() execute.DoStringAgg {
+	return a.newState()
+}
+
+// ApproxDistinctState is the HyperLogLog sketch of a column.
+type ApproxDistinctState struct {
+	precision uint
+	registers []uint8
+	mem       *memory.Allocator
+	buf       [8]byte
+	err       error
+}
+
+// add records the hash of a value in the sketch. The first bits
+// of the hash select the register and the register keeps the
+// longest run of leading zeros seen in the remaining bits.
+func (s *ApproxDistinctState) add(h uint64) {
+	idx := h >> (64 - s.precision)
+	w := h<<s.precision | 1<<(s.precision-1)
+	if rho := uint8(bits.LeadingZeros64(w) + 1); rho > s.registers[idx] {
+		s.registers[idx] = rho
+	}
+}
+
+func (s *ApproxDistinctState) addUint64(v uint64) {
+	binary.LittleEndian.PutUint64(s.buf[:], v)
+	s.add(xxhash.Sum64(s.buf[:]))
+}
+
+func (s *ApproxDistinctState) DoBool(vs *array.Boolean) {
+	if s.err != nil {
+		return
+	}
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			if vs.Value(i) {
+				s.addUint64(1)
+			} else {
+				s.addUint64(0)
+			}
+		}
+	}
+}
+
+func (s *ApproxDistinctState) DoInt(vs *array.Int) {
+	if s.err != nil {
+		return
+	}
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.addUint64(uint64(vs.Value(i)))
+		}
+	}
+}
+
+func (s *ApproxDistinctState) DoUInt(vs *array.Uint) {
+	if s.err != nil {
+		return
+	}
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.addUint64(vs.Value(i))
+		}
+	}
+}
+
+func (s *ApproxDistinctState) DoFloat(vs *array.Float) {
+	if s.err != nil {
+		return
+	}
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.addUint64(math.Float64bits(vs.Value(i)))
+		}
+	}
+}
+
+func (s *ApproxDistinctState) DoString(vs *array.String) {
+	if s.err != nil {
+		return
+	}
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.add(xxhash.Sum64String(vs.Value(i)))
+		}
+	}
+}
+
+func (s *ApproxDistinctState) Type() flux.ColType {
+	return flux.TInt
+}
+
+// ValueInt returns the estimate of the number of distinct values.
+// Linear counting is used for small cardinalities where
+// the HyperLogLog estimate is biased.
+func (s *ApproxDistinctState) ValueInt() int64 {
+	m := float64(len(s.registers))
+	if m == 0 {
+		return 0
+	}
+
+	sum, zeros := 0.0, 0
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(s.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+func (s *ApproxDistinctState) IsNull() bool {
+	return false
+}
+
+// Err implements execute.ErrorValueFunc.
+func (s *ApproxDistinctState) Err() error {
+	return s.err
+}
+
+func (s *ApproxDistinctState) Close() error {
+	if s.registers != nil {
+		s.mem.Account(-len(s.registers))
+		s.registers = nil
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package universe_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestApproxDistinct_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "default",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> approxDistinct(precision: 10)`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "approxDistinct2",
+						Spec: &universe.ApproxDistinctOpSpec{
+							Precision:             10,
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "approxDistinct2"},
+				},
+			},
+		},
+		{
+			Name:    "precision too small",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> approxDistinct(precision: 3)`,
+			WantErr: true,
+		},
+		{
+			Name:    "precision too large",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> approxDistinct(precision: 19)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestApproxDistinct_Process(t *testing.T) {
+	testCases := []struct {
+		name string
+		data func() *array.Float
+		want interface{}
+	}{
+		{
+			name: "duplicates",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 1, 2, 3, 4, 5}, nil)
+			},
+			want: int64(5),
+		},
+		{
+			name: "with nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendValues([]float64{1, 2}, nil)
+				b.AppendNull()
+				b.AppendValues([]float64{2}, nil)
+				return b.NewFloatArray()
+			},
+			want: int64(2),
+		},
+		{
+			name: "only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: int64(0),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.AggFuncTestHelper(
+				t,
+				universe.NewApproxDistinctAgg(14, &memory.Allocator{}),
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
+
+func TestApproxDistinct_Estimate(t *testing.T) {
+	const n = 10000
+	vs := make([]string, 0, 2*n)
+	for i := 0; i < n; i++ {
+		v := strconv.Itoa(i)
+		vs = append(vs, v, v)
+	}
+
+	mem := &memory.Allocator{}
+	agg := universe.NewApproxDistinctAgg(14, mem)
+	state := agg.NewStringAgg()
+	state.DoString(arrow.NewString(vs, nil))
+	if want, got := int64(1<<14), mem.Allocated(); want != got {
+		t.Errorf("unexpected allocated memory -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The standard error with 2^14 registers is about 0.8%.
+	got := state.(execute.IntValueFunc).ValueInt()
+	if got < n*98/100 || got > n*102/100 {
+		t.Errorf("estimate %d is not within 2%% of %d", got, n)
+	}
+
+	if err := state.(execute.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+func TestApproxDistinct_MemoryLimit(t *testing.T) {
+	limit := int64(1 << 10)
+	mem := &memory.Allocator{Limit: &limit}
+	agg := universe.NewApproxDistinctAgg(14, mem)
+	state := agg.NewIntAgg()
+	state.DoInt(arrow.NewInt([]int64{1, 2, 3}, nil))
+	if err := state.(execute.ErrorValueFunc).Err(); err == nil {
+		t.Fatal("expected error when the registers exceed the memory limit")
+	}
+}
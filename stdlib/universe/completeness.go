@@ -0,0 +1,195 @@
+package universe
+
+import (
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+)
+
+const CompletenessKind = "completeness"
+
+const (
+	// CompletenessColumnLabel is the column that contains
+	// the name of the column the completeness is for.
+	CompletenessColumnLabel = "_column"
+	// CompletenessLabel is the column that contains the completeness.
+	CompletenessLabel = "_completeness"
+)
+
+// CompletenessOpSpec computes the fraction of
+// non-null values in columns of each table.
+type CompletenessOpSpec struct {
+	Columns []string `json:"columns"`
+}
+
+func init() {
+	completenessSignature := runtime.MustLookupBuiltinType("universe", "completeness")
+
+	runtime.RegisterPackageValue("universe", CompletenessKind, flux.MustValue(flux.FunctionValue(CompletenessKind, createCompletenessOpSpec, completenessSignature)))
+	flux.RegisterOpSpec(CompletenessKind, newCompletenessOp)
+	plan.RegisterProcedureSpec(CompletenessKind, newCompletenessProcedure, CompletenessKind)
+	execute.RegisterTransformation(CompletenessKind, createCompletenessTransformation)
+}
+
+func createCompletenessOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(CompletenessOpSpec)
+	if cols, ok, err := args.GetArray("columns", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		columns, err := interpreter.ToStringArray(cols)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			return nil, errors.New(codes.Invalid, "completeness requires at least one column")
+		}
+		spec.Columns = columns
+	} else {
+		spec.Columns = []string{execute.DefaultValueColLabel}
+	}
+	return spec, nil
+}
+
+func newCompletenessOp() flux.OperationSpec {
+	return new(CompletenessOpSpec)
+}
+
+func (s *CompletenessOpSpec) Kind() flux.OperationKind {
+	return CompletenessKind
+}
+
+type CompletenessProcedureSpec struct {
+	plan.DefaultCost
+	Columns []string `json:"columns"`
+}
+
+func newCompletenessProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*CompletenessOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &CompletenessProcedureSpec{
+		Columns: spec.Columns,
+	}, nil
+}
+
+func (s *CompletenessProcedureSpec) Kind() plan.ProcedureKind {
+	return CompletenessKind
+}
+
+func (s *CompletenessProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(CompletenessProcedureSpec)
+	*ns = *s
+	if s.Columns != nil {
+		ns.Columns = make([]string, len(s.Columns))
+		copy(ns.Columns, s.Columns)
+	}
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *CompletenessProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createCompletenessTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*CompletenessProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewCompletenessTransformation(id, s, a.Allocator())
+}
+
+type completenessTransformation struct {
+	columns []string
+}
+
+// NewCompletenessTransformation creates a transformation that computes
+// the fraction of rows of each table where a column is not null.
+//
+// The output has one row for each column with the name of the column
+// in the _column column and its completeness in the _completeness column.
+// The completeness is null for a table with no rows.
+func NewCompletenessTransformation(id execute.DatasetID, spec *CompletenessProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &completenessTransformation{
+		columns: spec.Columns,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type completenessState struct {
+	total int64
+	valid []int64
+}
+
+func (t *completenessTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *completenessState
+	if state != nil {
+		s = state.(*completenessState)
+	} else {
+		s = &completenessState{valid: make([]int64, len(t.columns))}
+	}
+
+	n := chunk.Len()
+	for j, label := range t.columns {
+		idx := chunk.Index(label)
+		if idx < 0 {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+		}
+		s.valid[j] += int64(n - chunk.Values(idx).NullN())
+	}
+	s.total += int64(n)
+	return s, true, nil
+}
+
+func (t *completenessTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*completenessState)
+	n := len(t.columns)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+2),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), n, mem))
+	}
+
+	cb := arrowutil.NewStringBuilder(mem)
+	cb.Resize(n)
+	b := arrowutil.NewFloatBuilder(mem)
+	b.Resize(n)
+	for j, label := range t.columns {
+		cb.Append(label)
+		if s.total == 0 {
+			b.AppendNull()
+			continue
+		}
+		b.Append(float64(s.valid[j]) / float64(s.total))
+	}
+	buffer.Columns = append(buffer.Columns,
+		flux.ColMeta{Label: CompletenessColumnLabel, Type: flux.TString},
+		flux.ColMeta{Label: CompletenessLabel, Type: flux.TFloat},
+	)
+	buffer.Values = append(buffer.Values, cb.NewArray(), b.NewArray())
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *completenessTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestCompleteness_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.CompletenessProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "multiple columns",
+			spec: &universe.CompletenessProcedureSpec{
+				Columns: []string{"_value", "host"},
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "host", Type: flux.TString},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.0, "a", "x"},
+						{execute.Time(2), nil, "a", "x"},
+						{execute.Time(3), 3.0, nil, "x"},
+						{execute.Time(4), nil, "b", "x"},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_column", Type: flux.TString},
+					{Label: "_completeness", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"x", "_value", 0.5},
+					{"x", "host", 0.75},
+				},
+			}},
+		},
+		{
+			name: "empty table",
+			spec: &universe.CompletenessProcedureSpec{
+				Columns: []string{"_value"},
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols:   []string{"t0"},
+				KeyValues: []interface{}{"x"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_column", Type: flux.TString},
+					{Label: "_completeness", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"x", "_value", nil},
+				},
+			}},
+		},
+		{
+			name: "missing column",
+			spec: &universe.CompletenessProcedureSpec{
+				Columns: []string{"host"},
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "host" does not exist`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewCompletenessTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
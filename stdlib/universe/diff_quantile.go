@@ -0,0 +1,338 @@
+package universe
+
+import (
+	"sort"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const DiffQuantileKind = "diffQuantile"
+
+// DiffQuantileOpSpec computes the quantile of the
+// differences between subsequent values of each table.
+type DiffQuantileOpSpec struct {
+	Quantile    float64 `json:"quantile"`
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	Column      string  `json:"column"`
+	TimeColumn  string  `json:"timeColumn"`
+}
+
+func init() {
+	diffQuantileSignature := runtime.MustLookupBuiltinType("universe", "diffQuantile")
+
+	runtime.RegisterPackageValue("universe", DiffQuantileKind, flux.MustValue(flux.FunctionValue(DiffQuantileKind, createDiffQuantileOpSpec, diffQuantileSignature)))
+	flux.RegisterOpSpec(DiffQuantileKind, newDiffQuantileOp)
+	plan.RegisterProcedureSpec(DiffQuantileKind, newDiffQuantileProcedure, DiffQuantileKind)
+	execute.RegisterTransformation(DiffQuantileKind, createDiffQuantileTransformation)
+}
+
+func createDiffQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(DiffQuantileOpSpec)
+	if q, err := args.GetRequiredFloat("q"); err != nil {
+		return nil, err
+	} else if q < 0 || q > 1 {
+		return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+	} else {
+		spec.Quantile = q
+	}
+
+	if m, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		switch m {
+		case methodEstimateTdigest, methodExactMean, methodExactSelector:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown method %q, expected %q, %q, or %q", m, methodEstimateTdigest, methodExactMean, methodExactSelector)
+		}
+		spec.Method = m
+	} else {
+		spec.Method = defaultMethod
+	}
+
+	if c, ok, err := args.GetFloat("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if spec.Method != methodEstimateTdigest {
+			return nil, errors.New(codes.Invalid, "compression parameter is only valid for method estimate_tdigest")
+		}
+		if c <= 0 {
+			return nil, errors.New(codes.Invalid, "compression must be greater than 0")
+		}
+		spec.Compression = c
+	} else if spec.Method == methodEstimateTdigest {
+		spec.Compression = 1000
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func newDiffQuantileOp() flux.OperationSpec {
+	return new(DiffQuantileOpSpec)
+}
+
+func (s *DiffQuantileOpSpec) Kind() flux.OperationKind {
+	return DiffQuantileKind
+}
+
+type DiffQuantileProcedureSpec struct {
+	plan.DefaultCost
+	Quantile    float64 `json:"quantile"`
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	Column      string  `json:"column"`
+	TimeColumn  string  `json:"timeColumn"`
+}
+
+func newDiffQuantileProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*DiffQuantileOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &DiffQuantileProcedureSpec{
+		Quantile:    spec.Quantile,
+		Method:      spec.Method,
+		Compression: spec.Compression,
+		Column:      spec.Column,
+		TimeColumn:  spec.TimeColumn,
+	}, nil
+}
+
+func (s *DiffQuantileProcedureSpec) Kind() plan.ProcedureKind {
+	return DiffQuantileKind
+}
+
+func (s *DiffQuantileProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *DiffQuantileProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createDiffQuantileTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*DiffQuantileProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	fraction, err := getTDigestPoolFraction(a.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	t := newDiffQuantileTransformation(s, a.Allocator())
+	t.digests.setFreeDigestBudget(fraction)
+	t.digests.ctx = a.Context()
+	return execute.NewAggregateTransformation(id, t, a.Allocator())
+}
+
+// NewDiffQuantileTransformation creates a transformation that computes the
+// quantile of the differences between subsequent values of each table.
+//
+// The difference of each row is its value minus the value of the previous
+// row with a non-null value, so a table with n non-null values has n - 1
+// differences. Rows with a null value or time are skipped. The times of each
+// table must be in ascending order or the transformation returns an error.
+//
+// The differences are aggregated with the aggregate of the method so the
+// estimate_tdigest method shares the digests of a QuantileAgg. The output has
+// one row with the group key of the table and the quantile as a float in the
+// column. The quantile is null if the table has fewer than two values.
+func NewDiffQuantileTransformation(id execute.DatasetID, spec *DiffQuantileProcedureSpec, mem *memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	return execute.NewAggregateTransformation(id, newDiffQuantileTransformation(spec, mem), mem)
+}
+
+func newDiffQuantileTransformation(spec *DiffQuantileProcedureSpec, mem *memory.Allocator) *diffQuantileTransformation {
+	return &diffQuantileTransformation{
+		quantile:   spec.Quantile,
+		method:     spec.Method,
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+		digests:    NewQuantileAgg(spec.Quantile, spec.Compression, mem, 1),
+		mem:        mem,
+	}
+}
+
+type diffQuantileTransformation struct {
+	quantile   float64
+	method     string
+	column     string
+	timeColumn string
+	// digests allocates the digests of the estimate_tdigest method.
+	digests *QuantileAgg
+	mem     *memory.Allocator
+}
+
+// diffQuantileAgg is the state of the method that
+// aggregates the differences of a table.
+type diffQuantileAgg interface {
+	execute.DoFloatAgg
+	execute.FloatValueFunc
+	execute.Closer
+}
+
+type diffQuantileState struct {
+	typ flux.ColType
+	agg diffQuantileAgg
+	// last and lastTime are the value and time of the
+	// last row with a non-null value. ok reports if
+	// such a row has been seen.
+	last     float64
+	lastTime int64
+	ok       bool
+}
+
+func (s *diffQuantileState) Close() error {
+	return s.agg.Close()
+}
+
+func (t *diffQuantileTransformation) newAgg() diffQuantileAgg {
+	switch t.method {
+	case methodExactMean:
+		return NewExactQuantileAgg(t.quantile, 0, t.mem)
+	case methodExactSelector:
+		return &diffQuantileSelectorState{
+			ExactQuantileAgg: NewExactQuantileAgg(t.quantile, 0, t.mem),
+		}
+	default:
+		return t.digests.newState(t.quantile)
+	}
+}
+
+func (t *diffQuantileTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	timeIdx := chunk.Index(t.timeColumn)
+	if timeIdx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "time column %q does not exist", t.timeColumn)
+	}
+	if typ := chunk.Col(timeIdx).Type; typ != flux.TTime {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "time column %q must be of type time, got %v", t.timeColumn, typ)
+	}
+
+	var s *diffQuantileState
+	if state != nil {
+		s = state.(*diffQuantileState)
+	} else {
+		if chunk.Key().HasCol(t.column) {
+			return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+		}
+		switch typ := chunk.Col(idx).Type; typ {
+		case flux.TInt, flux.TUInt, flux.TFloat:
+			s = &diffQuantileState{typ: typ, agg: t.newAgg()}
+		default:
+			return nil, false, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+		}
+	}
+	if typ := chunk.Col(idx).Type; typ != s.typ {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "aggregate type conflict: %s != %s", typ, s.typ)
+	}
+
+	vs, ts := chunk.Values(idx), chunk.Ints(timeIdx)
+	b := array.NewFloatBuilder(mem)
+	b.Reserve(vs.Len())
+	for i, n := 0, vs.Len(); i < n; i++ {
+		if vs.IsNull(i) || ts.IsNull(i) {
+			continue
+		}
+		tm := ts.Value(i)
+		if s.ok && tm < s.lastTime {
+			return nil, false, errors.New(codes.FailedPrecondition, diffQuantileUnsortedTimeErr)
+		}
+
+		var v float64
+		switch vs := vs.(type) {
+		case *array.Int:
+			v = float64(vs.Value(i))
+		case *array.Uint:
+			v = float64(vs.Value(i))
+		case *array.Float:
+			v = vs.Value(i)
+		}
+		if s.ok {
+			b.Append(v - s.last)
+		}
+		s.last, s.lastTime, s.ok = v, tm, true
+	}
+	diffs := b.NewFloatArray()
+	if diffs.Len() > 0 {
+		s.agg.DoFloat(diffs)
+	}
+	diffs.Release()
+	return s, true, nil
+}
+
+func (t *diffQuantileTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*diffQuantileState)
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+1),
+	}
+	buffer.Columns = append(buffer.Columns, key.Cols()...)
+	buffer.Values = make([]array.Array, len(key.Cols()), cap(buffer.Columns))
+	for j := range key.Cols() {
+		buffer.Values[j] = arrow.Repeat(key.Cols()[j].Type, key.Value(j), 1, mem)
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{
+		Label: t.column,
+		Type:  flux.TFloat,
+	})
+	buffer.Values = append(buffer.Values, array.FloatRepeat(s.agg.ValueFloat(), s.agg.IsNull(), 1, mem))
+
+	if err := buffer.Validate(); err != nil {
+		return err
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *diffQuantileTransformation) Close() error {
+	return t.digests.Close()
+}
+
+const diffQuantileUnsortedTimeErr = "diffQuantile found out-of-order times in time column"
+
+// diffQuantileSelectorState selects the difference that the
+// exact selector of quantile would select from the differences.
+type diffQuantileSelectorState struct {
+	*ExactQuantileAgg
+}
+
+func (s *diffQuantileSelectorState) ValueFloat() float64 {
+	if len(s.data) == 0 {
+		return 0
+	}
+	sort.Float64s(s.data)
+	return s.data[getQuantileIndex(s.Quantile, len(s.data))]
+}
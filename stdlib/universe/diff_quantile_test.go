@@ -0,0 +1,187 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestDiffQuantile_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.RowWiseTable{
+			Table: &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(5), "a"},
+					{execute.Time(2), int64(1), "a"},
+					{execute.Time(3), int64(3), "a"},
+					{execute.Time(4), int64(9), "a"},
+					{execute.Time(5), int64(2), "a"},
+					{execute.Time(6), int64(7), "a"},
+				},
+			},
+		}}
+	}
+	want := func(v interface{}) []*executetest.Table {
+		return []*executetest.Table{{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{"a", v}},
+		}}
+	}
+
+	// The differences of the data are -4, 2, 6, -7, and 5.
+	state := universe.NewQuantileAgg(0.5, 1000, &memory.Allocator{}, 1).NewFloatAgg()
+	state.DoFloat(arrow.NewFloat([]float64{-4, 2, 6, -7, 5}, nil))
+	tdigest := state.(execute.FloatValueFunc).ValueFloat()
+
+	testCases := []struct {
+		name    string
+		spec    *universe.DiffQuantileProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "exact mean",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:   0.5,
+				Method:     "exact_mean",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: data(),
+			want: want(2.0),
+		},
+		{
+			name: "exact selector",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:   0.75,
+				Method:     "exact_selector",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: data(),
+			want: want(5.0),
+		},
+		{
+			name: "estimate tdigest",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:    0.5,
+				Method:      "estimate_tdigest",
+				Compression: 1000,
+				Column:      "_value",
+				TimeColumn:  "_time",
+			},
+			data: data(),
+			want: want(tdigest),
+		},
+		{
+			name: "nulls",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:   0.5,
+				Method:     "exact_mean",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), nil},
+					{nil, 100.0},
+					{execute.Time(3), 4.0},
+					{execute.Time(4), 8.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{{3.0}},
+			}},
+		},
+		{
+			name: "single value",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:    0.5,
+				Method:      "estimate_tdigest",
+				Compression: 1000,
+				Column:      "_value",
+				TimeColumn:  "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{{nil}},
+			}},
+		},
+		{
+			name: "unsorted",
+			spec: &universe.DiffQuantileProcedureSpec{
+				Quantile:   0.5,
+				Method:     "exact_mean",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 2.0},
+					{execute.Time(1), 4.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "diffQuantile found out-of-order times in time column"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewDiffQuantileTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package universe
 
 import (
+	"math"
+
 	"github.com/apache/arrow/go/v7/arrow/memory"
 
 	"github.com/influxdata/flux"
@@ -598,6 +600,11 @@ func (d *difference) updateInt(v int64, valid bool) (int64, bool) {
 		return 0, false
 	}
 	diff := v - prev
+	if (v >= 0) != (prev >= 0) && (diff >= 0) != (v >= 0) {
+		// The difference overflowed and cannot be
+		// represented as an int.
+		return 0, false
+	}
 	if diff >= 0 || !d.nonNegative {
 		return diff, true
 	} else if d.nonNegative && d.initialZero && v >= 0 {
@@ -620,6 +627,12 @@ func (d *difference) updateUInt(v uint64, valid bool) (int64, bool) {
 		d.valid = true
 		return 0, false
 	}
+	// The difference must fit in an int. A positive difference
+	// may be at most math.MaxInt64 and a negative difference
+	// may be at most one more in magnitude.
+	if v >= prev && v-prev > math.MaxInt64 || v < prev && prev-v > 1<<63 {
+		return 0, false
+	}
 	// Note: the unsigned substraction works correctly even for negative differences
 	// because of two's-complement arithmetic.
 	diff := int64(v - prev)
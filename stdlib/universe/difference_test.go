@@ -1,6 +1,7 @@
 package universe_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/influxdata/flux"
@@ -756,6 +757,70 @@ func TestDifference_Process(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "int overflow",
+			spec: &universe.DifferenceProcedureSpec{
+				Columns: []string{execute.DefaultValueColLabel},
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(math.MinInt64)},
+					{execute.Time(2), int64(math.MaxInt64)},
+					{execute.Time(3), int64(-1)},
+					{execute.Time(4), int64(math.MaxInt64 - 1)},
+					{execute.Time(5), int64(-3)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), nil},
+					{execute.Time(3), int64(math.MinInt64)},
+					{execute.Time(4), int64(math.MaxInt64)},
+					{execute.Time(5), nil},
+				},
+			}},
+		},
+		{
+			name: "uint overflow",
+			spec: &universe.DifferenceProcedureSpec{
+				Columns: []string{execute.DefaultValueColLabel},
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), uint64(0)},
+					{execute.Time(2), uint64(math.MaxUint64)},
+					{execute.Time(3), uint64(math.MaxInt64)},
+					{execute.Time(4), uint64(0)},
+					{execute.Time(5), uint64(1 << 63)},
+					{execute.Time(6), uint64(0)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), nil},
+					{execute.Time(3), int64(math.MinInt64)},
+					{execute.Time(4), int64(-math.MaxInt64)},
+					{execute.Time(5), nil},
+					{execute.Time(6), int64(math.MinInt64)},
+				},
+			}},
+		},
 		{
 			name: "uint rowwise",
 			spec: &universe.DifferenceProcedureSpec{
@@ -0,0 +1,291 @@
+package universe
+
+import (
+	"math"
+	"sort"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ECDFKind = "ecdf"
+
+// ECDFLabel is the column that contains the empirical cumulative probability.
+const ECDFLabel = "_ecdf"
+
+const (
+	ecdfTiesStep    = "step"
+	ecdfTiesAverage = "average"
+)
+
+// ECDFOpSpec computes the empirical cumulative
+// probability of each value within each table.
+type ECDFOpSpec struct {
+	Column string `json:"column"`
+	Ties   string `json:"ties"`
+}
+
+func init() {
+	ecdfSignature := runtime.MustLookupBuiltinType("universe", "ecdf")
+
+	runtime.RegisterPackageValue("universe", ECDFKind, flux.MustValue(flux.FunctionValue(ECDFKind, createECDFOpSpec, ecdfSignature)))
+	flux.RegisterOpSpec(ECDFKind, newECDFOp)
+	plan.RegisterProcedureSpec(ECDFKind, newECDFProcedure, ECDFKind)
+	execute.RegisterTransformation(ECDFKind, createECDFTransformation)
+}
+
+func createECDFOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(ECDFOpSpec)
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if ties, ok, err := args.GetString("ties"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Ties = ties
+	} else {
+		spec.Ties = ecdfTiesStep
+	}
+
+	switch spec.Ties {
+	case ecdfTiesStep, ecdfTiesAverage:
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown ties method %q, expected %q or %q", spec.Ties, ecdfTiesStep, ecdfTiesAverage)
+	}
+	return spec, nil
+}
+
+func newECDFOp() flux.OperationSpec {
+	return new(ECDFOpSpec)
+}
+
+func (s *ECDFOpSpec) Kind() flux.OperationKind {
+	return ECDFKind
+}
+
+type ECDFProcedureSpec struct {
+	plan.DefaultCost
+	Column string `json:"column"`
+	Ties   string `json:"ties"`
+}
+
+func newECDFProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ECDFOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ECDFProcedureSpec{
+		Column: spec.Column,
+		Ties:   spec.Ties,
+	}, nil
+}
+
+func (s *ECDFProcedureSpec) Kind() plan.ProcedureKind {
+	return ECDFKind
+}
+
+func (s *ECDFProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ECDFProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createECDFTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ECDFProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewECDFTransformation(id, s, a.Allocator())
+}
+
+type ecdfTransformation struct {
+	column string
+	ties   string
+}
+
+// NewECDFTransformation creates a transformation that adds the
+// empirical cumulative probability of the value in each row to
+// the _ecdf column. Rows keep their original order.
+//
+// The probability of a value is its rank among the non-null values
+// of the table divided by the number of non-null values. Tied values
+// either all receive the highest rank of the tie, which produces the
+// step function of the empirical distribution, or the average rank
+// of the tie. Null and NaN values have a null probability.
+func NewECDFTransformation(id execute.DatasetID, spec *ECDFProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &ecdfTransformation{
+		column: spec.Column,
+		ties:   spec.Ties,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type ecdfState struct {
+	chunks []table.Chunk
+
+	// values holds the value of every row and valid
+	// marks the rows that have a non-null value.
+	values []float64
+	valid  []bool
+}
+
+func (s *ecdfState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *ecdfTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *ecdfState
+	if state != nil {
+		s = state.(*ecdfState)
+	} else {
+		s = &ecdfState{}
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, vs.Value(i))
+			s.valid = append(s.valid, vs.IsValid(i) && !math.IsNaN(vs.Value(i)))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the ecdf of column %q of type %s", t.column, typ)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	return s, true, nil
+}
+
+func (t *ecdfTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*ecdfState)
+	if len(s.values) == 0 {
+		// Empty tables are passed through unchanged.
+		for _, chunk := range s.chunks {
+			chunk.Retain()
+			if err := d.Process(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	probs := t.probabilities(s)
+	offset := 0
+	for _, chunk := range s.chunks {
+		n := chunk.Len()
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for i := offset; i < offset+n; i++ {
+			if s.valid[i] {
+				b.Append(probs[i])
+			} else {
+				b.AppendNull()
+			}
+		}
+		offset += n
+
+		buffer := chunk.Buffer()
+		cols := make([]flux.ColMeta, len(buffer.Columns), len(buffer.Columns)+1)
+		copy(cols, buffer.Columns)
+		vs := make([]array.Array, len(buffer.Values), len(buffer.Values)+1)
+		for j := range vs {
+			vs[j] = buffer.Values[j]
+			vs[j].Retain()
+		}
+
+		if j := chunk.Index(ECDFLabel); j >= 0 {
+			vs[j].Release()
+			cols[j].Type, vs[j] = flux.TFloat, b.NewFloatArray()
+		} else {
+			cols = append(cols, flux.ColMeta{Label: ECDFLabel, Type: flux.TFloat})
+			vs = append(vs, b.NewFloatArray())
+		}
+
+		buffer.Columns, buffer.Values = cols, vs
+		if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probabilities computes the empirical cumulative
+// probability for each of the valid values.
+func (t *ecdfTransformation) probabilities(s *ecdfState) []float64 {
+	indices := make([]int, 0, len(s.values))
+	for i, valid := range s.valid {
+		if valid {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return s.values[indices[i]] < s.values[indices[j]]
+	})
+
+	n := float64(len(indices))
+	probs := make([]float64, len(s.values))
+	for start := 0; start < len(indices); {
+		// Find the end of the run of tied values.
+		end := start + 1
+		for end < len(indices) && s.values[indices[end]] == s.values[indices[start]] {
+			end++
+		}
+
+		rank := float64(end)
+		if t.ties == ecdfTiesAverage {
+			rank = float64(start+1+end) / 2
+		}
+		for _, i := range indices[start:end] {
+			probs[i] = rank / n
+		}
+		start = end
+	}
+	return probs
+}
+
+func (t *ecdfTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestECDF_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.ECDFProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "step",
+			spec: &universe.ECDFProcedureSpec{
+				Column: "_value",
+				Ties:   "step",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 3.0, "a"},
+					{execute.Time(2), 1.0, "a"},
+					{execute.Time(3), nil, "a"},
+					{execute.Time(4), 3.0, "a"},
+					{execute.Time(6), 2.0, "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_ecdf", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 3.0, "a", 1.0},
+					{execute.Time(2), 1.0, "a", 0.25},
+					{execute.Time(3), nil, "a", nil},
+					{execute.Time(4), 3.0, "a", 1.0},
+					{execute.Time(6), 2.0, "a", 0.5},
+				},
+			}},
+		},
+		{
+			name: "average across chunks",
+			spec: &universe.ECDFProcedureSpec{
+				Column: "_value",
+				Ties:   "average",
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(5)},
+						{execute.Time(2), int64(5)},
+						{execute.Time(3), int64(1)},
+						{execute.Time(4), int64(9)},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "_ecdf", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(5), 0.625},
+					{execute.Time(2), int64(5), 0.625},
+					{execute.Time(3), int64(1), 0.25},
+					{execute.Time(4), int64(9), 1.0},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewECDFTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -178247,11 +178247,11 @@ var FluxTestPackages = []*ast.Package{&ast.Package{
 			Errors:   nil,
 			Loc: &ast.SourceLocation{
 				End: ast.Position{
-					Column: 112,
-					Line:   35,
+					Column: 104,
+					Line:   51,
 				},
 				File:   "mode_test.flux",
-				Source: "package universe_test\n\n\nimport \"testing\"\n\noption now = () => 2030-01-01T00:00:00Z\n\ninData =\n    \"\n#datatype,string,long,string,string,dateTime:RFC3339,unsignedLong\n#group,false,false,true,true,false,false\n#default,_result,,,,,\n,result,table,_measurement,_field,_time,_value\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:05Z,70\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:15Z,48\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:25Z,33\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:35Z,63\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:45Z,48\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:55Z,63\n\"\noutData =\n    \"\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,63\n\"\nt_mode = (table=<-) =>\n    table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode()\n\ntest _mode = () => ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outData), fn: t_mode})",
+				Source: "package universe_test\n\n\nimport \"testing\"\n\noption now = () => 2030-01-01T00:00:00Z\n\ninData =\n    \"\n#datatype,string,long,string,string,dateTime:RFC3339,unsignedLong\n#group,false,false,true,true,false,false\n#default,_result,,,,,\n,result,table,_measurement,_field,_time,_value\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:05Z,70\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:15Z,48\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:25Z,33\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:35Z,63\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:45Z,48\n,,0,Sgf,DlXwgrw,2018-12-18T22:11:55Z,63\n\"\noutData =\n    \"\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,63\n\"\nt_mode = (table=<-) =>\n    table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode()\n\ntest _mode = () => ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outData), fn: t_mode})\n\noutDataMin =\n    \"\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n\"\nt_mode_min = (table=<-) =>\n    table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode(ties: \"min\")\n\ntest _mode_min = () =>\n    ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min})",
 				Start: ast.Position{
 					Column: 1,
 					Line:   1,
@@ -179353,6 +179353,1029 @@ var FluxTestPackages = []*ast.Package{&ast.Package{
 					},
 				},
 			},
+		}, &ast.VariableAssignment{
+			BaseNode: ast.BaseNode{
+				Comments: nil,
+				Errors:   nil,
+				Loc: &ast.SourceLocation{
+					End: ast.Position{
+						Column: 2,
+						Line:   44,
+					},
+					File:   "mode_test.flux",
+					Source: "outDataMin =\n    \"\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n\"",
+					Start: ast.Position{
+						Column: 1,
+						Line:   37,
+					},
+				},
+			},
+			ID: &ast.Identifier{
+				BaseNode: ast.BaseNode{
+					Comments: nil,
+					Errors:   nil,
+					Loc: &ast.SourceLocation{
+						End: ast.Position{
+							Column: 11,
+							Line:   37,
+						},
+						File:   "mode_test.flux",
+						Source: "outDataMin",
+						Start: ast.Position{
+							Column: 1,
+							Line:   37,
+						},
+					},
+				},
+				Name: "outDataMin",
+			},
+			Init: &ast.StringLiteral{
+				BaseNode: ast.BaseNode{
+					Comments: nil,
+					Errors:   nil,
+					Loc: &ast.SourceLocation{
+						End: ast.Position{
+							Column: 2,
+							Line:   44,
+						},
+						File:   "mode_test.flux",
+						Source: "\"\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n\"",
+						Start: ast.Position{
+							Column: 5,
+							Line:   38,
+						},
+					},
+				},
+				Value: "\n#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,unsignedLong\n#group,false,false,true,true,true,true,false\n#default,_result,,,,,,\n,result,table,_start,_stop,_measurement,_field,_value\n,,0,2018-12-01T00:00:00Z,2030-01-01T00:00:00Z,Sgf,DlXwgrw,48\n",
+			},
+		}, &ast.VariableAssignment{
+			BaseNode: ast.BaseNode{
+				Comments: nil,
+				Errors:   nil,
+				Loc: &ast.SourceLocation{
+					End: ast.Position{
+						Column: 29,
+						Line:   48,
+					},
+					File:   "mode_test.flux",
+					Source: "t_mode_min = (table=<-) =>\n    table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode(ties: \"min\")",
+					Start: ast.Position{
+						Column: 1,
+						Line:   45,
+					},
+				},
+			},
+			ID: &ast.Identifier{
+				BaseNode: ast.BaseNode{
+					Comments: nil,
+					Errors:   nil,
+					Loc: &ast.SourceLocation{
+						End: ast.Position{
+							Column: 11,
+							Line:   45,
+						},
+						File:   "mode_test.flux",
+						Source: "t_mode_min",
+						Start: ast.Position{
+							Column: 1,
+							Line:   45,
+						},
+					},
+				},
+				Name: "t_mode_min",
+			},
+			Init: &ast.FunctionExpression{
+				Arrow: nil,
+				BaseNode: ast.BaseNode{
+					Comments: nil,
+					Errors:   nil,
+					Loc: &ast.SourceLocation{
+						End: ast.Position{
+							Column: 29,
+							Line:   48,
+						},
+						File:   "mode_test.flux",
+						Source: "(table=<-) =>\n    table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode(ties: \"min\")",
+						Start: ast.Position{
+							Column: 14,
+							Line:   45,
+						},
+					},
+				},
+				Body: &ast.PipeExpression{
+					Argument: &ast.PipeExpression{
+						Argument: &ast.Identifier{
+							BaseNode: ast.BaseNode{
+								Comments: nil,
+								Errors:   nil,
+								Loc: &ast.SourceLocation{
+									End: ast.Position{
+										Column: 10,
+										Line:   46,
+									},
+									File:   "mode_test.flux",
+									Source: "table",
+									Start: ast.Position{
+										Column: 5,
+										Line:   46,
+									},
+								},
+							},
+							Name: "table",
+						},
+						BaseNode: ast.BaseNode{
+							Comments: nil,
+							Errors:   nil,
+							Loc: &ast.SourceLocation{
+								End: ast.Position{
+									Column: 46,
+									Line:   47,
+								},
+								File:   "mode_test.flux",
+								Source: "table\n        |> range(start: 2018-12-01T00:00:00Z)",
+								Start: ast.Position{
+									Column: 5,
+									Line:   46,
+								},
+							},
+						},
+						Call: &ast.CallExpression{
+							Arguments: []ast.Expression{&ast.ObjectExpression{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 45,
+											Line:   47,
+										},
+										File:   "mode_test.flux",
+										Source: "start: 2018-12-01T00:00:00Z",
+										Start: ast.Position{
+											Column: 18,
+											Line:   47,
+										},
+									},
+								},
+								Lbrace: nil,
+								Properties: []*ast.Property{&ast.Property{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 45,
+												Line:   47,
+											},
+											File:   "mode_test.flux",
+											Source: "start: 2018-12-01T00:00:00Z",
+											Start: ast.Position{
+												Column: 18,
+												Line:   47,
+											},
+										},
+									},
+									Comma: nil,
+									Key: &ast.Identifier{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 23,
+													Line:   47,
+												},
+												File:   "mode_test.flux",
+												Source: "start",
+												Start: ast.Position{
+													Column: 18,
+													Line:   47,
+												},
+											},
+										},
+										Name: "start",
+									},
+									Separator: nil,
+									Value: &ast.DateTimeLiteral{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 45,
+													Line:   47,
+												},
+												File:   "mode_test.flux",
+												Source: "2018-12-01T00:00:00Z",
+												Start: ast.Position{
+													Column: 25,
+													Line:   47,
+												},
+											},
+										},
+										Value: parser.MustParseTime("2018-12-01T00:00:00Z"),
+									},
+								}},
+								Rbrace: nil,
+								With:   nil,
+							}},
+							BaseNode: ast.BaseNode{
+								Comments: nil,
+								Errors:   nil,
+								Loc: &ast.SourceLocation{
+									End: ast.Position{
+										Column: 46,
+										Line:   47,
+									},
+									File:   "mode_test.flux",
+									Source: "range(start: 2018-12-01T00:00:00Z)",
+									Start: ast.Position{
+										Column: 12,
+										Line:   47,
+									},
+								},
+							},
+							Callee: &ast.Identifier{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 17,
+											Line:   47,
+										},
+										File:   "mode_test.flux",
+										Source: "range",
+										Start: ast.Position{
+											Column: 12,
+											Line:   47,
+										},
+									},
+								},
+								Name: "range",
+							},
+							Lparen: nil,
+							Rparen: nil,
+						},
+					},
+					BaseNode: ast.BaseNode{
+						Comments: nil,
+						Errors:   nil,
+						Loc: &ast.SourceLocation{
+							End: ast.Position{
+								Column: 29,
+								Line:   48,
+							},
+							File:   "mode_test.flux",
+							Source: "table\n        |> range(start: 2018-12-01T00:00:00Z)\n        |> mode(ties: \"min\")",
+							Start: ast.Position{
+								Column: 5,
+								Line:   46,
+							},
+						},
+					},
+					Call: &ast.CallExpression{
+						Arguments: []ast.Expression{&ast.ObjectExpression{
+							BaseNode: ast.BaseNode{
+								Comments: nil,
+								Errors:   nil,
+								Loc: &ast.SourceLocation{
+									End: ast.Position{
+										Column: 28,
+										Line:   48,
+									},
+									File:   "mode_test.flux",
+									Source: "ties: \"min\"",
+									Start: ast.Position{
+										Column: 17,
+										Line:   48,
+									},
+								},
+							},
+							Lbrace: nil,
+							Properties: []*ast.Property{&ast.Property{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 28,
+											Line:   48,
+										},
+										File:   "mode_test.flux",
+										Source: "ties: \"min\"",
+										Start: ast.Position{
+											Column: 17,
+											Line:   48,
+										},
+									},
+								},
+								Comma: nil,
+								Key: &ast.Identifier{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 21,
+												Line:   48,
+											},
+											File:   "mode_test.flux",
+											Source: "ties",
+											Start: ast.Position{
+												Column: 17,
+												Line:   48,
+											},
+										},
+									},
+									Name: "ties",
+								},
+								Separator: nil,
+								Value: &ast.StringLiteral{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 28,
+												Line:   48,
+											},
+											File:   "mode_test.flux",
+											Source: "\"min\"",
+											Start: ast.Position{
+												Column: 23,
+												Line:   48,
+											},
+										},
+									},
+									Value: "min",
+								},
+							}},
+							Rbrace: nil,
+							With:   nil,
+						}},
+						BaseNode: ast.BaseNode{
+							Comments: nil,
+							Errors:   nil,
+							Loc: &ast.SourceLocation{
+								End: ast.Position{
+									Column: 29,
+									Line:   48,
+								},
+								File:   "mode_test.flux",
+								Source: "mode(ties: \"min\")",
+								Start: ast.Position{
+									Column: 12,
+									Line:   48,
+								},
+							},
+						},
+						Callee: &ast.Identifier{
+							BaseNode: ast.BaseNode{
+								Comments: nil,
+								Errors:   nil,
+								Loc: &ast.SourceLocation{
+									End: ast.Position{
+										Column: 16,
+										Line:   48,
+									},
+									File:   "mode_test.flux",
+									Source: "mode",
+									Start: ast.Position{
+										Column: 12,
+										Line:   48,
+									},
+								},
+							},
+							Name: "mode",
+						},
+						Lparen: nil,
+						Rparen: nil,
+					},
+				},
+				Lparen: nil,
+				Params: []*ast.Property{&ast.Property{
+					BaseNode: ast.BaseNode{
+						Comments: nil,
+						Errors:   nil,
+						Loc: &ast.SourceLocation{
+							End: ast.Position{
+								Column: 23,
+								Line:   45,
+							},
+							File:   "mode_test.flux",
+							Source: "table=<-",
+							Start: ast.Position{
+								Column: 15,
+								Line:   45,
+							},
+						},
+					},
+					Comma: nil,
+					Key: &ast.Identifier{
+						BaseNode: ast.BaseNode{
+							Comments: nil,
+							Errors:   nil,
+							Loc: &ast.SourceLocation{
+								End: ast.Position{
+									Column: 20,
+									Line:   45,
+								},
+								File:   "mode_test.flux",
+								Source: "table",
+								Start: ast.Position{
+									Column: 15,
+									Line:   45,
+								},
+							},
+						},
+						Name: "table",
+					},
+					Separator: nil,
+					Value: &ast.PipeLiteral{BaseNode: ast.BaseNode{
+						Comments: nil,
+						Errors:   nil,
+						Loc: &ast.SourceLocation{
+							End: ast.Position{
+								Column: 23,
+								Line:   45,
+							},
+							File:   "mode_test.flux",
+							Source: "<-",
+							Start: ast.Position{
+								Column: 21,
+								Line:   45,
+							},
+						},
+					}},
+				}},
+				Rparan: nil,
+			},
+		}, &ast.TestStatement{
+			Assignment: &ast.VariableAssignment{
+				BaseNode: ast.BaseNode{
+					Comments: nil,
+					Errors:   nil,
+					Loc: &ast.SourceLocation{
+						End: ast.Position{
+							Column: 104,
+							Line:   51,
+						},
+						File:   "mode_test.flux",
+						Source: "_mode_min = () =>\n    ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min})",
+						Start: ast.Position{
+							Column: 6,
+							Line:   50,
+						},
+					},
+				},
+				ID: &ast.Identifier{
+					BaseNode: ast.BaseNode{
+						Comments: nil,
+						Errors:   nil,
+						Loc: &ast.SourceLocation{
+							End: ast.Position{
+								Column: 15,
+								Line:   50,
+							},
+							File:   "mode_test.flux",
+							Source: "_mode_min",
+							Start: ast.Position{
+								Column: 6,
+								Line:   50,
+							},
+						},
+					},
+					Name: "_mode_min",
+				},
+				Init: &ast.FunctionExpression{
+					Arrow: nil,
+					BaseNode: ast.BaseNode{
+						Comments: nil,
+						Errors:   nil,
+						Loc: &ast.SourceLocation{
+							End: ast.Position{
+								Column: 104,
+								Line:   51,
+							},
+							File:   "mode_test.flux",
+							Source: "() =>\n    ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min})",
+							Start: ast.Position{
+								Column: 18,
+								Line:   50,
+							},
+						},
+					},
+					Body: &ast.ParenExpression{
+						BaseNode: ast.BaseNode{
+							Comments: nil,
+							Errors:   nil,
+							Loc: &ast.SourceLocation{
+								End: ast.Position{
+									Column: 104,
+									Line:   51,
+								},
+								File:   "mode_test.flux",
+								Source: "({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min})",
+								Start: ast.Position{
+									Column: 5,
+									Line:   51,
+								},
+							},
+						},
+						Expression: &ast.ObjectExpression{
+							BaseNode: ast.BaseNode{
+								Comments: nil,
+								Errors:   nil,
+								Loc: &ast.SourceLocation{
+									End: ast.Position{
+										Column: 103,
+										Line:   51,
+									},
+									File:   "mode_test.flux",
+									Source: "{input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min}",
+									Start: ast.Position{
+										Column: 6,
+										Line:   51,
+									},
+								},
+							},
+							Lbrace: nil,
+							Properties: []*ast.Property{&ast.Property{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 46,
+											Line:   51,
+										},
+										File:   "mode_test.flux",
+										Source: "input: testing.loadStorage(csv: inData)",
+										Start: ast.Position{
+											Column: 7,
+											Line:   51,
+										},
+									},
+								},
+								Comma: nil,
+								Key: &ast.Identifier{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 12,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "input",
+											Start: ast.Position{
+												Column: 7,
+												Line:   51,
+											},
+										},
+									},
+									Name: "input",
+								},
+								Separator: nil,
+								Value: &ast.CallExpression{
+									Arguments: []ast.Expression{&ast.ObjectExpression{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 45,
+													Line:   51,
+												},
+												File:   "mode_test.flux",
+												Source: "csv: inData",
+												Start: ast.Position{
+													Column: 34,
+													Line:   51,
+												},
+											},
+										},
+										Lbrace: nil,
+										Properties: []*ast.Property{&ast.Property{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 45,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "csv: inData",
+													Start: ast.Position{
+														Column: 34,
+														Line:   51,
+													},
+												},
+											},
+											Comma: nil,
+											Key: &ast.Identifier{
+												BaseNode: ast.BaseNode{
+													Comments: nil,
+													Errors:   nil,
+													Loc: &ast.SourceLocation{
+														End: ast.Position{
+															Column: 37,
+															Line:   51,
+														},
+														File:   "mode_test.flux",
+														Source: "csv",
+														Start: ast.Position{
+															Column: 34,
+															Line:   51,
+														},
+													},
+												},
+												Name: "csv",
+											},
+											Separator: nil,
+											Value: &ast.Identifier{
+												BaseNode: ast.BaseNode{
+													Comments: nil,
+													Errors:   nil,
+													Loc: &ast.SourceLocation{
+														End: ast.Position{
+															Column: 45,
+															Line:   51,
+														},
+														File:   "mode_test.flux",
+														Source: "inData",
+														Start: ast.Position{
+															Column: 39,
+															Line:   51,
+														},
+													},
+												},
+												Name: "inData",
+											},
+										}},
+										Rbrace: nil,
+										With:   nil,
+									}},
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 46,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "testing.loadStorage(csv: inData)",
+											Start: ast.Position{
+												Column: 14,
+												Line:   51,
+											},
+										},
+									},
+									Callee: &ast.MemberExpression{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 33,
+													Line:   51,
+												},
+												File:   "mode_test.flux",
+												Source: "testing.loadStorage",
+												Start: ast.Position{
+													Column: 14,
+													Line:   51,
+												},
+											},
+										},
+										Lbrack: nil,
+										Object: &ast.Identifier{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 21,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "testing",
+													Start: ast.Position{
+														Column: 14,
+														Line:   51,
+													},
+												},
+											},
+											Name: "testing",
+										},
+										Property: &ast.Identifier{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 33,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "loadStorage",
+													Start: ast.Position{
+														Column: 22,
+														Line:   51,
+													},
+												},
+											},
+											Name: "loadStorage",
+										},
+										Rbrack: nil,
+									},
+									Lparen: nil,
+									Rparen: nil,
+								},
+							}, &ast.Property{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 86,
+											Line:   51,
+										},
+										File:   "mode_test.flux",
+										Source: "want: testing.loadMem(csv: outDataMin)",
+										Start: ast.Position{
+											Column: 48,
+											Line:   51,
+										},
+									},
+								},
+								Comma: nil,
+								Key: &ast.Identifier{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 52,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "want",
+											Start: ast.Position{
+												Column: 48,
+												Line:   51,
+											},
+										},
+									},
+									Name: "want",
+								},
+								Separator: nil,
+								Value: &ast.CallExpression{
+									Arguments: []ast.Expression{&ast.ObjectExpression{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 85,
+													Line:   51,
+												},
+												File:   "mode_test.flux",
+												Source: "csv: outDataMin",
+												Start: ast.Position{
+													Column: 70,
+													Line:   51,
+												},
+											},
+										},
+										Lbrace: nil,
+										Properties: []*ast.Property{&ast.Property{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 85,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "csv: outDataMin",
+													Start: ast.Position{
+														Column: 70,
+														Line:   51,
+													},
+												},
+											},
+											Comma: nil,
+											Key: &ast.Identifier{
+												BaseNode: ast.BaseNode{
+													Comments: nil,
+													Errors:   nil,
+													Loc: &ast.SourceLocation{
+														End: ast.Position{
+															Column: 73,
+															Line:   51,
+														},
+														File:   "mode_test.flux",
+														Source: "csv",
+														Start: ast.Position{
+															Column: 70,
+															Line:   51,
+														},
+													},
+												},
+												Name: "csv",
+											},
+											Separator: nil,
+											Value: &ast.Identifier{
+												BaseNode: ast.BaseNode{
+													Comments: nil,
+													Errors:   nil,
+													Loc: &ast.SourceLocation{
+														End: ast.Position{
+															Column: 85,
+															Line:   51,
+														},
+														File:   "mode_test.flux",
+														Source: "outDataMin",
+														Start: ast.Position{
+															Column: 75,
+															Line:   51,
+														},
+													},
+												},
+												Name: "outDataMin",
+											},
+										}},
+										Rbrace: nil,
+										With:   nil,
+									}},
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 86,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "testing.loadMem(csv: outDataMin)",
+											Start: ast.Position{
+												Column: 54,
+												Line:   51,
+											},
+										},
+									},
+									Callee: &ast.MemberExpression{
+										BaseNode: ast.BaseNode{
+											Comments: nil,
+											Errors:   nil,
+											Loc: &ast.SourceLocation{
+												End: ast.Position{
+													Column: 69,
+													Line:   51,
+												},
+												File:   "mode_test.flux",
+												Source: "testing.loadMem",
+												Start: ast.Position{
+													Column: 54,
+													Line:   51,
+												},
+											},
+										},
+										Lbrack: nil,
+										Object: &ast.Identifier{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 61,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "testing",
+													Start: ast.Position{
+														Column: 54,
+														Line:   51,
+													},
+												},
+											},
+											Name: "testing",
+										},
+										Property: &ast.Identifier{
+											BaseNode: ast.BaseNode{
+												Comments: nil,
+												Errors:   nil,
+												Loc: &ast.SourceLocation{
+													End: ast.Position{
+														Column: 69,
+														Line:   51,
+													},
+													File:   "mode_test.flux",
+													Source: "loadMem",
+													Start: ast.Position{
+														Column: 62,
+														Line:   51,
+													},
+												},
+											},
+											Name: "loadMem",
+										},
+										Rbrack: nil,
+									},
+									Lparen: nil,
+									Rparen: nil,
+								},
+							}, &ast.Property{
+								BaseNode: ast.BaseNode{
+									Comments: nil,
+									Errors:   nil,
+									Loc: &ast.SourceLocation{
+										End: ast.Position{
+											Column: 102,
+											Line:   51,
+										},
+										File:   "mode_test.flux",
+										Source: "fn: t_mode_min",
+										Start: ast.Position{
+											Column: 88,
+											Line:   51,
+										},
+									},
+								},
+								Comma: nil,
+								Key: &ast.Identifier{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 90,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "fn",
+											Start: ast.Position{
+												Column: 88,
+												Line:   51,
+											},
+										},
+									},
+									Name: "fn",
+								},
+								Separator: nil,
+								Value: &ast.Identifier{
+									BaseNode: ast.BaseNode{
+										Comments: nil,
+										Errors:   nil,
+										Loc: &ast.SourceLocation{
+											End: ast.Position{
+												Column: 102,
+												Line:   51,
+											},
+											File:   "mode_test.flux",
+											Source: "t_mode_min",
+											Start: ast.Position{
+												Column: 92,
+												Line:   51,
+											},
+										},
+									},
+									Name: "t_mode_min",
+								},
+							}},
+							Rbrace: nil,
+							With:   nil,
+						},
+						Lparen: nil,
+						Rparen: nil,
+					},
+					Lparen: nil,
+					Params: []*ast.Property{},
+					Rparan: nil,
+				},
+			},
+			BaseNode: ast.BaseNode{
+				Comments: nil,
+				Errors:   nil,
+				Loc: &ast.SourceLocation{
+					End: ast.Position{
+						Column: 104,
+						Line:   51,
+					},
+					File:   "mode_test.flux",
+					Source: "test _mode_min = () =>\n    ({input: testing.loadStorage(csv: inData), want: testing.loadMem(csv: outDataMin), fn: t_mode_min})",
+					Start: ast.Position{
+						Column: 1,
+						Line:   50,
+					},
+				},
+			},
 		}},
 		Eof: nil,
 		Imports: []*ast.ImportDeclaration{&ast.ImportDeclaration{
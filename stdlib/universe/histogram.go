@@ -25,6 +25,9 @@ type HistogramOpSpec struct {
 	CountColumn      string    `json:"countColumn"`
 	Bins             []float64 `json:"bins"`
 	Normalize        bool      `json:"normalize"`
+	// Overflow counts the values that are greater than the highest bin
+	// in an additional bin with an upper bound of positive infinity.
+	Overflow bool `json:"overflow"`
 }
 
 func init() {
@@ -66,12 +69,7 @@ func CreateHistogramOpSpec(args flux.Arguments, a *flux.Administration) (flux.Op
 	} else {
 		spec.CountColumn = execute.DefaultValueColLabel
 	}
-	binsArry, err := args.GetRequiredArray("bins", semantic.Float)
-	if err != nil {
-		return nil, err
-	}
-	spec.Bins, err = interpreter.ToFloatArray(binsArry)
-	if err != nil {
+	if err := readHistogramBins(args, spec); err != nil {
 		return nil, err
 	}
 	if normalize, ok, err := args.GetBool("normalize"); err != nil {
@@ -79,10 +77,81 @@ func CreateHistogramOpSpec(args flux.Arguments, a *flux.Administration) (flux.Op
 	} else if ok {
 		spec.Normalize = normalize
 	}
+	if overflow, ok, err := args.GetBool("overflow"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Overflow = overflow
+	}
 
 	return spec, nil
 }
 
+// readHistogramBins reads the bins of the histogram from either the
+// bins argument or the binCount, min, and max arguments.
+//
+// The bins created from a bin count are the upper bounds of binCount bins
+// of equal width between min and max, plus min itself so values below
+// min are counted separately.
+func readHistogramBins(args flux.Arguments, spec *HistogramOpSpec) error {
+	binsArr, hasBins, err := args.GetArray("bins", semantic.Float)
+	if err != nil {
+		return err
+	}
+	binCount, hasBinCount, err := args.GetInt("binCount")
+	if err != nil {
+		return err
+	}
+	if hasBins == hasBinCount {
+		return errors.New(codes.Invalid, "exactly one of bins or binCount must be specified")
+	}
+
+	if hasBins {
+		for _, name := range []string{"min", "max"} {
+			if _, ok := args.Get(name); ok {
+				return errors.Newf(codes.Invalid, "%s is only valid with binCount", name)
+			}
+		}
+		spec.Bins, err = interpreter.ToFloatArray(binsArr)
+		if err != nil {
+			return err
+		}
+	} else {
+		min, err := args.GetRequiredFloat("min")
+		if err != nil {
+			return err
+		}
+		max, err := args.GetRequiredFloat("max")
+		if err != nil {
+			return err
+		}
+		if binCount <= 0 {
+			return errors.Newf(codes.Invalid, "binCount must be greater than zero, got %d", binCount)
+		}
+		if !(min < max) || math.IsInf(min, 0) || math.IsInf(max, 0) {
+			return errors.Newf(codes.Invalid, "min must be less than max and both must be finite, got %v and %v", min, max)
+		}
+		width := (max - min) / float64(binCount)
+		spec.Bins = make([]float64, binCount+1)
+		for i := range spec.Bins {
+			spec.Bins[i] = min + width*float64(i)
+		}
+		// Avoid a rounding error in the last bound.
+		spec.Bins[binCount] = max
+	}
+
+	if len(spec.Bins) == 0 {
+		return errors.New(codes.Invalid, "bins must not be empty")
+	}
+	// The bins are sorted and duplicates are removed when
+	// the histogram is computed, but a NaN bin has no order.
+	for _, b := range spec.Bins {
+		if math.IsNaN(b) {
+			return errors.New(codes.Invalid, "bins must not contain NaN")
+		}
+	}
+	return nil
+}
+
 func newHistogramOp() flux.OperationSpec {
 	return new(HistogramOpSpec)
 }
@@ -141,11 +210,24 @@ type histogramTransformation struct {
 
 func NewHistogramTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *HistogramProcedureSpec) *histogramTransformation {
 	sort.Float64s(spec.Bins)
-	return &histogramTransformation{
+	t := &histogramTransformation{
 		d:     d,
 		cache: cache,
 		spec:  *spec,
 	}
+	// A duplicate bin would be output twice with the same
+	// count, so each upper bound is only kept once.
+	bins := make([]float64, 0, len(spec.Bins)+1)
+	for i, b := range spec.Bins {
+		if i == 0 || b != spec.Bins[i-1] {
+			bins = append(bins, b)
+		}
+	}
+	if spec.Overflow && (len(bins) == 0 || !math.IsInf(bins[len(bins)-1], 1)) {
+		bins = append(bins, math.Inf(1))
+	}
+	t.spec.Bins = bins
+	return t
 }
 
 func (t *histogramTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
@@ -187,13 +269,15 @@ func (t *histogramTransformation) Process(id execute.DatasetID, tbl flux.Table)
 	counts := make([]float64, len(t.spec.Bins))
 	err = tbl.Do(func(cr flux.ColReader) error {
 		vs := cr.Floats(valueIdx)
-		totalRows += float64(vs.Len() - vs.NullN())
 		for i := 0; i < vs.Len(); i++ {
 			if vs.IsNull(i) {
 				continue
 			}
 
 			v := vs.Value(i)
+			if math.IsNaN(v) {
+				continue
+			}
 			idx := sort.Search(len(t.spec.Bins), func(i int) bool {
 				return v <= t.spec.Bins[i]
 			})
@@ -203,6 +287,7 @@ func (t *histogramTransformation) Process(id execute.DatasetID, tbl flux.Table)
 			}
 			// Increment counter
 			counts[idx]++
+			totalRows++
 		}
 		return nil
 	})
@@ -253,6 +253,7 @@ func (t *histogramQuantileTransformation) computeQuantile(cdf []bucket) (float64
 		return 0, errors.New(codes.FailedPrecondition, "histogram is empty")
 	}
 	// Find rank index and check counts are monotonic
+	// and upper bounds are strictly increasing
 	prevCount := 0.0
 	totalCount := cdf[len(cdf)-1].count
 	rank := t.spec.Quantile * totalCount
@@ -263,6 +264,11 @@ func (t *histogramQuantileTransformation) computeQuantile(cdf []bucket) (float64
 		}
 		prevCount = b.count
 
+		// The comparison is false for a NaN upper bound so it is rejected as well.
+		if i > 0 && !(b.upperBound > cdf[i-1].upperBound) {
+			return 0, errors.Newf(codes.FailedPrecondition, "histogram records upper bounds are not strictly increasing: %v follows %v", b.upperBound, cdf[i-1].upperBound)
+		}
+
 		if rank >= b.count {
 			rankIdx = i
 		}
@@ -463,6 +463,83 @@ func TestHistogramQuantile_Process(t *testing.T) {
 			}},
 			wantErr: errors.New("unexpected null in the upperBoundColumn"),
 		},
+		{
+			name: "decreasing counts",
+			spec: &universe.HistogramQuantileProcedureSpec{
+				Quantile:         0.9,
+				CountColumn:      "_value",
+				UpperBoundColumn: "le",
+				ValueColumn:      "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.1, 1.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.2, 3.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.3, 2.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), math.Inf(1), 4.0},
+				},
+			}},
+			wantErr: errors.New("histogram records counts are not monotonic"),
+		},
+		{
+			name: "duplicate upper bound",
+			spec: &universe.HistogramQuantileProcedureSpec{
+				Quantile:         0.9,
+				CountColumn:      "_value",
+				UpperBoundColumn: "le",
+				ValueColumn:      "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.1, 1.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.2, 2.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.2, 3.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), math.Inf(1), 4.0},
+				},
+			}},
+			wantErr: errors.New("histogram records upper bounds are not strictly increasing: 0.2 follows 0.2"),
+		},
+		{
+			name: "NaN upper bound",
+			spec: &universe.HistogramQuantileProcedureSpec{
+				Quantile:         0.9,
+				CountColumn:      "_value",
+				UpperBoundColumn: "le",
+				ValueColumn:      "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 0.1, 1.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), math.NaN(), 2.0},
+					{execute.Time(1), execute.Time(3), execute.Time(1), math.Inf(1), 4.0},
+				},
+			}},
+			wantErr: errors.New("histogram records upper bounds are not strictly increasing: NaN follows 0.1"),
+		},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -5,9 +5,12 @@ import (
 	"testing"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
 	"github.com/influxdata/flux/stdlib/universe"
 )
 
@@ -22,6 +25,65 @@ func TestHistogramOperation_Marshaling(t *testing.T) {
 	querytest.OperationMarshalingTestHelper(t, data, op)
 }
 
+func TestHistogram_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "bin count",
+			Raw:  `from(bucket:"mybucket") |> histogram(binCount: 4, min: 0.0, max: 2.0)`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "histogram1",
+						Spec: &universe.HistogramOpSpec{
+							Column:           "_value",
+							UpperBoundColumn: "le",
+							CountColumn:      "_value",
+							Bins:             []float64{0, 0.5, 1, 1.5, 2},
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "histogram1"},
+				},
+			},
+		},
+		{
+			Name:    "bins and bin count",
+			Raw:     `from(bucket:"mybucket") |> histogram(bins: [1.0, 2.0], binCount: 4, min: 0.0, max: 2.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "bin count without max",
+			Raw:     `from(bucket:"mybucket") |> histogram(binCount: 4, min: 0.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "min greater than max",
+			Raw:     `from(bucket:"mybucket") |> histogram(binCount: 4, min: 2.0, max: 0.0)`,
+			WantErr: true,
+		},
+		{
+			Name: "NaN bin",
+			Raw: `import "math"
+from(bucket:"mybucket") |> histogram(bins: [1.0, math.NaN()])`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
 func TestHistogram_PassThrough(t *testing.T) {
 	executetest.TransformationPassThroughTestHelper(t, func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
 		s := universe.NewHistogramTransformation(
@@ -35,10 +97,11 @@ func TestHistogram_PassThrough(t *testing.T) {
 
 func TestHistogram_Process(t *testing.T) {
 	testCases := []struct {
-		name string
-		spec *universe.HistogramProcedureSpec
-		data []flux.Table
-		want []*executetest.Table
+		name    string
+		spec    *universe.HistogramProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
 	}{
 		{
 			name: "linear",
@@ -326,6 +389,182 @@ func TestHistogram_Process(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "overflow",
+			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
+				Column:           "_value",
+				UpperBoundColumn: "le",
+				CountColumn:      "_value",
+				Bins:             []float64{0, 10, 20},
+				Overflow:         true,
+			}},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), -5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 25.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), math.NaN()},
+					{execute.Time(1), execute.Time(3), execute.Time(2), math.Inf(1)},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), 0.0, 1.0},
+					{execute.Time(1), execute.Time(3), 10.0, 2.0},
+					{execute.Time(1), execute.Time(3), 20.0, 2.0},
+					{execute.Time(1), execute.Time(3), math.Inf(1), 4.0},
+				},
+			}},
+		},
+		{
+			name: "overflow without values",
+			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
+				Column:           "_value",
+				UpperBoundColumn: "le",
+				CountColumn:      "_value",
+				Bins:             []float64{0, 10, 20},
+				Overflow:         true,
+			}},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 15.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), 0.0, 0.0},
+					{execute.Time(1), execute.Time(3), 10.0, 1.0},
+					{execute.Time(1), execute.Time(3), 20.0, 2.0},
+					{execute.Time(1), execute.Time(3), math.Inf(1), 2.0},
+				},
+			}},
+		},
+		{
+			name: "overflow with infinite bin",
+			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
+				Column:           "_value",
+				UpperBoundColumn: "le",
+				CountColumn:      "_value",
+				Bins:             []float64{0, 10, math.Inf(1)},
+				Overflow:         true,
+			}},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 15.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), 0.0, 0.0},
+					{execute.Time(1), execute.Time(3), 10.0, 1.0},
+					{execute.Time(1), execute.Time(3), math.Inf(1), 2.0},
+				},
+			}},
+		},
+		{
+			name: "greater than highest bin",
+			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
+				Column:           "_value",
+				UpperBoundColumn: "le",
+				CountColumn:      "_value",
+				Bins:             []float64{0, 10, 20},
+			}},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 25.0},
+				},
+			}},
+			wantErr: errors.New(codes.OutOfRange, "found value greater than any bin, 3 3 25.000000 20.000000"),
+		},
+		{
+			name: "duplicate bins",
+			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
+				Column:           "_value",
+				UpperBoundColumn: "le",
+				CountColumn:      "_value",
+				Bins:             []float64{10, 0, 20, 10},
+			}},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), execute.Time(1), 5.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 10.0},
+					{execute.Time(1), execute.Time(3), execute.Time(2), 15.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "le", Type: flux.TFloat},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), execute.Time(3), 0.0, 0.0},
+					{execute.Time(1), execute.Time(3), 10.0, 2.0},
+					{execute.Time(1), execute.Time(3), 20.0, 3.0},
+				},
+			}},
+		},
 		{
 			name: "fibonacci",
 			spec: &universe.HistogramProcedureSpec{HistogramOpSpec: universe.HistogramOpSpec{
@@ -384,7 +623,7 @@ func TestHistogram_Process(t *testing.T) {
 				t,
 				tc.data,
 				tc.want,
-				nil,
+				tc.wantErr,
 				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
 					return universe.NewHistogramTransformation(d, c, tc.spec)
 				},
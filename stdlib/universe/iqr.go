@@ -0,0 +1,222 @@
+package universe
+
+import (
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const IQRKind = "iqr"
+
+// IQROpSpec computes the interquartile range of each table,
+// the difference between its 0.75 and 0.25 quantiles.
+type IQROpSpec struct {
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	iqrSignature := runtime.MustLookupBuiltinType("universe", "iqr")
+
+	runtime.RegisterPackageValue("universe", IQRKind, flux.MustValue(flux.FunctionValue(IQRKind, createIQROpSpec, iqrSignature)))
+	flux.RegisterOpSpec(IQRKind, newIQROp)
+	plan.RegisterProcedureSpec(IQRKind, newIQRProcedure, IQRKind)
+	execute.RegisterTransformation(IQRKind, createIQRTransformation)
+}
+
+func createIQROpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	// The method and compression are validated
+	// in the same way as they are for quantile.
+	qs := new(QuantileOpSpec)
+	if err := readQuantileOptions(args, qs); err != nil {
+		return nil, err
+	}
+	if qs.Method == methodGK {
+		return nil, errors.Newf(codes.Invalid, "method %s is not supported by iqr", methodGK)
+	}
+	spec := &IQROpSpec{
+		Method:                qs.Method,
+		Compression:           qs.Compression,
+		SimpleAggregateConfig: qs.SimpleAggregateConfig,
+	}
+	if qs.Method == methodExactSelector {
+		spec.Columns = []string{qs.SelectorConfig.Column}
+	}
+	return spec, nil
+}
+
+func newIQROp() flux.OperationSpec {
+	return new(IQROpSpec)
+}
+
+func (s *IQROpSpec) Kind() flux.OperationKind {
+	return IQRKind
+}
+
+type IQRProcedureSpec struct {
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	execute.SimpleAggregateConfig
+}
+
+func newIQRProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*IQROpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &IQRProcedureSpec{
+		Method:                spec.Method,
+		Compression:           spec.Compression,
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *IQRProcedureSpec) Kind() plan.ProcedureKind {
+	return IQRKind
+}
+
+func (s *IQRProcedureSpec) Copy() plan.ProcedureSpec {
+	return &IQRProcedureSpec{
+		Method:                s.Method,
+		Compression:           s.Compression,
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *IQRProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createIQRTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*IQRProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	fraction, err := getTDigestPoolFraction(a.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	agg := NewIQRAgg(ps.Method, ps.Compression, a.Allocator(), len(ps.Columns))
+	agg.quantiles.setFreeDigestBudget(fraction)
+	agg.quantiles.ctx = a.Context()
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
+}
+
+// IQRAgg computes the interquartile range of a float column with
+// one of the methods of quantile. The estimate_tdigest method adds
+// the values to a single t-digest and queries both quantiles from it.
+type IQRAgg struct {
+	Method string
+
+	// quantiles allocates the digests of the estimate_tdigest method.
+	quantiles *QuantileAgg
+}
+
+func NewIQRAgg(method string, compression float64, mem *memory.Allocator, size int) *IQRAgg {
+	return &IQRAgg{
+		Method:    method,
+		quantiles: NewQuantileAgg(0.5, compression, mem, size),
+	}
+}
+
+func (a *IQRAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *IQRAgg) NewIntAgg() execute.DoIntAgg {
+	return nil
+}
+
+func (a *IQRAgg) NewUIntAgg() execute.DoUIntAgg {
+	return nil
+}
+
+func (a *IQRAgg) NewFloatAgg() execute.DoFloatAgg {
+	switch a.Method {
+	case methodExactMean, methodExactSelector:
+		return &iqrExactState{selector: a.Method == methodExactSelector}
+	case methodP2:
+		return &iqrP2State{
+			lower: &P2QuantileAgg{Quantile: 0.25},
+			upper: &P2QuantileAgg{Quantile: 0.75},
+		}
+	default:
+		return &iqrTDigestState{QuantileAggState: a.quantiles.newState(0.5)}
+	}
+}
+
+func (a *IQRAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+func (a *IQRAgg) Close() error {
+	return a.quantiles.Close()
+}
+
+// iqrTDigestState queries both quantiles from the same digest.
+type iqrTDigestState struct {
+	*QuantileAggState
+}
+
+func (s *iqrTDigestState) ValueFloat() float64 {
+	return s.digest.Quantile(0.75) - s.digest.Quantile(0.25)
+}
+
+// iqrExactState computes both quantiles from the sorted values.
+// The selector computes the range between the values that
+// the exact selector of quantile would select.
+type iqrExactState struct {
+	ExactQuantileAgg
+	selector bool
+}
+
+func (s *iqrExactState) ValueFloat() float64 {
+	if len(s.data) == 0 {
+		return 0
+	}
+	if s.selector {
+		sort.Float64s(s.data)
+		n := len(s.data)
+		return s.data[getQuantileIndex(0.75, n)] - s.data[getQuantileIndex(0.25, n)]
+	}
+	s.Quantile = 0.75
+	upper := s.ExactQuantileAgg.ValueFloat()
+	s.Quantile = 0.25
+	return upper - s.ExactQuantileAgg.ValueFloat()
+}
+
+// iqrP2State estimates each quantile with its own markers
+// since the P-square algorithm tracks a single quantile.
+type iqrP2State struct {
+	lower, upper *P2QuantileAgg
+}
+
+func (s *iqrP2State) DoFloat(vs *array.Float) {
+	s.lower.DoFloat(vs)
+	s.upper.DoFloat(vs)
+}
+
+func (s *iqrP2State) Type() flux.ColType {
+	return flux.TFloat
+}
+
+func (s *iqrP2State) ValueFloat() float64 {
+	return s.upper.ValueFloat() - s.lower.ValueFloat()
+}
+
+func (s *iqrP2State) IsNull() bool {
+	return s.lower.IsNull()
+}
@@ -0,0 +1,176 @@
+package universe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestIQR_NewQuery(t *testing.T) {
+	query := func(id flux.OperationID, spec *universe.IQROpSpec) *flux.Spec {
+		return &flux.Spec{
+			Operations: []*flux.Operation{
+				{
+					ID: "from0",
+					Spec: &influxdb.FromOpSpec{
+						Bucket: influxdb.NameOrID{Name: "testdb"},
+					},
+				},
+				{
+					ID: "range1",
+					Spec: &universe.RangeOpSpec{
+						Start: flux.Time{
+							Relative:   -1 * time.Hour,
+							IsRelative: true,
+						},
+						Stop: flux.Time{
+							IsRelative: true,
+						},
+						TimeColumn:  "_time",
+						StartColumn: "_start",
+						StopColumn:  "_stop",
+					},
+				},
+				{
+					ID:   id,
+					Spec: spec,
+				},
+			},
+			Edges: []flux.Edge{
+				{Parent: "from0", Child: "range1"},
+				{Parent: "range1", Child: id},
+			},
+		}
+	}
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "default",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> iqr()`,
+			Want: query("iqr2", &universe.IQROpSpec{
+				Method:                "estimate_tdigest",
+				Compression:           1000,
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+		},
+		{
+			Name: "exact_mean",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> iqr(method: "exact_mean", column: "x")`,
+			Want: query("iqr2", &universe.IQROpSpec{
+				Method: "exact_mean",
+				SimpleAggregateConfig: execute.SimpleAggregateConfig{
+					Columns: []string{"x"},
+				},
+			}),
+		},
+		{
+			Name: "exact_selector",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> iqr(method: "exact_selector")`,
+			Want: query("iqr2", &universe.IQROpSpec{
+				Method:                "exact_selector",
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+		},
+		{
+			Name:    "unknown method",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> iqr(method: "bogus")`,
+			WantErr: true,
+		},
+		{
+			Name:    "compression with exact method",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> iqr(method: "exact_mean", compression: 100.0)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestIQR_Process(t *testing.T) {
+	// The quantiles of the estimates are computed
+	// separately to compare with the range.
+	quantile := func(agg execute.SimpleAggregate, data *array.Float) float64 {
+		state := agg.NewFloatAgg()
+		state.DoFloat(data)
+		return state.(execute.FloatValueFunc).ValueFloat()
+	}
+	normal := func() *array.Float {
+		return arrow.NewFloat(NormalData, nil)
+	}
+	tdigest := quantile(universe.NewQuantileAgg(0.75, 1000, &memory.Allocator{}, 1), normal()) -
+		quantile(universe.NewQuantileAgg(0.25, 1000, &memory.Allocator{}, 1), normal())
+	p2 := quantile(&universe.P2QuantileAgg{Quantile: 0.75}, normal()) -
+		quantile(&universe.P2QuantileAgg{Quantile: 0.25}, normal())
+
+	testCases := []struct {
+		name   string
+		method string
+		data   func() *array.Float
+		want   interface{}
+	}{
+		{
+			name:   "estimate_tdigest",
+			method: "estimate_tdigest",
+			data:   normal,
+			want:   tdigest,
+		},
+		{
+			name:   "exact_mean",
+			method: "exact_mean",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{8, 1, 7, 2, 6, 3, 5, 4}, nil)
+			},
+			want: 3.5,
+		},
+		{
+			name:   "exact_selector",
+			method: "exact_selector",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{8, 1, 7, 2, 6, 3, 5, 4}, nil)
+			},
+			want: 4.0,
+		},
+		{
+			name:   "p2",
+			method: "p2",
+			data:   normal,
+			want:   p2,
+		},
+		{
+			name:   "only nulls",
+			method: "exact_mean",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.AggFuncTestHelper(
+				t,
+				universe.NewIQRAgg(tc.method, 1000, &memory.Allocator{}, 1),
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package universe
+
+import (
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const KurtosisKind = "kurtosis"
+
+type KurtosisOpSpec struct {
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	kurtosisSignature := runtime.MustLookupBuiltinType("universe", "kurtosis")
+
+	runtime.RegisterPackageValue("universe", KurtosisKind, flux.MustValue(flux.FunctionValue(KurtosisKind, CreateKurtosisOpSpec, kurtosisSignature)))
+	flux.RegisterOpSpec(KurtosisKind, newKurtosisOp)
+	plan.RegisterProcedureSpec(KurtosisKind, newKurtosisProcedure, KurtosisKind)
+	execute.RegisterTransformation(KurtosisKind, createKurtosisTransformation)
+}
+func CreateKurtosisOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	s := new(KurtosisOpSpec)
+	if err := s.SimpleAggregateConfig.ReadArgs(args); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func newKurtosisOp() flux.OperationSpec {
+	return new(KurtosisOpSpec)
+}
+
+func (s *KurtosisOpSpec) Kind() flux.OperationKind {
+	return KurtosisKind
+}
+
+type KurtosisProcedureSpec struct {
+	execute.SimpleAggregateConfig
+}
+
+func newKurtosisProcedure(qs flux.OperationSpec, a plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*KurtosisOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &KurtosisProcedureSpec{
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *KurtosisProcedureSpec) Kind() plan.ProcedureKind {
+	return KurtosisKind
+}
+func (s *KurtosisProcedureSpec) Copy() plan.ProcedureSpec {
+	return &KurtosisProcedureSpec{
+		SimpleAggregateConfig: s.SimpleAggregateConfig,
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *KurtosisProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+// KurtosisAgg computes the excess kurtosis from the central moments
+// of the values, which are updated with each value in a single pass.
+type KurtosisAgg struct {
+	n, m1, m2, m3, m4 float64
+}
+
+func createKurtosisTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*KurtosisProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, new(KurtosisAgg), s.SimpleAggregateConfig, a.Allocator())
+}
+
+func (a *KurtosisAgg) reset() {
+	a.n = 0
+	a.m1 = 0
+	a.m2 = 0
+	a.m3 = 0
+	a.m4 = 0
+}
+func (a *KurtosisAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *KurtosisAgg) NewIntAgg() execute.DoIntAgg {
+	a.reset()
+	return a
+}
+
+func (a *KurtosisAgg) NewUIntAgg() execute.DoUIntAgg {
+	a.reset()
+	return a
+}
+
+func (a *KurtosisAgg) NewFloatAgg() execute.DoFloatAgg {
+	a.reset()
+	return a
+}
+
+func (a *KurtosisAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+// add updates the moments with a single value.
+// The moments must be updated from the highest to the lowest
+// since each update depends on the previous lower moments.
+func (a *KurtosisAgg) add(v float64) {
+	n0 := a.n
+	a.n++
+	delta := v - a.m1
+	deltaN := delta / a.n
+	deltaN2 := deltaN * deltaN
+	t := delta * deltaN * n0
+	a.m4 += t*deltaN2*(a.n*a.n-3*a.n+3) + 6*deltaN2*a.m2 - 4*deltaN*a.m3
+	a.m3 += t*deltaN*(a.n-2) - 3*deltaN*a.m2
+	a.m2 += t
+	a.m1 += deltaN
+}
+
+func (a *KurtosisAgg) DoInt(vs *array.Int) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		a.add(float64(vs.Value(i)))
+	}
+}
+func (a *KurtosisAgg) DoUInt(vs *array.Uint) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		a.add(float64(vs.Value(i)))
+	}
+}
+func (a *KurtosisAgg) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		a.add(vs.Value(i))
+	}
+}
+func (a *KurtosisAgg) Type() flux.ColType {
+	return flux.TFloat
+}
+func (a *KurtosisAgg) ValueFloat() float64 {
+	return a.n*a.m4/(a.m2*a.m2) - 3
+}
+
+// IsNull reports whether there are too few values to compute the kurtosis.
+func (a *KurtosisAgg) IsNull() bool {
+	return a.n < 4
+}
@@ -0,0 +1,104 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestKurtosisOperation_Marshaling(t *testing.T) {
+	data := []byte(`{"id":"kurtosis","kind":"kurtosis"}`)
+	op := &flux.Operation{
+		ID:   "kurtosis",
+		Spec: &universe.KurtosisOpSpec{},
+	}
+
+	querytest.OperationMarshalingTestHelper(t, data, op)
+}
+
+func TestKurtosis_Process(t *testing.T) {
+	testCases := []struct {
+		name string
+		data func() *array.Float
+		want interface{}
+	}{
+		{
+			name: "uniform",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4}, nil)
+			},
+			want: -1.36,
+		},
+		{
+			name: "outlier",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{2, 2, 3, 4, 10}, nil)
+			},
+			want: -0.058912627551020336,
+		},
+		{
+			name: "null short",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3}, nil)
+			},
+			want: nil,
+		},
+		{
+			name: "NaN divide by zero",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 1, 1, 1}, nil)
+			},
+			want: math.NaN(),
+		},
+		{
+			name: "empty",
+			data: func() *array.Float {
+				return arrow.NewFloat(nil, nil)
+			},
+			want: nil,
+		},
+		{
+			name: "with nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.Append(2)
+				b.AppendNull()
+				b.Append(3)
+				b.Append(4)
+				b.AppendNull()
+				b.Append(10)
+				return b.NewFloatArray()
+			},
+			want: -0.7980853277835589,
+		},
+		{
+			name: "only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.AggFuncTestHelper(
+				t,
+				new(universe.KurtosisAgg),
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package universe
 
 import (
 	"context"
+	"sync"
 
 	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
@@ -9,20 +10,44 @@ import (
 	"github.com/influxdata/flux/arrow"
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/arrowutil"
 	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/internal/execute/table"
 	"github.com/influxdata/flux/internal/feature"
+	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
 )
 
 const LimitKind = "limit"
 
+const (
+	// LimitMethodHead keeps the first n rows after the offset.
+	LimitMethodHead = "head"
+	// LimitMethodSample keeps n rows spread evenly across
+	// the rows after the offset.
+	LimitMethodSample = "sample"
+)
+
 // LimitOpSpec limits the number of rows returned per table.
 type LimitOpSpec struct {
 	N      int64 `json:"n"`
 	Offset int64 `json:"offset"`
+	// ResetOn is a list of columns that restart the limit
+	// whenever their values change from one row to the next.
+	ResetOn []string `json:"resetOn,omitempty"`
+	// Global limits the number of rows across all tables
+	// instead of the number of rows in each table.
+	Global bool `json:"global,omitempty"`
+	// Method is how the rows are chosen. It is either LimitMethodHead
+	// or LimitMethodSample. An empty method is the same as LimitMethodHead.
+	Method string `json:"method,omitempty"`
+	// Stride keeps every stride-th row after the offset
+	// until n rows are kept. A zero stride is the same as 1.
+	Stride int64 `json:"stride,omitempty"`
 }
 
 func init() {
@@ -31,7 +56,6 @@ func init() {
 	runtime.RegisterPackageValue("universe", LimitKind, flux.MustValue(flux.FunctionValue(LimitKind, createLimitOpSpec, limitSignature)))
 	flux.RegisterOpSpec(LimitKind, newLimitOp)
 	plan.RegisterProcedureSpec(LimitKind, newLimitProcedure, LimitKind)
-	// TODO register a range transformation. Currently range is only supported if it is pushed down into a select procedure.
 	execute.RegisterTransformation(LimitKind, createLimitTransformation)
 }
 
@@ -54,6 +78,69 @@ func createLimitOpSpec(args flux.Arguments, a *flux.Administration) (flux.Operat
 		spec.Offset = offset
 	}
 
+	if resetOn, ok, err := args.GetArray("resetOn", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		spec.ResetOn, err = interpreter.ToStringArray(resetOn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if global, ok, err := args.GetBool("global"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Global = global
+	}
+	if method, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		switch method {
+		case LimitMethodHead, LimitMethodSample:
+			spec.Method = method
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown limit method %q", method)
+		}
+	}
+
+	if stride, ok, err := args.GetInt("stride"); err != nil {
+		return nil, err
+	} else if ok {
+		if stride < 1 {
+			return nil, errors.Newf(codes.Invalid, "stride must be greater than zero, got %d", stride)
+		}
+		spec.Stride = stride
+	}
+
+	if spec.Global && len(spec.ResetOn) > 0 {
+		return nil, errors.New(codes.Invalid, "resetOn cannot be used with a global limit")
+	}
+	if spec.Offset < 0 {
+		if spec.Global {
+			return nil, errors.New(codes.Invalid, "a negative offset cannot be used with a global limit")
+		} else if len(spec.ResetOn) > 0 {
+			return nil, errors.New(codes.Invalid, "a negative offset cannot be used with resetOn")
+		}
+	}
+	if spec.Method == LimitMethodSample {
+		if spec.Offset < 0 {
+			return nil, errors.New(codes.Invalid, "a negative offset cannot be used with the sample method")
+		} else if spec.Global {
+			return nil, errors.New(codes.Invalid, "the sample method cannot be used with a global limit")
+		} else if len(spec.ResetOn) > 0 {
+			return nil, errors.New(codes.Invalid, "the sample method cannot be used with resetOn")
+		}
+	}
+	if spec.Stride > 1 {
+		if spec.Offset < 0 {
+			return nil, errors.New(codes.Invalid, "a negative offset cannot be used with a stride")
+		} else if spec.Method == LimitMethodSample {
+			return nil, errors.New(codes.Invalid, "the sample method cannot be used with a stride")
+		} else if len(spec.ResetOn) > 0 {
+			return nil, errors.New(codes.Invalid, "resetOn cannot be used with a stride")
+		}
+	}
+
 	return spec, nil
 }
 
@@ -67,8 +154,12 @@ func (s *LimitOpSpec) Kind() flux.OperationKind {
 
 type LimitProcedureSpec struct {
 	plan.DefaultCost
-	N      int64 `json:"n"`
-	Offset int64 `json:"offset"`
+	N       int64    `json:"n"`
+	Offset  int64    `json:"offset"`
+	ResetOn []string `json:"resetOn,omitempty"`
+	Global  bool     `json:"global,omitempty"`
+	Method  string   `json:"method,omitempty"`
+	Stride  int64    `json:"stride,omitempty"`
 }
 
 func newLimitProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
@@ -77,8 +168,12 @@ func newLimitProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Proc
 		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
 	}
 	return &LimitProcedureSpec{
-		N:      spec.N,
-		Offset: spec.Offset,
+		N:       spec.N,
+		Offset:  spec.Offset,
+		ResetOn: spec.ResetOn,
+		Global:  spec.Global,
+		Method:  spec.Method,
+		Stride:  spec.Stride,
 	}, nil
 }
 
@@ -88,6 +183,10 @@ func (s *LimitProcedureSpec) Kind() plan.ProcedureKind {
 func (s *LimitProcedureSpec) Copy() plan.ProcedureSpec {
 	ns := new(LimitProcedureSpec)
 	*ns = *s
+	if s.ResetOn != nil {
+		ns.ResetOn = make([]string, len(s.ResetOn))
+		copy(ns.ResetOn, s.ResetOn)
+	}
 	return ns
 }
 
@@ -106,7 +205,7 @@ func createLimitTransformation(id execute.DatasetID, mode execute.AccumulationMo
 		return NewNarrowLimitTransformation(s, id, a.Allocator())
 	}
 
-	t, d := NewLimitTransformation(s, id)
+	t, d := NewLimitTransformation(s, id, a.Allocator())
 	return t, d, nil
 }
 
@@ -114,16 +213,74 @@ type limitTransformation struct {
 	execute.ExecutionNode
 	d         *execute.PassthroughDataset
 	n, offset int
+	resetOn   []string
+	// global is the state shared by every table
+	// if the limit applies to the whole stream.
+	global *globalLimitState
+	// sample is set if the rows are sampled
+	// across the table instead of taken from the head.
+	sample bool
+	// stride is the distance between the rows that are kept.
+	stride int
+	// mem is the allocator of the rows that are copied
+	// when the rows are not contiguous.
+	mem *memory.Allocator
 }
 
-func NewLimitTransformation(spec *LimitProcedureSpec, id execute.DatasetID) (execute.Transformation, execute.Dataset) {
+// NewLimitTransformation creates a transformation that keeps n rows
+// of each table after skipping offset rows.
+//
+// If resetOn columns are specified, the offset and count restart
+// whenever the value of any of those columns differs from the value
+// in the previous row of the table. The previous row may be in an
+// earlier buffer of the same table so the count carries across buffer
+// boundaries until a value changes. Null values are equal to each other.
+//
+// If the offset is negative, it counts from the end of each table so the
+// rows are kept starting that many rows before the end. The last buffers
+// of the table are retained until the table ends, but only as many as are
+// needed to hold that many rows.
+//
+// If the limit is global, the offset and count are shared by every table
+// so at most n rows are kept across all of the tables. The rows that are
+// kept depend on the order in which the tables are read, which is not
+// stable, and the tables that are read after the limit is reached are empty.
+//
+// If the method is sample, n rows are kept that are spread evenly across
+// the rows of each table after the offset. The number of rows must be known
+// to compute the stride so every buffer of the table is retained until the
+// table ends. The rows are kept in their original order.
+//
+// If the stride is greater than 1, the rows at offset, offset+stride,
+// offset+2*stride, and so on are kept until n rows are kept. The position
+// of the next row to keep carries across buffer boundaries.
+//
+// The rows that are kept are copied with mem when they are not contiguous.
+func NewLimitTransformation(spec *LimitProcedureSpec, id execute.DatasetID, mem *memory.Allocator) (execute.Transformation, execute.Dataset) {
 	d := execute.NewPassthroughDataset(id)
+	t := newLimitTransformation(spec)
+	t.d = d
+	t.mem = mem
+	return t, d
+}
+
+func newLimitTransformation(spec *LimitProcedureSpec) *limitTransformation {
 	t := &limitTransformation{
-		d:      d,
-		n:      int(spec.N),
-		offset: int(spec.Offset),
+		n:       int(spec.N),
+		offset:  int(spec.Offset),
+		resetOn: spec.ResetOn,
+		sample:  spec.Method == LimitMethodSample,
+		stride:  int(spec.Stride),
 	}
-	return t, d
+	if t.stride < 1 {
+		t.stride = 1
+	}
+	if spec.Global {
+		t.global = &globalLimitState{
+			limitState: limitState{n: t.n, offset: t.offset},
+		}
+	}
+	return t
 }
 
 func (t *limitTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
@@ -141,30 +298,42 @@ func (t *limitTransformation) Process(id execute.DatasetID, tbl flux.Table) erro
 }
 
 func (t *limitTransformation) limitTable(ctx context.Context, w *table.StreamWriter, tbl flux.Table) error {
-	n, offset := t.n, t.offset
-	return tbl.Do(func(cr flux.ColReader) error {
-		if n <= 0 {
+	if len(t.resetOn) > 0 {
+		state := &limitState{n: t.n, offset: t.offset}
+		return tbl.Do(func(cr flux.ColReader) error {
+			ranges, err := t.resetRanges(cr, state)
+			if err != nil {
+				return err
+			}
+			for _, r := range ranges {
+				vs := make([]array.Array, len(cr.Cols()))
+				for j := range vs {
+					vs[j] = arrow.Slice(table.Values(cr, j), int64(r[0]), int64(r[1]))
+				}
+				if err := w.Write(vs); err != nil {
+					return err
+				}
+			}
 			return nil
-		}
-		l := cr.Len()
-		if l <= offset {
-			offset -= l
+		})
+	}
+
+	if t.offset < 0 {
+		return t.limitTableFromEnd(w, tbl)
+	} else if t.sample {
+		return t.limitTableSample(w, tbl)
+	} else if t.stride > 1 {
+		return t.limitTableStride(w, tbl)
+	}
+
+	state := &limitState{n: t.n, offset: t.offset}
+	return tbl.Do(func(cr flux.ColReader) error {
+		start, stop := t.take(state, cr.Len())
+		if start == stop {
 			// Skip entire batch
 			return nil
 		}
-		start := offset
-		stop := l
 		count := stop - start
-		if count > n {
-			count = n
-			stop = start + count
-		}
-
-		// Reduce the number of rows we will keep from the
-		// next buffer and set the offset to zero as it has been
-		// entirely consumed.
-		n -= count
-		offset = 0
 
 		vs := make([]array.Array, len(cr.Cols()))
 		for j := range vs {
@@ -180,6 +349,230 @@ func (t *limitTransformation) limitTable(ctx context.Context, w *table.StreamWri
 	})
 }
 
+// limitTableFromEnd limits a table when the offset counts from the end.
+func (t *limitTransformation) limitTableFromEnd(w *table.StreamWriter, tbl flux.Table) error {
+	var (
+		readers []flux.ColReader
+		sz      int
+	)
+	defer func() {
+		for _, cr := range readers {
+			cr.Release()
+		}
+	}()
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		cr.Retain()
+		readers = append(readers, cr)
+		sz += cr.Len()
+		for len(readers) > 1 && sz-readers[0].Len() >= -t.offset {
+			sz -= readers[0].Len()
+			readers[0].Release()
+			readers = readers[1:]
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	start, stop := t.fromEndRange(sz)
+	for _, cr := range readers {
+		l := cr.Len()
+		if lo, hi := clampRange(start, stop, l); lo < hi {
+			vs := make([]array.Array, len(cr.Cols()))
+			for j := range vs {
+				vs[j] = arrow.Slice(table.Values(cr, j), int64(lo), int64(hi))
+			}
+			if err := w.Write(vs); err != nil {
+				return err
+			}
+		}
+		start, stop = start-l, stop-l
+	}
+	return nil
+}
+
+// limitTableSample limits a table by sampling rows across the whole table.
+func (t *limitTransformation) limitTableSample(w *table.StreamWriter, tbl flux.Table) error {
+	var (
+		readers []flux.ColReader
+		sz      int
+	)
+	defer func() {
+		for _, cr := range readers {
+			cr.Release()
+		}
+	}()
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		cr.Retain()
+		readers = append(readers, cr)
+		sz += cr.Len()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	rows := t.sampleRows(sz)
+	for base, i := 0, 0; i < len(readers) && len(rows) > 0; i++ {
+		cr := readers[i]
+		l := cr.Len()
+		n := 0
+		for n < len(rows) && rows[n] < base+l {
+			n++
+		}
+		if n > 0 {
+			vs := make([]array.Array, len(cr.Cols()))
+			for j := range vs {
+				vs[j] = sampleValues(cr.Cols()[j].Type, table.Values(cr, j), rows[:n], base, t.mem)
+			}
+			if err := w.Write(vs); err != nil {
+				return err
+			}
+		}
+		rows, base = rows[n:], base+l
+	}
+	return nil
+}
+
+// limitTableStride limits a table by keeping every stride-th row.
+func (t *limitTransformation) limitTableStride(w *table.StreamWriter, tbl flux.Table) error {
+	state := &limitState{n: t.n, offset: t.offset}
+	return tbl.Do(func(cr flux.ColReader) error {
+		rows := t.takeStride(state, cr.Len())
+		if len(rows) == 0 {
+			return nil
+		}
+		vs := make([]array.Array, len(cr.Cols()))
+		for j := range vs {
+			vs[j] = sampleValues(cr.Cols()[j].Type, table.Values(cr, j), rows, 0, t.mem)
+		}
+		return w.Write(vs)
+	})
+}
+
+// sampleRows returns the index of each row that is kept from a table
+// with sz rows when the rows are sampled. The rows after the offset are
+// divided into n strides of equal length and the first row of each stride
+// is kept. If there are no more than n rows after the offset, they are
+// all kept.
+func (t *limitTransformation) sampleRows(sz int) []int {
+	l := sz - t.offset
+	if l <= 0 || t.n <= 0 {
+		return nil
+	}
+	n := t.n
+	if n > l {
+		n = l
+	}
+	rows := make([]int, n)
+	for k := range rows {
+		rows[k] = t.offset + k*l/n
+	}
+	return rows
+}
+
+// sampleValues copies the values of arr at the rows, relative
+// to base, into a new array of the same type.
+func sampleValues(typ flux.ColType, arr array.Array, rows []int, base int, mem arrowmem.Allocator) array.Array {
+	b := arrow.NewBuilder(typ, mem)
+	b.Resize(len(rows))
+	for _, i := range rows {
+		arrowutil.CopyValue(b, arr, i-base)
+	}
+	return b.NewArray()
+}
+
+// fromEndRange returns the range of rows to keep from the last sz rows
+// of a table that were retained when the offset counts from the end.
+// If there are fewer rows than the offset, the rows from the start of
+// the table are kept.
+func (t *limitTransformation) fromEndRange(sz int) (start, stop int) {
+	start = sz + t.offset
+	if start < 0 {
+		start = 0
+	}
+	stop = start + t.n
+	if stop > sz {
+		stop = sz
+	}
+	if t.n <= 0 {
+		stop = start
+	}
+	return start, stop
+}
+
+// clampRange returns the part of the range from start to stop
+// that is within a buffer with l rows.
+func clampRange(start, stop, l int) (lo, hi int) {
+	lo, hi = start, stop
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > l {
+		hi = l
+	}
+	return lo, hi
+}
+
+// take returns the range of rows to keep from a buffer with l rows
+// and updates the state for the next buffer. The state of the table
+// is ignored if the limit is global.
+func (t *limitTransformation) take(state *limitState, l int) (start, stop int) {
+	if t.global != nil {
+		t.global.mu.Lock()
+		defer t.global.mu.Unlock()
+		state = &t.global.limitState
+	}
+
+	if state.n <= 0 {
+		return 0, 0
+	}
+	if l <= state.offset {
+		state.offset -= l
+		return 0, 0
+	}
+	start, stop = state.offset, l
+	if stop-start > state.n {
+		stop = start + state.n
+	}
+
+	// Reduce the number of rows we will keep from the
+	// next buffer and set the offset to zero as it has been
+	// entirely consumed.
+	state.n -= stop - start
+	state.offset = 0
+	return start, stop
+}
+
+// takeStride returns the index of each row to keep from a buffer with
+// l rows when every stride-th row is kept and updates the state for the
+// next buffer. The state of the table is ignored if the limit is global.
+func (t *limitTransformation) takeStride(state *limitState, l int) []int {
+	if t.global != nil {
+		t.global.mu.Lock()
+		defer t.global.mu.Unlock()
+		state = &t.global.limitState
+	}
+	return t.strideRows(state, l)
+}
+
+// strideRows returns the index of each row to keep from a buffer
+// with l rows when every stride-th row is kept. The offset of the
+// state is left as the number of rows to skip in the next buffer
+// before the next row is kept.
+func (t *limitTransformation) strideRows(state *limitState, l int) []int {
+	var rows []int
+	i := state.offset
+	for ; i < l && state.n > 0; i += t.stride {
+		rows = append(rows, i)
+		state.n--
+	}
+	state.offset = 0
+	if i > l {
+		state.offset = i - l
+	}
+	return rows
+}
+
 func appendSlicedCols(reader flux.ColReader, builder execute.TableBuilder, start, stop int) error {
 	for j, c := range reader.Cols() {
 		if j > len(builder.Cols()) {
@@ -248,9 +641,101 @@ func (t *limitTransformation) Finish(id execute.DatasetID, err error) {
 }
 
 type limitState struct {
-	n      int
+	n int
+	// offset is the number of rows to skip before the next row is
+	// kept. With a stride, it is the phase of the stride after the
+	// first row is kept.
 	offset int
+
+	// prev holds the values of the resetOn
+	// columns in the last row that was read.
+	prev []values.Value
+}
+
+// globalLimitState is a limitState that is shared by every table
+// of a global limit. The tables may be read concurrently so the
+// state is only read and updated while holding the lock.
+type globalLimitState struct {
+	mu sync.Mutex
+	limitState
 }
+
+// resetRanges returns the ranges of rows to keep from the buffer when
+// the limit restarts on changes to the resetOn columns. Each range is
+// the start and stop index of consecutive rows that are kept.
+//
+// Each row is compared with the previous row of the buffer using the
+// arrays of the columns. Only the first row is compared with the last
+// row of the previous buffer, which is kept in the state.
+func (t *limitTransformation) resetRanges(cr flux.ColReader, state *limitState) ([][2]int, error) {
+	idxs := make([]int, len(t.resetOn))
+	cols := make([]array.Array, len(t.resetOn))
+	for k, label := range t.resetOn {
+		idxs[k] = execute.ColIdx(label, cr.Cols())
+		if idxs[k] < 0 {
+			return nil, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+		}
+		cols[k] = table.Values(cr, idxs[k])
+	}
+
+	var ranges [][2]int
+	l := cr.Len()
+	for i := 0; i < l; i++ {
+		changed := false
+		if i > 0 {
+			for _, vs := range cols {
+				if arrowutil.Compare(vs, vs, i-1, i) != 0 {
+					changed = true
+					break
+				}
+			}
+		} else if state.prev != nil {
+			for k, j := range idxs {
+				if !limitValuesEqual(state.prev[k], execute.ValueForRow(cr, i, j)) {
+					changed = true
+					break
+				}
+			}
+		}
+		if changed {
+			state.n, state.offset = t.n, t.offset
+		}
+
+		if state.offset > 0 {
+			state.offset--
+			continue
+		}
+		if state.n <= 0 {
+			continue
+		}
+		state.n--
+		if n := len(ranges); n > 0 && ranges[n-1][1] == i {
+			ranges[n-1][1] = i + 1
+		} else {
+			ranges = append(ranges, [2]int{i, i + 1})
+		}
+	}
+
+	if l > 0 {
+		if state.prev == nil {
+			state.prev = make([]values.Value, len(idxs))
+		}
+		for k, j := range idxs {
+			state.prev[k] = execute.ValueForRow(cr, l-1, j)
+		}
+	}
+	return ranges, nil
+}
+
+// limitValuesEqual reports whether two values are equal
+// where a null value is only equal to another null value.
+func limitValuesEqual(l, r values.Value) bool {
+	if l.IsNull() || r.IsNull() {
+		return l.IsNull() && r.IsNull()
+	}
+	return l.Equal(r)
+}
+
 type limitTransformationAdapter struct {
 	limitTransformation *limitTransformation
 }
@@ -263,9 +748,17 @@ func (t *limitTransformationAdapter) Process(
 	chunk table.Chunk,
 	state interface{},
 	dataset *execute.TransportDataset,
-	_ arrowmem.Allocator,
+	mem arrowmem.Allocator,
 ) (interface{}, bool, error) {
 
+	if g := t.limitTransformation.global; g != nil {
+		// The state of each table is not used by a global limit.
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		_, ok, err := t.processChunk(chunk, &g.limitState, dataset, mem)
+		return nil, ok, err
+	}
+
 	var state_ *limitState
 	// `.Process` is reentrant, so to speak. The first invocation will not
 	// include a value for `state`. Initialization happens here then is passed
@@ -275,15 +768,20 @@ func (t *limitTransformationAdapter) Process(
 	} else {
 		state_ = state.(*limitState)
 	}
-	return t.processChunk(chunk, state_, dataset)
+	return t.processChunk(chunk, state_, dataset, mem)
 }
 
 func (t *limitTransformationAdapter) processChunk(
 	chunk table.Chunk,
 	state *limitState,
 	dataset *execute.TransportDataset,
+	mem arrowmem.Allocator,
 ) (*limitState, bool, error) {
 
+	if len(t.limitTransformation.resetOn) > 0 {
+		return t.processResetChunk(chunk, state, dataset)
+	}
+
 	chunkLen := chunk.Len()
 
 	// Pass empty chunks along to downstream transformations for these cases.
@@ -312,6 +810,10 @@ func (t *limitTransformationAdapter) processChunk(
 		return state, true, nil
 	}
 
+	if t.limitTransformation.stride > 1 {
+		return t.processStrideChunk(chunk, state, dataset, mem)
+	}
+
 	start := state.offset
 	stop := chunkLen
 	count := stop - start
@@ -348,16 +850,216 @@ func (t *limitTransformationAdapter) processChunk(
 	return state, true, nil
 }
 
+// processStrideChunk keeps every stride-th row of a chunk.
+// The phase of the stride is carried to the next chunk in the state.
+func (t *limitTransformationAdapter) processStrideChunk(
+	chunk table.Chunk,
+	state *limitState,
+	dataset *execute.TransportDataset,
+	mem arrowmem.Allocator,
+) (*limitState, bool, error) {
+	rows := t.limitTransformation.strideRows(state, chunk.Len())
+	if len(rows) == 0 {
+		return state, true, nil
+	}
+	buf := chunk.Buffer()
+	buf.Values = make([]array.Array, chunk.NCols())
+	for idx := range buf.Values {
+		buf.Values[idx] = sampleValues(chunk.Col(idx).Type, chunk.Values(idx), rows, 0, mem)
+	}
+	if err := dataset.Process(table.ChunkFromBuffer(buf)); err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+// processResetChunk limits the rows of a chunk when the limit
+// restarts on changes to the resetOn columns.
+func (t *limitTransformationAdapter) processResetChunk(
+	chunk table.Chunk,
+	state *limitState,
+	dataset *execute.TransportDataset,
+) (*limitState, bool, error) {
+	buf := chunk.Buffer()
+	ranges, err := t.limitTransformation.resetRanges(&buf, state)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(ranges) == 0 {
+		// Produce an empty chunk so the table is still passed along.
+		ranges = [][2]int{{0, 0}}
+	}
+	for _, r := range ranges {
+		out := chunk.Buffer()
+		out.Values = make([]array.Array, chunk.NCols())
+		for idx := range out.Values {
+			out.Values[idx] = arrow.Slice(chunk.Values(idx), int64(r[0]), int64(r[1]))
+		}
+		if err := dataset.Process(table.ChunkFromBuffer(out)); err != nil {
+			return nil, false, err
+		}
+	}
+	return state, true, nil
+}
+
 func NewNarrowLimitTransformation(
 	spec *LimitProcedureSpec,
 	id execute.DatasetID,
 	mem *memory.Allocator,
 ) (execute.Transformation, execute.Dataset, error) {
+	if spec.Offset < 0 {
+		// The end of the table is only known when the table is
+		// flushed so the rows are kept in the state of an aggregate.
+		t := &limitFromEndTransformation{
+			limitTransformation: newLimitTransformation(spec),
+		}
+		return execute.NewAggregateTransformation(id, t, mem)
+	} else if spec.Method == LimitMethodSample {
+		// The stride depends on the number of rows in the table
+		// so every chunk is kept until the table is flushed.
+		t := &limitSampleTransformation{
+			limitTransformation: newLimitTransformation(spec),
+		}
+		return execute.NewAggregateTransformation(id, t, mem)
+	}
+
 	t := &limitTransformationAdapter{
-		limitTransformation: &limitTransformation{
-			n:      int(spec.N),
-			offset: int(spec.Offset),
-		},
+		limitTransformation: newLimitTransformation(spec),
 	}
 	return execute.NewNarrowStateTransformation(id, t, mem)
 }
+
+// limitFromEndTransformation limits each table when the offset
+// counts from the end of the table. The last chunks of each table are
+// retained, but only as many as are needed to hold -offset rows, and
+// the rows are written when the table is flushed or the input finishes.
+type limitFromEndTransformation struct {
+	limitTransformation *limitTransformation
+}
+
+// limitChunkState holds the chunks of a table that are retained
+// until the table is flushed along with their number of rows.
+type limitChunkState struct {
+	chunks []table.Chunk
+	sz     int
+}
+
+func (s *limitChunkState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *limitFromEndTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *limitChunkState
+	if state == nil {
+		s = &limitChunkState{}
+	} else {
+		s = state.(*limitChunkState)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	s.sz += chunk.Len()
+	for len(s.chunks) > 1 && s.sz-s.chunks[0].Len() >= -t.limitTransformation.offset {
+		s.sz -= s.chunks[0].Len()
+		s.chunks[0].Release()
+		s.chunks = s.chunks[1:]
+	}
+	return s, true, nil
+}
+
+func (t *limitFromEndTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*limitChunkState)
+	start, stop := t.limitTransformation.fromEndRange(s.sz)
+	written := false
+	for _, chunk := range s.chunks {
+		l := chunk.Len()
+		lo, hi := clampRange(start, stop, l)
+		start, stop = start-l, stop-l
+		if lo >= hi {
+			continue
+		}
+		if err := processLimitSlice(chunk, lo, hi, d); err != nil {
+			return err
+		}
+		written = true
+	}
+	if !written {
+		// Produce an empty chunk so the table is still passed along.
+		return processLimitSlice(s.chunks[0], 0, 0, d)
+	}
+	return nil
+}
+
+// processLimitSlice passes the rows from start to stop of the chunk downstream.
+func processLimitSlice(chunk table.Chunk, start, stop int, d *execute.TransportDataset) error {
+	buf := chunk.Buffer()
+	buf.Values = make([]array.Array, chunk.NCols())
+	for idx := range buf.Values {
+		buf.Values[idx] = arrow.Slice(chunk.Values(idx), int64(start), int64(stop))
+	}
+	return d.Process(table.ChunkFromBuffer(buf))
+}
+
+func (t *limitFromEndTransformation) Close() error {
+	return nil
+}
+
+// limitSampleTransformation limits each table by sampling rows
+// across the whole table. Every chunk of the table is retained and
+// the rows are written when the table is flushed or the input finishes.
+type limitSampleTransformation struct {
+	limitTransformation *limitTransformation
+}
+
+func (t *limitSampleTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *limitChunkState
+	if state == nil {
+		s = &limitChunkState{}
+	} else {
+		s = state.(*limitChunkState)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	s.sz += chunk.Len()
+	return s, true, nil
+}
+
+func (t *limitSampleTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*limitChunkState)
+	rows := t.limitTransformation.sampleRows(s.sz)
+	written := false
+	for base, i := 0, 0; i < len(s.chunks) && len(rows) > 0; i++ {
+		chunk := s.chunks[i]
+		l := chunk.Len()
+		n := 0
+		for n < len(rows) && rows[n] < base+l {
+			n++
+		}
+		if n > 0 {
+			buf := chunk.Buffer()
+			buf.Values = make([]array.Array, chunk.NCols())
+			for idx := range buf.Values {
+				buf.Values[idx] = sampleValues(chunk.Col(idx).Type, chunk.Values(idx), rows[:n], base, mem)
+			}
+			if err := d.Process(table.ChunkFromBuffer(buf)); err != nil {
+				return err
+			}
+			written = true
+		}
+		rows, base = rows[n:], base+l
+	}
+	if !written {
+		// Produce an empty chunk so the table is still passed along.
+		return processLimitSlice(s.chunks[0], 0, 0, d)
+	}
+	return nil
+}
+
+func (t *limitSampleTransformation) Close() error {
+	return nil
+}
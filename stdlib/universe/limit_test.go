@@ -268,6 +268,370 @@ func TestLimit_Process(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "negative offset multiple batches",
+			spec: &universe.LimitProcedureSpec{
+				N:      2,
+				Offset: -3,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.RowWiseTable{
+					Table: &executetest.Table{
+						ColMeta: []flux.ColMeta{
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{execute.Time(1), 2.0},
+							{execute.Time(2), 1.0},
+							{execute.Time(3), 0.0},
+							{execute.Time(4), 3.0},
+							{execute.Time(5), 4.0},
+						},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(3), 0.0},
+					{execute.Time(4), 3.0},
+				},
+			}},
+		},
+		{
+			name: "negative offset more than rows",
+			spec: &universe.LimitProcedureSpec{
+				N:      2,
+				Offset: -10,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 2.0},
+						{execute.Time(2), 1.0},
+						{execute.Time(3), 0.0},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), 1.0},
+				},
+			}},
+		},
+		{
+			name: "sample with offset multiple batches",
+			spec: &universe.LimitProcedureSpec{
+				N:      3,
+				Offset: 1,
+				Method: universe.LimitMethodSample,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.RowWiseTable{
+					Table: &executetest.Table{
+						ColMeta: []flux.ColMeta{
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{execute.Time(1), 2.0},
+							{execute.Time(2), 1.0},
+							{execute.Time(3), 0.0},
+							{execute.Time(4), 3.0},
+							{execute.Time(5), 4.0},
+							{execute.Time(6), 6.0},
+							{execute.Time(7), 5.0},
+							{execute.Time(8), 7.0},
+						},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 1.0},
+					{execute.Time(4), 3.0},
+					{execute.Time(6), 6.0},
+				},
+			}},
+		},
+		{
+			name: "stride with offset multiple batches",
+			spec: &universe.LimitProcedureSpec{
+				N:      2,
+				Offset: 1,
+				Stride: 3,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.RowWiseTable{
+					Table: &executetest.Table{
+						ColMeta: []flux.ColMeta{
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{execute.Time(1), 2.0},
+							{execute.Time(2), 1.0},
+							{execute.Time(3), 0.0},
+							{execute.Time(4), 3.0},
+							{execute.Time(5), 4.0},
+							{execute.Time(6), 6.0},
+							{execute.Time(7), 5.0},
+							{execute.Time(8), 7.0},
+						},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 1.0},
+					{execute.Time(5), 4.0},
+				},
+			}},
+		},
+		{
+			name: "stride single batch",
+			spec: &universe.LimitProcedureSpec{
+				N:      5,
+				Stride: 2,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 2.0},
+						{execute.Time(2), 1.0},
+						{execute.Time(3), 0.0},
+						{execute.Time(4), 3.0},
+						{execute.Time(5), 4.0},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(3), 0.0},
+					{execute.Time(5), 4.0},
+				},
+			}},
+		},
+		{
+			name: "sample fewer rows than n",
+			spec: &universe.LimitProcedureSpec{
+				N:      5,
+				Method: universe.LimitMethodSample,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 2.0},
+						{execute.Time(2), 1.0},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), 1.0},
+				},
+			}},
+		},
+		{
+			name: "global",
+			spec: &universe.LimitProcedureSpec{
+				N:      4,
+				Offset: 1,
+				Global: true,
+			},
+			data: func() []flux.Table {
+				return []flux.Table{
+					&executetest.Table{
+						KeyCols: []string{"t1"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t1", Type: flux.TString},
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{"a", execute.Time(1), 3.0},
+							{"a", execute.Time(2), 2.0},
+							{"a", execute.Time(3), 1.0},
+						},
+					},
+					&executetest.Table{
+						KeyCols: []string{"t1"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t1", Type: flux.TString},
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{"b", execute.Time(4), 3.0},
+							{"b", execute.Time(5), 2.0},
+							{"b", execute.Time(6), 1.0},
+						},
+					},
+					&executetest.Table{
+						KeyCols: []string{"t1"},
+						ColMeta: []flux.ColMeta{
+							{Label: "t1", Type: flux.TString},
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{"c", execute.Time(7), 3.0},
+						},
+					},
+				}
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t1"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t1", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"a", execute.Time(2), 2.0},
+						{"a", execute.Time(3), 1.0},
+					},
+				},
+				{
+					KeyCols: []string{"t1"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t1", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{"b", execute.Time(4), 3.0},
+						{"b", execute.Time(5), 2.0},
+					},
+				},
+				{
+					KeyCols:   []string{"t1"},
+					KeyValues: []interface{}{"c"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t1", Type: flux.TString},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+				},
+			},
+		},
+		{
+			name: "reset on column",
+			spec: &universe.LimitProcedureSpec{
+				N:       2,
+				Offset:  1,
+				ResetOn: []string{"_start"},
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(0), execute.Time(1), 1.0},
+						{execute.Time(0), execute.Time(2), 2.0},
+						{execute.Time(0), execute.Time(3), 3.0},
+						{execute.Time(0), execute.Time(4), 4.0},
+						{execute.Time(10), execute.Time(11), 5.0},
+						{execute.Time(10), execute.Time(12), 6.0},
+						{nil, execute.Time(13), 7.0},
+						{nil, execute.Time(14), 8.0},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(2), 2.0},
+					{execute.Time(0), execute.Time(3), 3.0},
+					{execute.Time(10), execute.Time(12), 6.0},
+					{nil, execute.Time(14), 8.0},
+				},
+			}},
+		},
+		{
+			name: "reset on column across buffers",
+			spec: &universe.LimitProcedureSpec{
+				N:       1,
+				ResetOn: []string{"window"},
+			},
+			data: func() []flux.Table {
+				return []flux.Table{&executetest.RowWiseTable{
+					Table: &executetest.Table{
+						ColMeta: []flux.ColMeta{
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_value", Type: flux.TInt},
+							{Label: "window", Type: flux.TString},
+						},
+						Data: [][]interface{}{
+							{execute.Time(1), int64(1), "a"},
+							{execute.Time(2), int64(2), "a"},
+							{execute.Time(3), int64(3), "b"},
+							{execute.Time(4), int64(4), "b"},
+							{execute.Time(5), int64(5), "a"},
+						},
+					},
+				}}
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "window", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(1), "a"},
+					{execute.Time(3), int64(3), "b"},
+					{execute.Time(5), int64(5), "a"},
+				},
+			}},
+		},
 	}
 	for _, tc := range testCases {
 		// Regular limit...
@@ -278,7 +642,7 @@ func TestLimit_Process(t *testing.T) {
 				tc.want,
 				nil,
 				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
-					return universe.NewLimitTransformation(tc.spec, id)
+					return universe.NewLimitTransformation(tc.spec, id, alloc)
 				},
 			)
 		})
@@ -371,7 +735,7 @@ func TestProcess_Limit_MultiBuffer(t *testing.T) {
 		N:      4,
 		Offset: 2,
 	}
-	tr, d := universe.NewLimitTransformation(spec, executetest.RandomDatasetID())
+	tr, d := universe.NewLimitTransformation(spec, executetest.RandomDatasetID(), mem)
 	store := executetest.NewDataStore()
 	d.AddTransformation(store)
 
@@ -410,6 +774,60 @@ func TestProcess_Limit_MultiBuffer(t *testing.T) {
 	}
 }
 
+func TestLimit_Allocator(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec *universe.LimitProcedureSpec
+	}{
+		{
+			name: "sample",
+			spec: &universe.LimitProcedureSpec{
+				N:      2,
+				Method: universe.LimitMethodSample,
+			},
+		},
+		{
+			name: "stride",
+			spec: &universe.LimitProcedureSpec{
+				N:      2,
+				Stride: 2,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			in := &executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0},
+					{execute.Time(2), 2.0},
+					{execute.Time(3), 3.0},
+					{execute.Time(4), 4.0},
+				},
+			}
+
+			mem := &memory.Allocator{}
+			tr, d := universe.NewLimitTransformation(tc.spec, executetest.RandomDatasetID(), mem)
+			store := executetest.NewDataStore()
+			d.AddTransformation(store)
+
+			parentID := executetest.RandomDatasetID()
+			if err := tr.Process(parentID, in); err != nil {
+				t.Fatal(err)
+			}
+			tr.Finish(parentID, nil)
+
+			if mem.MaxAllocated() == 0 {
+				t.Error("expected the rows that are kept to be copied with the allocator of the transformation")
+			}
+		})
+	}
+}
+
 func TestProcess_NarrowLimit_MultiBuffer(t *testing.T) {
 	key := execute.NewGroupKey(nil, nil)
 	mem := &memory.Allocator{}
@@ -552,7 +970,7 @@ func benchmarkLimit(b *testing.B, n, l int) {
 			return gen.Input(context.Background(), schema)
 		},
 		func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
-			return universe.NewLimitTransformation(spec, id)
+			return universe.NewLimitTransformation(spec, id, alloc)
 		},
 	)
 }
@@ -0,0 +1,286 @@
+package universe
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const MedianResampleKind = "medianResample"
+
+// MedianResampleOpSpec downsamples each table to the
+// median of consecutive buckets of a fixed number of rows.
+type MedianResampleOpSpec struct {
+	BucketSize int64  `json:"bucketSize"`
+	Column     string `json:"column"`
+	TimeColumn string `json:"timeColumn"`
+	Partial    bool   `json:"partial"`
+}
+
+func init() {
+	medianResampleSignature := runtime.MustLookupBuiltinType("universe", "medianResample")
+
+	runtime.RegisterPackageValue("universe", MedianResampleKind, flux.MustValue(flux.FunctionValue(MedianResampleKind, createMedianResampleOpSpec, medianResampleSignature)))
+	flux.RegisterOpSpec(MedianResampleKind, newMedianResampleOp)
+	plan.RegisterProcedureSpec(MedianResampleKind, newMedianResampleProcedure, MedianResampleKind)
+	execute.RegisterTransformation(MedianResampleKind, createMedianResampleTransformation)
+}
+
+func createMedianResampleOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(MedianResampleOpSpec)
+	if bucketSize, err := args.GetRequiredInt("bucketSize"); err != nil {
+		return nil, err
+	} else if bucketSize <= 0 {
+		return nil, errors.New(codes.Invalid, `parameter "bucketSize" must be positive`)
+	} else {
+		spec.BucketSize = bucketSize
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+
+	if partial, ok, err := args.GetBool("partial"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Partial = partial
+	} else {
+		spec.Partial = true
+	}
+	return spec, nil
+}
+
+func newMedianResampleOp() flux.OperationSpec {
+	return new(MedianResampleOpSpec)
+}
+
+func (s *MedianResampleOpSpec) Kind() flux.OperationKind {
+	return MedianResampleKind
+}
+
+type MedianResampleProcedureSpec struct {
+	plan.DefaultCost
+	BucketSize int64  `json:"bucketSize"`
+	Column     string `json:"column"`
+	TimeColumn string `json:"timeColumn"`
+	Partial    bool   `json:"partial"`
+}
+
+func newMedianResampleProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MedianResampleOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MedianResampleProcedureSpec{
+		BucketSize: spec.BucketSize,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+		Partial:    spec.Partial,
+	}, nil
+}
+
+func (s *MedianResampleProcedureSpec) Kind() plan.ProcedureKind {
+	return MedianResampleKind
+}
+
+func (s *MedianResampleProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *MedianResampleProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createMedianResampleTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*MedianResampleProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewMedianResampleTransformation(id, s, a.Allocator())
+}
+
+type medianResampleTransformation struct {
+	bucketSize int
+	column     string
+	timeColumn string
+	partial    bool
+}
+
+// NewMedianResampleTransformation creates a transformation that groups
+// consecutive rows of each table into buckets of bucketSize rows and
+// outputs one row per bucket with the median of the bucket.
+//
+// Buckets are formed in the order rows are read and continue across
+// buffers of the same table. The time of each output row is the time
+// of the middle row of the bucket. The median ignores null and NaN values
+// and is null if a bucket has no other values. The final bucket of a table
+// may have fewer rows than bucketSize. It is output if partial is set and
+// dropped otherwise.
+func NewMedianResampleTransformation(id execute.DatasetID, spec *MedianResampleProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &medianResampleTransformation{
+		bucketSize: int(spec.BucketSize),
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+		partial:    spec.Partial,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type medianResampleState struct {
+	// bucketTimes holds the time of each row in the current
+	// bucket and values holds its non-null values.
+	bucketTimes      []int64
+	bucketTimesValid []bool
+	values           []float64
+
+	// The time and median of each bucket that has been completed.
+	times      []int64
+	timesValid []bool
+	medians    []float64
+	valid      []bool
+}
+
+func (t *medianResampleTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *medianResampleState
+	if state != nil {
+		s = state.(*medianResampleState)
+	} else {
+		s = &medianResampleState{}
+	}
+
+	timeIdx := chunk.Index(t.timeColumn)
+	if timeIdx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.timeColumn)
+	} else if typ := chunk.Col(timeIdx).Type; typ != flux.TTime {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "time column %q must be of type time, got %s", t.timeColumn, typ)
+	}
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+
+	var value func(i int) (float64, bool)
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		value = func(i int) (float64, bool) {
+			return vs.Value(i), vs.IsValid(i) && !math.IsNaN(vs.Value(i))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the median of column %q of type %s", t.column, typ)
+	}
+
+	times := chunk.Ints(timeIdx)
+	for i, l := 0, chunk.Len(); i < l; i++ {
+		s.bucketTimes = append(s.bucketTimes, times.Value(i))
+		s.bucketTimesValid = append(s.bucketTimesValid, times.IsValid(i))
+		if v, ok := value(i); ok {
+			s.values = append(s.values, v)
+		}
+		if len(s.bucketTimes) == t.bucketSize {
+			t.closeBucket(s)
+		}
+	}
+	return s, true, nil
+}
+
+// closeBucket computes the median of the current bucket
+// and starts a new bucket.
+func (t *medianResampleTransformation) closeBucket(s *medianResampleState) {
+	mid := (len(s.bucketTimes) - 1) / 2
+	s.times = append(s.times, s.bucketTimes[mid])
+	s.timesValid = append(s.timesValid, s.bucketTimesValid[mid])
+
+	agg := &ExactQuantileAgg{Quantile: 0.5, data: s.values}
+	s.medians = append(s.medians, agg.ValueFloat())
+	s.valid = append(s.valid, !agg.IsNull())
+
+	s.bucketTimes = s.bucketTimes[:0]
+	s.bucketTimesValid = s.bucketTimesValid[:0]
+	s.values = s.values[:0]
+}
+
+func (t *medianResampleTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*medianResampleState)
+	if len(s.bucketTimes) > 0 && t.partial {
+		t.closeBucket(s)
+	}
+	n := len(s.times)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+2),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), n, mem))
+	}
+
+	tb := arrowutil.NewIntBuilder(mem)
+	tb.Resize(n)
+	for i, tm := range s.times {
+		if s.timesValid[i] {
+			tb.Append(tm)
+		} else {
+			tb.AppendNull()
+		}
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.timeColumn, Type: flux.TTime})
+	buffer.Values = append(buffer.Values, tb.NewArray())
+
+	b := arrowutil.NewFloatBuilder(mem)
+	b.Resize(n)
+	for i, v := range s.medians {
+		if s.valid[i] {
+			b.Append(v)
+		} else {
+			b.AppendNull()
+		}
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.column, Type: flux.TFloat})
+	buffer.Values = append(buffer.Values, b.NewArray())
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *medianResampleTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestMedianResample_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.RowWiseTable{
+			Table: &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(5), "a"},
+					{execute.Time(2), int64(1), "a"},
+					{execute.Time(3), int64(3), "a"},
+					{execute.Time(4), int64(2), "a"},
+					{execute.Time(5), nil, "a"},
+					{execute.Time(6), int64(8), "a"},
+					{execute.Time(7), int64(10), "a"},
+				},
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name    string
+		spec    *universe.MedianResampleProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "partial bucket",
+			spec: &universe.MedianResampleProcedureSpec{
+				BucketSize: 3,
+				Column:     "_value",
+				TimeColumn: "_time",
+				Partial:    true,
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", execute.Time(2), 3.0},
+					{"a", execute.Time(5), 5.0},
+					{"a", execute.Time(7), 10.0},
+				},
+			}},
+		},
+		{
+			name: "drop partial bucket",
+			spec: &universe.MedianResampleProcedureSpec{
+				BucketSize: 3,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", execute.Time(2), 3.0},
+					{"a", execute.Time(5), 5.0},
+				},
+			}},
+		},
+		{
+			name: "null bucket",
+			spec: &universe.MedianResampleProcedureSpec{
+				BucketSize: 2,
+				Column:     "_value",
+				TimeColumn: "_time",
+				Partial:    true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), nil},
+					{execute.Time(3), 1.5},
+					{execute.Time(4), 2.5},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(3), 2.0},
+				},
+			}},
+		},
+		{
+			name: "missing time column",
+			spec: &universe.MedianResampleProcedureSpec{
+				BucketSize: 2,
+				Column:     "_value",
+				TimeColumn: "time",
+				Partial:    true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "time" does not exist`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewMedianResampleTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
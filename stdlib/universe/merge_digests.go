@@ -0,0 +1,301 @@
+package universe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/tdigest"
+)
+
+const MergeDigestsKind = "mergeDigests"
+
+// digestVersion is the version of the serialized t-digest format.
+// It is the first byte of every serialized digest.
+const digestVersion byte = 1
+
+// MergeDigestsOpSpec merges the t-digests of each
+// table into a single digest or a quantile.
+type MergeDigestsOpSpec struct {
+	Column      string   `json:"column"`
+	Compression float64  `json:"compression"`
+	Quantile    *float64 `json:"q,omitempty"`
+}
+
+func init() {
+	mergeDigestsSignature := runtime.MustLookupBuiltinType("universe", "mergeDigests")
+
+	runtime.RegisterPackageValue("universe", MergeDigestsKind, flux.MustValue(flux.FunctionValue(MergeDigestsKind, createMergeDigestsOpSpec, mergeDigestsSignature)))
+	flux.RegisterOpSpec(MergeDigestsKind, newMergeDigestsOp)
+	plan.RegisterProcedureSpec(MergeDigestsKind, newMergeDigestsProcedure, MergeDigestsKind)
+	execute.RegisterTransformation(MergeDigestsKind, createMergeDigestsTransformation)
+}
+
+func createMergeDigestsOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(MergeDigestsOpSpec)
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if c, ok, err := args.GetFloat("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if c <= 0 {
+			return nil, errors.New(codes.Invalid, "compression must be greater than zero")
+		}
+		spec.Compression = c
+	} else {
+		spec.Compression = 1000
+	}
+
+	if q, ok, err := args.GetFloat("q"); err != nil {
+		return nil, err
+	} else if ok {
+		if q < 0 || q > 1 {
+			return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+		}
+		spec.Quantile = &q
+	}
+	return spec, nil
+}
+
+func newMergeDigestsOp() flux.OperationSpec {
+	return new(MergeDigestsOpSpec)
+}
+
+func (s *MergeDigestsOpSpec) Kind() flux.OperationKind {
+	return MergeDigestsKind
+}
+
+type MergeDigestsProcedureSpec struct {
+	plan.DefaultCost
+	Column      string   `json:"column"`
+	Compression float64  `json:"compression"`
+	Quantile    *float64 `json:"q,omitempty"`
+}
+
+func newMergeDigestsProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MergeDigestsOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MergeDigestsProcedureSpec{
+		Column:      spec.Column,
+		Compression: spec.Compression,
+		Quantile:    spec.Quantile,
+	}, nil
+}
+
+func (s *MergeDigestsProcedureSpec) Kind() plan.ProcedureKind {
+	return MergeDigestsKind
+}
+
+func (s *MergeDigestsProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(MergeDigestsProcedureSpec)
+	*ns = *s
+	if s.Quantile != nil {
+		q := *s.Quantile
+		ns.Quantile = &q
+	}
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *MergeDigestsProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createMergeDigestsTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*MergeDigestsProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewMergeDigestsTransformation(id, s, a.Allocator())
+}
+
+type mergeDigestsTransformation struct {
+	column      string
+	compression float64
+	quantile    *float64
+}
+
+// NewMergeDigestsTransformation creates a transformation that merges
+// the values of a column of each table into a single t-digest.
+//
+// A numeric column is added to the digest value by value and a string
+// column must hold serialized digests with the same compression, which
+// are merged into the digest. The output has one row with the group key
+// of the table and either the serialized digest as a string or, if a
+// quantile is set, the estimated quantile of the digest as a float.
+// The quantile of a table without any values is null.
+func NewMergeDigestsTransformation(id execute.DatasetID, spec *MergeDigestsProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &mergeDigestsTransformation{
+		column:      spec.Column,
+		compression: spec.Compression,
+		quantile:    spec.Quantile,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+func (t *mergeDigestsTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var digest *tdigest.TDigest
+	if state != nil {
+		digest = state.(*tdigest.TDigest)
+	} else {
+		digest = tdigest.NewWithCompression(t.compression)
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot merge the digests of a column that is part of the group key")
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				digest.Add(float64(vs.Value(i)), 1)
+			}
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				digest.Add(float64(vs.Value(i)), 1)
+			}
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				digest.Add(vs.Value(i), 1)
+			}
+		}
+	case flux.TString:
+		vs := chunk.Strings(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsNull(i) {
+				continue
+			}
+			centroids, err := decodeDigest(vs.Value(i), t.compression)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, codes.Inherit, "invalid digest in column %q", t.column)
+			}
+			digest.AddCentroidList(centroids)
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot merge the digests of column %q of type %s", t.column, typ)
+	}
+	return digest, true, nil
+}
+
+func (t *mergeDigestsTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	digest := state.(*tdigest.TDigest)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+1),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), 1, mem))
+	}
+
+	if t.quantile == nil {
+		b := arrowutil.NewStringBuilder(mem)
+		b.Append(encodeDigest(digest, t.compression))
+		buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.column, Type: flux.TString})
+		buffer.Values = append(buffer.Values, b.NewArray())
+	} else {
+		b := arrowutil.NewFloatBuilder(mem)
+		if digest.Count() > 0 {
+			b.Append(digest.Quantile(*t.quantile))
+		} else {
+			b.AppendNull()
+		}
+		buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.column, Type: flux.TFloat})
+		buffer.Values = append(buffer.Values, b.NewArray())
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *mergeDigestsTransformation) Close() error {
+	return nil
+}
+
+// encodeDigest serializes the centroids of a digest as base64. The encoded
+// bytes are the format version, the compression, the number of centroids,
+// and the mean and weight of each centroid in little endian order.
+func encodeDigest(digest *tdigest.TDigest, compression float64) string {
+	centroids := digest.Centroids(nil)
+
+	var buf bytes.Buffer
+	buf.Grow(1 + 8 + 4 + 16*len(centroids))
+	buf.WriteByte(digestVersion)
+	_ = binary.Write(&buf, binary.LittleEndian, compression)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(centroids)))
+	for _, c := range centroids {
+		_ = binary.Write(&buf, binary.LittleEndian, c.Mean)
+		_ = binary.Write(&buf, binary.LittleEndian, c.Weight)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// decodeDigest parses the centroids of a digest serialized by encodeDigest.
+// The digest must have been built with the given compression so that it
+// can be merged with other digests without losing accuracy.
+func decodeDigest(s string, compression float64) (tdigest.CentroidList, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "digest is not valid base64")
+	}
+	if len(data) < 1+8+4 {
+		return nil, errors.New(codes.Invalid, "digest is truncated")
+	}
+	if data[0] != digestVersion {
+		return nil, errors.Newf(codes.Invalid, "unsupported digest version %d", data[0])
+	}
+	if c := math.Float64frombits(binary.LittleEndian.Uint64(data[1:])); c != compression {
+		return nil, errors.Newf(codes.Invalid, "digest has compression %v, expected %v", c, compression)
+	}
+	n := int(binary.LittleEndian.Uint32(data[9:]))
+	data = data[13:]
+	if len(data) != 16*n {
+		return nil, errors.New(codes.Invalid, "digest is truncated")
+	}
+
+	centroids := make(tdigest.CentroidList, n)
+	for i := range centroids {
+		centroids[i].Mean = math.Float64frombits(binary.LittleEndian.Uint64(data[16*i:]))
+		centroids[i].Weight = math.Float64frombits(binary.LittleEndian.Uint64(data[16*i+8:]))
+		if centroids[i].Weight <= 0 || math.IsNaN(centroids[i].Mean) {
+			return nil, errors.New(codes.Invalid, "digest contains an invalid centroid")
+		}
+	}
+	return centroids, nil
+}
@@ -0,0 +1,239 @@
+package universe_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestMergeDigests_Process(t *testing.T) {
+	q := 0.5
+	testCases := []struct {
+		name    string
+		spec    *universe.MergeDigestsProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "quantile of values",
+			spec: &universe.MergeDigestsProcedureSpec{
+				Column:      "_value",
+				Compression: 1000,
+				Quantile:    &q,
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(7), "a"},
+					{execute.Time(2), int64(1), "a"},
+					{execute.Time(3), nil, "a"},
+					{execute.Time(4), int64(4), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", 4.0},
+				},
+			}},
+		},
+		{
+			name: "empty quantile",
+			spec: &universe.MergeDigestsProcedureSpec{
+				Column:      "_value",
+				Compression: 1000,
+				Quantile:    &q,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{nil},
+				},
+			}},
+		},
+		{
+			name: "invalid digest",
+			spec: &universe.MergeDigestsProcedureSpec{
+				Column:      "_value",
+				Compression: 1000,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "AQ=="},
+				},
+			}},
+			wantErr: errors.New(codes.Invalid, `invalid digest in column "_value": digest is truncated`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewMergeDigestsTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+// mergeDigests runs the mergeDigests transformation over
+// the tables and returns the tables that it produces.
+func mergeDigests(t *testing.T, spec *universe.MergeDigestsProcedureSpec, data []flux.Table) []*executetest.Table {
+	t.Helper()
+
+	store := executetest.NewDataStore()
+	tr, d, err := universe.NewMergeDigestsTransformation(executetest.RandomDatasetID(), spec, &memory.Allocator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetTriggerSpec(plan.DefaultTriggerSpec)
+	d.AddTransformation(store)
+
+	parentID := executetest.RandomDatasetID()
+	for _, tbl := range data {
+		if err := tr.Process(parentID, tbl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr.Finish(parentID, nil)
+
+	got, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(got)
+	sort.Sort(executetest.SortedTables(got))
+	return got
+}
+
+func TestMergeDigests_Rollup(t *testing.T) {
+	const (
+		clusters = 2
+		hosts    = 4
+		points   = 2500
+	)
+	clusterNames := []string{"c0", "c1"}
+	hostNames := []string{"h0", "h1", "h2", "h3"}
+
+	// Build a table of values for each host in each cluster
+	// and keep the values of each cluster for the exact quantiles.
+	rnd := rand.New(rand.NewSource(42))
+	exact := make(map[string][]float64)
+	var data []flux.Table
+	for c := 0; c < clusters; c++ {
+		for h := 0; h < hosts; h++ {
+			tbl := &executetest.Table{
+				KeyCols: []string{"cluster", "host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "cluster", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+				},
+			}
+			for i := 0; i < points; i++ {
+				v := rnd.NormFloat64()*float64(h+1) + float64(10*c)
+				tbl.Data = append(tbl.Data, []interface{}{v, clusterNames[c], hostNames[h]})
+				exact[clusterNames[c]] = append(exact[clusterNames[c]], v)
+			}
+			data = append(data, tbl)
+		}
+	}
+
+	// The first level computes a digest for each host.
+	hostDigests := mergeDigests(t, &universe.MergeDigestsProcedureSpec{
+		Column:      "_value",
+		Compression: 1000,
+	}, data)
+	if want, got := clusters*hosts, len(hostDigests); want != got {
+		t.Fatalf("unexpected number of host digests -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The second level regroups the host digests by cluster
+	// and merges them into the quantiles of each cluster.
+	// A table can only be read once so they are created for
+	// each quantile.
+	byCluster := func() []flux.Table {
+		tables := make([]flux.Table, clusters)
+		for c := range tables {
+			tbl := &executetest.Table{
+				KeyCols: []string{"cluster"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TString},
+					{Label: "cluster", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+				},
+			}
+			// The columns of the host digests are sorted so
+			// each row is the digest, the cluster, and the host.
+			for _, hd := range hostDigests {
+				if row := hd.Data[0]; row[1] == clusterNames[c] {
+					tbl.Data = append(tbl.Data, row)
+				}
+			}
+			tables[c] = tbl
+		}
+		return tables
+	}
+
+	for _, q := range []float64{0.1, 0.5, 0.99} {
+		q := q
+		got := mergeDigests(t, &universe.MergeDigestsProcedureSpec{
+			Column:      "_value",
+			Compression: 1000,
+			Quantile:    &q,
+		}, byCluster())
+		for _, tbl := range got {
+			cluster := tbl.Data[0][1].(string)
+			values := exact[cluster]
+			sort.Float64s(values)
+			want := values[int(q*float64(len(values)-1))]
+			if v := tbl.Data[0][0].(float64); math.Abs(v-want) > 0.05 {
+				t.Errorf("unexpected quantile %v of cluster %s -want/+got:\n\t- %v\n\t+ %v", q, cluster, want, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package universe
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+)
+
+const (
+	ExactQuantileStateKind = "exactQuantileState"
+	MergeExactQuantileKind = "mergeExactQuantile"
+)
+
+func init() {
+	plan.RegisterParallelizeRules(ParallelizeExactQuantileRule{})
+	execute.RegisterTransformation(ExactQuantileStateKind, createExactQuantileStateTransformation)
+	execute.RegisterTransformation(MergeExactQuantileKind, createMergeExactQuantileTransformation)
+}
+
+// ExactQuantileStateProcedureSpec computes the state of an exact quantile
+// for each table. The state holds the sorted values of the table and the
+// counts of the skipped values and it replaces the values of each column
+// as a base64 string. It runs in each parallel copy of a plan so the
+// values are sorted in parallel.
+type ExactQuantileStateProcedureSpec struct {
+	*ExactQuantileAggProcedureSpec
+}
+
+func (s *ExactQuantileStateProcedureSpec) Kind() plan.ProcedureKind {
+	return ExactQuantileStateKind
+}
+
+func (s *ExactQuantileStateProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	ns.ExactQuantileAggProcedureSpec = s.ExactQuantileAggProcedureSpec.Copy().(*ExactQuantileAggProcedureSpec)
+	return &ns
+}
+
+func createExactQuantileStateTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*ExactQuantileStateProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	// The values are never written to a temporary
+	// file since the state holds all of them.
+	agg := &exactQuantileStateAgg{
+		agg: newExactQuantileAggFromSpec(ps.ExactQuantileAggProcedureSpec, a.Allocator()),
+	}
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
+}
+
+// exactQuantileStateAgg is an aggregate that encodes
+// the state of the exact quantile of each column.
+type exactQuantileStateAgg struct {
+	agg *ExactQuantileAgg
+}
+
+func (a *exactQuantileStateAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *exactQuantileStateAgg) NewIntAgg() execute.DoIntAgg {
+	return nil
+}
+
+func (a *exactQuantileStateAgg) NewUIntAgg() execute.DoUIntAgg {
+	return nil
+}
+
+func (a *exactQuantileStateAgg) NewFloatAgg() execute.DoFloatAgg {
+	return &exactQuantileState{agg: a.agg.Copy()}
+}
+
+func (a *exactQuantileStateAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+type exactQuantileState struct {
+	agg *ExactQuantileAgg
+	err error
+}
+
+func (s *exactQuantileState) DoFloat(vs *array.Float) {
+	s.agg.DoFloat(vs)
+}
+
+func (s *exactQuantileState) Type() flux.ColType {
+	return flux.TString
+}
+
+// IsNull is always false so the counts of the skipped
+// values are merged even if no values were added.
+func (s *exactQuantileState) IsNull() bool {
+	return false
+}
+
+func (s *exactQuantileState) ValueString() string {
+	data, err := marshalQuantileState(true, s.agg.quantileCounts, s.agg.Sorted())
+	if err != nil {
+		s.err = err
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Err implements execute.ErrorValueFunc.
+func (s *exactQuantileState) Err() error {
+	return s.agg.Err()
+}
+
+// Close reports any error from encoding the state.
+func (s *exactQuantileState) Close() error {
+	return s.err
+}
+
+// MergeExactQuantileProcedureSpec merges the states of an exact
+// quantile that were computed by each parallel copy of a plan and
+// computes the quantile of each group key from the sorted values
+// of the states.
+type MergeExactQuantileProcedureSpec struct {
+	*ExactQuantileAggProcedureSpec
+}
+
+func (s *MergeExactQuantileProcedureSpec) Kind() plan.ProcedureKind {
+	return MergeExactQuantileKind
+}
+
+func (s *MergeExactQuantileProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	ns.ExactQuantileAggProcedureSpec = s.ExactQuantileAggProcedureSpec.Copy().(*ExactQuantileAggProcedureSpec)
+	return &ns
+}
+
+func createMergeExactQuantileTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*MergeExactQuantileProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	t, d := NewMergeExactQuantileTransformation(id, ps, a.Allocator())
+	return t, d, nil
+}
+
+type mergeExactQuantileTransformation struct {
+	execute.ExecutionNode
+	d       execute.Dataset
+	cache   execute.TableBuilderCache
+	agg     *ExactQuantileAgg
+	columns []string
+
+	// states holds the aggregate of each column for each group key.
+	// The tables of a group key may come from more than one parallel
+	// copy so the quantile is computed when the input finishes.
+	states *execute.GroupLookup
+}
+
+// NewMergeExactQuantileTransformation creates a transformation that
+// merges the states of an exact quantile in each of the columns. The
+// sorted values of the states with the same group key are merged as
+// runs so the quantile is computed without sorting the values again.
+// The output has the same columns as the exact quantile aggregate.
+func NewMergeExactQuantileTransformation(id execute.DatasetID, spec *MergeExactQuantileProcedureSpec, mem *memory.Allocator) (execute.Transformation, execute.Dataset) {
+	cache := execute.NewTableBuilderCache(mem)
+	d := execute.NewDataset(id, execute.DiscardingMode, cache)
+	t := &mergeExactQuantileTransformation{
+		d:       d,
+		cache:   cache,
+		agg:     newExactQuantileAggFromSpec(spec.ExactQuantileAggProcedureSpec, mem),
+		columns: spec.Columns,
+		states:  execute.NewGroupLookup(),
+	}
+	return t, d
+}
+
+func (t *mergeExactQuantileTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *mergeExactQuantileTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	var aggs []*ExactQuantileAgg
+	if v, ok := t.states.Lookup(tbl.Key()); ok {
+		aggs = v.([]*ExactQuantileAgg)
+	} else {
+		agg, err := t.agg.ForKey(tbl.Key())
+		if err != nil {
+			return err
+		}
+		aggs = make([]*ExactQuantileAgg, len(t.columns))
+		for j := range aggs {
+			aggs[j] = agg.(*ExactQuantileAgg).Copy()
+		}
+		t.states.Set(tbl.Key(), aggs)
+	}
+
+	idxs := make([]int, len(t.columns))
+	for j, label := range t.columns {
+		idxs[j] = execute.ColIdx(label, tbl.Cols())
+		if idxs[j] < 0 {
+			return errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+		} else if typ := tbl.Cols()[idxs[j]].Type; typ != flux.TString {
+			return errors.Newf(codes.Internal, "exact quantile state of column %q has type %v", label, typ)
+		}
+	}
+
+	return tbl.Do(func(cr flux.ColReader) error {
+		for j, idx := range idxs {
+			vs := cr.Strings(idx)
+			for i := 0; i < vs.Len(); i++ {
+				if vs.IsNull(i) {
+					continue
+				}
+				data, err := base64.StdEncoding.DecodeString(vs.Value(i))
+				if err != nil {
+					return errors.Wrap(err, codes.Internal, "exact quantile state is not valid base64")
+				}
+				if err := aggs[j].merge(data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// computeTable builds the table of a group key
+// with the quantile of each column.
+func (t *mergeExactQuantileTransformation) computeTable(key flux.GroupKey, aggs []*ExactQuantileAgg) error {
+	builder, created := t.cache.TableBuilder(key)
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "aggregate found duplicate table with key: %v", key)
+	}
+	if err := execute.AddTableKeyCols(key, builder); err != nil {
+		return err
+	}
+
+	for j, label := range t.columns {
+		agg := aggs[j]
+		bj, err := builder.AddCol(flux.ColMeta{Label: label, Type: flux.TFloat})
+		if err != nil {
+			return err
+		}
+		auxValues := agg.AuxiliaryValues()
+		for i, col := range agg.AuxiliaryColumns() {
+			aj, err := builder.AddCol(col)
+			if err != nil {
+				return err
+			}
+			if err := builder.AppendValue(aj, auxValues[i]); err != nil {
+				return err
+			}
+		}
+
+		if agg.IsNull() {
+			err = builder.AppendNil(bj)
+		} else {
+			err = builder.AppendFloat(bj, agg.ValueFloat())
+		}
+		if err != nil {
+			return err
+		}
+		if err := agg.Close(); err != nil {
+			return err
+		}
+	}
+	return execute.AppendKeyValues(key, builder)
+}
+
+func (t *mergeExactQuantileTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+func (t *mergeExactQuantileTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+func (t *mergeExactQuantileTransformation) Finish(id execute.DatasetID, err error) {
+	if err == nil {
+		err = t.states.Range(func(key flux.GroupKey, value interface{}) error {
+			return t.computeTable(key, value.([]*ExactQuantileAgg))
+		})
+	}
+	// Release the runs of the groups that were not computed
+	// because of an error. Closing an aggregate twice is a no-op.
+	t.states.Range(func(key flux.GroupKey, value interface{}) error {
+		for _, agg := range value.([]*ExactQuantileAgg) {
+			_ = agg.Close()
+		}
+		return nil
+	})
+	t.states.Clear()
+	t.d.Finish(err)
+}
+
+// ParallelizeExactQuantileRule splits an exact quantile that follows a
+// parallel merge so that each parallel copy sorts its own values. The
+// state of the exact quantile is computed before the merge and the
+// sorted values of the states are merged after it.
+type ParallelizeExactQuantileRule struct{}
+
+func (ParallelizeExactQuantileRule) Name() string {
+	return "ParallelizeExactQuantileRule"
+}
+
+func (ParallelizeExactQuantileRule) Pattern() plan.Pattern {
+	return plan.Pat(ExactQuantileAggKind, plan.Pat(ParallelMergeKind, plan.Any()))
+}
+
+func (ParallelizeExactQuantileRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	mergeNode, ok := node.Predecessors()[0].(*plan.PhysicalPlanNode)
+	if !ok || len(mergeNode.Successors()) != 1 || len(mergeNode.Predecessors()) != 1 {
+		return node, false, nil
+	}
+	attr, ok := mergeNode.OutputAttrs[plan.ParallelMergeKey].(plan.ParallelMergeAttribute)
+	if !ok {
+		return node, false, nil
+	}
+	spec := node.ProcedureSpec().(*ExactQuantileAggProcedureSpec)
+
+	// Compute the state in each parallel copy
+	// before the copies are merged.
+	parentNode := mergeNode.Predecessors()[0]
+	stateNode := plan.CreateUniquePhysicalNode(ctx, "exactQuantileState", &ExactQuantileStateProcedureSpec{
+		ExactQuantileAggProcedureSpec: spec.Copy().(*ExactQuantileAggProcedureSpec),
+	})
+	stateNode.SetRequiredAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: attr.Factor})
+	stateNode.SetOutputAttr(plan.ParallelRunKey, plan.ParallelRunAttribute{Factor: attr.Factor})
+	for i, succ := range parentNode.Successors() {
+		if succ == mergeNode {
+			parentNode.Successors()[i] = stateNode
+		}
+	}
+	stateNode.AddPredecessors(parentNode)
+	stateNode.AddSuccessors(mergeNode)
+	mergeNode.ClearPredecessors()
+	mergeNode.AddPredecessors(stateNode)
+
+	newNode := plan.CreateUniquePhysicalNode(ctx, "mergeExactQuantile", &MergeExactQuantileProcedureSpec{
+		ExactQuantileAggProcedureSpec: spec.Copy().(*ExactQuantileAggProcedureSpec),
+	})
+	mergeNode.ClearSuccessors()
+	mergeNode.AddSuccessors(newNode)
+	newNode.AddPredecessors(mergeNode)
+	return newNode, true, nil
+}
@@ -0,0 +1,87 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestParallelizeExactQuantileRule(t *testing.T) {
+	from := &influxdb.FromProcedureSpec{
+		Bucket: influxdb.NameOrID{Name: "testbucket"},
+	}
+	exact := &universe.ExactQuantileAggProcedureSpec{
+		Quantile:              0.9,
+		Interpolation:         "nearest",
+		SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+	}
+	run := plan.ParallelRunAttribute{Factor: 4}
+	merge := plan.ParallelMergeAttribute{Factor: 4}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name:  "parallel merge",
+			Rules: []plan.Rule{universe.ParallelizeExactQuantileRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("from0", from,
+						plantest.WithOutputAttr(plan.ParallelRunKey, run)),
+					plantest.CreatePhysicalNode("merge1", &universe.PartitionMergeProcedureSpec{},
+						plantest.WithRequiredAttr(plan.ParallelRunKey, run),
+						plantest.WithOutputAttr(plan.ParallelMergeKey, merge)),
+					plan.CreatePhysicalNode("quantile2", exact),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("from0", from,
+						plantest.WithOutputAttr(plan.ParallelRunKey, run)),
+					plantest.CreatePhysicalNode("exactQuantileState", &universe.ExactQuantileStateProcedureSpec{
+						ExactQuantileAggProcedureSpec: exact,
+					},
+						plantest.WithRequiredAttr(plan.ParallelRunKey, run),
+						plantest.WithOutputAttr(plan.ParallelRunKey, run)),
+					plantest.CreatePhysicalNode("merge1", &universe.PartitionMergeProcedureSpec{},
+						plantest.WithRequiredAttr(plan.ParallelRunKey, run),
+						plantest.WithOutputAttr(plan.ParallelMergeKey, merge)),
+					plan.CreatePhysicalNode("mergeExactQuantile", &universe.MergeExactQuantileProcedureSpec{
+						ExactQuantileAggProcedureSpec: exact,
+					}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+			},
+		},
+		{
+			Name:  "no parallel merge",
+			Rules: []plan.Rule{universe.ParallelizeExactQuantileRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from0", from),
+					plan.CreatePhysicalNode("quantile1", exact),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+			},
+			NoChange: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
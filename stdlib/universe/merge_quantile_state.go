@@ -0,0 +1,185 @@
+package universe
+
+import (
+	"encoding/base64"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const MergeQuantileStateKind = "mergeQuantileState"
+
+// MergeQuantileStateOpSpec merges the serialized
+// quantile states of each table and computes a quantile.
+type MergeQuantileStateOpSpec struct {
+	Quantile    float64 `json:"quantile"`
+	Compression float64 `json:"compression"`
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	mergeQuantileStateSignature := runtime.MustLookupBuiltinType("universe", "mergeQuantileState")
+
+	runtime.RegisterPackageValue("universe", MergeQuantileStateKind, flux.MustValue(flux.FunctionValue(MergeQuantileStateKind, createMergeQuantileStateOpSpec, mergeQuantileStateSignature)))
+	flux.RegisterOpSpec(MergeQuantileStateKind, newMergeQuantileStateOp)
+	plan.RegisterProcedureSpec(MergeQuantileStateKind, newMergeQuantileStateProcedure, MergeQuantileStateKind)
+	execute.RegisterTransformation(MergeQuantileStateKind, createMergeQuantileStateTransformation)
+}
+
+func createMergeQuantileStateOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(MergeQuantileStateOpSpec)
+	q, err := args.GetRequiredFloat("q")
+	if err != nil {
+		return nil, err
+	}
+	if q < 0 || q > 1 {
+		return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+	}
+	spec.Quantile = q
+
+	if c, ok, err := args.GetFloat("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if c <= 0 {
+			return nil, errors.New(codes.Invalid, "compression must be greater than zero")
+		}
+		spec.Compression = c
+	} else {
+		spec.Compression = 1000
+	}
+
+	if err := spec.SimpleAggregateConfig.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func newMergeQuantileStateOp() flux.OperationSpec {
+	return new(MergeQuantileStateOpSpec)
+}
+
+func (s *MergeQuantileStateOpSpec) Kind() flux.OperationKind {
+	return MergeQuantileStateKind
+}
+
+type MergeQuantileStateProcedureSpec struct {
+	Quantile    float64 `json:"quantile"`
+	Compression float64 `json:"compression"`
+	execute.SimpleAggregateConfig
+}
+
+func newMergeQuantileStateProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MergeQuantileStateOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MergeQuantileStateProcedureSpec{
+		Quantile:              spec.Quantile,
+		Compression:           spec.Compression,
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *MergeQuantileStateProcedureSpec) Kind() plan.ProcedureKind {
+	return MergeQuantileStateKind
+}
+
+func (s *MergeQuantileStateProcedureSpec) Copy() plan.ProcedureSpec {
+	return &MergeQuantileStateProcedureSpec{
+		Quantile:              s.Quantile,
+		Compression:           s.Compression,
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *MergeQuantileStateProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createMergeQuantileStateTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*MergeQuantileStateProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	fraction, err := getTDigestPoolFraction(a.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	size := len(ps.SimpleAggregateConfig.Columns)
+	quantiles := NewQuantileAgg(ps.Quantile, ps.Compression, a.Allocator(), size)
+	quantiles.setFreeDigestBudget(fraction)
+	quantiles.ctx = a.Context()
+	agg := NewMergeQuantileStateAgg(quantiles)
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
+}
+
+// MergeQuantileStateAgg is an aggregate of string columns that hold
+// quantile states encoded by QuantileAggState.MarshalBinary as base64.
+// The states of a column are merged into a single t-digest and the
+// quantile of the parent QuantileAgg is computed from the merged digest.
+//
+// The digests are allocated by the parent so they are accounted
+// for in the same way as the digests of the quantile aggregate.
+type MergeQuantileStateAgg struct {
+	agg *QuantileAgg
+}
+
+func NewMergeQuantileStateAgg(agg *QuantileAgg) *MergeQuantileStateAgg {
+	return &MergeQuantileStateAgg{agg: agg}
+}
+
+func (a *MergeQuantileStateAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *MergeQuantileStateAgg) NewIntAgg() execute.DoIntAgg {
+	return nil
+}
+
+func (a *MergeQuantileStateAgg) NewUIntAgg() execute.DoUIntAgg {
+	return nil
+}
+
+func (a *MergeQuantileStateAgg) NewFloatAgg() execute.DoFloatAgg {
+	return nil
+}
+
+func (a *MergeQuantileStateAgg) NewStringAgg() execute.DoStringAgg {
+	return &mergeQuantileStateAggState{
+		QuantileAggState: a.agg.newState(a.agg.Quantile),
+	}
+}
+
+func (a *MergeQuantileStateAgg) Close() error {
+	return a.agg.Close()
+}
+
+type mergeQuantileStateAggState struct {
+	*QuantileAggState
+}
+
+func (s *mergeQuantileStateAggState) DoString(vs *array.String) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) || s.err != nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(vs.Value(i))
+		if err != nil {
+			s.err = errors.Wrap(err, codes.Invalid, "quantile state is not valid base64")
+			continue
+		}
+		if err := s.merge(data); err != nil {
+			s.err = errors.Wrap(err, codes.Invalid, "invalid quantile state")
+		}
+	}
+}
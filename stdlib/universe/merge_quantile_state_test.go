@@ -0,0 +1,218 @@
+package universe_test
+
+import (
+	"context"
+	"encoding/base64"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/tdigest"
+)
+
+func TestMergeQuantileState_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "default",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> mergeQuantileState(q: 0.99)`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "mergeQuantileState2",
+						Spec: &universe.MergeQuantileStateOpSpec{
+							Quantile:              0.99,
+							Compression:           1000,
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "mergeQuantileState2"},
+				},
+			},
+		},
+		{
+			Name:    "quantile out of range",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> mergeQuantileState(q: 1.5)`,
+			WantErr: true,
+		},
+		{
+			Name:    "invalid compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> mergeQuantileState(q: 0.5, compression: 0.0)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// encodeQuantileState returns the base64 encoded
+// state of a t-digest quantile of the values.
+func encodeQuantileState(t testing.TB, vs []float64) string {
+	t.Helper()
+	state := universe.NewQuantileAgg(0.5, 1000, &memory.Allocator{}, 1).NewFloatAgg()
+	state.DoFloat(arrow.NewFloat(vs, nil))
+	data, err := state.(*universe.QuantileAggState).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestMergeQuantileState_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    [][]interface{}
+		want    [][]interface{}
+		wantErr error
+	}{
+		{
+			name: "merge",
+			data: [][]interface{}{
+				{execute.Time(1), encodeQuantileState(t, []float64{1, 2, 3})},
+				{execute.Time(2), nil},
+				{execute.Time(3), encodeQuantileState(t, []float64{4, 5})},
+			},
+			want: [][]interface{}{{3.0}},
+		},
+		{
+			name: "only nulls",
+			data: [][]interface{}{
+				{execute.Time(1), nil},
+			},
+			want: [][]interface{}{{nil}},
+		},
+		{
+			name: "invalid state",
+			data: [][]interface{}{
+				{execute.Time(1), "bm90IGEgc3RhdGU="},
+			},
+			wantErr: errors.New(codes.Invalid, "invalid quantile state: failed to decode quantile state: unexpected EOF"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var want []*executetest.Table
+			if tc.wantErr == nil {
+				want = []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: tc.want,
+				}}
+			}
+			executetest.ProcessTestHelper2(
+				t,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: tc.data,
+				}},
+				want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					agg := universe.NewMergeQuantileStateAgg(universe.NewQuantileAgg(0.5, 1000, alloc, 1))
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, agg, execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestMergeQuantileState_RoundTrip(t *testing.T) {
+	const (
+		compression = 1000.0
+		windows     = 10
+	)
+	sorted := make([]float64, len(NormalData))
+	copy(sorted, NormalData)
+	sort.Float64s(sorted)
+	rank := func(v float64) float64 {
+		return float64(sort.SearchFloat64s(sorted, v)) / float64(len(sorted))
+	}
+
+	size := len(NormalData) / windows
+	states := make([]string, windows)
+	for i := range states {
+		states[i] = encodeQuantileState(t, NormalData[i*size:(i+1)*size])
+	}
+
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99, 0.999} {
+		single := universe.NewQuantileAgg(q, compression, &memory.Allocator{}, 1).NewFloatAgg()
+		single.DoFloat(arrow.NewFloat(NormalData, nil))
+
+		mem := &memory.Allocator{}
+		agg := universe.NewMergeQuantileStateAgg(universe.NewQuantileAgg(q, compression, mem, 1))
+		merged := agg.NewStringAgg()
+		merged.DoString(arrow.NewString(states, nil))
+		if err := merged.(execute.ErrorValueFunc).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := mem.Allocated(), int64(tdigest.ByteSizeForCompression(compression)); got != want {
+			t.Errorf("unexpected allocated memory for merged digest -want/+got:\n\t- %d\n\t+ %d", want, got)
+		}
+
+		// The merged quantile must be within the rank
+		// error of the compression of the single-pass quantile.
+		want := single.(execute.FloatValueFunc).ValueFloat()
+		got := merged.(execute.FloatValueFunc).ValueFloat()
+		if diff := math.Abs(rank(got) - rank(want)); diff > 1/compression {
+			t.Errorf("quantile %v: merged value %v differs from single-pass value %v by rank %v", q, got, want, diff)
+		}
+
+		if err := merged.(execute.Closer).Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := agg.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := mem.Allocated(); got != 0 {
+			t.Errorf("expected all memory to be released, got %d bytes", got)
+		}
+	}
+}
@@ -3,19 +3,33 @@ package universe
 import (
 	"sort"
 
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
 	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
 	"github.com/influxdata/flux/values"
 )
 
 const ModeKind = "mode"
+const ModeAggKind = "mode-aggregate"
+
+// The ties policies of mode. All outputs every mode of a table
+// and min outputs only the smallest one.
+const (
+	modeTiesAll = "all"
+	modeTiesMin = "min"
+)
 
 type ModeOpSpec struct {
 	Column string `json:"column"`
+	Ties   string `json:"ties,omitempty"`
 }
 
 func init() {
@@ -25,6 +39,7 @@ func init() {
 	flux.RegisterOpSpec(ModeKind, newModeOp)
 	plan.RegisterProcedureSpec(ModeKind, newModeProcedure, ModeKind)
 	execute.RegisterTransformation(ModeKind, createModeTransformation)
+	execute.RegisterTransformation(ModeAggKind, createModeAggTransformation)
 }
 
 func CreateModeOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
@@ -41,6 +56,17 @@ func CreateModeOpSpec(args flux.Arguments, a *flux.Administration) (flux.Operati
 	} else {
 		spec.Column = execute.DefaultValueColLabel
 	}
+
+	if ties, ok, err := args.GetString("ties"); err != nil {
+		return nil, err
+	} else if ok {
+		switch ties {
+		case modeTiesAll, modeTiesMin:
+			spec.Ties = ties
+		default:
+			return nil, errors.Newf(codes.Invalid, "mode ties must be %q or %q, got %q", modeTiesAll, modeTiesMin, ties)
+		}
+	}
 	return spec, nil
 }
 
@@ -63,6 +89,11 @@ func newModeProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Proce
 		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
 	}
 
+	if spec.Ties == modeTiesMin {
+		return &ModeAggProcedureSpec{
+			Column: spec.Column,
+		}, nil
+	}
 	return &ModeProcedureSpec{
 		Column: spec.Column,
 	}, nil
@@ -560,3 +591,419 @@ func (t *modeTransformation) UpdateProcessingTime(id execute.DatasetID, pt execu
 func (t *modeTransformation) Finish(id execute.DatasetID, err error) {
 	t.d.Finish(err)
 }
+
+// ModeAggProcedureSpec computes the mode of a column with a ModeAgg.
+// Unlike mode(), it produces a single value for each table, which is
+// the smallest of the modes. It is used by mode() with ties set to min.
+type ModeAggProcedureSpec struct {
+	plan.DefaultCost
+	Column string
+}
+
+func (s *ModeAggProcedureSpec) Kind() plan.ProcedureKind {
+	return ModeAggKind
+}
+func (s *ModeAggProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(ModeAggProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ModeAggProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createModeAggTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ModeAggProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewModeAggTransformation(id, s, a.Allocator())
+}
+
+// NewModeAggTransformation creates a transformation that outputs
+// the mode of the column of the spec in the _value column of a
+// single row with the group key of each table. The counts of the
+// values are accounted for with mem.
+func NewModeAggTransformation(id execute.DatasetID, spec *ModeAggProcedureSpec, mem *memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &modeAggTransformation{
+		column: spec.Column,
+		agg:    NewModeAgg(mem),
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type modeAggTransformation struct {
+	column string
+	agg    *ModeAgg
+}
+
+// modeAggState holds the aggregate of a
+// table and the type of the column it counts.
+type modeAggState struct {
+	typ flux.ColType
+	vf  execute.ValueFunc
+}
+
+func (s *modeAggState) Close() error {
+	return s.vf.(execute.Closer).Close()
+}
+
+func (t *modeAggTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	typ := chunk.Col(idx).Type
+
+	var s *modeAggState
+	if state != nil {
+		s = state.(*modeAggState)
+		if typ != s.typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "aggregate type conflict: %s != %s", typ, s.typ)
+		}
+	} else {
+		s = &modeAggState{typ: typ}
+		switch typ {
+		case flux.TBool:
+			s.vf = t.agg.NewBoolAgg()
+		case flux.TInt, flux.TTime:
+			// Times are counted as the integers they are stored as.
+			s.vf = t.agg.NewIntAgg()
+		case flux.TUInt:
+			s.vf = t.agg.NewUIntAgg()
+		case flux.TFloat:
+			s.vf = t.agg.NewFloatAgg()
+		case flux.TString:
+			s.vf = t.agg.NewStringAgg()
+		default:
+			return nil, false, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+		}
+	}
+
+	switch typ {
+	case flux.TBool:
+		s.vf.(execute.DoBoolAgg).DoBool(chunk.Bools(idx))
+	case flux.TInt, flux.TTime:
+		s.vf.(execute.DoIntAgg).DoInt(chunk.Ints(idx))
+	case flux.TUInt:
+		s.vf.(execute.DoUIntAgg).DoUInt(chunk.Uints(idx))
+	case flux.TFloat:
+		s.vf.(execute.DoFloatAgg).DoFloat(chunk.Floats(idx))
+	case flux.TString:
+		s.vf.(execute.DoStringAgg).DoString(chunk.Strings(idx))
+	}
+	if err := s.vf.(execute.ErrorValueFunc).Err(); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *modeAggTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*modeAggState)
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+1),
+	}
+	buffer.Columns = append(buffer.Columns, key.Cols()...)
+	buffer.Values = make([]array.Array, len(key.Cols()), cap(buffer.Columns))
+	for j := range key.Cols() {
+		buffer.Values[j] = arrow.Repeat(key.Cols()[j].Type, key.Value(j), 1, mem)
+	}
+
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{
+		Label: execute.DefaultValueColLabel,
+		Type:  s.typ,
+	})
+	if s.vf.IsNull() {
+		buffer.Values = append(buffer.Values, arrow.Nulls(s.typ, 1, mem))
+	} else {
+		buffer.Values = append(buffer.Values, arrow.Repeat(s.typ, s.value(), 1, mem))
+	}
+
+	if err := buffer.Validate(); err != nil {
+		return err
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+// value returns the mode of a state with a value.
+func (s *modeAggState) value() values.Value {
+	switch vf := s.vf.(type) {
+	case execute.BoolValueFunc:
+		return values.NewBool(vf.ValueBool())
+	case execute.IntValueFunc:
+		if s.typ == flux.TTime {
+			return values.NewTime(values.Time(vf.ValueInt()))
+		}
+		return values.NewInt(vf.ValueInt())
+	case execute.UIntValueFunc:
+		return values.NewUInt(vf.ValueUInt())
+	case execute.FloatValueFunc:
+		return values.NewFloat(vf.ValueFloat())
+	default:
+		return values.NewString(vf.(execute.StringValueFunc).ValueString())
+	}
+}
+
+func (t *modeAggTransformation) Close() error {
+	return nil
+}
+
+// modeEntrySize is the approximate number of bytes used by the
+// count of a value in a mode aggregate in addition to the value.
+const modeEntrySize = 16
+
+// ModeAgg finds the most frequent non-null value of a column.
+// The smallest of the most frequent values is used when there
+// is a tie. The value is null if every value is null.
+//
+// The counts of the values are accounted for with the allocator
+// of the aggregate if it is set and released when a state is closed.
+type ModeAgg struct {
+	mem *memory.Allocator
+}
+
+// NewModeAgg creates a mode aggregate that accounts
+// for the counts of its values with mem.
+func NewModeAgg(mem *memory.Allocator) *ModeAgg {
+	return &ModeAgg{mem: mem}
+}
+
+func (a *ModeAgg) NewBoolAgg() execute.DoBoolAgg {
+	return new(ModeBoolAgg)
+}
+
+func (a *ModeAgg) NewIntAgg() execute.DoIntAgg {
+	return &ModeIntAgg{counts: make(map[int64]int64), modeMemory: modeMemory{mem: a.mem}}
+}
+
+func (a *ModeAgg) NewUIntAgg() execute.DoUIntAgg {
+	return &ModeUIntAgg{counts: make(map[uint64]int64), modeMemory: modeMemory{mem: a.mem}}
+}
+
+func (a *ModeAgg) NewFloatAgg() execute.DoFloatAgg {
+	return &ModeFloatAgg{counts: make(map[float64]int64), modeMemory: modeMemory{mem: a.mem}}
+}
+
+func (a *ModeAgg) NewStringAgg() execute.DoStringAgg {
+	return &ModeStringAgg{counts: make(map[string]int64), modeMemory: modeMemory{mem: a.mem}}
+}
+
+// modeMemory accounts for the memory of the counts of a mode
+// aggregate. Once the memory limit is exceeded, the error is
+// reported by Err and no more values are counted.
+type modeMemory struct {
+	mem   *memory.Allocator
+	bytes int
+	err   error
+}
+
+// account accounts for size bytes and
+// reports whether the value may be counted.
+func (m *modeMemory) account(size int) bool {
+	if m.err != nil {
+		return false
+	} else if m.mem == nil {
+		return true
+	}
+	if err := m.mem.Account(size); err != nil {
+		m.err = err
+		return false
+	}
+	m.bytes += size
+	return true
+}
+
+// Err implements execute.ErrorValueFunc.
+func (m *modeMemory) Err() error {
+	return m.err
+}
+
+// Close releases the memory of the counts.
+func (m *modeMemory) Close() error {
+	if m.mem != nil {
+		m.mem.Account(-m.bytes)
+	}
+	m.bytes = 0
+	return nil
+}
+
+type ModeBoolAgg struct {
+	trues, falses int64
+}
+
+func (a *ModeBoolAgg) DoBool(vs *array.Boolean) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		if vs.Value(i) {
+			a.trues++
+		} else {
+			a.falses++
+		}
+	}
+}
+func (a *ModeBoolAgg) Type() flux.ColType {
+	return flux.TBool
+}
+func (a *ModeBoolAgg) ValueBool() bool {
+	// false is the smallest value so it wins a tie.
+	return a.trues > a.falses
+}
+func (a *ModeBoolAgg) IsNull() bool {
+	return a.trues == 0 && a.falses == 0
+}
+
+// Err implements execute.ErrorValueFunc.
+func (a *ModeBoolAgg) Err() error {
+	return nil
+}
+
+// Close implements execute.Closer.
+func (a *ModeBoolAgg) Close() error {
+	return nil
+}
+
+type ModeIntAgg struct {
+	counts map[int64]int64
+	modeMemory
+}
+
+func (a *ModeIntAgg) DoInt(vs *array.Int) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		v := vs.Value(i)
+		if _, ok := a.counts[v]; !ok && !a.account(8+modeEntrySize) {
+			return
+		}
+		a.counts[v]++
+	}
+}
+func (a *ModeIntAgg) Type() flux.ColType {
+	return flux.TInt
+}
+func (a *ModeIntAgg) ValueInt() int64 {
+	var mode, max int64
+	for v, n := range a.counts {
+		if n > max || (n == max && v < mode) {
+			mode, max = v, n
+		}
+	}
+	return mode
+}
+func (a *ModeIntAgg) IsNull() bool {
+	return len(a.counts) == 0
+}
+
+type ModeUIntAgg struct {
+	counts map[uint64]int64
+	modeMemory
+}
+
+func (a *ModeUIntAgg) DoUInt(vs *array.Uint) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		v := vs.Value(i)
+		if _, ok := a.counts[v]; !ok && !a.account(8+modeEntrySize) {
+			return
+		}
+		a.counts[v]++
+	}
+}
+func (a *ModeUIntAgg) Type() flux.ColType {
+	return flux.TUInt
+}
+func (a *ModeUIntAgg) ValueUInt() uint64 {
+	var (
+		mode uint64
+		max  int64
+	)
+	for v, n := range a.counts {
+		if n > max || (n == max && v < mode) {
+			mode, max = v, n
+		}
+	}
+	return mode
+}
+func (a *ModeUIntAgg) IsNull() bool {
+	return len(a.counts) == 0
+}
+
+type ModeFloatAgg struct {
+	counts map[float64]int64
+	modeMemory
+}
+
+func (a *ModeFloatAgg) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		v := vs.Value(i)
+		if _, ok := a.counts[v]; !ok && !a.account(8+modeEntrySize) {
+			return
+		}
+		a.counts[v]++
+	}
+}
+func (a *ModeFloatAgg) Type() flux.ColType {
+	return flux.TFloat
+}
+func (a *ModeFloatAgg) ValueFloat() float64 {
+	var (
+		mode float64
+		max  int64
+	)
+	for v, n := range a.counts {
+		if n > max || (n == max && v < mode) {
+			mode, max = v, n
+		}
+	}
+	return mode
+}
+func (a *ModeFloatAgg) IsNull() bool {
+	return len(a.counts) == 0
+}
+
+type ModeStringAgg struct {
+	counts map[string]int64
+	modeMemory
+}
+
+func (a *ModeStringAgg) DoString(vs *array.String) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		v := vs.Value(i)
+		if _, ok := a.counts[v]; !ok && !a.account(16+len(v)+modeEntrySize) {
+			return
+		}
+		a.counts[v]++
+	}
+}
+func (a *ModeStringAgg) Type() flux.ColType {
+	return flux.TString
+}
+func (a *ModeStringAgg) ValueString() string {
+	var (
+		mode string
+		max  int64
+	)
+	for v, n := range a.counts {
+		if n > max || (n == max && v < mode) {
+			mode, max = v, n
+		}
+	}
+	return mode
+}
+func (a *ModeStringAgg) IsNull() bool {
+	return len(a.counts) == 0
+}
@@ -3,9 +3,15 @@ package universe_test
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/stdlib/universe"
 )
 
@@ -971,3 +977,268 @@ func TestMode_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestModeAgg_Process(t *testing.T) {
+	testCases := []struct {
+		name string
+		data func() *array.Float
+		want interface{}
+	}{
+		{
+			name: "mode",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{3, 1, 3, 2, 3, 1}, nil)
+			},
+			want: 3.0,
+		},
+		{
+			name: "tie",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{5, 2, 5, 2, 7}, nil)
+			},
+			want: 2.0,
+		},
+		{
+			name: "with nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendValues([]float64{4, 1}, nil)
+				b.AppendNull()
+				b.AppendNull()
+				b.AppendNull()
+				b.AppendValues([]float64{4}, nil)
+				return b.NewFloatArray()
+			},
+			want: 4.0,
+		},
+		{
+			name: "only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.AggFuncTestHelper(
+				t,
+				new(universe.ModeAgg),
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
+
+func TestModeAgg_Types(t *testing.T) {
+	agg := new(universe.ModeAgg)
+
+	ia := agg.NewIntAgg()
+	ia.DoInt(arrow.NewInt([]int64{-1, 4, 4, -1, 9}, nil))
+
+	ua := agg.NewUIntAgg()
+	ua.DoUInt(arrow.NewUint([]uint64{8, 2, 8}, nil))
+
+	sa := agg.NewStringAgg()
+	sa.DoString(arrow.NewString([]string{"b", "c", "a", "c", "a"}, nil))
+
+	ba := agg.NewBoolAgg()
+	ba.DoBool(arrow.NewBool([]bool{true, false, true, false}, nil))
+
+	got := []interface{}{
+		ia.(execute.IntValueFunc).ValueInt(),
+		ua.(execute.UIntValueFunc).ValueUInt(),
+		sa.(execute.StringValueFunc).ValueString(),
+		ba.(execute.BoolValueFunc).ValueBool(),
+	}
+	want := []interface{}{int64(-1), uint64(8), "a", false}
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected modes -want/+got:\n%s", cmp.Diff(want, got))
+	}
+
+	if !agg.NewIntAgg().IsNull() {
+		t.Error("expected mode of no values to be null")
+	}
+}
+
+func TestModeAgg_Transformation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.ModeAggProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "smallest mode",
+			spec: &universe.ModeAggProcedureSpec{Column: "_value"},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(7), "a"},
+					{execute.Time(2), int64(3), "a"},
+					{execute.Time(3), int64(7), "a"},
+					{execute.Time(4), int64(3), "a"},
+					{execute.Time(5), nil, "a"},
+				},
+			}, &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(9), "b"},
+				},
+			}},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"a", int64(3)},
+					},
+				},
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"b", int64(9)},
+					},
+				},
+			},
+		},
+		{
+			name: "time column",
+			spec: &universe.ModeAggProcedureSpec{Column: "_time"},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 1.0},
+					{execute.Time(1), 2.0},
+					{execute.Time(2), 3.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TTime},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2)},
+				},
+			}},
+		},
+		{
+			name: "only nulls",
+			spec: &universe.ModeAggProcedureSpec{Column: "_value"},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{nil},
+				},
+			}},
+		},
+		{
+			name: "missing column",
+			spec: &universe.ModeAggProcedureSpec{Column: "x"},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "x" does not exist`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewModeAggTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestModeAgg_Memory(t *testing.T) {
+	mem := &memory.Allocator{}
+	agg := universe.NewModeAgg(mem)
+
+	sa := agg.NewStringAgg()
+	sa.DoString(arrow.NewString([]string{"a", "bb", "a", "ccc"}, nil))
+	if mem.Allocated() == 0 {
+		t.Fatal("expected the counts of the values to be accounted for")
+	}
+	if err := sa.(execute.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected the memory of the counts to be released, got %d bytes", got)
+	}
+
+	// The aggregate reports an error once the limit is exceeded.
+	limit := int64(64)
+	mem = &memory.Allocator{Limit: &limit}
+	fa := universe.NewModeAgg(mem).NewFloatAgg()
+	vs := make([]float64, 100)
+	for i := range vs {
+		vs[i] = float64(i)
+	}
+	fa.DoFloat(arrow.NewFloat(vs, nil))
+	if err := fa.(execute.ErrorValueFunc).Err(); err == nil {
+		t.Fatal("expected the memory limit to be exceeded")
+	}
+	if err := fa.(execute.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected the memory of the counts to be released, got %d bytes", got)
+	}
+}
@@ -0,0 +1,319 @@
+package universe
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const (
+	MovingMinKind = "movingMin"
+	MovingMaxKind = "movingMax"
+)
+
+// MovingExtremeOpSpec computes the minimum or maximum
+// value over the trailing n rows of each row.
+type MovingExtremeOpSpec struct {
+	N       int64  `json:"n"`
+	Column  string `json:"column"`
+	Partial bool   `json:"partial"`
+	Max     bool   `json:"max"`
+}
+
+func init() {
+	movingMinSignature := runtime.MustLookupBuiltinType("universe", "movingMin")
+	movingMaxSignature := runtime.MustLookupBuiltinType("universe", "movingMax")
+
+	runtime.RegisterPackageValue("universe", MovingMinKind, flux.MustValue(flux.FunctionValue(MovingMinKind, createMovingExtremeOpSpec(false), movingMinSignature)))
+	runtime.RegisterPackageValue("universe", MovingMaxKind, flux.MustValue(flux.FunctionValue(MovingMaxKind, createMovingExtremeOpSpec(true), movingMaxSignature)))
+	flux.RegisterOpSpec(MovingMinKind, newMovingExtremeOp(false))
+	flux.RegisterOpSpec(MovingMaxKind, newMovingExtremeOp(true))
+	plan.RegisterProcedureSpec(MovingMinKind, newMovingExtremeProcedure, MovingMinKind)
+	plan.RegisterProcedureSpec(MovingMaxKind, newMovingExtremeProcedure, MovingMaxKind)
+	execute.RegisterTransformation(MovingMinKind, createMovingExtremeTransformation)
+	execute.RegisterTransformation(MovingMaxKind, createMovingExtremeTransformation)
+}
+
+func createMovingExtremeOpSpec(max bool) func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	return func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+		if err := a.AddParentFromArgs(args); err != nil {
+			return nil, err
+		}
+
+		spec := &MovingExtremeOpSpec{Max: max}
+		if n, err := args.GetRequiredInt("n"); err != nil {
+			return nil, err
+		} else if n <= 0 {
+			return nil, errors.Newf(codes.Invalid, "cannot take moving extreme with a period of %v (must be greater than 0)", n)
+		} else {
+			spec.N = n
+		}
+
+		if col, ok, err := args.GetString("column"); err != nil {
+			return nil, err
+		} else if ok {
+			spec.Column = col
+		} else {
+			spec.Column = execute.DefaultValueColLabel
+		}
+
+		if partial, ok, err := args.GetBool("partial"); err != nil {
+			return nil, err
+		} else if ok {
+			spec.Partial = partial
+		}
+		return spec, nil
+	}
+}
+
+func newMovingExtremeOp(max bool) func() flux.OperationSpec {
+	return func() flux.OperationSpec {
+		return &MovingExtremeOpSpec{Max: max}
+	}
+}
+
+func (s *MovingExtremeOpSpec) Kind() flux.OperationKind {
+	if s.Max {
+		return MovingMaxKind
+	}
+	return MovingMinKind
+}
+
+type MovingExtremeProcedureSpec struct {
+	plan.DefaultCost
+	N       int64  `json:"n"`
+	Column  string `json:"column"`
+	Partial bool   `json:"partial"`
+	Max     bool   `json:"max"`
+}
+
+func newMovingExtremeProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MovingExtremeOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MovingExtremeProcedureSpec{
+		N:       spec.N,
+		Column:  spec.Column,
+		Partial: spec.Partial,
+		Max:     spec.Max,
+	}, nil
+}
+
+func (s *MovingExtremeProcedureSpec) Kind() plan.ProcedureKind {
+	if s.Max {
+		return MovingMaxKind
+	}
+	return MovingMinKind
+}
+
+func (s *MovingExtremeProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *MovingExtremeProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createMovingExtremeTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*MovingExtremeProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewMovingExtremeTransformation(id, s, a.Allocator())
+}
+
+type movingExtremeTransformation struct {
+	n       int64
+	column  string
+	partial bool
+	max     bool
+}
+
+// NewMovingExtremeTransformation creates a transformation that replaces
+// the value of each row with the minimum or maximum non-null value of
+// the trailing n rows, including the row itself.
+//
+// The candidates for the extreme are kept in a monotonic deque that is
+// retained across buffers of the same table, so each row is processed
+// in amortized constant time. Rows before the window has filled are null
+// unless partial is set, in which case they use the rows seen so far.
+// Null and NaN values are not candidates but still occupy a row of the
+// window, so the output is null if the window only contains such values.
+func NewMovingExtremeTransformation(id execute.DatasetID, spec *MovingExtremeProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &movingExtremeTransformation{
+		n:       spec.N,
+		column:  spec.Column,
+		partial: spec.Partial,
+		max:     spec.Max,
+	}
+	return execute.NewNarrowStateTransformation(id, t, mem)
+}
+
+// movingExtremeEntry is a candidate for the extreme
+// of a window along with the index of its row.
+type movingExtremeEntry struct {
+	idx int64
+	i   int64
+	u   uint64
+	f   float64
+}
+
+type movingExtremeState struct {
+	typ flux.ColType
+	// count is the number of rows that have been read.
+	count int64
+	// deque holds the candidates ordered by row index starting
+	// at head. Their values are monotonic so the front is the extreme.
+	deque []movingExtremeEntry
+	head  int
+}
+
+// push adds a value to the back of the deque after removing
+// every candidate that it replaces as the extreme.
+func (s *movingExtremeState) push(e movingExtremeEntry, replaces func(e, back movingExtremeEntry) bool) {
+	for len(s.deque) > s.head && replaces(e, s.deque[len(s.deque)-1]) {
+		s.deque = s.deque[:len(s.deque)-1]
+	}
+	s.deque = append(s.deque, e)
+}
+
+// evict removes the candidates that are no longer within the window
+// ending at the last row that was read.
+func (s *movingExtremeState) evict(n int64) {
+	for s.head < len(s.deque) && s.deque[s.head].idx <= s.count-1-n {
+		s.head++
+	}
+	// Reclaim the space before the head once it is
+	// at least half of the deque.
+	if s.head > 0 && s.head >= len(s.deque)/2 {
+		s.deque = append(s.deque[:0], s.deque[s.head:]...)
+		s.head = 0
+	}
+}
+
+// front returns the current extreme.
+func (s *movingExtremeState) front() movingExtremeEntry {
+	return s.deque[s.head]
+}
+
+// ready reports whether the front of the deque should be emitted.
+func (t *movingExtremeTransformation) ready(s *movingExtremeState) bool {
+	return len(s.deque) > s.head && (t.partial || s.count >= t.n)
+}
+
+func (t *movingExtremeTransformation) Process(chunk table.Chunk, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	typ := chunk.Col(idx).Type
+
+	var s *movingExtremeState
+	if state != nil {
+		s = state.(*movingExtremeState)
+		if s.typ != typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q changed type from %s to %s", t.column, s.typ, typ)
+		}
+	} else {
+		s = &movingExtremeState{typ: typ}
+	}
+
+	n := chunk.Len()
+	var vs array.Array
+	switch typ {
+	case flux.TInt, flux.TTime:
+		in := chunk.Ints(idx)
+		replaces := func(e, back movingExtremeEntry) bool {
+			return (t.max && e.i >= back.i) || (!t.max && e.i <= back.i)
+		}
+		b := arrowutil.NewIntBuilder(mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			if in.IsValid(i) {
+				s.push(movingExtremeEntry{idx: s.count, i: in.Value(i)}, replaces)
+			}
+			s.count++
+			s.evict(t.n)
+			if t.ready(s) {
+				b.Append(s.front().i)
+			} else {
+				b.AppendNull()
+			}
+		}
+		vs = b.NewArray()
+	case flux.TUInt:
+		in := chunk.Uints(idx)
+		replaces := func(e, back movingExtremeEntry) bool {
+			return (t.max && e.u >= back.u) || (!t.max && e.u <= back.u)
+		}
+		b := arrowutil.NewUintBuilder(mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			if in.IsValid(i) {
+				s.push(movingExtremeEntry{idx: s.count, u: in.Value(i)}, replaces)
+			}
+			s.count++
+			s.evict(t.n)
+			if t.ready(s) {
+				b.Append(s.front().u)
+			} else {
+				b.AppendNull()
+			}
+		}
+		vs = b.NewArray()
+	case flux.TFloat:
+		in := chunk.Floats(idx)
+		replaces := func(e, back movingExtremeEntry) bool {
+			return (t.max && e.f >= back.f) || (!t.max && e.f <= back.f)
+		}
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			if in.IsValid(i) && !math.IsNaN(in.Value(i)) {
+				s.push(movingExtremeEntry{idx: s.count, f: in.Value(i)}, replaces)
+			}
+			s.count++
+			s.evict(t.n)
+			if t.ready(s) {
+				b.Append(s.front().f)
+			} else {
+				b.AppendNull()
+			}
+		}
+		vs = b.NewArray()
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the moving extreme of column %q of type %s", t.column, typ)
+	}
+
+	buffer := chunk.Buffer()
+	values := make([]array.Array, len(buffer.Values))
+	for j := range values {
+		if j == idx {
+			values[j] = vs
+			continue
+		}
+		values[j] = buffer.Values[j]
+		values[j].Retain()
+	}
+	buffer.Values = values
+	if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *movingExtremeTransformation) Close() error {
+	return nil
+}
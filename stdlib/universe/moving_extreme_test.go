@@ -0,0 +1,175 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestMovingExtreme_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.RowWiseTable{
+			Table: &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(4), "a"},
+					{execute.Time(2), int64(1), "a"},
+					{execute.Time(3), int64(3), "a"},
+					{execute.Time(4), int64(5), "a"},
+					{execute.Time(5), int64(2), "a"},
+					{execute.Time(6), int64(0), "a"},
+				},
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name string
+		spec *universe.MovingExtremeProcedureSpec
+		data []flux.Table
+		want []*executetest.Table
+	}{
+		{
+			name: "min",
+			spec: &universe.MovingExtremeProcedureSpec{
+				N:      3,
+				Column: "_value",
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil, "a"},
+					{execute.Time(2), nil, "a"},
+					{execute.Time(3), int64(1), "a"},
+					{execute.Time(4), int64(1), "a"},
+					{execute.Time(5), int64(2), "a"},
+					{execute.Time(6), int64(0), "a"},
+				},
+			}},
+		},
+		{
+			name: "max partial",
+			spec: &universe.MovingExtremeProcedureSpec{
+				N:       3,
+				Column:  "_value",
+				Partial: true,
+				Max:     true,
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(4), "a"},
+					{execute.Time(2), int64(4), "a"},
+					{execute.Time(3), int64(4), "a"},
+					{execute.Time(4), int64(5), "a"},
+					{execute.Time(5), int64(5), "a"},
+					{execute.Time(6), int64(5), "a"},
+				},
+			}},
+		},
+		{
+			name: "nulls and NaN",
+			spec: &universe.MovingExtremeProcedureSpec{
+				N:      2,
+				Column: "_value",
+				Max:    true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.5},
+					{execute.Time(2), nil},
+					{execute.Time(3), math.NaN()},
+					{execute.Time(4), -2.0},
+					{execute.Time(5), -3.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), 1.5},
+					{execute.Time(3), nil},
+					{execute.Time(4), -2.0},
+					{execute.Time(5), -2.0},
+				},
+			}},
+		},
+		{
+			name: "time column",
+			spec: &universe.MovingExtremeProcedureSpec{
+				N:      2,
+				Column: "_time",
+				Max:    true,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(3), uint64(1)},
+					{execute.Time(1), uint64(2)},
+					{execute.Time(2), uint64(3)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{nil, uint64(1)},
+					{execute.Time(3), uint64(2)},
+					{execute.Time(2), uint64(3)},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewMovingExtremeTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package universe
+
+import (
+	"math"
+	"sort"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/tdigest"
+)
+
+const MovingQuantileKind = "movingQuantile"
+
+// MovingQuantileOpSpec computes the quantile
+// of the trailing n rows of each row.
+type MovingQuantileOpSpec struct {
+	N           int64   `json:"n"`
+	Quantile    float64 `json:"quantile"`
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	Column      string  `json:"column"`
+}
+
+func init() {
+	movingQuantileSignature := runtime.MustLookupBuiltinType("universe", "movingQuantile")
+
+	runtime.RegisterPackageValue("universe", MovingQuantileKind, flux.MustValue(flux.FunctionValue(MovingQuantileKind, createMovingQuantileOpSpec, movingQuantileSignature)))
+	flux.RegisterOpSpec(MovingQuantileKind, newMovingQuantileOp)
+	plan.RegisterProcedureSpec(MovingQuantileKind, newMovingQuantileProcedure, MovingQuantileKind)
+	execute.RegisterTransformation(MovingQuantileKind, createMovingQuantileTransformation)
+}
+
+func createMovingQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(MovingQuantileOpSpec)
+	if n, err := args.GetRequiredInt("n"); err != nil {
+		return nil, err
+	} else if n <= 0 {
+		return nil, errors.Newf(codes.Invalid, "cannot take moving quantile with a period of %v (must be greater than 0)", n)
+	} else {
+		spec.N = n
+	}
+
+	if q, err := args.GetRequiredFloat("q"); err != nil {
+		return nil, err
+	} else if q < 0 || q > 1 {
+		return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+	} else {
+		spec.Quantile = q
+	}
+
+	if m, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		switch m {
+		case methodEstimateTdigest, methodExactMean, methodExactSelector:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown method %q, expected %q, %q, or %q", m, methodEstimateTdigest, methodExactMean, methodExactSelector)
+		}
+		spec.Method = m
+	} else {
+		spec.Method = defaultMethod
+	}
+
+	if c, ok, err := args.GetFloat("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if spec.Method != methodEstimateTdigest {
+			return nil, errors.New(codes.Invalid, "compression parameter is only valid for method estimate_tdigest")
+		}
+		if c <= 0 {
+			return nil, errors.New(codes.Invalid, "compression must be greater than 0")
+		}
+		spec.Compression = c
+	} else if spec.Method == methodEstimateTdigest {
+		spec.Compression = 1000
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	return spec, nil
+}
+
+func newMovingQuantileOp() flux.OperationSpec {
+	return new(MovingQuantileOpSpec)
+}
+
+func (s *MovingQuantileOpSpec) Kind() flux.OperationKind {
+	return MovingQuantileKind
+}
+
+type MovingQuantileProcedureSpec struct {
+	plan.DefaultCost
+	N           int64   `json:"n"`
+	Quantile    float64 `json:"quantile"`
+	Method      string  `json:"method"`
+	Compression float64 `json:"compression"`
+	Column      string  `json:"column"`
+}
+
+func newMovingQuantileProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MovingQuantileOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MovingQuantileProcedureSpec{
+		N:           spec.N,
+		Quantile:    spec.Quantile,
+		Method:      spec.Method,
+		Compression: spec.Compression,
+		Column:      spec.Column,
+	}, nil
+}
+
+func (s *MovingQuantileProcedureSpec) Kind() plan.ProcedureKind {
+	return MovingQuantileKind
+}
+
+func (s *MovingQuantileProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *MovingQuantileProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createMovingQuantileTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*MovingQuantileProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewMovingQuantileTransformation(id, s, a.Allocator())
+}
+
+type movingQuantileTransformation struct {
+	n           int64
+	quantile    float64
+	method      string
+	compression float64
+	column      string
+}
+
+// NewMovingQuantileTransformation creates a transformation that replaces
+// the value of each row with the quantile of the trailing n rows, including
+// the row itself. The output value is always a float.
+//
+// The window is kept in a ring buffer that is retained across buffers of
+// the same table. Rows before the window has filled are null. Null and NaN
+// values are left out of the quantile but still occupy a row of the window,
+// so the output is null if the window only contains such values.
+//
+// The exact methods keep the values of the window in a sorted slice that
+// is updated as rows enter and leave the window, so they hold 2n values.
+// The estimate_tdigest method cannot remove a value from a digest. It splits
+// the rows into blocks and keeps a digest of every complete block within the
+// window. The quantile of a row merges those digests with the values of the
+// blocks that are only partly within the window, so it holds the n values
+// of the ring buffer and about n/b digests, where b is the block size.
+func NewMovingQuantileTransformation(id execute.DatasetID, spec *MovingQuantileProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &movingQuantileTransformation{
+		n:           spec.N,
+		quantile:    spec.Quantile,
+		method:      spec.Method,
+		compression: spec.Compression,
+		column:      spec.Column,
+	}
+	return execute.NewNarrowStateTransformation(id, t, mem)
+}
+
+// movingQuantileWindow computes the quantile of a sliding window.
+type movingQuantileWindow interface {
+	// push adds a row to the window in place of the oldest row.
+	push(v float64, ok bool)
+	// value returns the quantile of the window.
+	value() (float64, bool)
+}
+
+type movingQuantileState struct {
+	typ    flux.ColType
+	window movingQuantileWindow
+}
+
+func (t *movingQuantileTransformation) newWindow() movingQuantileWindow {
+	ring := movingQuantileRing{
+		values: make([]float64, t.n),
+		valid:  make([]bool, t.n),
+	}
+	if t.method == methodEstimateTdigest {
+		// The cost of a row is about n/b merged digests of at most
+		// compression centroids each plus 2b values, which is lowest
+		// when the block size is the square root of n times compression.
+		b := int64(math.Ceil(math.Sqrt(float64(t.n) * t.compression / 2)))
+		if b > t.n {
+			b = t.n
+		}
+		return &movingQuantileTDigest{
+			movingQuantileRing: ring,
+			quantile:           t.quantile,
+			compression:        t.compression,
+			blockSize:          b,
+			scratch:            tdigest.NewWithCompression(t.compression),
+		}
+	}
+	return &movingQuantileExact{
+		movingQuantileRing: ring,
+		quantile:           t.quantile,
+		selector:           t.method == methodExactSelector,
+	}
+}
+
+// movingQuantileRing is a ring buffer of the rows of the window.
+type movingQuantileRing struct {
+	values []float64
+	valid  []bool
+	// count is the number of rows that have been read.
+	count int64
+}
+
+// at returns the row with the given index. The row must be within the window.
+func (r *movingQuantileRing) at(idx int64) (float64, bool) {
+	i := idx % int64(len(r.values))
+	return r.values[i], r.valid[i]
+}
+
+// replace stores a row in place of the oldest row and returns the oldest row.
+// The returned row is not valid if the window has not filled.
+func (r *movingQuantileRing) replace(v float64, ok bool) (float64, bool) {
+	i := r.count % int64(len(r.values))
+	old, oldOk := r.values[i], r.valid[i]
+	r.values[i], r.valid[i] = v, ok
+	r.count++
+	return old, oldOk
+}
+
+func (r *movingQuantileRing) full() bool {
+	return r.count >= int64(len(r.values))
+}
+
+// movingQuantileExact keeps the valid values of the window sorted.
+type movingQuantileExact struct {
+	movingQuantileRing
+	quantile float64
+	selector bool
+	sorted   []float64
+}
+
+func (w *movingQuantileExact) push(v float64, ok bool) {
+	ok = ok && !math.IsNaN(v)
+	if old, oldOk := w.replace(v, ok); oldOk {
+		i := sort.SearchFloat64s(w.sorted, old)
+		w.sorted = append(w.sorted[:i], w.sorted[i+1:]...)
+	}
+	if ok {
+		i := sort.SearchFloat64s(w.sorted, v)
+		w.sorted = append(w.sorted, 0)
+		copy(w.sorted[i+1:], w.sorted[i:])
+		w.sorted[i] = v
+	}
+}
+
+func (w *movingQuantileExact) value() (float64, bool) {
+	n := len(w.sorted)
+	if !w.full() || n == 0 {
+		return 0, false
+	}
+	if w.selector {
+		return w.sorted[getQuantileIndex(w.quantile, n)], true
+	}
+	x := w.quantile * float64(n-1)
+	x0, x1 := math.Floor(x), math.Ceil(x)
+	y0, y1 := w.sorted[int(x0)], w.sorted[int(x1)]
+	if x0 == x1 {
+		return y0, true
+	}
+	return y0*(x1-x) + y1*(x-x0), true
+}
+
+// movingQuantileBlock is the digest of the valid values
+// of a complete block of rows.
+type movingQuantileBlock struct {
+	start  int64
+	digest *tdigest.TDigest
+}
+
+// movingQuantileTDigest estimates the quantile of the window from the
+// digests of the blocks of rows that are entirely within the window.
+// Blocks start at multiples of the block size.
+type movingQuantileTDigest struct {
+	movingQuantileRing
+	quantile    float64
+	compression float64
+	blockSize   int64
+	// blocks are the complete blocks that overlap the window
+	// ordered by their first row.
+	blocks []movingQuantileBlock
+	// current is the digest of the block that is being read.
+	current *tdigest.TDigest
+	scratch *tdigest.TDigest
+}
+
+func (w *movingQuantileTDigest) push(v float64, ok bool) {
+	ok = ok && !math.IsNaN(v)
+	w.replace(v, ok)
+
+	if w.current == nil {
+		w.current = tdigest.NewWithCompression(w.compression)
+	}
+	if ok {
+		w.current.Add(v, 1)
+	}
+	if w.count%w.blockSize == 0 {
+		w.blocks = append(w.blocks, movingQuantileBlock{
+			start:  w.count - w.blockSize,
+			digest: w.current,
+		})
+		w.current = nil
+	}
+
+	// Drop the blocks whose first row has left the window.
+	start := w.count - int64(len(w.values))
+	i := 0
+	for i < len(w.blocks) && w.blocks[i].start < start {
+		i++
+	}
+	if i > 0 {
+		w.blocks = append(w.blocks[:0], w.blocks[i:]...)
+	}
+}
+
+func (w *movingQuantileTDigest) value() (float64, bool) {
+	if !w.full() {
+		return 0, false
+	}
+	w.scratch.Reset()
+
+	// The window starts with the rows before the first complete
+	// block and ends with the rows of the block that is being read.
+	start, end := w.count-int64(len(w.values)), w.count
+	if len(w.blocks) > 0 {
+		first, last := w.blocks[0], w.blocks[len(w.blocks)-1]
+		w.addRows(start, first.start)
+		for _, b := range w.blocks {
+			w.scratch.Merge(b.digest)
+		}
+		w.addRows(last.start+w.blockSize, end)
+	} else {
+		w.addRows(start, end)
+	}
+
+	if w.scratch.Count() == 0 {
+		return 0, false
+	}
+	return w.scratch.Quantile(w.quantile), true
+}
+
+// addRows adds the valid values of the rows in [from, to) to the scratch digest.
+func (w *movingQuantileTDigest) addRows(from, to int64) {
+	for idx := from; idx < to; idx++ {
+		if v, ok := w.at(idx); ok {
+			w.scratch.Add(v, 1)
+		}
+	}
+}
+
+func (t *movingQuantileTransformation) Process(chunk table.Chunk, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	typ := chunk.Col(idx).Type
+
+	var s *movingQuantileState
+	if state != nil {
+		s = state.(*movingQuantileState)
+		if s.typ != typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q changed type from %s to %s", t.column, s.typ, typ)
+		}
+	} else {
+		s = &movingQuantileState{
+			typ:    typ,
+			window: t.newWindow(),
+		}
+	}
+
+	var value func(i int) (float64, bool)
+	switch typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		value = func(i int) (float64, bool) {
+			return vs.Value(i), vs.IsValid(i)
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the moving quantile of column %q of type %s", t.column, typ)
+	}
+
+	n := chunk.Len()
+	b := arrowutil.NewFloatBuilder(mem)
+	b.Resize(n)
+	for i := 0; i < n; i++ {
+		s.window.push(value(i))
+		if q, ok := s.window.value(); ok {
+			b.Append(q)
+		} else {
+			b.AppendNull()
+		}
+	}
+
+	buffer := chunk.Buffer()
+	cols := make([]flux.ColMeta, len(buffer.Columns))
+	copy(cols, buffer.Columns)
+	cols[idx].Type = flux.TFloat
+	vs := make([]array.Array, len(buffer.Values))
+	for j := range vs {
+		if j == idx {
+			vs[j] = b.NewArray()
+			continue
+		}
+		vs[j] = buffer.Values[j]
+		vs[j].Retain()
+	}
+	buffer.Columns, buffer.Values = cols, vs
+	if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *movingQuantileTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,167 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestMovingQuantile_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.RowWiseTable{
+			Table: &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(5), "a"},
+					{execute.Time(2), int64(1), "a"},
+					{execute.Time(3), int64(3), "a"},
+					{execute.Time(4), int64(9), "a"},
+					{execute.Time(5), int64(2), "a"},
+					{execute.Time(6), int64(7), "a"},
+				},
+			},
+		}}
+	}
+	want := func(vs ...interface{}) []*executetest.Table {
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "t0", Type: flux.TString},
+			},
+		}
+		for i, v := range vs {
+			tbl.Data = append(tbl.Data, []interface{}{execute.Time(i + 1), v, "a"})
+		}
+		return []*executetest.Table{tbl}
+	}
+
+	testCases := []struct {
+		name    string
+		spec    *universe.MovingQuantileProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "exact mean",
+			spec: &universe.MovingQuantileProcedureSpec{
+				N:        3,
+				Quantile: 0.75,
+				Method:   "exact_mean",
+				Column:   "_value",
+			},
+			data: data(),
+			want: want(nil, nil, 4.0, 6.0, 6.0, 8.0),
+		},
+		{
+			name: "exact selector",
+			spec: &universe.MovingQuantileProcedureSpec{
+				N:        3,
+				Quantile: 0.75,
+				Method:   "exact_selector",
+				Column:   "_value",
+			},
+			data: data(),
+			want: want(nil, nil, 5.0, 9.0, 9.0, 9.0),
+		},
+		{
+			name: "estimate tdigest",
+			spec: &universe.MovingQuantileProcedureSpec{
+				N:           3,
+				Quantile:    0.5,
+				Method:      "estimate_tdigest",
+				Compression: 1000,
+				Column:      "_value",
+			},
+			data: data(),
+			want: want(nil, nil, 3.0, 3.0, 3.0, 7.0),
+		},
+		{
+			name: "nulls",
+			spec: &universe.MovingQuantileProcedureSpec{
+				N:        2,
+				Quantile: 0.5,
+				Method:   "exact_mean",
+				Column:   "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), nil},
+					{execute.Time(3), 4.0},
+					{execute.Time(4), 8.0},
+					{execute.Time(5), nil},
+					{execute.Time(6), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), 2.0},
+					{execute.Time(3), 4.0},
+					{execute.Time(4), 6.0},
+					{execute.Time(5), 8.0},
+					{execute.Time(6), nil},
+				},
+			}},
+		},
+		{
+			name: "unsupported type",
+			spec: &universe.MovingQuantileProcedureSpec{
+				N:        1,
+				Quantile: 0.5,
+				Method:   "exact_mean",
+				Column:   "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "a"},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `cannot compute the moving quantile of column "_value" of type string`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewMovingQuantileTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package universe
+
+import (
+	"math"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ProductKind = "product"
+
+const (
+	// productMethodDirect multiplies the values together.
+	productMethodDirect = "direct"
+	// productMethodLog sums the logarithms of the values and
+	// exponentiates the result to avoid intermediate overflow
+	// and underflow.
+	productMethodLog = "log"
+)
+
+type ProductOpSpec struct {
+	Method string `json:"method"`
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	productSignature := runtime.MustLookupBuiltinType("universe", "product")
+
+	runtime.RegisterPackageValue("universe", ProductKind, flux.MustValue(flux.FunctionValue(ProductKind, CreateProductOpSpec, productSignature)))
+	flux.RegisterOpSpec(ProductKind, newProductOp)
+	plan.RegisterProcedureSpec(ProductKind, newProductProcedure, ProductKind)
+	execute.RegisterTransformation(ProductKind, createProductTransformation)
+}
+
+func CreateProductOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	s := new(ProductOpSpec)
+	if method, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		switch method {
+		case productMethodDirect, productMethodLog:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown method %q, expected %q or %q", method, productMethodDirect, productMethodLog)
+		}
+		s.Method = method
+	} else {
+		s.Method = productMethodDirect
+	}
+
+	if err := s.SimpleAggregateConfig.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newProductOp() flux.OperationSpec {
+	return new(ProductOpSpec)
+}
+
+func (s *ProductOpSpec) Kind() flux.OperationKind {
+	return ProductKind
+}
+
+type ProductProcedureSpec struct {
+	Method string `json:"method"`
+	execute.SimpleAggregateConfig
+}
+
+func newProductProcedure(qs flux.OperationSpec, a plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ProductOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ProductProcedureSpec{
+		Method:                spec.Method,
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *ProductProcedureSpec) Kind() plan.ProcedureKind {
+	return ProductKind
+}
+
+func (s *ProductProcedureSpec) Copy() plan.ProcedureSpec {
+	return &ProductProcedureSpec{
+		Method:                s.Method,
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ProductProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func (s *ProductProcedureSpec) AggregateMethod() string {
+	return ProductKind
+}
+func (s *ProductProcedureSpec) ReAggregateSpec() plan.ProcedureSpec {
+	return &ProductProcedureSpec{Method: s.Method}
+}
+
+func createProductTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ProductProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	agg := &ProductAgg{Log: s.Method == productMethodLog}
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, s.SimpleAggregateConfig, a.Allocator())
+}
+
+// ProductAgg computes the product of the non-null values as a float.
+// If Log is set, the product is computed from the sum of the
+// logarithms of the magnitudes of the values.
+type ProductAgg struct {
+	Log bool
+}
+
+func (a *ProductAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+func (a *ProductAgg) NewIntAgg() execute.DoIntAgg {
+	return a.newState()
+}
+func (a *ProductAgg) NewUIntAgg() execute.DoUIntAgg {
+	return a.newState()
+}
+func (a *ProductAgg) NewFloatAgg() execute.DoFloatAgg {
+	return a.newState()
+}
+func (a *ProductAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+func (a *ProductAgg) newState() *ProductAggState {
+	return &ProductAggState{log: a.Log, product: 1}
+}
+
+type ProductAggState struct {
+	log bool
+	ok  bool
+
+	// product is the running product for the direct method.
+	product float64
+
+	// logSum is the sum of the logarithms of the magnitudes
+	// for the log method. The sign of the product is tracked
+	// separately along with whether any value was zero.
+	logSum   float64
+	negative bool
+	zero     bool
+}
+
+func (s *ProductAggState) add(v float64) {
+	s.ok = true
+	if !s.log {
+		s.product *= v
+		return
+	}
+	switch {
+	case v == 0:
+		s.zero = true
+	case math.IsNaN(v):
+		s.logSum = math.NaN()
+	default:
+		if v < 0 {
+			s.negative = !s.negative
+		}
+		s.logSum += math.Log(math.Abs(v))
+	}
+}
+
+func (s *ProductAggState) DoInt(vs *array.Int) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.add(float64(vs.Value(i)))
+		}
+	}
+}
+
+func (s *ProductAggState) DoUInt(vs *array.Uint) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.add(float64(vs.Value(i)))
+		}
+	}
+}
+
+func (s *ProductAggState) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.add(vs.Value(i))
+		}
+	}
+}
+
+func (s *ProductAggState) Type() flux.ColType {
+	return flux.TFloat
+}
+
+func (s *ProductAggState) ValueFloat() float64 {
+	if !s.log {
+		return s.product
+	}
+	if math.IsNaN(s.logSum) {
+		return s.logSum
+	} else if s.zero {
+		return 0
+	}
+	product := math.Exp(s.logSum)
+	if s.negative {
+		product = -product
+	}
+	return product
+}
+
+func (s *ProductAggState) IsNull() bool {
+	return !s.ok
+}
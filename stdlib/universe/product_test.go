@@ -0,0 +1,102 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestProduct_Process(t *testing.T) {
+	testCases := []struct {
+		name string
+		log  bool
+		data func() *array.Float
+		want interface{}
+	}{
+		{
+			name: "nonzero",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, -5}, nil)
+			},
+			want: -120.0,
+		},
+		{
+			name: "zero",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 0, 4}, nil)
+			},
+			want: 0.0,
+		},
+		{
+			name: "empty",
+			data: func() *array.Float {
+				return arrow.NewFloat(nil, nil)
+			},
+			want: nil,
+		},
+		{
+			name: "with nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendValues([]float64{2, 3}, nil)
+				b.AppendNull()
+				b.AppendValues([]float64{4}, nil)
+				return b.NewFloatArray()
+			},
+			want: 24.0,
+		},
+		{
+			name: "only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+		{
+			name: "overflow",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1e200, 1e200, 1e-300}, nil)
+			},
+			want: math.Inf(1),
+		},
+		{
+			name: "log overflow",
+			log:  true,
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1e200, -1e200, 1e-300}, nil)
+			},
+			want: -math.Exp(math.Log(1e200) + math.Log(1e200) + math.Log(1e-300)),
+		},
+		{
+			name: "log zero",
+			log:  true,
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1e200, 0, 1e200}, nil)
+			},
+			want: 0.0,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.data()
+			defer data.Release()
+
+			executetest.AggFuncTestHelper(
+				t,
+				&universe.ProductAgg{Log: tc.log},
+				data,
+				tc.want,
+			)
+		})
+	}
+}
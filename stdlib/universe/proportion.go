@@ -0,0 +1,308 @@
+package universe
+
+import (
+	"math"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+const ProportionKind = "proportion"
+
+// ProportionOpSpec computes the fraction of non-null values
+// that satisfy a comparison against a threshold.
+type ProportionOpSpec struct {
+	Op        string       `json:"op"`
+	Threshold values.Value `json:"threshold"`
+	execute.SimpleAggregateConfig
+}
+
+func init() {
+	proportionSignature := runtime.MustLookupBuiltinType("universe", "proportion")
+
+	runtime.RegisterPackageValue("universe", ProportionKind, flux.MustValue(flux.FunctionValue(ProportionKind, CreateProportionOpSpec, proportionSignature)))
+	flux.RegisterOpSpec(ProportionKind, newProportionOp)
+	plan.RegisterProcedureSpec(ProportionKind, newProportionProcedure, ProportionKind)
+	execute.RegisterTransformation(ProportionKind, createProportionTransformation)
+}
+
+func CreateProportionOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(ProportionOpSpec)
+	op, err := args.GetRequiredString("op")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := proportionOps[op]; !ok {
+		return nil, errors.Newf(codes.Invalid, "unknown comparison operator %q", op)
+	}
+	spec.Op = op
+
+	threshold, err := args.GetRequired("threshold")
+	if err != nil {
+		return nil, err
+	}
+	switch n := threshold.Type().Nature(); n {
+	case semantic.Int, semantic.UInt, semantic.Float:
+	default:
+		return nil, errors.Newf(codes.Invalid, "threshold must be an int, uint, or float, got %v", n)
+	}
+	spec.Threshold = threshold
+
+	if err := spec.SimpleAggregateConfig.ReadArgs(args); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func newProportionOp() flux.OperationSpec {
+	return new(ProportionOpSpec)
+}
+
+func (s *ProportionOpSpec) Kind() flux.OperationKind {
+	return ProportionKind
+}
+
+type ProportionProcedureSpec struct {
+	Op        string       `json:"op"`
+	Threshold values.Value `json:"threshold"`
+	execute.SimpleAggregateConfig
+}
+
+func newProportionProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ProportionOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ProportionProcedureSpec{
+		Op:                    spec.Op,
+		Threshold:             spec.Threshold,
+		SimpleAggregateConfig: spec.SimpleAggregateConfig,
+	}, nil
+}
+
+func (s *ProportionProcedureSpec) Kind() plan.ProcedureKind {
+	return ProportionKind
+}
+
+func (s *ProportionProcedureSpec) Copy() plan.ProcedureSpec {
+	return &ProportionProcedureSpec{
+		Op:                    s.Op,
+		Threshold:             s.Threshold,
+		SimpleAggregateConfig: s.SimpleAggregateConfig.Copy(),
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ProportionProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createProportionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ProportionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	agg, err := NewProportionAgg(s.Op, s.Threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, s.SimpleAggregateConfig, a.Allocator())
+}
+
+// proportionOps maps each comparison operator to a function that
+// reports whether the result of comparing a value with the
+// threshold satisfies the operator.
+var proportionOps = map[string]func(cmp int) bool{
+	"==": func(cmp int) bool { return cmp == 0 },
+	"!=": func(cmp int) bool { return cmp != 0 },
+	"<":  func(cmp int) bool { return cmp < 0 },
+	"<=": func(cmp int) bool { return cmp <= 0 },
+	">":  func(cmp int) bool { return cmp > 0 },
+	">=": func(cmp int) bool { return cmp >= 0 },
+}
+
+// ProportionAgg computes the fraction of non-null values that
+// satisfy a comparison against a threshold.
+type ProportionAgg struct {
+	op        string
+	satisfies func(cmp int) bool
+	threshold values.Value
+}
+
+func NewProportionAgg(op string, threshold values.Value) (*ProportionAgg, error) {
+	satisfies, ok := proportionOps[op]
+	if !ok {
+		return nil, errors.Newf(codes.Invalid, "unknown comparison operator %q", op)
+	}
+	return &ProportionAgg{
+		op:        op,
+		satisfies: satisfies,
+		threshold: threshold,
+	}, nil
+}
+
+func (a *ProportionAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *ProportionAgg) NewIntAgg() execute.DoIntAgg {
+	return &ProportionAggState{agg: a}
+}
+
+func (a *ProportionAgg) NewUIntAgg() execute.DoUIntAgg {
+	return &ProportionAggState{agg: a}
+}
+
+func (a *ProportionAgg) NewFloatAgg() execute.DoFloatAgg {
+	return &ProportionAggState{agg: a}
+}
+
+func (a *ProportionAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+// compareInt compares the value with the threshold and returns
+// -1, 0, or 1 if the value is less than, equal to, or greater than
+// the threshold. False is returned if the values are not comparable.
+func (a *ProportionAgg) compareInt(v int64) (int, bool) {
+	switch a.threshold.Type().Nature() {
+	case semantic.Int:
+		return compareInt64(v, a.threshold.Int()), true
+	case semantic.UInt:
+		if v < 0 {
+			return -1, true
+		}
+		return compareUint64(uint64(v), a.threshold.UInt()), true
+	default:
+		return compareFloat64(float64(v), a.threshold.Float())
+	}
+}
+
+func (a *ProportionAgg) compareUInt(v uint64) (int, bool) {
+	switch a.threshold.Type().Nature() {
+	case semantic.Int:
+		t := a.threshold.Int()
+		if t < 0 {
+			return 1, true
+		}
+		return compareUint64(v, uint64(t)), true
+	case semantic.UInt:
+		return compareUint64(v, a.threshold.UInt()), true
+	default:
+		return compareFloat64(float64(v), a.threshold.Float())
+	}
+}
+
+func (a *ProportionAgg) compareFloat(v float64) (int, bool) {
+	switch a.threshold.Type().Nature() {
+	case semantic.Int:
+		return compareFloat64(v, float64(a.threshold.Int()))
+	case semantic.UInt:
+		return compareFloat64(v, float64(a.threshold.UInt()))
+	default:
+		return compareFloat64(v, a.threshold.Float())
+	}
+}
+
+// test reports whether the result of a comparison satisfies the operator.
+// Values that cannot be compared, such as NaN, only satisfy "!=".
+func (a *ProportionAgg) test(cmp int, ok bool) bool {
+	if !ok {
+		return a.op == "!="
+	}
+	return a.satisfies(cmp)
+}
+
+func compareInt64(l, r int64) int {
+	if l < r {
+		return -1
+	} else if l > r {
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(l, r uint64) int {
+	if l < r {
+		return -1
+	} else if l > r {
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(l, r float64) (int, bool) {
+	if math.IsNaN(l) || math.IsNaN(r) {
+		return 0, false
+	}
+	if l < r {
+		return -1, true
+	} else if l > r {
+		return 1, true
+	}
+	return 0, true
+}
+
+type ProportionAggState struct {
+	agg        *ProportionAgg
+	n, matched int64
+}
+
+func (s *ProportionAggState) DoInt(vs *array.Int) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.n++
+			if s.agg.test(s.agg.compareInt(vs.Value(i))) {
+				s.matched++
+			}
+		}
+	}
+}
+
+func (s *ProportionAggState) DoUInt(vs *array.Uint) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.n++
+			if s.agg.test(s.agg.compareUInt(vs.Value(i))) {
+				s.matched++
+			}
+		}
+	}
+}
+
+func (s *ProportionAggState) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsValid(i) {
+			s.n++
+			if s.agg.test(s.agg.compareFloat(vs.Value(i))) {
+				s.matched++
+			}
+		}
+	}
+}
+
+func (s *ProportionAggState) Type() flux.ColType {
+	return flux.TFloat
+}
+
+func (s *ProportionAggState) ValueFloat() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	return float64(s.matched) / float64(s.n)
+}
+
+func (s *ProportionAggState) IsNull() bool {
+	return s.n == 0
+}
@@ -0,0 +1,154 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func TestProportion_Process(t *testing.T) {
+	testCases := []struct {
+		name      string
+		op        string
+		threshold values.Value
+		data      func() *array.Float
+		want      interface{}
+	}{
+		{
+			name:      "less than",
+			op:        "<",
+			threshold: values.NewFloat(500),
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{100, 200, 500, 600}, nil)
+			},
+			want: 0.5,
+		},
+		{
+			name:      "less than or equal int threshold",
+			op:        "<=",
+			threshold: values.NewInt(500),
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{100, 200, 500, 600}, nil)
+			},
+			want: 0.75,
+		},
+		{
+			name:      "with nulls",
+			op:        ">",
+			threshold: values.NewFloat(1),
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.Append(0)
+				b.AppendNull()
+				b.Append(2)
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: 0.5,
+		},
+		{
+			name:      "NaN",
+			op:        "!=",
+			threshold: values.NewFloat(1),
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, math.NaN()}, nil)
+			},
+			want: 0.5,
+		},
+		{
+			name:      "only nulls",
+			op:        "==",
+			threshold: values.NewFloat(1),
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			agg, err := universe.NewProportionAgg(tc.op, tc.threshold)
+			if err != nil {
+				t.Fatal(err)
+			}
+			executetest.AggFuncTestHelper(
+				t,
+				agg,
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
+
+func TestProportion_IntegerThresholds(t *testing.T) {
+	testCases := []struct {
+		name      string
+		op        string
+		threshold values.Value
+		do        func(agg execute.SimpleAggregate) execute.ValueFunc
+		want      float64
+	}{
+		{
+			name:      "int values uint threshold",
+			op:        ">=",
+			threshold: values.NewUInt(0),
+			do: func(agg execute.SimpleAggregate) execute.ValueFunc {
+				vf := agg.NewIntAgg()
+				vf.DoInt(arrow.NewInt([]int64{-5, 0, 5, math.MinInt64}, nil))
+				return vf
+			},
+			want: 0.5,
+		},
+		{
+			name:      "uint values negative int threshold",
+			op:        ">",
+			threshold: values.NewInt(-1),
+			do: func(agg execute.SimpleAggregate) execute.ValueFunc {
+				vf := agg.NewUIntAgg()
+				vf.DoUInt(arrow.NewUint([]uint64{0, math.MaxUint64}, nil))
+				return vf
+			},
+			want: 1,
+		},
+		{
+			name:      "int values exact int threshold",
+			op:        "==",
+			threshold: values.NewInt(math.MaxInt64),
+			do: func(agg execute.SimpleAggregate) execute.ValueFunc {
+				vf := agg.NewIntAgg()
+				vf.DoInt(arrow.NewInt([]int64{math.MaxInt64, math.MaxInt64 - 1}, nil))
+				return vf
+			},
+			want: 0.5,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			agg, err := universe.NewProportionAgg(tc.op, tc.threshold)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vf := tc.do(agg)
+			if vf.IsNull() {
+				t.Fatal("unexpected null value")
+			}
+			if got := vf.(execute.FloatValueFunc).ValueFloat(); got != tc.want {
+				t.Fatalf("unexpected value -want/+got:\n\t- %v\n\t+ %v", tc.want, got)
+			}
+		})
+	}
+}
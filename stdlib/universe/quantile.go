@@ -1,42 +1,176 @@
 package universe
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
 	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
 	"github.com/influxdata/flux/values"
 	"github.com/influxdata/tdigest"
 )
 
 const QuantileKind = "quantile"
+const MedianKind = "median"
 const ExactQuantileAggKind = "exact-quantile-aggregate"
 const ExactQuantileSelectKind = "exact-quantile-selector"
+const P2QuantileAggKind = "p2-quantile-aggregate"
+const GKQuantileAggKind = "gk-quantile-aggregate"
 
 const (
 	methodEstimateTdigest = "estimate_tdigest"
 	methodExactMean       = "exact_mean"
 	methodExactSelector   = "exact_selector"
+	methodP2              = "p2"
+	methodGK              = "gk"
 
 	defaultMethod = methodEstimateTdigest
+
+	// defaultEpsilon is the rank error of the gk method
+	// when epsilon is not specified.
+	defaultEpsilon = 0.01
+)
+
+const (
+	// nonFiniteSkip ignores NaN and infinite values.
+	nonFiniteSkip = "skip"
+	// nonFiniteNull treats NaN and infinite values as null.
+	nonFiniteNull = "null"
+	// nonFiniteError fails the query on a NaN or infinite value.
+	nonFiniteError = "error"
+	// nonFiniteInclude adds NaN and infinite values to the quantile.
+	nonFiniteInclude = "include"
+)
+
+const (
+	// nullBehaviorSkip ignores null values.
+	nullBehaviorSkip = "skip"
+	// nullBehaviorError fails the query on a null value.
+	nullBehaviorError = "error"
+	// nullBehaviorFill adds the fill value in place of a null value.
+	nullBehaviorFill = "fill"
+)
+
+const (
+	// interpolationLinear interpolates linearly between the two nearest ranks.
+	interpolationLinear = "linear"
+	// interpolationLower selects the lower of the two nearest ranks.
+	interpolationLower = "lower"
+	// interpolationHigher selects the higher of the two nearest ranks.
+	interpolationHigher = "higher"
+	// interpolationNearest selects the nearest rank, rounding half to even.
+	interpolationNearest = "nearest"
+	// interpolationMidpoint averages the two nearest ranks.
+	interpolationMidpoint = "midpoint"
+)
+
+const (
+	// tieBreakFirst selects the first row in input order
+	// among the rows with the selected value.
+	tieBreakFirst = "first"
+	// tieBreakLast selects the last row in input order.
+	tieBreakLast = "last"
+	// tieBreakMin selects the row with the smallest value
+	// in the tie break column.
+	tieBreakMin = "min"
+	// tieBreakMax selects the row with the largest value
+	// in the tie break column.
+	tieBreakMax = "max"
+)
+
+const (
+	// maxErrorCompressionFactor is the product of the compression
+	// and the largest rank error of the quantiles between 0.001 and
+	// 0.999 measured on normal, exponential, and uniform data. It is
+	// at most 0.2 for every compression from 5 to 5000 and is rounded
+	// up so the error stays below the requested bound.
+	maxErrorCompressionFactor = 0.25
+
+	// maxQuantileError is the largest maxError that is accepted.
+	// It resolves to the smallest compression that was calibrated.
+	maxQuantileError = 0.05
 )
 
+// CompressionForMaxError returns the t-digest compression that keeps the
+// rank error of an estimated quantile below maxError. The rank error is the
+// difference between the requested quantile and the fraction of the values
+// that are less than the estimate. The error is inversely proportional to
+// the compression, so halving maxError doubles the memory used by a digest.
+func CompressionForMaxError(maxError float64) float64 {
+	return math.Ceil(maxErrorCompressionFactor / maxError)
+}
+
 type QuantileOpSpec struct {
-	Quantile    float64 `json:"quantile"`
-	Compression float64 `json:"compression"`
-	Method      string  `json:"method"`
+	Quantile float64 `json:"quantile"`
+	// Quantiles are computed from a single t-digest in place
+	// of Quantile. Each quantile is reported in its own column.
+	Quantiles   []float64 `json:"quantiles,omitempty"`
+	Compression float64   `json:"compression"`
+	Method      string    `json:"method"`
+	// Epsilon is the largest rank error of the gk method
+	// as a fraction of the number of values.
+	Epsilon float64 `json:"epsilon,omitempty"`
+	// QuantileColumn is a group key column whose value is used
+	// to look up the quantile for each table in QuantileLookup.
+	// Tables whose value is not in the lookup use Quantile.
+	QuantileColumn string             `json:"quantileColumn,omitempty"`
+	QuantileLookup map[string]float64 `json:"quantileLookup,omitempty"`
+	// CountSkipped reports the number of null, NaN, and infinite
+	// values in the _nullCount, _nanCount, and _infCount columns.
+	CountSkipped bool `json:"countSkipped,omitempty"`
+	// WithCount reports the number of values added
+	// to the t-digest in the _count column.
+	WithCount bool `json:"withCount,omitempty"`
+	// NonFinite is the policy for NaN and infinite values.
+	// An empty policy skips them.
+	NonFinite string `json:"nonFinite,omitempty"`
+	// NullBehavior is the policy for null values. An empty policy
+	// skips them. FillValue replaces null values with the fill policy.
+	NullBehavior string  `json:"nullBehavior,omitempty"`
+	FillValue    float64 `json:"fillValue,omitempty"`
+	// SelectorColumns are the columns the exact selector sorts by
+	// in order. Ties in a column are broken by the next column.
+	SelectorColumns []string `json:"selectorColumns,omitempty"`
+	// Interpolation is how the exact mean method computes a quantile
+	// that falls between two ranks. An empty interpolation is linear.
+	Interpolation string `json:"interpolation,omitempty"`
+	// WeightColumn is the column with the weight of each
+	// value that is added to the t-digest.
+	WeightColumn string `json:"weightColumn,omitempty"`
+	// TieBreak is how the exact selector chooses between the rows
+	// that share the selected value. TieBreakColumn is the column
+	// that is compared by the min and max tie breaks.
+	TieBreak       string `json:"tieBreak,omitempty"`
+	TieBreakColumn string `json:"tieBreakColumn,omitempty"`
 	// quantile is either an aggregate, or a selector based on the options
 	execute.SimpleAggregateConfig
 	execute.SelectorConfig
 }
 
+// MedianOpSpec computes the 0.5 quantile of each table
+// with the same methods as QuantileOpSpec.
+type MedianOpSpec struct {
+	QuantileOpSpec
+}
+
 func init() {
 	quantileSignature := runtime.MustLookupBuiltinType("universe", "quantile")
 
@@ -44,9 +178,19 @@ func init() {
 
 	flux.RegisterOpSpec(QuantileKind, newQuantileOp)
 	plan.RegisterProcedureSpec(QuantileKind, newQuantileProcedure, QuantileKind)
+
+	medianSignature := runtime.MustLookupBuiltinType("universe", "median")
+
+	runtime.RegisterPackageValue("universe", MedianKind, flux.MustValue(flux.FunctionValue(MedianKind, CreateMedianOpSpec, medianSignature)))
+
+	flux.RegisterOpSpec(MedianKind, newMedianOp)
+	plan.RegisterProcedureSpec(MedianKind, newMedianProcedure, MedianKind)
+
 	execute.RegisterTransformation(QuantileKind, createQuantileTransformation)
 	execute.RegisterTransformation(ExactQuantileAggKind, createExactQuantileAggTransformation)
 	execute.RegisterTransformation(ExactQuantileSelectKind, createExactQuantileSelectTransformation)
+	execute.RegisterTransformation(P2QuantileAggKind, createP2QuantileAggTransformation)
+	execute.RegisterTransformation(GKQuantileAggKind, createGKQuantileAggTransformation)
 }
 
 func CreateQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
@@ -55,18 +199,68 @@ func CreateQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.Ope
 	}
 
 	spec := new(QuantileOpSpec)
-	p, err := args.GetRequiredFloat("q")
+	p, hasQ, err := args.GetFloat("q")
+	if err != nil {
+		return nil, err
+	}
+	qs, hasQs, err := args.GetArray("quantiles", semantic.Float)
 	if err != nil {
 		return nil, err
 	}
-	spec.Quantile = p
+	if hasQ == hasQs {
+		return nil, errors.New(codes.Invalid, "exactly one of q or quantiles must be specified")
+	}
 
-	if spec.Quantile < 0 || spec.Quantile > 1 {
-		return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+	if hasQ {
+		spec.Quantile = p
+		if spec.Quantile < 0 || spec.Quantile > 1 {
+			return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+		}
+	} else if spec.Quantiles, err = readQuantiles(qs); err != nil {
+		return nil, err
 	}
 
-	if m, ok, err := args.GetString("method"); err != nil {
+	if err := readQuantileLookup(args, spec); err != nil {
 		return nil, err
+	}
+	if len(spec.Quantiles) > 0 && spec.QuantileColumn != "" {
+		return nil, errors.New(codes.Invalid, "qColumn and qLookup are not valid with quantiles")
+	}
+
+	if err := readQuantileOptions(args, spec); err != nil {
+		return nil, err
+	}
+
+	if col, ok, err := args.GetString("weightColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		if spec.Method != methodEstimateTdigest {
+			return nil, errors.New(codes.Invalid, "weightColumn parameter is only valid for method estimate_tdigest")
+		}
+		spec.WeightColumn = col
+	}
+	return spec, nil
+}
+
+func CreateMedianOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &MedianOpSpec{
+		QuantileOpSpec: QuantileOpSpec{Quantile: 0.5},
+	}
+	if err := readQuantileOptions(args, &spec.QuantileOpSpec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// readQuantileOptions reads the arguments shared by quantile and median
+// that select and configure the method used to compute the quantile.
+func readQuantileOptions(args flux.Arguments, spec *QuantileOpSpec) error {
+	if m, ok, err := args.GetString("method"); err != nil {
+		return err
 	} else if ok {
 		spec.Method = m
 	} else {
@@ -74,13 +268,32 @@ func CreateQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.Ope
 	}
 
 	if c, ok, err := args.GetFloat("compression"); err != nil {
-		return nil, err
+		return err
 	} else if ok {
 		spec.Compression = c
 	}
 
 	if spec.Compression > 0 && spec.Method != methodEstimateTdigest {
-		return nil, errors.New(codes.Invalid, "compression parameter is only valid for method estimate_tdigest")
+		return errors.New(codes.Invalid, "compression parameter is only valid for method estimate_tdigest")
+	}
+
+	if len(spec.Quantiles) > 0 && spec.Method != methodEstimateTdigest {
+		return errors.New(codes.Invalid, "quantiles parameter is only valid for method estimate_tdigest")
+	}
+
+	if e, ok, err := args.GetFloat("maxError"); err != nil {
+		return err
+	} else if ok {
+		if spec.Method != methodEstimateTdigest {
+			return errors.New(codes.Invalid, "maxError parameter is only valid for method estimate_tdigest")
+		}
+		if spec.Compression > 0 {
+			return errors.New(codes.Invalid, "compression and maxError parameters cannot both be specified")
+		}
+		if e <= 0 || e > maxQuantileError {
+			return errors.Newf(codes.Invalid, "maxError must be greater than 0 and at most %v, got %v", maxQuantileError, e)
+		}
+		spec.Compression = CompressionForMaxError(e)
 	}
 
 	// Set default Compression if not exact
@@ -88,20 +301,262 @@ func CreateQuantileOpSpec(args flux.Arguments, a *flux.Administration) (flux.Ope
 		spec.Compression = 1000
 	}
 
+	if e, ok, err := args.GetFloat("epsilon"); err != nil {
+		return err
+	} else if ok {
+		if spec.Method != methodGK {
+			return errors.New(codes.Invalid, "epsilon parameter is only valid for method gk")
+		}
+		if e <= 0 || e >= 1 {
+			return errors.Newf(codes.Invalid, "epsilon must be between 0 and 1 (exclusive), got %v", e)
+		}
+		spec.Epsilon = e
+	} else if spec.Method == methodGK {
+		spec.Epsilon = defaultEpsilon
+	}
+
+	if c, ok, err := args.GetBool("countSkipped"); err != nil {
+		return err
+	} else if ok {
+		spec.CountSkipped = c
+	}
+
+	if spec.CountSkipped && spec.Method == methodExactSelector {
+		return errors.New(codes.Invalid, "countSkipped parameter is not valid for method exact_selector")
+	}
+
+	if c, ok, err := args.GetBool("withCount"); err != nil {
+		return err
+	} else if ok {
+		spec.WithCount = c
+	}
+
+	if spec.WithCount && spec.Method != methodEstimateTdigest {
+		return errors.New(codes.Invalid, "withCount parameter is only valid for method estimate_tdigest")
+	}
+
+	if p, ok, err := args.GetString("nonFinite"); err != nil {
+		return err
+	} else if ok {
+		switch p {
+		case nonFiniteSkip, nonFiniteNull, nonFiniteError, nonFiniteInclude:
+		default:
+			return errors.Newf(codes.Invalid, "unknown nonFinite policy %q, expected %q, %q, %q, or %q", p, nonFiniteSkip, nonFiniteNull, nonFiniteError, nonFiniteInclude)
+		}
+		if spec.Method == methodExactSelector {
+			return errors.New(codes.Invalid, "nonFinite parameter is not valid for method exact_selector")
+		}
+		spec.NonFinite = p
+	}
+
+	if b, ok, err := args.GetString("nullBehavior"); err != nil {
+		return err
+	} else if ok {
+		switch b {
+		case nullBehaviorSkip, nullBehaviorError, nullBehaviorFill:
+		default:
+			return errors.Newf(codes.Invalid, "unknown nullBehavior %q, expected %q, %q, or %q", b, nullBehaviorSkip, nullBehaviorError, nullBehaviorFill)
+		}
+		if spec.Method == methodExactSelector {
+			return errors.New(codes.Invalid, "nullBehavior parameter is not valid for method exact_selector")
+		}
+		spec.NullBehavior = b
+	}
+
+	if v, ok, err := args.GetFloat("fillValue"); err != nil {
+		return err
+	} else if ok {
+		if spec.NullBehavior != nullBehaviorFill {
+			return errors.New(codes.Invalid, "fillValue parameter is only valid with nullBehavior fill")
+		}
+		spec.FillValue = v
+	} else if spec.NullBehavior == nullBehaviorFill {
+		return errors.New(codes.Invalid, "nullBehavior fill requires a fillValue")
+	}
+
+	if i, ok, err := args.GetString("interpolation"); err != nil {
+		return err
+	} else if ok {
+		switch i {
+		case interpolationLinear, interpolationLower, interpolationHigher, interpolationNearest, interpolationMidpoint:
+		default:
+			return errors.Newf(codes.Invalid, "unknown interpolation %q, expected %q, %q, %q, %q, or %q", i, interpolationLinear, interpolationLower, interpolationHigher, interpolationNearest, interpolationMidpoint)
+		}
+		if spec.Method != methodExactMean {
+			return errors.New(codes.Invalid, "interpolation parameter is only valid for method exact_mean")
+		}
+		spec.Interpolation = i
+	}
+
 	switch spec.Method {
 	case methodExactSelector:
 		if err := spec.SelectorConfig.ReadArgs(args); err != nil {
-			return nil, err
+			return err
+		}
+		if err := readSelectorColumns(args, spec); err != nil {
+			return err
+		}
+		if err := readTieBreak(args, spec); err != nil {
+			return err
 		}
-	case methodEstimateTdigest, methodExactMean:
+	case methodEstimateTdigest, methodExactMean, methodP2, methodGK:
 		if err := spec.SimpleAggregateConfig.ReadArgs(args); err != nil {
-			return nil, err
+			return err
 		}
 	default:
-		return nil, errors.Newf(codes.Invalid, "unknown method %s", spec.Method)
+		return errors.Newf(codes.Invalid, "unknown method %s", spec.Method)
 	}
 
-	return spec, nil
+	if _, ok := args.Get("columns"); ok && spec.Method != methodExactSelector {
+		return errors.New(codes.Invalid, "columns parameter is only valid for method exact_selector")
+	}
+	if spec.Method != methodExactSelector {
+		for _, name := range []string{"tieBreak", "tieBreakColumn"} {
+			if _, ok := args.Get(name); ok {
+				return errors.Newf(codes.Invalid, "%s parameter is only valid for method exact_selector", name)
+			}
+		}
+	}
+	return nil
+}
+
+// readTieBreak reads the tieBreak and tieBreakColumn arguments of the
+// exact selector. The min and max tie breaks require a column and the
+// column is not valid with the other tie breaks.
+func readTieBreak(args flux.Arguments, spec *QuantileOpSpec) error {
+	if tb, ok, err := args.GetString("tieBreak"); err != nil {
+		return err
+	} else if ok {
+		switch tb {
+		case tieBreakFirst, tieBreakLast, tieBreakMin, tieBreakMax:
+		default:
+			return errors.Newf(codes.Invalid, "unknown tieBreak %q, expected %q, %q, %q, or %q", tb, tieBreakFirst, tieBreakLast, tieBreakMin, tieBreakMax)
+		}
+		spec.TieBreak = tb
+	}
+
+	if col, ok, err := args.GetString("tieBreakColumn"); err != nil {
+		return err
+	} else if ok {
+		if spec.TieBreak != tieBreakMin && spec.TieBreak != tieBreakMax {
+			return errors.New(codes.Invalid, "tieBreakColumn parameter is only valid with tieBreak min or max")
+		}
+		spec.TieBreakColumn = col
+	} else if spec.TieBreak == tieBreakMin || spec.TieBreak == tieBreakMax {
+		return errors.Newf(codes.Invalid, "tieBreak %q requires a tieBreakColumn", spec.TieBreak)
+	}
+	return nil
+}
+
+// readSelectorColumns reads the columns argument of the exact selector.
+// The first column replaces the selector column and the remaining
+// columns break ties in the order they are listed.
+func readSelectorColumns(args flux.Arguments, spec *QuantileOpSpec) error {
+	arr, ok, err := args.GetArray("columns", semantic.String)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+	if _, ok := args.Get("column"); ok {
+		return errors.New(codes.Invalid, "column and columns are mutually exclusive")
+	}
+	cols, err := interpreter.ToStringArray(arr)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return errors.New(codes.Invalid, "columns must not be empty")
+	}
+	spec.SelectorConfig.Column = cols[0]
+	spec.SelectorColumns = cols
+	return nil
+}
+
+// readQuantiles reads the quantiles argument. There must be at least
+// one quantile and each quantile must be between 0 and 1 and unique
+// so that every quantile is reported in a distinct column.
+func readQuantiles(arr values.Array) ([]float64, error) {
+	qs, err := interpreter.ToFloatArray(arr)
+	if err != nil {
+		return nil, err
+	}
+	if len(qs) == 0 {
+		return nil, errors.New(codes.Invalid, "quantiles must not be empty")
+	}
+	seen := make(map[float64]bool, len(qs))
+	for _, q := range qs {
+		if q < 0 || q > 1 {
+			return nil, errors.Newf(codes.Invalid, "quantile %v must be between 0 and 1", q)
+		}
+		if seen[q] {
+			return nil, errors.Newf(codes.Invalid, "duplicate quantile %v", q)
+		}
+		seen[q] = true
+	}
+	return qs, nil
+}
+
+// readQuantileLookup reads the qColumn and qLookup arguments.
+// Both must be specified together and each quantile
+// in the lookup must be between 0 and 1.
+func readQuantileLookup(args flux.Arguments, spec *QuantileOpSpec) error {
+	col, hasCol, err := args.GetString("qColumn")
+	if err != nil {
+		return err
+	}
+	lookup, hasLookup, err := args.GetObject("qLookup")
+	if err != nil {
+		return err
+	}
+	if hasCol != hasLookup {
+		return errors.New(codes.Invalid, "qColumn and qLookup must be specified together")
+	} else if !hasCol {
+		return nil
+	}
+
+	spec.QuantileColumn = col
+	spec.QuantileLookup = make(map[string]float64, lookup.Len())
+	lookup.Range(func(name string, v values.Value) {
+		if err != nil {
+			return
+		}
+		if v.Type().Nature() != semantic.Float {
+			err = errors.Newf(codes.Invalid, "qLookup value for %q must be a float, got %v", name, v.Type())
+			return
+		}
+		q := v.Float()
+		if q < 0 || q > 1 {
+			err = errors.Newf(codes.Invalid, "qLookup value for %q must be between 0 and 1", name)
+			return
+		}
+		spec.QuantileLookup[name] = q
+	})
+	return err
+}
+
+// resolveQuantile returns the quantile to compute for the table
+// with the given group key. If the lookup column is not part of
+// the group key, or its value is not present in the lookup,
+// the default quantile is returned.
+func resolveQuantile(q float64, column string, lookup map[string]float64, key flux.GroupKey) (float64, error) {
+	if column == "" {
+		return q, nil
+	}
+	idx := execute.ColIdx(column, key.Cols())
+	if idx < 0 || key.IsNull(idx) {
+		return q, nil
+	}
+	if typ := key.Cols()[idx].Type; typ != flux.TString {
+		return 0, errors.Newf(codes.FailedPrecondition, "quantile lookup column %q must be a string, got %v", column, typ)
+	}
+	if v, ok := lookup[key.ValueString(idx)]; ok {
+		q = v
+	}
+	if q < 0 || q > 1 {
+		return 0, errors.Newf(codes.Invalid, "quantile for key %v must be between 0 and 1", key)
+	}
+	return q, nil
 }
 
 func newQuantileOp() flux.OperationSpec {
@@ -112,9 +567,26 @@ func (s *QuantileOpSpec) Kind() flux.OperationKind {
 	return QuantileKind
 }
 
+func newMedianOp() flux.OperationSpec {
+	return new(MedianOpSpec)
+}
+
+func (s *MedianOpSpec) Kind() flux.OperationKind {
+	return MedianKind
+}
+
 type TDigestQuantileProcedureSpec struct {
-	Quantile    float64 `json:"quantile"`
-	Compression float64 `json:"compression"`
+	Quantile       float64            `json:"quantile"`
+	Quantiles      []float64          `json:"quantiles,omitempty"`
+	QuantileColumn string             `json:"quantileColumn,omitempty"`
+	QuantileLookup map[string]float64 `json:"quantileLookup,omitempty"`
+	Compression    float64            `json:"compression"`
+	CountSkipped   bool               `json:"countSkipped,omitempty"`
+	WithCount      bool               `json:"withCount,omitempty"`
+	NonFinite      string             `json:"nonFinite,omitempty"`
+	NullBehavior   string             `json:"nullBehavior,omitempty"`
+	FillValue      float64            `json:"fillValue,omitempty"`
+	WeightColumn   string             `json:"weightColumn,omitempty"`
 	execute.SimpleAggregateConfig
 }
 
@@ -124,7 +596,16 @@ func (s *TDigestQuantileProcedureSpec) Kind() plan.ProcedureKind {
 func (s *TDigestQuantileProcedureSpec) Copy() plan.ProcedureSpec {
 	return &TDigestQuantileProcedureSpec{
 		Quantile:              s.Quantile,
+		Quantiles:             s.Quantiles,
+		QuantileColumn:        s.QuantileColumn,
+		QuantileLookup:        s.QuantileLookup,
 		Compression:           s.Compression,
+		CountSkipped:          s.CountSkipped,
+		WithCount:             s.WithCount,
+		NonFinite:             s.NonFinite,
+		NullBehavior:          s.NullBehavior,
+		FillValue:             s.FillValue,
+		WeightColumn:          s.WeightColumn,
 		SimpleAggregateConfig: s.SimpleAggregateConfig,
 	}
 }
@@ -135,7 +616,14 @@ func (s *TDigestQuantileProcedureSpec) TriggerSpec() plan.TriggerSpec {
 }
 
 type ExactQuantileAggProcedureSpec struct {
-	Quantile float64 `json:"quantile"`
+	Quantile       float64            `json:"quantile"`
+	QuantileColumn string             `json:"quantileColumn,omitempty"`
+	QuantileLookup map[string]float64 `json:"quantileLookup,omitempty"`
+	CountSkipped   bool               `json:"countSkipped,omitempty"`
+	NonFinite      string             `json:"nonFinite,omitempty"`
+	NullBehavior   string             `json:"nullBehavior,omitempty"`
+	FillValue      float64            `json:"fillValue,omitempty"`
+	Interpolation  string             `json:"interpolation,omitempty"`
 	execute.SimpleAggregateConfig
 }
 
@@ -143,7 +631,17 @@ func (s *ExactQuantileAggProcedureSpec) Kind() plan.ProcedureKind {
 	return ExactQuantileAggKind
 }
 func (s *ExactQuantileAggProcedureSpec) Copy() plan.ProcedureSpec {
-	return &ExactQuantileAggProcedureSpec{Quantile: s.Quantile, SimpleAggregateConfig: s.SimpleAggregateConfig}
+	return &ExactQuantileAggProcedureSpec{
+		Quantile:              s.Quantile,
+		QuantileColumn:        s.QuantileColumn,
+		QuantileLookup:        s.QuantileLookup,
+		CountSkipped:          s.CountSkipped,
+		NonFinite:             s.NonFinite,
+		NullBehavior:          s.NullBehavior,
+		FillValue:             s.FillValue,
+		Interpolation:         s.Interpolation,
+		SimpleAggregateConfig: s.SimpleAggregateConfig,
+	}
 }
 
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
@@ -151,8 +649,45 @@ func (s *ExactQuantileAggProcedureSpec) TriggerSpec() plan.TriggerSpec {
 	return plan.NarrowTransformationTriggerSpec{}
 }
 
+type P2QuantileAggProcedureSpec struct {
+	Quantile       float64            `json:"quantile"`
+	QuantileColumn string             `json:"quantileColumn,omitempty"`
+	QuantileLookup map[string]float64 `json:"quantileLookup,omitempty"`
+	CountSkipped   bool               `json:"countSkipped,omitempty"`
+	NonFinite      string             `json:"nonFinite,omitempty"`
+	NullBehavior   string             `json:"nullBehavior,omitempty"`
+	FillValue      float64            `json:"fillValue,omitempty"`
+	execute.SimpleAggregateConfig
+}
+
+func (s *P2QuantileAggProcedureSpec) Kind() plan.ProcedureKind {
+	return P2QuantileAggKind
+}
+func (s *P2QuantileAggProcedureSpec) Copy() plan.ProcedureSpec {
+	return &P2QuantileAggProcedureSpec{
+		Quantile:              s.Quantile,
+		QuantileColumn:        s.QuantileColumn,
+		QuantileLookup:        s.QuantileLookup,
+		CountSkipped:          s.CountSkipped,
+		NonFinite:             s.NonFinite,
+		NullBehavior:          s.NullBehavior,
+		FillValue:             s.FillValue,
+		SimpleAggregateConfig: s.SimpleAggregateConfig,
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *P2QuantileAggProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
 type ExactQuantileSelectProcedureSpec struct {
-	Quantile float64 `json:"quantile"`
+	Quantile        float64            `json:"quantile"`
+	QuantileColumn  string             `json:"quantileColumn,omitempty"`
+	QuantileLookup  map[string]float64 `json:"quantileLookup,omitempty"`
+	SelectorColumns []string           `json:"selectorColumns,omitempty"`
+	TieBreak        string             `json:"tieBreak,omitempty"`
+	TieBreakColumn  string             `json:"tieBreakColumn,omitempty"`
 	execute.SelectorConfig
 }
 
@@ -160,7 +695,19 @@ func (s *ExactQuantileSelectProcedureSpec) Kind() plan.ProcedureKind {
 	return ExactQuantileSelectKind
 }
 func (s *ExactQuantileSelectProcedureSpec) Copy() plan.ProcedureSpec {
-	return &ExactQuantileSelectProcedureSpec{Quantile: s.Quantile}
+	ns := &ExactQuantileSelectProcedureSpec{
+		Quantile:       s.Quantile,
+		QuantileColumn: s.QuantileColumn,
+		QuantileLookup: s.QuantileLookup,
+		TieBreak:       s.TieBreak,
+		TieBreakColumn: s.TieBreakColumn,
+		SelectorConfig: s.SelectorConfig,
+	}
+	if len(s.SelectorColumns) > 0 {
+		ns.SelectorColumns = make([]string, len(s.SelectorColumns))
+		copy(ns.SelectorColumns, s.SelectorColumns)
+	}
+	return ns
 }
 
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
@@ -178,11 +725,47 @@ func newQuantileProcedure(qs flux.OperationSpec, a plan.Administration) (plan.Pr
 	case methodExactMean:
 		return &ExactQuantileAggProcedureSpec{
 			Quantile:              spec.Quantile,
+			QuantileColumn:        spec.QuantileColumn,
+			QuantileLookup:        spec.QuantileLookup,
+			CountSkipped:          spec.CountSkipped,
+			NonFinite:             spec.NonFinite,
+			NullBehavior:          spec.NullBehavior,
+			FillValue:             spec.FillValue,
+			Interpolation:         spec.Interpolation,
+			SimpleAggregateConfig: spec.SimpleAggregateConfig,
+		}, nil
+	case methodP2:
+		return &P2QuantileAggProcedureSpec{
+			Quantile:              spec.Quantile,
+			QuantileColumn:        spec.QuantileColumn,
+			QuantileLookup:        spec.QuantileLookup,
+			CountSkipped:          spec.CountSkipped,
+			NonFinite:             spec.NonFinite,
+			NullBehavior:          spec.NullBehavior,
+			FillValue:             spec.FillValue,
+			SimpleAggregateConfig: spec.SimpleAggregateConfig,
+		}, nil
+	case methodGK:
+		return &GKQuantileAggProcedureSpec{
+			Quantile:              spec.Quantile,
+			Epsilon:               spec.Epsilon,
+			QuantileColumn:        spec.QuantileColumn,
+			QuantileLookup:        spec.QuantileLookup,
+			CountSkipped:          spec.CountSkipped,
+			NonFinite:             spec.NonFinite,
+			NullBehavior:          spec.NullBehavior,
+			FillValue:             spec.FillValue,
 			SimpleAggregateConfig: spec.SimpleAggregateConfig,
 		}, nil
 	case methodExactSelector:
 		return &ExactQuantileSelectProcedureSpec{
-			Quantile: spec.Quantile,
+			Quantile:        spec.Quantile,
+			QuantileColumn:  spec.QuantileColumn,
+			QuantileLookup:  spec.QuantileLookup,
+			SelectorColumns: spec.SelectorColumns,
+			TieBreak:        spec.TieBreak,
+			TieBreakColumn:  spec.TieBreakColumn,
+			SelectorConfig:  spec.SelectorConfig,
 		}, nil
 	case methodEstimateTdigest:
 		fallthrough
@@ -190,17 +773,65 @@ func newQuantileProcedure(qs flux.OperationSpec, a plan.Administration) (plan.Pr
 		// default to estimated quantile
 		return &TDigestQuantileProcedureSpec{
 			Quantile:              spec.Quantile,
+			Quantiles:             spec.Quantiles,
+			QuantileColumn:        spec.QuantileColumn,
+			QuantileLookup:        spec.QuantileLookup,
 			Compression:           spec.Compression,
+			CountSkipped:          spec.CountSkipped,
+			WithCount:             spec.WithCount,
+			NonFinite:             spec.NonFinite,
+			NullBehavior:          spec.NullBehavior,
+			FillValue:             spec.FillValue,
+			WeightColumn:          spec.WeightColumn,
 			SimpleAggregateConfig: spec.SimpleAggregateConfig,
 		}, nil
 	}
 }
 
+func newMedianProcedure(qs flux.OperationSpec, a plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MedianOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return newQuantileProcedure(&spec.QuantileOpSpec, a)
+}
+
 type QuantileAgg struct {
 	Quantile,
 	Compression float64
-	freeDigests []*tdigest.TDigest
-	mem         *memory.Allocator
+	// Quantiles are reported by ValueFloats
+	// of the states of the aggregate.
+	Quantiles []float64
+	// QuantileColumn and QuantileLookup optionally
+	// override Quantile for each group key.
+	QuantileColumn string
+	QuantileLookup map[string]float64
+	// CountSkipped reports the number of null, NaN, and infinite values.
+	CountSkipped bool
+	// WithCount reports the number of values added to the digest.
+	WithCount bool
+	// NonFinite is the policy for NaN and infinite values.
+	// An empty policy skips them.
+	NonFinite string
+	// NullBehavior is the policy for null values. An empty
+	// policy skips them. FillValue replaces null values
+	// with the fill policy.
+	NullBehavior string
+	FillValue    float64
+	freeDigests  []*tdigest.TDigest
+	// freeBytes is the size of the free digests and freeBudget
+	// is the number of bytes they may use before a digest is
+	// released instead of being kept for reuse.
+	freeBytes  int
+	freeBudget int
+	mem        *memory.Allocator
+	// ctx is the context of the query. The states stop adding
+	// values once it is cancelled. It is nil if the aggregate
+	// was not created by the executor.
+	ctx context.Context
+	// stats receives the statistics of each digest
+	// when it is closed. It is nil if they are not emitted.
+	stats *execute.AggStats
 }
 
 func NewQuantileAgg(q, comp float64, mem *memory.Allocator, size int) *QuantileAgg {
@@ -209,17 +840,59 @@ func NewQuantileAgg(q, comp float64, mem *memory.Allocator, size int) *QuantileA
 		Quantile:    q,
 		Compression: comp,
 		freeDigests: digests,
+		freeBudget:  size * tdigest.ByteSizeForCompression(comp),
 		mem:         mem,
 	}
 }
 
+// getTDigestPoolFraction returns the fraction of the memory
+// limit that the free digests of a t-digest aggregate may use.
+func getTDigestPoolFraction(ctx context.Context) (float64, error) {
+	if !execute.HaveExecutionDependencies(ctx) {
+		return 0, nil
+	}
+	opts := execute.GetExecutionDependencies(ctx).ExecutionOptions
+	if opts == nil {
+		return 0, nil
+	}
+	if f := opts.TDigestPoolFraction; f < 0 || f > 1 || math.IsNaN(f) {
+		return 0, errors.Newf(codes.Invalid, "t-digest pool fraction must be between 0 and 1, got %v", f)
+	}
+	return opts.TDigestPoolFraction, nil
+}
+
+// setFreeDigestBudget limits the free digests to the fraction
+// of the memory limit of the allocator. The budget of one digest
+// per column is kept if the fraction is zero or there is no limit.
+func (a *QuantileAgg) setFreeDigestBudget(fraction float64) {
+	if fraction <= 0 || a.mem == nil || a.mem.Limit == nil {
+		return
+	}
+	a.freeBudget = int(fraction * float64(*a.mem.Limit))
+}
+
 func createQuantileTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
 	ps, ok := spec.(*TDigestQuantileProcedureSpec)
 	if !ok {
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", ps)
 	}
+	fraction, err := getTDigestPoolFraction(a.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	stats := execute.GetAggStats(a.Context())
+	if len(ps.Quantiles) > 0 || ps.WeightColumn != "" {
+		return newTDigestQuantilesTransformation(a.Context(), id, ps, a.Allocator(), stats, fraction)
+	}
 	size := len(ps.SimpleAggregateConfig.Columns)
 	agg := NewQuantileAgg(ps.Quantile, ps.Compression, a.Allocator(), size)
+	agg.setFreeDigestBudget(fraction)
+	agg.QuantileColumn, agg.QuantileLookup = ps.QuantileColumn, ps.QuantileLookup
+	agg.CountSkipped, agg.NonFinite = ps.CountSkipped, ps.NonFinite
+	agg.NullBehavior, agg.FillValue = ps.NullBehavior, ps.FillValue
+	agg.WithCount = ps.WithCount
+	agg.stats = stats
+	agg.ctx = a.Context()
 	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
 }
 
@@ -231,16 +904,19 @@ func (a *QuantileAgg) popFreeDigest() *tdigest.TDigest {
 	i := len(a.freeDigests) - 1
 	d := a.freeDigests[i]
 	a.freeDigests = a.freeDigests[:i]
+	a.freeBytes -= tdigest.ByteSizeForCompression(a.Compression)
 	return d
 }
 
 func (a *QuantileAgg) pushFreeDigest(d *tdigest.TDigest) {
 	if d != nil {
-		if len(a.freeDigests) < cap(a.freeDigests) {
+		size := tdigest.ByteSizeForCompression(a.Compression)
+		if a.freeBytes+size <= a.freeBudget {
 			d.Reset()
 			a.freeDigests = append(a.freeDigests, d)
+			a.freeBytes += size
 		} else {
-			a.mem.Account(tdigest.ByteSizeForCompression(a.Compression) * -1)
+			a.mem.Account(size * -1)
 		}
 	}
 }
@@ -259,9 +935,23 @@ func (a *QuantileAgg) NewUIntAgg() execute.DoUIntAgg {
 	return agg.(execute.DoUIntAgg)
 }
 
+// ForKey implements execute.GroupKeySimpleAggregate.
+func (a *QuantileAgg) ForKey(key flux.GroupKey) (execute.SimpleAggregate, error) {
+	q, err := resolveQuantile(a.Quantile, a.QuantileColumn, a.QuantileLookup, key)
+	if err != nil {
+		return nil, err
+	}
+	return &quantileAggForKey{QuantileAgg: a, quantile: q}, nil
+}
+
 func (a *QuantileAgg) NewFloatAgg() execute.DoFloatAgg {
+	return a.newState(a.Quantile)
+}
+
+func (a *QuantileAgg) newState(quantile float64) *QuantileAggState {
 	q := &QuantileAggState{
-		parent: a,
+		parent:   a,
+		quantile: quantile,
 	}
 	if len(a.freeDigests) > 0 {
 		q.digest = a.popFreeDigest()
@@ -276,68 +966,693 @@ func (a *QuantileAgg) NewStringAgg() execute.DoStringAgg {
 	return nil
 }
 
+// quantileAggForKey shares the free digests of its
+// parent but computes the quantile resolved for a
+// specific group key.
+type quantileAggForKey struct {
+	*QuantileAgg
+	quantile float64
+}
+
+func (a *quantileAggForKey) NewIntAgg() execute.DoIntAgg {
+	return a.newState(a.quantile)
+}
+
+func (a *quantileAggForKey) NewUIntAgg() execute.DoUIntAgg {
+	return a.newState(a.quantile)
+}
+
+func (a *quantileAggForKey) NewFloatAgg() execute.DoFloatAgg {
+	return a.newState(a.quantile)
+}
+
 func (a *QuantileAgg) Close() error {
-	for i := 0; i < len(a.freeDigests); i++ {
-		a.mem.Account(tdigest.ByteSizeForCompression(a.Compression) * -1)
-	}
+	a.mem.Account(a.freeBytes * -1)
+	a.freeBytes = 0
 	a.freeDigests = nil
 	return nil
 }
 
-type QuantileAggState struct {
-	digest *tdigest.TDigest
-	parent *QuantileAgg
-	ok     bool
+// quantileCounts tracks the values that are not
+// added to a quantile along with any error caused
+// by a non-finite value.
+type quantileCounts struct {
+	nullCount, nanCount, infCount int64
+	err                           error
+	// count is the number of values that were added to the
+	// quantile. It is only tracked by the t-digest quantile.
+	count int64
 }
 
-func (s *QuantileAggState) DoFloat(vs *array.Float) {
-	for i := 0; i < vs.Len(); i++ {
-		if vs.IsValid(i) {
-			s.digest.Add(vs.Value(i), 1)
-			s.ok = true
-		}
+// accept reports whether a valid float should be added to
+// the quantile according to the policy for non-finite values.
+// NaN and infinite values are counted as nulls with the null
+// policy and in their own counts otherwise.
+func (c *quantileCounts) accept(v float64, policy string) bool {
+	isNaN, isInf := math.IsNaN(v), math.IsInf(v, 0)
+	if !isNaN && !isInf {
+		return true
 	}
-}
-
-func (s *QuantileAggState) DoInt(vs *array.Int) {
-	for i := 0; i < vs.Len(); i++ {
-		if vs.IsValid(i) {
-			s.digest.Add(float64(vs.Value(i)), 1)
-			s.ok = true
+	switch policy {
+	case nonFiniteNull:
+		c.nullCount++
+		return false
+	case nonFiniteError:
+		if c.err == nil {
+			c.err = errors.Newf(codes.Invalid, "quantile found non-finite value %v", v)
 		}
+		return false
 	}
+	if isNaN {
+		c.nanCount++
+	} else {
+		c.infCount++
+	}
+	return policy == nonFiniteInclude
 }
 
-func (s *QuantileAggState) DoUInt(vs *array.Uint) {
-	for i := 0; i < vs.Len(); i++ {
-		if vs.IsValid(i) {
-			s.digest.Add(float64(vs.Value(i)), 1)
-			s.ok = true
+// null handles a null value according to the null behavior.
+// It returns the value to add to the quantile in its place
+// and false if nothing should be added.
+func (c *quantileCounts) null(behavior string, fill float64) (float64, bool) {
+	switch behavior {
+	case nullBehaviorFill:
+		return fill, true
+	case nullBehaviorError:
+		if c.err == nil {
+			c.err = errors.New(codes.FailedPrecondition, "quantile found null value")
 		}
+		return 0, false
 	}
+	c.nullCount++
+	return 0, false
 }
 
-func (s *QuantileAggState) Type() flux.ColType {
-	return flux.TFloat
+// Err implements execute.ErrorValueFunc.
+func (c *quantileCounts) Err() error {
+	return c.err
 }
 
-func (s *QuantileAggState) ValueFloat() float64 {
-	return s.digest.Quantile(s.parent.Quantile)
+func (c *quantileCounts) values() []values.Value {
+	return []values.Value{
+		values.NewInt(c.nullCount),
+		values.NewInt(c.nanCount),
+		values.NewInt(c.infCount),
+	}
+}
+
+// TDigestStatsKey is the metadata key of the TDigestStats
+// of each digest computed by quantile when the EmitAggStats
+// execution option is set.
+const TDigestStatsKey = "flux/tdigest-stats"
+
+// TDigestStats describes the digest that computed a quantile
+// so its compression can be compared with the data it summarized.
+type TDigestStats struct {
+	Compression float64
+	// Centroids is the number of centroids of the digest
+	// and Weight is the total weight of its values.
+	Centroids int
+	Weight    float64
+}
+
+// quantileCancelInterval is the number of values a t-digest state
+// adds between checks of whether the query has been cancelled.
+const quantileCancelInterval = 4096
+
+type QuantileAggState struct {
+	digest   *tdigest.TDigest
+	parent   *QuantileAgg
+	quantile float64
+	ok       bool
+
+	quantileCounts
+}
+
+func (s *QuantileAggState) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if s.cancelled(i) {
+			return
+		}
+		if vs.IsNull(i) {
+			s.doNull()
+			continue
+		}
+		v := vs.Value(i)
+		if !s.accept(v, s.parent.NonFinite) {
+			continue
+		}
+		s.digest.Add(v, 1)
+		s.ok = true
+		s.count++
+	}
+}
+
+func (s *QuantileAggState) DoInt(vs *array.Int) {
+	for i := 0; i < vs.Len(); i++ {
+		if s.cancelled(i) {
+			return
+		}
+		if vs.IsNull(i) {
+			s.doNull()
+			continue
+		}
+		s.digest.Add(float64(vs.Value(i)), 1)
+		s.ok = true
+		s.count++
+	}
+}
+
+func (s *QuantileAggState) DoUInt(vs *array.Uint) {
+	for i := 0; i < vs.Len(); i++ {
+		if s.cancelled(i) {
+			return
+		}
+		if vs.IsNull(i) {
+			s.doNull()
+			continue
+		}
+		s.digest.Add(float64(vs.Value(i)), 1)
+		s.ok = true
+		s.count++
+	}
+}
+
+// cancelled reports whether the context of the parent has been
+// cancelled and records its error. The context is only checked
+// every quantileCancelInterval values so that a large array can
+// be abandoned promptly without checking it for every value.
+func (s *QuantileAggState) cancelled(i int) bool {
+	if i%quantileCancelInterval != 0 || s.parent.ctx == nil {
+		return false
+	}
+	if err := s.parent.ctx.Err(); err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return true
+	}
+	return false
+}
+
+// doNull adds the fill value in place of a null value
+// if the null behavior of the parent is to fill them.
+func (s *QuantileAggState) doNull() {
+	if v, ok := s.null(s.parent.NullBehavior, s.parent.FillValue); ok {
+		s.digest.Add(v, 1)
+		s.ok = true
+		s.count++
+	}
+}
+
+// doWeighted adds each value to the digest with the weight of
+// its row. Rows with a null or zero weight are skipped and a
+// negative weight is an error. A null value is replaced by
+// the fill value before it is weighted.
+func (s *QuantileAggState) doWeighted(vs, weights array.Array) {
+	for i := 0; i < vs.Len(); i++ {
+		if s.cancelled(i) {
+			return
+		}
+		var v float64
+		if vs.IsNull(i) {
+			fill, ok := s.null(s.parent.NullBehavior, s.parent.FillValue)
+			if !ok {
+				continue
+			}
+			v = fill
+		} else {
+			v = floatValue(vs, i)
+		}
+		if weights.IsNull(i) {
+			continue
+		}
+		w := floatValue(weights, i)
+		if math.IsNaN(w) || math.IsInf(w, 0) {
+			if s.err == nil {
+				s.err = errors.Newf(codes.Invalid, "quantile weight must be finite, got %v", w)
+			}
+			return
+		} else if w < 0 {
+			if s.err == nil {
+				s.err = errors.Newf(codes.Invalid, "quantile weight must not be negative, got %v", w)
+			}
+			return
+		} else if w == 0 {
+			continue
+		}
+		if !s.accept(v, s.parent.NonFinite) {
+			continue
+		}
+		s.digest.Add(v, w)
+		s.ok = true
+		s.count++
+	}
+}
+
+// floatValue returns the value of a numeric array as a float.
+func floatValue(arr array.Array, i int) float64 {
+	switch arr := arr.(type) {
+	case *array.Int:
+		return float64(arr.Value(i))
+	case *array.Uint:
+		return float64(arr.Value(i))
+	default:
+		return arr.(*array.Float).Value(i)
+	}
+}
+
+func (s *QuantileAggState) Type() flux.ColType {
+	return flux.TFloat
+}
+
+func (s *QuantileAggState) ValueFloat() float64 {
+	return s.digest.Quantile(s.quantile)
+}
+
+// ValueFloats returns the value of each of the
+// Quantiles of the parent aggregate.
+func (s *QuantileAggState) ValueFloats() []float64 {
+	vs := make([]float64, len(s.parent.Quantiles))
+	for i, q := range s.parent.Quantiles {
+		vs[i] = s.digest.Quantile(q)
+	}
+	return vs
 }
 
 func (s *QuantileAggState) IsNull() bool {
 	return !s.ok
 }
 
+// AuxiliaryColumns implements execute.AuxiliaryValueFunc.
+func (s *QuantileAggState) AuxiliaryColumns() []flux.ColMeta {
+	var cols []flux.ColMeta
+	if s.parent.CountSkipped {
+		cols = append(cols, skippedCountColumns...)
+	}
+	if s.parent.WithCount {
+		cols = append(cols, valueCountColumn)
+	}
+	return cols
+}
+
+// AuxiliaryValues implements execute.AuxiliaryValueFunc.
+func (s *QuantileAggState) AuxiliaryValues() []values.Value {
+	var vs []values.Value
+	if s.parent.CountSkipped {
+		vs = append(vs, s.quantileCounts.values()...)
+	}
+	if s.parent.WithCount {
+		vs = append(vs, values.NewInt(s.count))
+	}
+	return vs
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// The encoded state is the state merged by mergeQuantileState
+// and the state that the t-digest quantile records in a checkpoint.
+func (s *QuantileAggState) MarshalBinary() ([]byte, error) {
+	centroids := s.digest.Centroids(nil)
+	floats := make([]float64, 0, 2*len(centroids))
+	for _, c := range centroids {
+		floats = append(floats, c.Mean, c.Weight)
+	}
+	return marshalQuantileState(s.ok, s.quantileCounts, floats)
+}
+
+func (s *QuantileAggState) UnmarshalBinary(data []byte) error {
+	s.digest.Reset()
+	s.ok, s.quantileCounts = false, quantileCounts{}
+	return s.merge(data)
+}
+
+// merge adds the centroids and counts of a state encoded by
+// MarshalBinary to the state. The digest of the state keeps its
+// own compression, so states encoded with a different compression
+// are merged with the accuracy of this state.
+func (s *QuantileAggState) merge(data []byte) error {
+	ok, counts, floats, err := unmarshalQuantileState(data)
+	if err != nil {
+		return err
+	}
+	if len(floats)%2 != 0 {
+		return errors.New(codes.Internal, "invalid quantile state")
+	}
+	for i := 0; i < len(floats); i += 2 {
+		s.digest.Add(floats[i], floats[i+1])
+	}
+	s.ok = s.ok || ok
+	s.nullCount += counts.nullCount
+	s.nanCount += counts.nanCount
+	s.infCount += counts.infCount
+	s.count += counts.count
+	return nil
+}
+
+// marshalQuantileState encodes the counts and values of a quantile state.
+func marshalQuantileState(ok bool, counts quantileCounts, floats []float64) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, v := range []interface{}{ok, counts.nullCount, counts.nanCount, counts.infCount, counts.count, int64(len(floats)), floats} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			return nil, errors.Wrap(err, codes.Internal, "failed to encode quantile state")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalQuantileState(data []byte) (ok bool, counts quantileCounts, floats []float64, err error) {
+	r := bytes.NewReader(data)
+	var n int64
+	for _, v := range []interface{}{&ok, &counts.nullCount, &counts.nanCount, &counts.infCount, &counts.count, &n} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return false, quantileCounts{}, nil, errors.Wrap(err, codes.Internal, "failed to decode quantile state")
+		}
+	}
+	if n < 0 || n*8 != int64(r.Len()) {
+		return false, quantileCounts{}, nil, errors.New(codes.Internal, "invalid quantile state")
+	}
+	floats = make([]float64, n)
+	if err := binary.Read(r, binary.LittleEndian, floats); err != nil {
+		return false, quantileCounts{}, nil, errors.Wrap(err, codes.Internal, "failed to decode quantile state")
+	}
+	return ok, counts, floats, nil
+}
+
+// Close reports the statistics of the digest if they are
+// emitted and returns the digest to its parent for reuse.
 func (s *QuantileAggState) Close() error {
+	if s.digest != nil && s.parent.stats != nil {
+		s.parent.stats.Add(TDigestStatsKey, TDigestStats{
+			Compression: s.parent.Compression,
+			Centroids:   len(s.digest.Centroids(nil)),
+			Weight:      s.digest.Count(),
+		})
+	}
 	s.parent.pushFreeDigest(s.digest)
 	s.digest = nil
 	return nil
 }
 
+type tdigestQuantilesTransformation struct {
+	agg          *QuantileAgg
+	columns      []string
+	weightColumn string
+}
+
+// NewTDigestQuantilesTransformation creates a transformation that computes
+// each of the Quantiles of the spec from a single t-digest per column.
+//
+// The output has one row with the group key of the table and a float column
+// for each quantile of each column. The column is named after the aggregated
+// column and the quantile as a percentile, so the 0.95 quantile of _value is
+// reported in _value_p95. A quantile is null if the column has no values.
+// If the spec has no Quantiles, the Quantile of each column is reported in
+// the column itself in the same way as the quantile aggregate.
+//
+// Each value is added to the t-digest with the weight from the WeightColumn
+// of the spec if it is set. Rows with a null or zero weight are skipped.
+func NewTDigestQuantilesTransformation(id execute.DatasetID, spec *TDigestQuantileProcedureSpec, mem *memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	return newTDigestQuantilesTransformation(context.Background(), id, spec, mem, nil, 0)
+}
+
+// newTDigestQuantilesTransformation creates the transformation and
+// reports the statistics of each of its digests to stats if it is set.
+// The free digests may use the fraction of the memory limit of mem.
+// The digests stop adding values once ctx is cancelled if it is set.
+func newTDigestQuantilesTransformation(ctx context.Context, id execute.DatasetID, spec *TDigestQuantileProcedureSpec, mem *memory.Allocator, stats *execute.AggStats, fraction float64) (execute.Transformation, execute.Dataset, error) {
+	agg := NewQuantileAgg(spec.Quantile, spec.Compression, mem, len(spec.Columns))
+	agg.setFreeDigestBudget(fraction)
+	agg.ctx = ctx
+	agg.stats = stats
+	agg.Quantiles = spec.Quantiles
+	agg.QuantileColumn, agg.QuantileLookup = spec.QuantileColumn, spec.QuantileLookup
+	agg.CountSkipped, agg.NonFinite = spec.CountSkipped, spec.NonFinite
+	agg.NullBehavior, agg.FillValue = spec.NullBehavior, spec.FillValue
+	agg.WithCount = spec.WithCount
+	t := &tdigestQuantilesTransformation{
+		agg:          agg,
+		columns:      spec.Columns,
+		weightColumn: spec.WeightColumn,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+// tdigestQuantilesState holds the state of each column
+// along with the type of the column it was created for.
+type tdigestQuantilesState struct {
+	types  []flux.ColType
+	states []*QuantileAggState
+}
+
+func (s *tdigestQuantilesState) Close() error {
+	for _, state := range s.states {
+		if err := state.Close(); err != nil {
+			return err
+		}
+	}
+	s.states = nil
+	return nil
+}
+
+func (t *tdigestQuantilesTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *tdigestQuantilesState
+	if state != nil {
+		s = state.(*tdigestQuantilesState)
+	} else {
+		q, err := resolveQuantile(t.agg.Quantile, t.agg.QuantileColumn, t.agg.QuantileLookup, chunk.Key())
+		if err != nil {
+			return nil, false, err
+		}
+		s = &tdigestQuantilesState{
+			types:  make([]flux.ColType, len(t.columns)),
+			states: make([]*QuantileAggState, len(t.columns)),
+		}
+		for j, label := range t.columns {
+			idx := chunk.Index(label)
+			if idx < 0 {
+				return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+			}
+			if chunk.Key().HasCol(label) {
+				return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+			}
+			switch typ := chunk.Col(idx).Type; typ {
+			case flux.TInt, flux.TUInt, flux.TFloat:
+				s.types[j] = typ
+			default:
+				return nil, false, errors.Newf(codes.FailedPrecondition, "unsupported aggregate column type %v", typ)
+			}
+			s.states[j] = t.agg.newState(q)
+		}
+	}
+
+	var weights array.Array
+	if t.weightColumn != "" {
+		idx := chunk.Index(t.weightColumn)
+		if idx < 0 {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "weight column %q does not exist", t.weightColumn)
+		}
+		switch typ := chunk.Col(idx).Type; typ {
+		case flux.TInt, flux.TUInt, flux.TFloat:
+			weights = chunk.Values(idx)
+		default:
+			return nil, false, errors.Newf(codes.FailedPrecondition, "weight column %q must be numeric, got %v", t.weightColumn, typ)
+		}
+	}
+
+	for j, label := range t.columns {
+		idx := chunk.Index(label)
+		if idx < 0 {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", label)
+		}
+		if typ := chunk.Col(idx).Type; typ != s.types[j] {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "aggregate type conflict: %s != %s", typ, s.types[j])
+		}
+		switch {
+		case weights != nil:
+			s.states[j].doWeighted(chunk.Values(idx), weights)
+		case s.types[j] == flux.TInt:
+			s.states[j].DoInt(chunk.Ints(idx))
+		case s.types[j] == flux.TUInt:
+			s.states[j].DoUInt(chunk.Uints(idx))
+		case s.types[j] == flux.TFloat:
+			s.states[j].DoFloat(chunk.Floats(idx))
+		}
+		if err := s.states[j].Err(); err != nil {
+			return nil, false, err
+		}
+	}
+	return s, true, nil
+}
+
+// tdigestQuantilesSnapshot is the encoded form of a tdigestQuantilesState.
+type tdigestQuantilesSnapshot struct {
+	Types  []flux.ColType
+	States [][]byte
+}
+
+// SnapshotState implements execute.StateSnapshotter.
+func (t *tdigestQuantilesTransformation) SnapshotState(key flux.GroupKey, state interface{}) ([]byte, error) {
+	s := state.(*tdigestQuantilesState)
+	snapshot := tdigestQuantilesSnapshot{
+		Types:  s.types,
+		States: make([][]byte, len(s.states)),
+	}
+	for j, state := range s.states {
+		data, err := state.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		snapshot.States[j] = data
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to encode quantile state")
+	}
+	return buf.Bytes(), nil
+}
+
+// RestoreState implements execute.StateSnapshotter.
+func (t *tdigestQuantilesTransformation) RestoreState(key flux.GroupKey, data []byte) (interface{}, error) {
+	var snapshot tdigestQuantilesSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "failed to decode quantile state")
+	}
+	if len(snapshot.States) != len(t.columns) || len(snapshot.Types) != len(t.columns) {
+		return nil, errors.Newf(codes.Internal, "checkpoint has %d quantile states, expected %d", len(snapshot.States), len(t.columns))
+	}
+
+	q, err := resolveQuantile(t.agg.Quantile, t.agg.QuantileColumn, t.agg.QuantileLookup, key)
+	if err != nil {
+		return nil, err
+	}
+	s := &tdigestQuantilesState{
+		types:  snapshot.Types,
+		states: make([]*QuantileAggState, 0, len(snapshot.States)),
+	}
+	for _, data := range snapshot.States {
+		state := t.agg.newState(q)
+		s.states = append(s.states, state)
+		if err := state.UnmarshalBinary(data); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (t *tdigestQuantilesTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*tdigestQuantilesState)
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+len(t.columns)*len(t.agg.Quantiles)),
+	}
+	buffer.Columns = append(buffer.Columns, key.Cols()...)
+	buffer.Values = make([]array.Array, len(key.Cols()), cap(buffer.Columns))
+	for j := range key.Cols() {
+		buffer.Values[j] = arrow.Repeat(key.Cols()[j].Type, key.Value(j), 1, mem)
+	}
+
+	for j, label := range t.columns {
+		if len(t.agg.Quantiles) == 0 {
+			buffer.Columns = append(buffer.Columns, flux.ColMeta{
+				Label: label,
+				Type:  flux.TFloat,
+			})
+			buffer.Values = append(buffer.Values, array.FloatRepeat(s.states[j].ValueFloat(), s.states[j].IsNull(), 1, mem))
+			continue
+		}
+		vs := s.states[j].ValueFloats()
+		for i, q := range t.agg.Quantiles {
+			buffer.Columns = append(buffer.Columns, flux.ColMeta{
+				Label: quantileColumnLabel(label, q),
+				Type:  flux.TFloat,
+			})
+			buffer.Values = append(buffer.Values, array.FloatRepeat(vs[i], s.states[j].IsNull(), 1, mem))
+		}
+	}
+	for _, state := range s.states {
+		for i, col := range state.AuxiliaryColumns() {
+			v := state.AuxiliaryValues()[i]
+			buffer.Columns = append(buffer.Columns, col)
+			buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, v, 1, mem))
+		}
+	}
+
+	if err := buffer.Validate(); err != nil {
+		return err
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *tdigestQuantilesTransformation) Close() error {
+	return t.agg.Close()
+}
+
+// quantileColumnLabel returns the label of the column that reports
+// the quantile q of a column. The quantile is written as a percentile
+// without any trailing zeros, such as p50 for 0.5 or p99.9 for 0.999.
+// The percentile is formatted from the decimal digits of the quantile
+// so that it is not affected by the rounding of multiplying it by 100.
+func quantileColumnLabel(column string, q float64) string {
+	digits := strconv.FormatFloat(q, 'f', -1, 64)
+	whole, frac := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	p := strings.TrimLeft(whole+frac[:2], "0")
+	if p == "" {
+		p = "0"
+	}
+	if len(frac) > 2 {
+		p += "." + frac[2:]
+	}
+	return column + "_p" + p
+}
+
 type ExactQuantileAgg struct {
 	Quantile float64
-	data     []float64
+	// QuantileColumn and QuantileLookup optionally
+	// override Quantile for each group key.
+	QuantileColumn string
+	QuantileLookup map[string]float64
+	// CountSkipped reports the number of null, NaN, and infinite values.
+	CountSkipped bool
+	// NonFinite is the policy for NaN and infinite values.
+	// An empty policy skips them.
+	NonFinite string
+	// NullBehavior is the policy for null values. An empty
+	// policy skips them. FillValue replaces null values
+	// with the fill policy.
+	NullBehavior string
+	FillValue    float64
+	// Interpolation is how a quantile that falls between
+	// two ranks is computed. An empty interpolation is linear.
+	Interpolation string
+	// SpillThreshold is the number of values that are buffered in
+	// memory before they are sorted and written to a temporary file.
+	// The memory of the buffer is accounted for with the allocator.
+	// A threshold of zero keeps every value in memory.
+	SpillThreshold int
+	data           []float64
+
+	mem *memory.Allocator
+	// accounted is the number of bytes accounted for the buffer
+	// and spill holds the values written to a temporary file.
+	accounted int
+	spill     *quantileSpill
+	// runs are the sorted values of the states that were merged
+	// into the aggregate. They are merged with the other values
+	// when the quantile is computed instead of being sorted again.
+	// runBytes is the number of bytes accounted for the runs.
+	runs     [][]float64
+	runBytes int
+
+	quantileCounts
 }
 
 func createExactQuantileAggTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
@@ -345,18 +1660,63 @@ func createExactQuantileAggTransformation(id execute.DatasetID, mode execute.Acc
 	if !ok {
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", ps)
 	}
-	agg := &ExactQuantileAgg{
-		Quantile: ps.Quantile,
+	agg := newExactQuantileAggFromSpec(ps, a.Allocator())
+	if execute.HaveExecutionDependencies(a.Context()) {
+		if opts := execute.GetExecutionDependencies(a.Context()).ExecutionOptions; opts != nil {
+			agg.SpillThreshold = opts.ExactQuantileSpillThreshold
+		}
 	}
 	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
 }
 
+func newExactQuantileAggFromSpec(ps *ExactQuantileAggProcedureSpec, mem *memory.Allocator) *ExactQuantileAgg {
+	return &ExactQuantileAgg{
+		Quantile:       ps.Quantile,
+		QuantileColumn: ps.QuantileColumn,
+		QuantileLookup: ps.QuantileLookup,
+		CountSkipped:   ps.CountSkipped,
+		NonFinite:      ps.NonFinite,
+		NullBehavior:   ps.NullBehavior,
+		FillValue:      ps.FillValue,
+		Interpolation:  ps.Interpolation,
+		mem:            mem,
+	}
+}
+
+// NewExactQuantileAgg creates an exact quantile that buffers up to
+// spillThreshold values in memory accounted for with mem before it
+// writes them to a temporary file.
+func NewExactQuantileAgg(q float64, spillThreshold int, mem *memory.Allocator) *ExactQuantileAgg {
+	return &ExactQuantileAgg{
+		Quantile:       q,
+		SpillThreshold: spillThreshold,
+		mem:            mem,
+	}
+}
+
 func (a *ExactQuantileAgg) Copy() *ExactQuantileAgg {
 	na := new(ExactQuantileAgg)
 	*na = *a
 	na.data = nil
+	na.accounted = 0
+	na.spill = nil
+	na.runs = nil
+	na.runBytes = 0
+	na.quantileCounts = quantileCounts{}
 	return na
 }
+
+// ForKey implements execute.GroupKeySimpleAggregate.
+func (a *ExactQuantileAgg) ForKey(key flux.GroupKey) (execute.SimpleAggregate, error) {
+	q, err := resolveQuantile(a.Quantile, a.QuantileColumn, a.QuantileLookup, key)
+	if err != nil {
+		return nil, err
+	}
+	na := a.Copy()
+	na.Quantile = q
+	return na, nil
+}
+
 func (a *ExactQuantileAgg) NewBoolAgg() execute.DoBoolAgg {
 	return nil
 }
@@ -378,14 +1738,17 @@ func (a *ExactQuantileAgg) NewStringAgg() execute.DoStringAgg {
 }
 
 func (a *ExactQuantileAgg) DoFloat(vs *array.Float) {
-	if vs.NullN() == 0 {
-		a.data = append(a.data, vs.Float64Values()...)
+	if a.SpillThreshold > 0 {
+		a.doFloatSpill(vs)
 		return
 	}
 
 	// Check if we have enough space for the floats
 	// inside of the array.
 	l := vs.Len() - vs.NullN()
+	if a.NullBehavior == nullBehaviorFill {
+		l = vs.Len()
+	}
 	if len(a.data)+l > cap(a.data) {
 		// We do not. Create an array with the needed size and
 		// copy over the existing data.
@@ -395,39 +1758,498 @@ func (a *ExactQuantileAgg) DoFloat(vs *array.Float) {
 	}
 
 	for i := 0; i < vs.Len(); i++ {
-		if vs.IsValid(i) {
+		if vs.IsNull(i) {
+			if v, ok := a.null(a.NullBehavior, a.FillValue); ok {
+				a.data = append(a.data, v)
+			}
+			continue
+		}
+		if a.accept(vs.Value(i), a.NonFinite) {
 			a.data = append(a.data, vs.Value(i))
 		}
 	}
 }
 
+// doFloatSpill adds the values to a buffer of SpillThreshold values.
+// Each time the buffer is full, it is sorted and written to the
+// temporary file as a run. The file is removed if there is an error
+// since the aggregate is not closed when it reports an error.
+func (a *ExactQuantileAgg) doFloatSpill(vs *array.Float) {
+	if a.err != nil {
+		return
+	}
+	if a.accounted == 0 {
+		// Values restored from a checkpoint may not fit in the buffer
+		// until the buffer is written to the temporary file.
+		n := a.SpillThreshold
+		if len(a.data) > n {
+			n = len(a.data)
+		}
+		if err := a.mem.Account(8 * n); err != nil {
+			a.err = err
+			return
+		}
+		a.accounted = 8 * n
+		data := make([]float64, len(a.data), n)
+		copy(data, a.data)
+		a.data = data
+	}
+
+	for i := 0; i < vs.Len() && a.err == nil; i++ {
+		if vs.IsNull(i) {
+			if v, ok := a.null(a.NullBehavior, a.FillValue); ok {
+				a.data = append(a.data, v)
+			}
+		} else if a.accept(vs.Value(i), a.NonFinite) {
+			a.data = append(a.data, vs.Value(i))
+		}
+		if len(a.data) >= a.SpillThreshold {
+			a.spillRun()
+		}
+	}
+	if a.err != nil {
+		a.closeSpill()
+	}
+}
+
+// spillRun sorts the buffer, writes it to the
+// temporary file as a run, and empties the buffer.
+func (a *ExactQuantileAgg) spillRun() {
+	if a.spill == nil {
+		spill, err := newQuantileSpill()
+		if err != nil {
+			a.err = errors.Wrap(err, codes.Internal, "failed to create quantile spill file")
+			return
+		}
+		a.spill = spill
+	}
+	sort.Float64s(a.data)
+	if err := a.spill.write(a.data); err != nil {
+		a.err = errors.Wrap(err, codes.Internal, "failed to spill quantile values")
+		return
+	}
+	a.data = a.data[:0]
+}
+
+// closeSpill removes the temporary file if there is one.
+func (a *ExactQuantileAgg) closeSpill() error {
+	if a.spill == nil {
+		return nil
+	}
+	err := a.spill.close()
+	a.spill = nil
+	return err
+}
+
+// Close removes the temporary file, releases the memory
+// of the buffer and the merged runs and reports any error
+// from reading the temporary file when the quantile was computed.
+func (a *ExactQuantileAgg) Close() error {
+	err := a.closeSpill()
+	if a.accounted > 0 {
+		a.mem.Account(-a.accounted)
+		a.accounted = 0
+	}
+	if a.runBytes > 0 {
+		a.mem.Account(-a.runBytes)
+		a.runBytes = 0
+	}
+	a.data, a.runs = nil, nil
+	if a.err != nil {
+		return a.err
+	}
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+// so the values can be recorded in a checkpoint.
+func (a *ExactQuantileAgg) MarshalBinary() ([]byte, error) {
+	if a.spill != nil {
+		return nil, errors.New(codes.Unimplemented, "cannot checkpoint a quantile that has spilled to disk")
+	}
+	return marshalQuantileState(len(a.data) > 0, a.quantileCounts, a.data)
+}
+
+// Sorted sorts the values that are buffered in memory and returns them.
+// The values that were written to a temporary file or merged from other
+// states are not included. The returned slice must not be modified.
+func (a *ExactQuantileAgg) Sorted() []float64 {
+	sort.Float64s(a.data)
+	return a.data
+}
+
+// merge adds the counts and the sorted values of a state encoded
+// with the sorted values of another aggregate. The values are kept
+// as a separate run so they are not sorted again. The memory of the
+// run is accounted for with the allocator until the aggregate is closed.
+func (a *ExactQuantileAgg) merge(data []byte) error {
+	_, counts, floats, err := unmarshalQuantileState(data)
+	if err != nil {
+		return err
+	}
+	if len(floats) > 0 {
+		if err := a.mem.Account(8 * len(floats)); err != nil {
+			return err
+		}
+		a.runBytes += 8 * len(floats)
+		a.runs = append(a.runs, floats)
+	}
+	a.nullCount += counts.nullCount
+	a.nanCount += counts.nanCount
+	a.infCount += counts.infCount
+	return nil
+}
+
+func (a *ExactQuantileAgg) UnmarshalBinary(data []byte) error {
+	_, counts, floats, err := unmarshalQuantileState(data)
+	if err != nil {
+		return err
+	}
+	a.data, a.quantileCounts = floats, counts
+	return nil
+}
+
 func (a *ExactQuantileAgg) Type() flux.ColType {
 	return flux.TFloat
 }
 
 func (a *ExactQuantileAgg) ValueFloat() float64 {
+	n := a.count()
+	if n == 0 {
+		return 0
+	}
 	sort.Float64s(a.data)
 
-	x := a.Quantile * float64(len(a.data)-1)
+	x := a.Quantile * float64(n-1)
 	x0 := math.Floor(x)
 	x1 := math.Ceil(x)
+	ys, err := a.ranks(int(x0), int(x1), int(math.RoundToEven(x)))
+	if err != nil {
+		a.err = errors.Wrap(err, codes.Internal, "failed to read quantile spill file")
+		return 0
+	}
+	y0, y1 := ys[0], ys[1]
+
+	switch a.Interpolation {
+	case interpolationLower:
+		return y0
+	case interpolationHigher:
+		return y1
+	case interpolationNearest:
+		return ys[2]
+	case interpolationMidpoint:
+		return (y0 + y1) / 2
+	}
 
 	if x0 == x1 {
-		return a.data[int(x0)]
+		return y0
 	}
 
 	// Linear interpolate
-	y0 := a.data[int(x0)]
-	y1 := a.data[int(x1)]
 	y := y0*(x1-x) + y1*(x-x0)
 
 	return y
 }
 
+// count returns the number of values that have been added.
+func (a *ExactQuantileAgg) count() int {
+	n := len(a.data)
+	for _, run := range a.runs {
+		n += len(run)
+	}
+	if a.spill != nil {
+		n += a.spill.n
+	}
+	return n
+}
+
+// ranks returns the values at each of the ranks of the sorted values,
+// merging the values in memory with the merged runs and the runs in
+// the temporary file.
+func (a *ExactQuantileAgg) ranks(ranks ...int) ([]float64, error) {
+	if a.spill == nil && len(a.runs) == 0 {
+		ys := make([]float64, len(ranks))
+		for i, rank := range ranks {
+			ys[i] = a.data[rank]
+		}
+		return ys, nil
+	}
+
+	readers := make([]*quantileRunReader, 0, len(a.runs)+1)
+	readers = append(readers, &quantileRunReader{values: a.data})
+	for _, run := range a.runs {
+		readers = append(readers, &quantileRunReader{values: run})
+	}
+	if a.spill != nil {
+		readers = append(readers, a.spill.readers()...)
+	}
+	return mergeRanks(readers, ranks...)
+}
+
 func (a *ExactQuantileAgg) IsNull() bool {
-	return len(a.data) == 0
+	return a.count() == 0
+}
+
+// AuxiliaryColumns implements execute.AuxiliaryValueFunc.
+func (a *ExactQuantileAgg) AuxiliaryColumns() []flux.ColMeta {
+	if !a.CountSkipped {
+		return nil
+	}
+	return skippedCountColumns
+}
+
+// AuxiliaryValues implements execute.AuxiliaryValueFunc.
+func (a *ExactQuantileAgg) AuxiliaryValues() []values.Value {
+	if !a.CountSkipped {
+		return nil
+	}
+	return a.quantileCounts.values()
+}
+
+// P2QuantileAgg estimates a quantile with the P-square algorithm
+// of Jain and Chlamtac. The estimate is maintained with five markers
+// so the memory used does not depend on the number of values.
+type P2QuantileAgg struct {
+	Quantile float64
+	// QuantileColumn and QuantileLookup optionally
+	// override Quantile for each group key.
+	QuantileColumn string
+	QuantileLookup map[string]float64
+	// CountSkipped reports the number of null, NaN, and infinite values.
+	CountSkipped bool
+	// NonFinite is the policy for NaN and infinite values.
+	// An empty policy skips them.
+	NonFinite string
+	// NullBehavior is the policy for null values. An empty
+	// policy skips them. FillValue replaces null values
+	// with the fill policy.
+	NullBehavior string
+	FillValue    float64
+
+	// count is the number of values that have been added.
+	// heights holds the first values until there are five
+	// of them and then the heights of the markers.
+	// positions holds the positions of the markers.
+	count     int64
+	heights   [5]float64
+	positions [5]float64
+
+	quantileCounts
+}
+
+func createP2QuantileAggTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*P2QuantileAggProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", ps)
+	}
+	agg := &P2QuantileAgg{
+		Quantile:       ps.Quantile,
+		QuantileColumn: ps.QuantileColumn,
+		QuantileLookup: ps.QuantileLookup,
+		CountSkipped:   ps.CountSkipped,
+		NonFinite:      ps.NonFinite,
+		NullBehavior:   ps.NullBehavior,
+		FillValue:      ps.FillValue,
+	}
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
+}
+
+func (a *P2QuantileAgg) Copy() *P2QuantileAgg {
+	na := new(P2QuantileAgg)
+	*na = *a
+	na.count = 0
+	na.quantileCounts = quantileCounts{}
+	return na
+}
+
+// ForKey implements execute.GroupKeySimpleAggregate.
+func (a *P2QuantileAgg) ForKey(key flux.GroupKey) (execute.SimpleAggregate, error) {
+	q, err := resolveQuantile(a.Quantile, a.QuantileColumn, a.QuantileLookup, key)
+	if err != nil {
+		return nil, err
+	}
+	na := a.Copy()
+	na.Quantile = q
+	return na, nil
+}
+
+func (a *P2QuantileAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *P2QuantileAgg) NewIntAgg() execute.DoIntAgg {
+	return nil
+}
+
+func (a *P2QuantileAgg) NewUIntAgg() execute.DoUIntAgg {
+	return nil
+}
+
+func (a *P2QuantileAgg) NewFloatAgg() execute.DoFloatAgg {
+	return a.Copy()
+}
+
+func (a *P2QuantileAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+func (a *P2QuantileAgg) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len(); i++ {
+		if vs.IsNull(i) {
+			if v, ok := a.null(a.NullBehavior, a.FillValue); ok {
+				a.add(v)
+			}
+			continue
+		}
+		if a.accept(vs.Value(i), a.NonFinite) {
+			a.add(vs.Value(i))
+		}
+	}
+}
+
+// add adds a value to the estimate.
+func (a *P2QuantileAgg) add(v float64) {
+	h, n := &a.heights, &a.positions
+	if a.count < 5 {
+		h[a.count] = v
+		a.count++
+		if a.count == 5 {
+			sort.Float64s(h[:])
+			for i := range n {
+				n[i] = float64(i)
+			}
+		}
+		return
+	}
+
+	// Find the cell that the value falls in,
+	// extending the extreme markers if needed.
+	var k int
+	switch {
+	case v < h[0]:
+		h[0] = v
+	case v >= h[4]:
+		h[4] = v
+		k = 3
+	default:
+		for v >= h[k+1] {
+			k++
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		n[i]++
+	}
+	a.count++
+
+	// Adjust the heights of the middle markers
+	// that are off of their desired positions.
+	p := a.Quantile
+	increments := [5]float64{0, p / 2, p, (1 + p) / 2, 1}
+	for i := 1; i < 4; i++ {
+		d := float64(a.count-1)*increments[i] - n[i]
+		if (d >= 1 && n[i+1]-n[i] > 1) || (d <= -1 && n[i-1]-n[i] < -1) {
+			d = math.Copysign(1, d)
+			v := a.parabolic(i, d)
+			if h[i-1] < v && v < h[i+1] {
+				h[i] = v
+			} else {
+				j := i + int(d)
+				h[i] += d * (h[j] - h[i]) / (n[j] - n[i])
+			}
+			n[i] += d
+		}
+	}
+}
+
+// parabolic returns the height of marker i moved by d
+// using the piecewise parabolic prediction formula.
+func (a *P2QuantileAgg) parabolic(i int, d float64) float64 {
+	h, n := &a.heights, &a.positions
+	return h[i] + d/(n[i+1]-n[i-1])*((n[i]-n[i-1]+d)*(h[i+1]-h[i])/(n[i+1]-n[i])+(n[i+1]-n[i]-d)*(h[i]-h[i-1])/(n[i]-n[i-1]))
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+// so the markers can be recorded in a checkpoint.
+func (a *P2QuantileAgg) MarshalBinary() ([]byte, error) {
+	floats := make([]float64, 0, 1+len(a.heights)+len(a.positions))
+	floats = append(floats, float64(a.count))
+	floats = append(floats, a.heights[:]...)
+	floats = append(floats, a.positions[:]...)
+	return marshalQuantileState(a.count > 0, a.quantileCounts, floats)
+}
+
+func (a *P2QuantileAgg) UnmarshalBinary(data []byte) error {
+	_, counts, floats, err := unmarshalQuantileState(data)
+	if err != nil {
+		return err
+	}
+	if len(floats) != 1+len(a.heights)+len(a.positions) {
+		return errors.New(codes.Internal, "invalid quantile state")
+	}
+	a.count = int64(floats[0])
+	copy(a.heights[:], floats[1:])
+	copy(a.positions[:], floats[1+len(a.heights):])
+	a.quantileCounts = counts
+	return nil
+}
+
+func (a *P2QuantileAgg) Type() flux.ColType {
+	return flux.TFloat
+}
+
+func (a *P2QuantileAgg) ValueFloat() float64 {
+	if a.count >= 5 {
+		// The extreme markers are the exact minimum and maximum.
+		switch a.Quantile {
+		case 0:
+			return a.heights[0]
+		case 1:
+			return a.heights[4]
+		}
+		return a.heights[2]
+	}
+
+	// The markers are not initialized until there are
+	// five values so interpolate the values directly.
+	data := make([]float64, a.count)
+	copy(data, a.heights[:a.count])
+	sort.Float64s(data)
+	exact := ExactQuantileAgg{Quantile: a.Quantile, data: data}
+	return exact.ValueFloat()
+}
+
+func (a *P2QuantileAgg) IsNull() bool {
+	return a.count == 0
 }
 
+// AuxiliaryColumns implements execute.AuxiliaryValueFunc.
+func (a *P2QuantileAgg) AuxiliaryColumns() []flux.ColMeta {
+	if !a.CountSkipped {
+		return nil
+	}
+	return skippedCountColumns
+}
+
+// AuxiliaryValues implements execute.AuxiliaryValueFunc.
+func (a *P2QuantileAgg) AuxiliaryValues() []values.Value {
+	if !a.CountSkipped {
+		return nil
+	}
+	return a.quantileCounts.values()
+}
+
+// skippedCountColumns are the columns reported by the quantile
+// aggregates when counting skipped values.
+var skippedCountColumns = []flux.ColMeta{
+	{Label: "_nullCount", Type: flux.TInt},
+	{Label: "_nanCount", Type: flux.TInt},
+	{Label: "_infCount", Type: flux.TInt},
+}
+
+// valueCountColumn is the column reported by the t-digest quantile
+// with the number of values that were added to the digest.
+var valueCountColumn = flux.ColMeta{Label: "_count", Type: flux.TInt}
+
 func createExactQuantileSelectTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
 	ps, ok := spec.(*ExactQuantileSelectProcedureSpec)
 	if !ok {
@@ -469,189 +2291,192 @@ func (t *ExactQuantileSelectorTransformation) Process(id execute.DatasetID, tbl
 		return errors.Newf(codes.FailedPrecondition, "no column %q exists", t.spec.Column)
 	}
 
+	quantile, err := resolveQuantile(t.spec.Quantile, t.spec.QuantileColumn, t.spec.QuantileLookup, tbl.Key())
+	if err != nil {
+		return err
+	}
+
 	var row execute.Row
-	switch typ := tbl.Cols()[valueIdx].Type; typ {
-	case flux.TFloat:
-		type floatValue struct {
-			value float64
-			row   execute.Row
-		}
-
-		var rows []floatValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.Floats(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, floatValue{
-						value: vs.Value(i),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
-			}
-			return nil
-		}); err != nil {
+	if len(t.spec.SelectorColumns) > 1 || t.spec.TieBreak != "" {
+		row, err = t.selectRowByColumns(tbl, quantile)
+		if err != nil {
 			return err
 		}
+		return t.appendRow(tbl, row)
+	}
 
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				return rows[i].value < rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
-		}
-	case flux.TInt:
-		type intValue struct {
-			value int64
-			row   execute.Row
-		}
-
-		var rows []intValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.Ints(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, intValue{
-						value: vs.Value(i),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
-			}
-			return nil
-		}); err != nil {
-			return err
-		}
+	row, err = selectRowByIndex(tbl, t.spec.Column, func(n int) int {
+		return getQuantileIndex(quantile, n)
+	})
+	if err != nil {
+		return err
+	}
+	return t.appendRow(tbl, row)
+}
 
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				return rows[i].value < rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
-		}
-	case flux.TUInt:
-		type uintValue struct {
-			value uint64
-			row   execute.Row
-		}
-
-		var rows []uintValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.UInts(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, uintValue{
-						value: vs.Value(i),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
-			}
-			return nil
-		}); err != nil {
-			return err
+// selectRowByIndex returns the row at an index of the non-null rows of
+// the table sorted by the values of the column. Rows with equal values
+// remain in input order. The index function is called with the number
+// of non-null rows, which is never zero. If there are no such rows,
+// the row has no values.
+func selectRowByIndex(tbl flux.Table, column string, index func(n int) int) (execute.Row, error) {
+	valueIdx := execute.ColIdx(column, tbl.Cols())
+	if valueIdx < 0 {
+		return execute.Row{}, errors.Newf(codes.FailedPrecondition, "no column %q exists", column)
+	}
+
+	rows, release, err := readSelectorRows(tbl, valueIdx)
+	defer release()
+	if err != nil || len(rows) == 0 {
+		return execute.Row{}, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareSelectorValues(rows[i], rows[j], valueIdx) < 0
+	})
+	return rows[index(len(rows))].row(), nil
+}
+
+// selectRowByColumns returns the row at the quantile of the table sorted
+// lexicographically by the selector columns. Rows with a null value in the
+// first column are ignored and a null value in any of the remaining columns
+// sorts after all other values.
+//
+// When a tie break is set, the rows that share the selector values of the
+// row at the quantile are ordered by the tie break and the first of them
+// is returned instead.
+func (t *ExactQuantileSelectorTransformation) selectRowByColumns(tbl flux.Table, quantile float64) (execute.Row, error) {
+	labels := t.spec.SelectorColumns
+	if len(labels) == 0 {
+		labels = []string{t.spec.Column}
+	}
+	idxs := make([]int, len(labels))
+	for i, label := range labels {
+		idxs[i] = execute.ColIdx(label, tbl.Cols())
+		if idxs[i] < 0 {
+			return execute.Row{}, errors.Newf(codes.FailedPrecondition, "no column %q exists", label)
 		}
+	}
+	tieIdx := -1
+	if t.spec.TieBreak == tieBreakMin || t.spec.TieBreak == tieBreakMax {
+		tieIdx = execute.ColIdx(t.spec.TieBreakColumn, tbl.Cols())
+		if tieIdx < 0 {
+			return execute.Row{}, errors.Newf(codes.FailedPrecondition, "no column %q exists", t.spec.TieBreakColumn)
+		}
+	}
 
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				return rows[i].value < rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
-		}
-	case flux.TString:
-		type stringValue struct {
-			value string
-			row   execute.Row
-		}
-
-		var rows []stringValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.Strings(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, stringValue{
-						value: vs.Value(i),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
+	rows, release, err := readSelectorRows(tbl, idxs[0])
+	defer release()
+	if err != nil || len(rows) == 0 {
+		return execute.Row{}, err
+	}
+	compareKeys := func(i, j int) int {
+		for _, idx := range idxs {
+			if c := compareSelectorValues(rows[i], rows[j], idx); c != 0 {
+				return c
 			}
-			return nil
-		}); err != nil {
-			return err
 		}
-
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				return rows[i].value < rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
-		}
-	case flux.TTime:
-		type timeValue struct {
-			value values.Time
-			row   execute.Row
-		}
-
-		var rows []timeValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.Times(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, timeValue{
-						value: values.Time(vs.Value(i)),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
+		return 0
+	}
+	// The rows are in input order so the stable sort
+	// keeps the first row first among equal rows.
+	sort.SliceStable(rows, func(i, j int) bool {
+		if c := compareKeys(i, j); c != 0 {
+			return c < 0
+		}
+		switch t.spec.TieBreak {
+		case tieBreakLast:
+			return rows[i].pos > rows[j].pos
+		case tieBreakMin:
+			return compareSelectorValues(rows[i], rows[j], tieIdx) < 0
+		case tieBreakMax:
+			// Null values sort last for both tie breaks.
+			iNull, jNull := rows[i].isNull(tieIdx), rows[j].isNull(tieIdx)
+			if iNull || jNull {
+				return !iNull && jNull
 			}
-			return nil
-		}); err != nil {
-			return err
+			return compareSelectorValues(rows[i], rows[j], tieIdx) > 0
+		}
+		return false
+	})
+	index := getQuantileIndex(quantile, len(rows))
+	if t.spec.TieBreak != "" {
+		for index > 0 && compareKeys(index-1, index) == 0 {
+			index--
 		}
+	}
+	return rows[index].row(), nil
+}
 
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				return rows[i].value < rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
-		}
-	case flux.TBool:
-		type boolValue struct {
-			value bool
-			row   execute.Row
-		}
-
-		var rows []boolValue
-		if err := tbl.Do(func(cr flux.ColReader) error {
-			vs := cr.Bools(valueIdx)
-			for i := 0; i < vs.Len(); i++ {
-				if vs.IsValid(i) {
-					rows = append(rows, boolValue{
-						value: vs.Value(i),
-						row:   execute.ReadRow(i, cr),
-					})
-				}
+// selectorRow is a row of a table that an exact selector chooses from.
+// The chunk of the table that holds the row is retained so the values
+// of the row are compared in place instead of being copied.
+type selectorRow struct {
+	cr flux.ColReader
+	i  int
+	// pos is the position of the row in the input.
+	pos int
+}
+
+// isNull reports whether the value of column j is null.
+func (r selectorRow) isNull(j int) bool {
+	return table.Values(r.cr, j).IsNull(r.i)
+}
+
+// row reads the values of the row.
+func (r selectorRow) row() execute.Row {
+	return execute.ReadRow(r.i, r.cr)
+}
+
+// readSelectorRows returns the rows of the table in input order that are
+// not null in column j. The returned function releases the chunks that
+// hold the rows and must be called even if there is an error.
+func readSelectorRows(tbl flux.Table, j int) ([]selectorRow, func(), error) {
+	var (
+		rows   []selectorRow
+		chunks []flux.ColReader
+	)
+	release := func() {
+		for _, cr := range chunks {
+			cr.Release()
+		}
+	}
+	err := tbl.Do(func(cr flux.ColReader) error {
+		vs := table.Values(cr, j)
+		n := len(rows)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				rows = append(rows, selectorRow{cr: cr, i: i, pos: len(rows)})
 			}
-			return nil
-		}); err != nil {
-			return err
 		}
-
-		if len(rows) > 0 {
-			sort.SliceStable(rows, func(i, j int) bool {
-				if rows[i].value == rows[j].value {
-					return false
-				}
-				return rows[j].value
-			})
-			index := getQuantileIndex(t.spec.Quantile, len(rows))
-			row = rows[index].row
+		if len(rows) > n {
+			cr.Retain()
+			chunks = append(chunks, cr)
 		}
-	default:
-		execute.PanicUnknownType(typ)
+		return nil
+	})
+	return rows, release, err
+}
+
+// compareSelectorValues compares the values of column j of two rows with the
+// same order as the single column selector. Null values sort after all other
+// values.
+func compareSelectorValues(a, b selectorRow, j int) int {
+	x, y := table.Values(a.cr, j), table.Values(b.cr, j)
+	switch xNull, yNull := x.IsNull(a.i), y.IsNull(b.i); {
+	case xNull && yNull:
+		return 0
+	case xNull:
+		return 1
+	case yNull:
+		return -1
 	}
+	return arrowutil.Compare(x, y, a.i, b.i)
+}
 
+// appendRow appends the selected row to the table with the
+// group key of tbl. If no row was selected, a row with only
+// the group key values is appended.
+func (t *ExactQuantileSelectorTransformation) appendRow(tbl flux.Table, row execute.Row) error {
 	builder, created := t.cache.TableBuilder(tbl.Key())
 	if !created {
 		return errors.Newf(codes.FailedPrecondition, "found duplicate table with key: %v", tbl.Key())
@@ -675,8 +2500,7 @@ func (t *ExactQuantileSelectorTransformation) Process(id execute.DatasetID, tbl
 			continue
 		}
 
-		v := values.New(row.Values[j])
-		if err := builder.AppendValue(j, v); err != nil {
+		if err := appendRowValue(builder, j, col.Type, row.Values[j]); err != nil {
 			return err
 		}
 	}
@@ -684,6 +2508,41 @@ func (t *ExactQuantileSelectorTransformation) Process(id execute.DatasetID, tbl
 	return nil
 }
 
+// appendRowValue appends a value read by execute.ReadRow to the
+// column of the builder with the append method of the column type
+// so the value is not boxed in a values.Value.
+func appendRowValue(builder execute.TableBuilder, j int, typ flux.ColType, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return builder.AppendNil(j)
+	case bool:
+		if typ == flux.TBool {
+			return builder.AppendBool(j, v)
+		}
+	case int64:
+		if typ == flux.TInt {
+			return builder.AppendInt(j, v)
+		}
+	case uint64:
+		if typ == flux.TUInt {
+			return builder.AppendUInt(j, v)
+		}
+	case float64:
+		if typ == flux.TFloat {
+			return builder.AppendFloat(j, v)
+		}
+	case string:
+		if typ == flux.TString {
+			return builder.AppendString(j, v)
+		}
+	case values.Time:
+		if typ == flux.TTime {
+			return builder.AppendTime(j, v)
+		}
+	}
+	return builder.AppendValue(j, values.New(v))
+}
+
 func getQuantileIndex(quantile float64, len int) int {
 	x := quantile * float64(len)
 	index := int(math.Ceil(x))
@@ -0,0 +1,301 @@
+package universe
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/values"
+)
+
+type GKQuantileAggProcedureSpec struct {
+	Quantile       float64            `json:"quantile"`
+	Epsilon        float64            `json:"epsilon"`
+	QuantileColumn string             `json:"quantileColumn,omitempty"`
+	QuantileLookup map[string]float64 `json:"quantileLookup,omitempty"`
+	CountSkipped   bool               `json:"countSkipped,omitempty"`
+	NonFinite      string             `json:"nonFinite,omitempty"`
+	NullBehavior   string             `json:"nullBehavior,omitempty"`
+	FillValue      float64            `json:"fillValue,omitempty"`
+	execute.SimpleAggregateConfig
+}
+
+func (s *GKQuantileAggProcedureSpec) Kind() plan.ProcedureKind {
+	return GKQuantileAggKind
+}
+func (s *GKQuantileAggProcedureSpec) Copy() plan.ProcedureSpec {
+	return &GKQuantileAggProcedureSpec{
+		Quantile:              s.Quantile,
+		Epsilon:               s.Epsilon,
+		QuantileColumn:        s.QuantileColumn,
+		QuantileLookup:        s.QuantileLookup,
+		CountSkipped:          s.CountSkipped,
+		NonFinite:             s.NonFinite,
+		NullBehavior:          s.NullBehavior,
+		FillValue:             s.FillValue,
+		SimpleAggregateConfig: s.SimpleAggregateConfig,
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *GKQuantileAggProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createGKQuantileAggTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	ps, ok := spec.(*GKQuantileAggProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", ps)
+	}
+	agg := NewGKQuantileAgg(ps.Quantile, ps.Epsilon, a.Allocator())
+	agg.QuantileColumn, agg.QuantileLookup = ps.QuantileColumn, ps.QuantileLookup
+	agg.CountSkipped, agg.NonFinite = ps.CountSkipped, ps.NonFinite
+	agg.NullBehavior, agg.FillValue = ps.NullBehavior, ps.FillValue
+	return execute.NewSimpleAggregateTransformation(a.Context(), id, agg, ps.SimpleAggregateConfig, a.Allocator())
+}
+
+// gkTuple is a value of the summary along with the number of values
+// it covers since the previous tuple, g, and the uncertainty of its
+// rank, delta.
+type gkTuple struct {
+	v        float64
+	g, delta int64
+}
+
+// gkTupleSize is the number of bytes that are accounted for each tuple.
+const gkTupleSize = 24
+
+// GKQuantileAgg computes a quantile with the Greenwald-Khanna algorithm.
+// The rank of the returned value differs from the rank of the quantile
+// by at most Epsilon times the number of values, whatever the order or
+// distribution of the values.
+//
+// The summary keeps O(1/Epsilon * log(Epsilon * n)) tuples. The memory of
+// the tuples is accounted for with the allocator as the summary grows.
+type GKQuantileAgg struct {
+	Quantile float64
+	// Epsilon is the largest rank error as a
+	// fraction of the number of values.
+	Epsilon float64
+	// QuantileColumn and QuantileLookup optionally
+	// override Quantile for each group key.
+	QuantileColumn string
+	QuantileLookup map[string]float64
+	// CountSkipped reports the number of null, NaN, and infinite values.
+	CountSkipped bool
+	// NonFinite is the policy for NaN and infinite values.
+	// An empty policy skips them.
+	NonFinite string
+	// NullBehavior is the policy for null values. An empty
+	// policy skips them. FillValue replaces null values
+	// with the fill policy.
+	NullBehavior string
+	FillValue    float64
+
+	mem *memory.Allocator
+	// n is the number of values that have been added.
+	// tuples is the summary ordered by value.
+	n      int64
+	tuples []gkTuple
+
+	quantileCounts
+}
+
+func NewGKQuantileAgg(q, epsilon float64, mem *memory.Allocator) *GKQuantileAgg {
+	return &GKQuantileAgg{
+		Quantile: q,
+		Epsilon:  epsilon,
+		mem:      mem,
+	}
+}
+
+func (a *GKQuantileAgg) Copy() *GKQuantileAgg {
+	na := new(GKQuantileAgg)
+	*na = *a
+	na.n = 0
+	na.tuples = nil
+	na.quantileCounts = quantileCounts{}
+	return na
+}
+
+// ForKey implements execute.GroupKeySimpleAggregate.
+func (a *GKQuantileAgg) ForKey(key flux.GroupKey) (execute.SimpleAggregate, error) {
+	q, err := resolveQuantile(a.Quantile, a.QuantileColumn, a.QuantileLookup, key)
+	if err != nil {
+		return nil, err
+	}
+	na := a.Copy()
+	na.Quantile = q
+	return na, nil
+}
+
+func (a *GKQuantileAgg) NewBoolAgg() execute.DoBoolAgg {
+	return nil
+}
+
+func (a *GKQuantileAgg) NewIntAgg() execute.DoIntAgg {
+	return nil
+}
+
+func (a *GKQuantileAgg) NewUIntAgg() execute.DoUIntAgg {
+	return nil
+}
+
+func (a *GKQuantileAgg) NewFloatAgg() execute.DoFloatAgg {
+	return a.Copy()
+}
+
+func (a *GKQuantileAgg) NewStringAgg() execute.DoStringAgg {
+	return nil
+}
+
+func (a *GKQuantileAgg) DoFloat(vs *array.Float) {
+	for i := 0; i < vs.Len() && a.err == nil; i++ {
+		if vs.IsNull(i) {
+			if v, ok := a.null(a.NullBehavior, a.FillValue); ok {
+				a.add(v)
+			}
+			continue
+		}
+		if a.accept(vs.Value(i), a.NonFinite) {
+			a.add(vs.Value(i))
+		}
+	}
+}
+
+// add inserts a value into the summary. A value between the first and
+// last tuples has a rank uncertainty of up to 2 * Epsilon * n. The summary
+// is compressed every 1 / (2 * Epsilon) values.
+func (a *GKQuantileAgg) add(v float64) {
+	if len(a.tuples) == cap(a.tuples) && !a.grow() {
+		return
+	}
+
+	i := sort.Search(len(a.tuples), func(i int) bool {
+		return a.tuples[i].v > v
+	})
+	var delta int64
+	if i > 0 && i < len(a.tuples) {
+		delta = int64(math.Floor(2 * a.Epsilon * float64(a.n)))
+	}
+	a.tuples = append(a.tuples, gkTuple{})
+	copy(a.tuples[i+1:], a.tuples[i:])
+	a.tuples[i] = gkTuple{v: v, g: 1, delta: delta}
+	a.n++
+
+	interval := int64(1 / (2 * a.Epsilon))
+	if interval < 1 {
+		interval = 1
+	}
+	if a.n%interval == 0 {
+		a.compress()
+	}
+}
+
+// grow doubles the capacity of the summary
+// and accounts for the additional memory.
+func (a *GKQuantileAgg) grow() bool {
+	size := 2 * cap(a.tuples)
+	if size < 16 {
+		size = 16
+	}
+	if err := a.mem.Account((size - cap(a.tuples)) * gkTupleSize); err != nil {
+		a.err = err
+		return false
+	}
+	tuples := make([]gkTuple, len(a.tuples), size)
+	copy(tuples, a.tuples)
+	a.tuples = tuples
+	return true
+}
+
+// compress merges each tuple into its successor when the merged tuple
+// covers at most 2 * Epsilon * n values. The first and last tuples are
+// never merged away so they remain the minimum and maximum.
+func (a *GKQuantileAgg) compress() {
+	if len(a.tuples) < 3 {
+		return
+	}
+	threshold := int64(math.Floor(2 * a.Epsilon * float64(a.n)))
+
+	// The tuples are written back from the end of the
+	// summary so out is never before the tuple being read.
+	out := len(a.tuples) - 1
+	for i := len(a.tuples) - 2; i >= 1; i-- {
+		t, next := a.tuples[i], &a.tuples[out]
+		if t.g+next.g+next.delta <= threshold {
+			next.g += t.g
+			continue
+		}
+		out--
+		a.tuples[out] = t
+	}
+	out--
+	a.tuples[out] = a.tuples[0]
+	n := copy(a.tuples, a.tuples[out:])
+	a.tuples = a.tuples[:n]
+}
+
+func (a *GKQuantileAgg) Type() flux.ColType {
+	return flux.TFloat
+}
+
+// ValueFloat returns the value of the first tuple whose minimum and
+// maximum ranks are both within Epsilon * n of the rank of the quantile.
+func (a *GKQuantileAgg) ValueFloat() float64 {
+	if len(a.tuples) == 0 {
+		return 0
+	}
+	switch a.Quantile {
+	case 0:
+		return a.tuples[0].v
+	case 1:
+		return a.tuples[len(a.tuples)-1].v
+	}
+
+	rank := math.Max(math.Ceil(a.Quantile*float64(a.n)), 1)
+	e := a.Epsilon * float64(a.n)
+	var rmin int64
+	for _, t := range a.tuples {
+		rmin += t.g
+		if rmax := rmin + t.delta; float64(rmax)-e <= rank && rank <= float64(rmin)+e {
+			return t.v
+		}
+	}
+	return a.tuples[len(a.tuples)-1].v
+}
+
+func (a *GKQuantileAgg) IsNull() bool {
+	return a.n == 0
+}
+
+// AuxiliaryColumns implements execute.AuxiliaryValueFunc.
+func (a *GKQuantileAgg) AuxiliaryColumns() []flux.ColMeta {
+	if !a.CountSkipped {
+		return nil
+	}
+	return skippedCountColumns
+}
+
+// AuxiliaryValues implements execute.AuxiliaryValueFunc.
+func (a *GKQuantileAgg) AuxiliaryValues() []values.Value {
+	if !a.CountSkipped {
+		return nil
+	}
+	return a.quantileCounts.values()
+}
+
+// Close releases the memory of the summary.
+func (a *GKQuantileAgg) Close() error {
+	if cap(a.tuples) > 0 {
+		a.mem.Account(-cap(a.tuples) * gkTupleSize)
+		a.tuples = nil
+	}
+	return nil
+}
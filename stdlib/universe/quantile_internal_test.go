@@ -0,0 +1,156 @@
+package universe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/memory"
+)
+
+func TestQuantile_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	agg := NewQuantileAgg(0.5, 1000, &memory.Allocator{}, 1)
+	agg.ctx = ctx
+
+	vs := make([]float64, 3*quantileCancelInterval)
+	for i := range vs {
+		vs[i] = float64(i)
+	}
+	state := agg.newState(0.5)
+	state.DoFloat(arrow.NewFloat(vs, nil))
+	if err := state.Err(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := state.count, int64(len(vs)); got != want {
+		t.Fatalf("unexpected count -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The state stops adding values once the query is cancelled.
+	cancel()
+	state.DoFloat(arrow.NewFloat(vs, nil))
+	if got, want := state.Err(), context.Canceled; got != want {
+		t.Fatalf("unexpected error -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if got, want := state.count, int64(len(vs)); got != want {
+		t.Errorf("expected no values to be added after cancellation, got %d", got-want)
+	}
+	if err := state.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExactQuantile_MergeMemory(t *testing.T) {
+	data, err := marshalQuantileState(true, quantileCounts{}, []float64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem := &memory.Allocator{}
+	agg := NewExactQuantileAgg(0.5, 0, mem)
+	for i := 0; i < 2; i++ {
+		if err := agg.merge(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := mem.Allocated(), int64(2*4*8); got != want {
+		t.Fatalf("unexpected accounted bytes -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got, want := agg.ValueFloat(), 2.5; got != want {
+		t.Errorf("unexpected quantile -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if err := agg.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected the memory of the runs to be released, got %d bytes", got)
+	}
+
+	// A run that does not fit in the memory limit is not merged.
+	limit := int64(6 * 8)
+	mem = &memory.Allocator{Limit: &limit}
+	agg = NewExactQuantileAgg(0.5, 0, mem)
+	if err := agg.merge(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.merge(data); err == nil {
+		t.Fatal("expected the memory limit to be exceeded")
+	}
+	if err := agg.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected the memory of the runs to be released, got %d bytes", got)
+	}
+}
+
+func TestTDigestQuantiles_RestoreState(t *testing.T) {
+	// newTransformation creates the transformation
+	// of a quantile with the default options.
+	newTransformation := func() *tdigestQuantilesTransformation {
+		return &tdigestQuantilesTransformation{
+			agg:     NewQuantileAgg(0.5, 1000, &memory.Allocator{}, 1),
+			columns: []string{execute.DefaultValueColLabel},
+		}
+	}
+	aggregate := func(tr *tdigestQuantilesTransformation, state interface{}, vs ...float64) interface{} {
+		tbl := &executetest.Table{
+			ColMeta: []flux.ColMeta{{Label: execute.DefaultValueColLabel, Type: flux.TFloat}},
+		}
+		for _, v := range vs {
+			tbl.Data = append(tbl.Data, []interface{}{v})
+		}
+		if err := tbl.Do(func(cr flux.ColReader) error {
+			var err error
+			state, _, err = tr.Aggregate(table.ChunkFromReader(cr), state, memory.DefaultAllocator)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return state
+	}
+
+	var _ execute.StateSnapshotter = newTransformation()
+
+	tr := newTransformation()
+	state := aggregate(tr, nil, 1, 2, 3, 4, 5)
+	data, err := tr.SnapshotState(execute.NewGroupKey(nil, nil), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTransformation()
+	rs, err := restored.RestoreState(execute.NewGroupKey(nil, nil), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs = aggregate(restored, rs, 6, 7)
+
+	s := rs.(*tdigestQuantilesState)
+	if got, want := s.types, []flux.ColType{flux.TFloat}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("unexpected column types -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if s.states[0].IsNull() {
+		t.Fatal("unexpected null value")
+	}
+	if got, want := s.states[0].ValueFloat(), 4.0; got != want {
+		t.Errorf("unexpected quantile -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if got, want := s.states[0].count, int64(7); got != want {
+		t.Errorf("unexpected count -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot of a different number of columns is rejected.
+	other := newTransformation()
+	other.columns = []string{"a", "b"}
+	if _, err := other.RestoreState(execute.NewGroupKey(nil, nil), data); err == nil {
+		t.Error("expected an error restoring a snapshot of another column count")
+	}
+}
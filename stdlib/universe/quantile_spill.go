@@ -0,0 +1,200 @@
+package universe
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+// quantileSpillBufferSize is the number of values that are encoded
+// or decoded at a time when a run is written to or read from a file.
+const quantileSpillBufferSize = 1024
+
+// quantileSpill holds the sorted runs of values that an exact
+// quantile has written to a temporary file.
+type quantileSpill struct {
+	f    *os.File
+	runs []quantileRun
+	// n is the number of values in all of the runs.
+	n   int
+	buf []byte
+}
+
+// quantileRun is a run of n sorted values at an offset of the file.
+type quantileRun struct {
+	offset int64
+	n      int
+}
+
+func newQuantileSpill() (*quantileSpill, error) {
+	f, err := ioutil.TempFile("", "flux-quantile-")
+	if err != nil {
+		return nil, err
+	}
+	return &quantileSpill{f: f}, nil
+}
+
+// write appends the sorted values to the file as a new run.
+func (s *quantileSpill) write(vs []float64) error {
+	if s.buf == nil {
+		s.buf = make([]byte, 8*quantileSpillBufferSize)
+	}
+
+	run := quantileRun{offset: int64(s.n) * 8, n: len(vs)}
+	offset := run.offset
+	for i := 0; i < len(vs); i += quantileSpillBufferSize {
+		chunk := vs[i:]
+		if len(chunk) > quantileSpillBufferSize {
+			chunk = chunk[:quantileSpillBufferSize]
+		}
+		b := s.buf[:8*len(chunk)]
+		for j, v := range chunk {
+			binary.LittleEndian.PutUint64(b[8*j:], math.Float64bits(v))
+		}
+		if _, err := s.f.WriteAt(b, offset); err != nil {
+			return err
+		}
+		offset += int64(len(b))
+	}
+	s.runs = append(s.runs, run)
+	s.n += len(vs)
+	return nil
+}
+
+// readers returns a reader of each run of the file.
+func (s *quantileSpill) readers() []*quantileRunReader {
+	readers := make([]*quantileRunReader, 0, len(s.runs))
+	for _, run := range s.runs {
+		readers = append(readers, &quantileRunReader{
+			r:         s.f,
+			offset:    run.offset,
+			remaining: run.n,
+		})
+	}
+	return readers
+}
+
+// mergeRanks returns the values at each of the ranks of the values
+// of the sorted runs merged in order. Each rank must be less than the
+// total number of values.
+//
+// The runs are merged with a heap of the next value of each run and
+// only the values up to the largest rank are read.
+func mergeRanks(readers []*quantileRunReader, ranks ...int) ([]float64, error) {
+	h := make(quantileRunHeap, 0, len(readers))
+	for _, r := range readers {
+		if ok, err := r.next(); err != nil {
+			return nil, err
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+
+	last := 0
+	for _, rank := range ranks {
+		if rank > last {
+			last = rank
+		}
+	}
+	out := make([]float64, len(ranks))
+	for rank := 0; rank <= last && len(h) > 0; rank++ {
+		r := h[0]
+		for k, want := range ranks {
+			if want == rank {
+				out[k] = r.head
+			}
+		}
+		if ok, err := r.next(); err != nil {
+			return nil, err
+		} else if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return out, nil
+}
+
+// close closes and removes the file.
+func (s *quantileSpill) close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// quantileRunReader reads the values of a run in order. A reader
+// without a file reads the values it was created with.
+type quantileRunReader struct {
+	r         io.ReaderAt
+	offset    int64
+	remaining int
+	buf       []byte
+	decoded   []float64
+
+	// values are the decoded values that have not been read
+	// and head is the value that was read last.
+	values []float64
+	head   float64
+}
+
+// next reads the next value of the run into head.
+// It returns false if there are no more values.
+func (r *quantileRunReader) next() (bool, error) {
+	if len(r.values) == 0 {
+		if r.remaining == 0 {
+			return false, nil
+		}
+		if err := r.fill(); err != nil {
+			return false, err
+		}
+	}
+	r.head, r.values = r.values[0], r.values[1:]
+	return true, nil
+}
+
+// fill decodes the next values of the run from the file.
+func (r *quantileRunReader) fill() error {
+	n := r.remaining
+	if n > quantileSpillBufferSize {
+		n = quantileSpillBufferSize
+	}
+	if r.buf == nil {
+		r.buf = make([]byte, 8*quantileSpillBufferSize)
+		r.decoded = make([]float64, quantileSpillBufferSize)
+	}
+	b := r.buf[:8*n]
+	if _, err := r.r.ReadAt(b, r.offset); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		r.decoded[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	r.values = r.decoded[:n]
+	r.offset += int64(len(b))
+	r.remaining -= n
+	return nil
+}
+
+// quantileRunHeap orders the readers by the value they read last.
+// NaN is ordered before other values in the same way as sort.Float64s.
+type quantileRunHeap []*quantileRunReader
+
+func (h quantileRunHeap) Len() int { return len(h) }
+func (h quantileRunHeap) Less(i, j int) bool {
+	a, b := h[i].head, h[j].head
+	return a < b || (math.IsNaN(a) && !math.IsNaN(b))
+}
+func (h quantileRunHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *quantileRunHeap) Push(x interface{}) { *h = append(*h, x.(*quantileRunReader)) }
+func (h *quantileRunHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
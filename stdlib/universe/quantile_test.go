@@ -1,18 +1,26 @@
 package universe_test
 
 import (
+	"context"
+	"encoding"
+	"fmt"
+	"math"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/array"
 	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/querytest"
 	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
 	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/tdigest"
 )
 
 func TestQuantile_NewQuery(t *testing.T) {
@@ -269,202 +277,1648 @@ func TestQuantile_NewQuery(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "tdigest quantiles",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(quantiles: [0.5, 0.99])`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantiles:             []float64{0.5, 0.99},
+							Compression:           1000,
+							Method:                "estimate_tdigest",
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
+		{
+			Name: "p2",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "p2")`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantile:              0.99,
+							Method:                "p2",
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
+		{
+			Name: "median",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> median()`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "median2",
+						Spec: &universe.MedianOpSpec{
+							QuantileOpSpec: universe.QuantileOpSpec{
+								Quantile:              0.5,
+								Compression:           1000,
+								Method:                "estimate_tdigest",
+								SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+							},
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "median2"},
+				},
+			},
+		},
+		{
+			Name: "median exact_selector",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> median(method: "exact_selector", column: "x")`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "median2",
+						Spec: &universe.MedianOpSpec{
+							QuantileOpSpec: universe.QuantileOpSpec{
+								Quantile:       0.5,
+								Method:         "exact_selector",
+								SelectorConfig: execute.SelectorConfig{Column: "x"},
+							},
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "median2"},
+				},
+			},
+		},
+		{
+			Name: "exact_mean interpolation",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", interpolation: "nearest")`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantile:              0.99,
+							Method:                "exact_mean",
+							Interpolation:         "nearest",
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
+		{
+			Name: "exact_selector columns",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.9, method: "exact_selector", columns: ["_value", "rank"])`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantile:        0.9,
+							Method:          "exact_selector",
+							SelectorColumns: []string{"_value", "rank"},
+							SelectorConfig:  execute.SelectorConfig{Column: "_value"},
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
+		{
+			Name: "tdigest with maxError",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, maxError: 0.005)`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantile:              0.99,
+							Compression:           50,
+							Method:                "estimate_tdigest",
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
+		{
+			Name: "gk",
+			Raw:  `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "gk", epsilon: 0.001)`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "testdb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop: flux.Time{
+								IsRelative: true,
+							},
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "quantile2",
+						Spec: &universe.QuantileOpSpec{
+							Quantile:              0.99,
+							Method:                "gk",
+							Epsilon:               0.001,
+							SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "quantile2"},
+				},
+			},
+		},
 		// errors
 		{
-			Name:    "wrong method",
-			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "non_existent_method")`,
-			WantErr: true,
+			Name:    "gk with compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "gk", compression: 800.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "epsilon with tdigest",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, epsilon: 0.01)`,
+			WantErr: true,
+		},
+		{
+			Name:    "epsilon out of range",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "gk", epsilon: 1.5)`,
+			WantErr: true,
+		},
+		{
+			Name:    "maxError with compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, compression: 800.0, maxError: 0.01)`,
+			WantErr: true,
+		},
+		{
+			Name:    "maxError with exact method",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", maxError: 0.01)`,
+			WantErr: true,
+		},
+		{
+			Name:    "maxError out of range",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, maxError: 0.5)`,
+			WantErr: true,
+		},
+		{
+			Name:    "tieBreak with exact mean",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_mean", tieBreak: "last")`,
+			WantErr: true,
+		},
+		{
+			Name:    "unknown tieBreak",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_selector", tieBreak: "random")`,
+			WantErr: true,
+		},
+		{
+			Name:    "tieBreak min without column",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_selector", tieBreak: "min")`,
+			WantErr: true,
+		},
+		{
+			Name:    "tieBreakColumn with tieBreak last",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_selector", tieBreak: "last", tieBreakColumn: "rank")`,
+			WantErr: true,
+		},
+		{
+			Name:    "withCount with exact mean",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_mean", withCount: true)`,
+			WantErr: true,
+		},
+		{
+			Name:    "unknown nullBehavior",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, nullBehavior: "zero")`,
+			WantErr: true,
+		},
+		{
+			Name:    "nullBehavior fill without fillValue",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, nullBehavior: "fill")`,
+			WantErr: true,
+		},
+		{
+			Name:    "fillValue without nullBehavior fill",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, fillValue: 0.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "nullBehavior with exact selector",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, method: "exact_selector", nullBehavior: "error")`,
+			WantErr: true,
+		},
+		{
+			Name:    "p2 with compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "p2", compression: 800.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "q and quantiles",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.5, quantiles: [0.99])`,
+			WantErr: true,
+		},
+		{
+			Name:    "no quantile",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile()`,
+			WantErr: true,
+		},
+		{
+			Name:    "quantiles out of range",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(quantiles: [0.5, 1.5])`,
+			WantErr: true,
+		},
+		{
+			Name:    "duplicate quantiles",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(quantiles: [0.5, 0.5])`,
+			WantErr: true,
+		},
+		{
+			Name:    "exact quantiles",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(quantiles: [0.5, 0.9], method: "exact_mean")`,
+			WantErr: true,
+		},
+		{
+			Name:    "wrong method",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "non_existent_method")`,
+			WantErr: true,
+		},
+		{
+			Name:    "non-tdigest with compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", compression: 800.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "median non-tdigest with compression",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> median(method: "exact_mean", compression: 800.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "tdigest with interpolation",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, interpolation: "lower")`,
+			WantErr: true,
+		},
+		{
+			Name:    "selector with interpolation",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_selector", interpolation: "lower")`,
+			WantErr: true,
+		},
+		{
+			Name:    "unknown interpolation",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", interpolation: "cubic")`,
+			WantErr: true,
+		},
+		{
+			Name:    "selector with column and columns",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_selector", column: "1", columns: ["1", "2"])`,
+			WantErr: true,
+		},
+		{
+			Name:    "weightColumn with exact method",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", weightColumn: "_weight")`,
+			WantErr: true,
+		},
+		{
+			Name:    "aggregate with columns",
+			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", columns: ["1", "2"])`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestQuantileOperation_Marshaling(t *testing.T) {
+	data := []byte(`{"id":"quantile","kind":"quantile","spec":{"quantile":0.9}}`)
+	op := &flux.Operation{
+		ID: "quantile",
+		Spec: &universe.QuantileOpSpec{
+			Quantile: 0.9,
+		},
+	}
+
+	querytest.OperationMarshalingTestHelper(t, data, op)
+}
+
+func TestQuantile_Process(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          func() *array.Float
+		quantile      float64
+		exact         bool
+		interpolation string
+		p2            bool
+		want          interface{}
+	}{
+		{
+			name: "zero",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{0, 0, 0}, nil)
+			},
+			quantile: 0.5,
+			want:     0.0,
+		},
+		{
+			name: "50th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			},
+			quantile: 0.5,
+			want:     3.0,
+		},
+		{
+			name: "75th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			},
+			quantile: 0.75,
+			want:     4.0,
+		},
+		{
+			name: "90th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			},
+			quantile: 0.9,
+			want:     5.0,
+		},
+		{
+			name: "99th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			},
+			quantile: 0.99,
+			want:     5.0,
+		},
+		{
+			name: "exact 50th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			},
+			quantile: 0.5,
+			exact:    true,
+			want:     3.0,
+		},
+		{
+			name: "exact 75th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			},
+			quantile: 0.75,
+			exact:    true,
+			want:     4.0,
+		},
+		{
+			name: "exact 90th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			},
+			quantile: 0.9,
+			exact:    true,
+			want:     4.6,
+		},
+		{
+			name: "exact 99th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			},
+			quantile: 0.99,
+			exact:    true,
+			want:     4.96,
+		},
+		{
+			name: "exact 100th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			},
+			quantile: 1,
+			exact:    true,
+			want:     5.0,
+		},
+		{
+			name: "exact 50th normal",
+			data: func() *array.Float {
+				return arrow.NewFloat(NormalData, nil)
+			},
+			quantile: 0.5,
+			exact:    true,
+			want:     10.000736834856248,
+		},
+		{
+			name: "normal",
+			data: func() *array.Float {
+				return arrow.NewFloat(NormalData, nil)
+			},
+			quantile: 0.9,
+			want:     13.842132136909889,
+		},
+		{
+			name: "exact lower",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{4, 3, 2, 1}, nil)
+			},
+			quantile:      0.4,
+			exact:         true,
+			interpolation: "lower",
+			want:          2.0,
+		},
+		{
+			name: "exact higher",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{4, 3, 2, 1}, nil)
+			},
+			quantile:      0.4,
+			exact:         true,
+			interpolation: "higher",
+			want:          3.0,
+		},
+		{
+			name: "exact nearest",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{4, 3, 2, 1}, nil)
+			},
+			quantile:      0.4,
+			exact:         true,
+			interpolation: "nearest",
+			want:          2.0,
+		},
+		{
+			// The quantile is halfway between the second and third
+			// values, so the value with the even rank is used.
+			name: "exact nearest halfway",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{4, 3, 2, 1}, nil)
+			},
+			quantile:      0.5,
+			exact:         true,
+			interpolation: "nearest",
+			want:          3.0,
+		},
+		{
+			name: "exact midpoint",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{4, 3, 2, 1}, nil)
+			},
+			quantile:      0.4,
+			exact:         true,
+			interpolation: "midpoint",
+			want:          2.5,
+		},
+		{
+			name: "p2 50th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, nil)
+			},
+			quantile: 0.5,
+			p2:       true,
+			want:     6.0,
+		},
+		{
+			name: "p2 100th",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, nil)
+			},
+			quantile: 1,
+			p2:       true,
+			want:     11.0,
+		},
+		{
+			name: "p2 fewer than five values",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{5, 1, 3, 4}, nil)
+			},
+			quantile: 0.5,
+			p2:       true,
+			want:     3.5,
+		},
+		{
+			name: "p2 only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			p2:   true,
+			want: nil,
+		},
+		{
+			name: "empty",
+			data: func() *array.Float {
+				return arrow.NewFloat(nil, nil)
+			},
+			want: nil,
+		},
+		{
+			name: "with nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendValues([]float64{1, 3, 3}, nil)
+				b.AppendNull()
+				b.AppendValues([]float64{5, 5, 4, 3}, nil)
+				b.AppendNull()
+				b.AppendValues([]float64{1}, nil)
+				return b.NewFloatArray()
+			},
+			quantile: 0.5,
+			want:     3.0,
+		},
+		{
+			name: "only nulls",
+			data: func() *array.Float {
+				b := arrow.NewFloatBuilder(nil)
+				defer b.Release()
+				b.AppendNull()
+				b.AppendNull()
+				return b.NewFloatArray()
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var agg execute.SimpleAggregate
+			if tc.exact {
+				agg = &universe.ExactQuantileAgg{Quantile: tc.quantile, Interpolation: tc.interpolation}
+			} else if tc.p2 {
+				agg = &universe.P2QuantileAgg{Quantile: tc.quantile}
+			} else {
+				agg = universe.NewQuantileAgg(tc.quantile, 1000.0, &memory.Allocator{}, 1)
+			}
+			executetest.AggFuncTestHelper(
+				t,
+				agg,
+				tc.data(),
+				tc.want,
+			)
+		})
+	}
+}
+
+func TestQuantile_QuantileLookup(t *testing.T) {
+	lookup := map[string]float64{"a": 0.0, "b": 1.0}
+	newTable := func(t0 string) *executetest.Table {
+		return &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "t0", Type: flux.TString},
+			},
+			Data: [][]interface{}{
+				{execute.Time(1), 1.0, t0},
+				{execute.Time(2), 2.0, t0},
+				{execute.Time(3), 3.0, t0},
+			},
+		}
+	}
+	want := []*executetest.Table{
+		{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{"a", 1.0}},
 		},
 		{
-			Name:    "non-tdigest with compression",
-			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_mean", compression: 800.0)`,
-			WantErr: true,
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{"b", 3.0}},
 		},
 		{
-			Name:    "selector with columns",
-			Raw:     `from(bucket:"testdb") |> range(start: -1h) |> quantile(q: 0.99, method: "exact_selector", columns: ["1", "2"])`,
-			WantErr: true,
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{{"c", 2.0}},
 		},
 	}
-	for _, tc := range tests {
+
+	testCases := []struct {
+		name string
+		agg  func() execute.SimpleAggregate
+	}{
+		{
+			name: "tdigest",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(0.5, 1000.0, &memory.Allocator{}, 1)
+				agg.QuantileColumn, agg.QuantileLookup = "t0", lookup
+				return agg
+			},
+		},
+		{
+			name: "exact mean",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{
+					Quantile:       0.5,
+					QuantileColumn: "t0",
+					QuantileLookup: lookup,
+				}
+			},
+		},
+	}
+	for _, tc := range testCases {
 		tc := tc
-		t.Run(tc.Name, func(t *testing.T) {
-			t.Parallel()
-			querytest.NewQueryTestHelper(t, tc)
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				[]flux.Table{newTable("a"), newTable("b"), newTable("c")},
+				want,
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, tc.agg(), execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
 		})
 	}
 }
 
-func TestQuantileOperation_Marshaling(t *testing.T) {
-	data := []byte(`{"id":"quantile","kind":"quantile","spec":{"quantile":0.9}}`)
-	op := &flux.Operation{
-		ID: "quantile",
-		Spec: &universe.QuantileOpSpec{
-			Quantile: 0.9,
+func TestQuantile_CountSkipped(t *testing.T) {
+	testCases := []struct {
+		name string
+		agg  func() execute.SimpleAggregate
+		data [][]interface{}
+		want [][]interface{}
+	}{
+		{
+			name: "tdigest",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.CountSkipped = true
+				return agg
+			},
+			data: [][]interface{}{
+				{execute.Time(1), 1.0},
+				{execute.Time(2), nil},
+				{execute.Time(3), 3.0},
+				{execute.Time(4), nil},
+			},
+			want: [][]interface{}{{3.0, int64(2), int64(0), int64(0)}},
+		},
+		{
+			name: "exact mean",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 1.0, CountSkipped: true}
+			},
+			data: [][]interface{}{
+				{execute.Time(1), 1.0},
+				{execute.Time(2), nil},
+				{execute.Time(3), math.NaN()},
+				{execute.Time(4), 3.0},
+				{execute.Time(5), math.Inf(1)},
+			},
+			want: [][]interface{}{{3.0, int64(1), int64(1), int64(1)}},
+		},
+		{
+			name: "only nulls",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5, CountSkipped: true}
+			},
+			data: [][]interface{}{
+				{execute.Time(1), nil},
+				{execute.Time(2), nil},
+			},
+			want: [][]interface{}{{nil, int64(2), int64(0), int64(0)}},
 		},
 	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: tc.data,
+				}},
+				[]*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_nullCount", Type: flux.TInt},
+						{Label: "_nanCount", Type: flux.TInt},
+						{Label: "_infCount", Type: flux.TInt},
+					},
+					Data: tc.want,
+				}},
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, tc.agg(), execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
 
-	querytest.OperationMarshalingTestHelper(t, data, op)
+func TestQuantile_WithCount(t *testing.T) {
+	testCases := []struct {
+		name string
+		agg  func() execute.SimpleAggregate
+		data [][]interface{}
+		want [][]interface{}
+	}{
+		{
+			name: "skipped values",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.WithCount = true
+				return agg
+			},
+			data: [][]interface{}{
+				{execute.Time(1), 1.0},
+				{execute.Time(2), nil},
+				{execute.Time(3), math.NaN()},
+				{execute.Time(4), 3.0},
+				{execute.Time(5), 2.0},
+			},
+			want: [][]interface{}{{3.0, int64(3)}},
+		},
+		{
+			name: "fill",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.WithCount, agg.NullBehavior, agg.FillValue = true, "fill", 10.0
+				return agg
+			},
+			data: [][]interface{}{
+				{execute.Time(1), 1.0},
+				{execute.Time(2), nil},
+			},
+			want: [][]interface{}{{10.0, int64(2)}},
+		},
+		{
+			name: "only nulls",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(0.5, 1000.0, &memory.Allocator{}, 1)
+				agg.WithCount = true
+				return agg
+			},
+			data: [][]interface{}{
+				{execute.Time(1), nil},
+				{execute.Time(2), nil},
+			},
+			want: [][]interface{}{{nil, int64(0)}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: tc.data,
+				}},
+				[]*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_count", Type: flux.TInt},
+					},
+					Data: tc.want,
+				}},
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, tc.agg(), execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestQuantile_FreeDigests(t *testing.T) {
+	size := int64(tdigest.ByteSizeForCompression(1000))
+	mem := &memory.Allocator{}
+	agg := universe.NewQuantileAgg(0.5, 1000, mem, 2)
+
+	states := make([]execute.DoFloatAgg, 3)
+	for i := range states {
+		states[i] = agg.NewFloatAgg()
+		states[i].DoFloat(arrow.NewFloat([]float64{float64(i)}, nil))
+	}
+	if got, want := mem.Allocated(), 3*size; got != want {
+		t.Fatalf("unexpected allocated bytes -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The budget holds two digests so the third one is released.
+	for _, state := range states {
+		if err := state.(execute.Closer).Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := mem.Allocated(), 2*size; got != want {
+		t.Fatalf("unexpected allocated bytes -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// A new state reuses a free digest without accounting for it again.
+	state := agg.NewFloatAgg()
+	if got, want := mem.Allocated(), 2*size; got != want {
+		t.Fatalf("unexpected allocated bytes -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if err := state.(execute.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := agg.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected the free digests to be released, got %d bytes", got)
+	}
+}
+
+func TestQuantile_Quantiles(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec *universe.TDigestQuantileProcedureSpec
+		data []flux.Table
+		want []*executetest.Table
+	}{
+		{
+			name: "multiple columns",
+			spec: &universe.TDigestQuantileProcedureSpec{
+				Quantiles:   []float64{0, 0.5, 1},
+				Compression: 1000,
+				SimpleAggregateConfig: execute.SimpleAggregateConfig{
+					Columns: []string{"_value", "count"},
+				},
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "count", Type: flux.TInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 4.0, int64(30), "a"},
+						{execute.Time(2), 1.0, int64(10), "a"},
+						{execute.Time(3), nil, nil, "a"},
+						{execute.Time(4), 7.0, int64(20), "a"},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value_p0", Type: flux.TFloat},
+					{Label: "_value_p50", Type: flux.TFloat},
+					{Label: "_value_p100", Type: flux.TFloat},
+					{Label: "count_p0", Type: flux.TFloat},
+					{Label: "count_p50", Type: flux.TFloat},
+					{Label: "count_p100", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", 1.0, 4.0, 7.0, 10.0, 20.0, 30.0},
+				},
+			}},
+		},
+		{
+			name: "count skipped",
+			spec: &universe.TDigestQuantileProcedureSpec{
+				Quantiles:             []float64{0.5, 0.999},
+				Compression:           1000,
+				CountSkipped:          true,
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), math.NaN()},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value_p50", Type: flux.TFloat},
+					{Label: "_value_p99.9", Type: flux.TFloat},
+					{Label: "_nullCount", Type: flux.TInt},
+					{Label: "_nanCount", Type: flux.TInt},
+					{Label: "_infCount", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{nil, nil, int64(1), int64(1), int64(0)},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewTDigestQuantilesTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestQuantile_WeightColumn(t *testing.T) {
+	digest := tdigest.NewWithCompression(1000)
+	digest.Add(1, 1)
+	digest.Add(2, 1)
+	digest.Add(10, 8)
+
+	data := func(weightType flux.ColType, weights ...interface{}) []flux.Table {
+		return []flux.Table{&executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "_weight", Type: weightType},
+				{Label: "t0", Type: flux.TString},
+			},
+			Data: [][]interface{}{
+				{execute.Time(1), 1.0, weights[0], "a"},
+				{execute.Time(2), 2.0, weights[1], "a"},
+				{execute.Time(3), 10.0, weights[2], "a"},
+				{execute.Time(4), 5.0, weights[3], "a"},
+				{execute.Time(5), nil, weights[4], "a"},
+			},
+		}}
+	}
+	testCases := []struct {
+		name      string
+		weight    string
+		quantiles []float64
+		data      []flux.Table
+		want      []*executetest.Table
+		wantErr   error
+	}{
+		{
+			name:   "int weights",
+			weight: "_weight",
+			data:   data(flux.TInt, int64(1), int64(1), int64(8), int64(0), int64(3)),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", digest.Quantile(0.5)},
+				},
+			}},
+		},
+		{
+			name:   "uint weights",
+			weight: "_weight",
+			data:   data(flux.TUInt, uint64(1), uint64(1), uint64(8), uint64(0), uint64(3)),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", digest.Quantile(0.5)},
+				},
+			}},
+		},
+		{
+			name:      "int weights with quantiles",
+			weight:    "_weight",
+			quantiles: []float64{0.1, 0.5, 0.9},
+			data:      data(flux.TInt, int64(1), int64(1), int64(8), int64(0), int64(3)),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value_p10", Type: flux.TFloat},
+					{Label: "_value_p50", Type: flux.TFloat},
+					{Label: "_value_p90", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", digest.Quantile(0.1), digest.Quantile(0.5), digest.Quantile(0.9)},
+				},
+			}},
+		},
+		{
+			name:   "float weights with null",
+			weight: "_weight",
+			data:   data(flux.TFloat, 1.0, 1.0, 8.0, nil, 3.0),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", digest.Quantile(0.5)},
+				},
+			}},
+		},
+		{
+			name:    "missing weight column",
+			weight:  "count",
+			data:    data(flux.TInt, int64(1), int64(1), int64(8), int64(0), int64(3)),
+			wantErr: errors.New(codes.FailedPrecondition, `weight column "count" does not exist`),
+		},
+		{
+			name:    "non-numeric weight column",
+			weight:  "_weight",
+			data:    data(flux.TString, "1", "1", "8", "0", "3"),
+			wantErr: errors.New(codes.FailedPrecondition, `weight column "_weight" must be numeric, got string`),
+		},
+		{
+			name:    "negative weight",
+			weight:  "_weight",
+			data:    data(flux.TInt, int64(1), int64(-1), int64(8), int64(0), int64(3)),
+			wantErr: errors.New(codes.Invalid, "quantile weight must not be negative, got -1"),
+		},
+		{
+			name:    "NaN weight",
+			weight:  "_weight",
+			data:    data(flux.TFloat, 1.0, math.NaN(), 8.0, 0.0, 3.0),
+			wantErr: errors.New(codes.Invalid, "quantile weight must be finite, got NaN"),
+		},
+		{
+			name:    "+Inf weight",
+			weight:  "_weight",
+			data:    data(flux.TFloat, 1.0, 1.0, math.Inf(1), 0.0, 3.0),
+			wantErr: errors.New(codes.Invalid, "quantile weight must be finite, got +Inf"),
+		},
+		{
+			name:    "-Inf weight",
+			weight:  "_weight",
+			data:    data(flux.TFloat, 1.0, 1.0, 8.0, math.Inf(-1), 3.0),
+			wantErr: errors.New(codes.Invalid, "quantile weight must be finite, got -Inf"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &universe.TDigestQuantileProcedureSpec{
+				Quantile:              0.5,
+				Compression:           1000,
+				Quantiles:             tc.quantiles,
+				WeightColumn:          tc.weight,
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewTDigestQuantilesTransformation(id, spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestQuantile_MaxError(t *testing.T) {
+	sorted := make([]float64, len(NormalData))
+	copy(sorted, NormalData)
+	sort.Float64s(sorted)
+
+	for _, maxError := range []float64{0.05, 0.01, 0.001} {
+		compression := universe.CompressionForMaxError(maxError)
+		for _, q := range []float64{0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+			state := universe.NewQuantileAgg(q, compression, &memory.Allocator{}, 1).NewFloatAgg()
+			state.DoFloat(arrow.NewFloat(NormalData, nil))
+			estimate := state.(execute.FloatValueFunc).ValueFloat()
+
+			// The rank error is the difference between the quantile
+			// and the fraction of the values below the estimate.
+			rank := float64(sort.SearchFloat64s(sorted, estimate)) / float64(len(sorted))
+			if got := math.Abs(rank - q); got > maxError {
+				t.Errorf("unexpected rank error for quantile %v with maxError %v (compression %v): %v", q, maxError, compression, got)
+			}
+		}
+	}
 }
 
-func TestQuantile_Process(t *testing.T) {
+func TestQuantile_GK(t *testing.T) {
+	const n = 100000
 	testCases := []struct {
-		name     string
-		data     func() *array.Float
-		quantile float64
-		exact    bool
-		want     interface{}
+		name  string
+		value func(i int) float64
 	}{
 		{
-			name: "zero",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{0, 0, 0}, nil)
-			},
-			quantile: 0.5,
-			want:     0.0,
+			name:  "ascending",
+			value: func(i int) float64 { return float64(i) },
 		},
 		{
-			name: "50th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
-			},
-			quantile: 0.5,
-			want:     3.0,
+			name:  "descending",
+			value: func(i int) float64 { return float64(n - i) },
 		},
 		{
-			name: "75th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			name: "alternating",
+			value: func(i int) float64 {
+				if i%2 == 0 {
+					return float64(i)
+				}
+				return float64(-i)
 			},
-			quantile: 0.75,
-			want:     4.0,
 		},
 		{
-			name: "90th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
-			},
-			quantile: 0.9,
-			want:     5.0,
+			name:  "normal",
+			value: func(i int) float64 { return NormalData[i] },
 		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			data := make([]float64, n)
+			for i := range data {
+				data[i] = tc.value(i)
+			}
+			sorted := make([]float64, n)
+			copy(sorted, data)
+			sort.Float64s(sorted)
+
+			for _, epsilon := range []float64{0.01, 0.001} {
+				for _, q := range []float64{0, 0.001, 0.01, 0.25, 0.5, 0.75, 0.99, 0.999, 1} {
+					mem := &memory.Allocator{}
+					state := universe.NewGKQuantileAgg(q, epsilon, mem).NewFloatAgg()
+					state.DoFloat(arrow.NewFloat(data, nil))
+					if err := state.(execute.ErrorValueFunc).Err(); err != nil {
+						t.Fatal(err)
+					}
+					if mem.Allocated() == 0 {
+						t.Error("expected the summary to be accounted for")
+					}
+					estimate := state.(execute.FloatValueFunc).ValueFloat()
+
+					// The rank error is the distance between the rank
+					// of the estimate and the rank of the quantile.
+					rank := float64(sort.SearchFloat64s(sorted, estimate) + 1)
+					want := math.Max(math.Ceil(q*n), 1)
+					if got := math.Abs(rank-want) / n; got > epsilon {
+						t.Errorf("unexpected rank error for quantile %v with epsilon %v: %v", q, epsilon, got)
+					}
+
+					if err := state.(execute.Closer).Close(); err != nil {
+						t.Fatal(err)
+					}
+					if got := mem.Allocated(); got != 0 {
+						t.Errorf("expected the summary to be released, got %d bytes", got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQuantile_ExactSpill(t *testing.T) {
+	interpolations := []string{"linear", "lower", "higher", "nearest", "midpoint"}
+	for _, spillThreshold := range []int{7, 100, len(NormalData)} {
+		for _, interpolation := range interpolations {
+			for _, q := range []float64{0, 0.01, 0.25, 0.5, 0.9, 0.999, 1} {
+				want := &universe.ExactQuantileAgg{Quantile: q, Interpolation: interpolation}
+				want.DoFloat(arrow.NewFloat(NormalData, nil))
+
+				mem := &memory.Allocator{}
+				agg := universe.NewExactQuantileAgg(q, spillThreshold, mem)
+				agg.Interpolation = interpolation
+				state := agg.NewFloatAgg()
+				// Add the values in arrays that do not line
+				// up with the runs written to the file.
+				for i := 0; i < len(NormalData); i += 33 {
+					j := i + 33
+					if j > len(NormalData) {
+						j = len(NormalData)
+					}
+					state.DoFloat(arrow.NewFloat(NormalData[i:j], nil))
+				}
+				if got := mem.Allocated(); got != int64(8*spillThreshold) {
+					t.Errorf("expected the buffer of %d values to be accounted for, got %d bytes", spillThreshold, got)
+				}
+
+				if got, want := state.(execute.FloatValueFunc).ValueFloat(), want.ValueFloat(); got != want {
+					t.Errorf("unexpected %s quantile %v with spill threshold %d: got %v want %v", interpolation, q, spillThreshold, got, want)
+				}
+				if err := state.(execute.Closer).Close(); err != nil {
+					t.Fatal(err)
+				}
+				if got := mem.Allocated(); got != 0 {
+					t.Errorf("expected the buffer to be released, got %d bytes", got)
+				}
+			}
+		}
+	}
+}
+
+func TestQuantile_NonFinite(t *testing.T) {
+	data := [][]interface{}{
+		{execute.Time(1), 1.0},
+		{execute.Time(2), math.Inf(1)},
+		{execute.Time(3), 3.0},
+		{execute.Time(4), math.NaN()},
+		{execute.Time(5), math.Inf(-1)},
+	}
+	testCases := []struct {
+		name    string
+		agg     func() execute.SimpleAggregate
+		want    [][]interface{}
+		wantErr error
+	}{
 		{
-			name: "99th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1}, nil)
+			name: "tdigest skip",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.CountSkipped = true
+				return agg
 			},
-			quantile: 0.99,
-			want:     5.0,
+			want: [][]interface{}{{3.0, int64(0), int64(1), int64(2)}},
 		},
 		{
-			name: "exact 50th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			name: "tdigest error",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(0.5, 1000.0, &memory.Allocator{}, 1)
+				agg.NonFinite = "error"
+				agg.CountSkipped = true
+				return agg
 			},
-			quantile: 0.5,
-			exact:    true,
-			want:     3.0,
+			wantErr: errors.New(codes.Invalid, "quantile found non-finite value +Inf"),
 		},
 		{
-			name: "exact 75th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			name: "exact mean skip",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.0, NonFinite: "skip", CountSkipped: true}
 			},
-			quantile: 0.75,
-			exact:    true,
-			want:     4.0,
+			want: [][]interface{}{{1.0, int64(0), int64(1), int64(2)}},
 		},
 		{
-			name: "exact 90th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			name: "exact mean null",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5, NonFinite: "null", CountSkipped: true}
 			},
-			quantile: 0.9,
-			exact:    true,
-			want:     4.6,
+			want: [][]interface{}{{2.0, int64(3), int64(0), int64(0)}},
 		},
 		{
-			name: "exact 99th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			name: "exact mean include",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 1.0, NonFinite: "include", CountSkipped: true}
 			},
-			quantile: 0.99,
-			exact:    true,
-			want:     4.96,
+			want: [][]interface{}{{math.Inf(1), int64(0), int64(1), int64(2)}},
 		},
 		{
-			name: "exact 100th",
-			data: func() *array.Float {
-				return arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil)
+			name: "exact mean error",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5, NonFinite: "error", CountSkipped: true}
 			},
-			quantile: 1,
-			exact:    true,
-			want:     5.0,
+			wantErr: errors.New(codes.Invalid, "quantile found non-finite value +Inf"),
 		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var want []*executetest.Table
+			if tc.wantErr == nil {
+				want = []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_nullCount", Type: flux.TInt},
+						{Label: "_nanCount", Type: flux.TInt},
+						{Label: "_infCount", Type: flux.TInt},
+					},
+					Data: tc.want,
+				}}
+			}
+			executetest.ProcessTestHelper2(
+				t,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: data,
+				}},
+				want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, tc.agg(), execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestQuantile_NullBehavior(t *testing.T) {
+	data := [][]interface{}{
+		{execute.Time(1), 1.0},
+		{execute.Time(2), nil},
+		{execute.Time(3), 3.0},
+		{execute.Time(4), nil},
+		{execute.Time(5), 5.0},
+	}
+	testCases := []struct {
+		name    string
+		agg     func() execute.SimpleAggregate
+		want    [][]interface{}
+		wantErr error
+	}{
 		{
-			name: "exact 50th normal",
-			data: func() *array.Float {
-				return arrow.NewFloat(NormalData, nil)
+			name: "tdigest skip",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.CountSkipped, agg.NullBehavior = true, "skip"
+				return agg
 			},
-			quantile: 0.5,
-			exact:    true,
-			want:     10.000736834856248,
+			want: [][]interface{}{{5.0, int64(2), int64(0), int64(0)}},
 		},
 		{
-			name: "normal",
-			data: func() *array.Float {
-				return arrow.NewFloat(NormalData, nil)
+			name: "tdigest fill",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(1.0, 1000.0, &memory.Allocator{}, 1)
+				agg.CountSkipped, agg.NullBehavior, agg.FillValue = true, "fill", 10.0
+				return agg
 			},
-			quantile: 0.9,
-			want:     13.842132136909889,
+			want: [][]interface{}{{10.0, int64(0), int64(0), int64(0)}},
 		},
 		{
-			name: "empty",
-			data: func() *array.Float {
-				return arrow.NewFloat(nil, nil)
+			name: "tdigest error",
+			agg: func() execute.SimpleAggregate {
+				agg := universe.NewQuantileAgg(0.5, 1000.0, &memory.Allocator{}, 1)
+				agg.CountSkipped, agg.NullBehavior = true, "error"
+				return agg
 			},
-			want: nil,
+			wantErr: errors.New(codes.FailedPrecondition, "quantile found null value"),
 		},
 		{
-			name: "with nulls",
-			data: func() *array.Float {
-				b := arrow.NewFloatBuilder(nil)
-				defer b.Release()
-				b.AppendValues([]float64{1, 3, 3}, nil)
-				b.AppendNull()
-				b.AppendValues([]float64{5, 5, 4, 3}, nil)
-				b.AppendNull()
-				b.AppendValues([]float64{1}, nil)
-				return b.NewFloatArray()
+			name: "exact mean fill",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5, NullBehavior: "fill", FillValue: 0.0, CountSkipped: true}
 			},
-			quantile: 0.5,
-			want:     3.0,
+			want: [][]interface{}{{1.0, int64(0), int64(0), int64(0)}},
 		},
 		{
-			name: "only nulls",
-			data: func() *array.Float {
-				b := arrow.NewFloatBuilder(nil)
-				defer b.Release()
-				b.AppendNull()
-				b.AppendNull()
-				return b.NewFloatArray()
+			name: "exact mean error",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5, NullBehavior: "error", CountSkipped: true}
 			},
-			want: nil,
+			wantErr: errors.New(codes.FailedPrecondition, "quantile found null value"),
 		},
 	}
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			var agg execute.SimpleAggregate
-			if tc.exact {
-				agg = &universe.ExactQuantileAgg{Quantile: tc.quantile}
-			} else {
-				agg = universe.NewQuantileAgg(tc.quantile, 1000.0, &memory.Allocator{}, 1)
+			var want []*executetest.Table
+			if tc.wantErr == nil {
+				want = []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_nullCount", Type: flux.TInt},
+						{Label: "_nanCount", Type: flux.TInt},
+						{Label: "_infCount", Type: flux.TInt},
+					},
+					Data: tc.want,
+				}}
 			}
-			executetest.AggFuncTestHelper(
+			executetest.ProcessTestHelper2(
 				t,
-				agg,
-				tc.data(),
-				tc.want,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: data,
+				}},
+				want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := execute.NewSimpleAggregateTransformation(executetest.NewTestExecuteDependencies().Inject(context.Background()), id, tc.agg(), execute.DefaultSimpleAggregateConfig, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
 			)
 		})
 	}
@@ -474,6 +1928,7 @@ func TestQuantileSelector_Process(t *testing.T) {
 	testCases := []struct {
 		name     string
 		quantile float64
+		columns  []string
 		data     []flux.Table
 		want     []*executetest.Table
 	}{
@@ -739,6 +2194,40 @@ func TestQuantileSelector_Process(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name:     "select_50_columns",
+			quantile: 0.5,
+			columns:  []string{"_value", "t2"},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t1"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t1", Type: flux.TString},
+					{Label: "t2", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 1.0, "a", "w"},
+					{execute.Time(10), 2.0, "a", nil},
+					{execute.Time(20), 2.0, "a", "y"},
+					{execute.Time(30), 3.0, "a", "v"},
+					{execute.Time(40), 2.0, "a", "x"},
+					{execute.Time(50), nil, "a", "u"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t1"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t1", Type: flux.TString},
+					{Label: "t2", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(20), 2.0, "a", "y"},
+				},
+			}},
+		},
 		{
 			name:     "empty",
 			quantile: 0.5,
@@ -776,7 +2265,96 @@ func TestQuantileSelector_Process(t *testing.T) {
 				tc.want,
 				nil,
 				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
-					return universe.NewExactQuantileSelectorTransformation(d, c, &universe.ExactQuantileSelectProcedureSpec{Quantile: tc.quantile}, executetest.UnlimitedAllocator)
+					spec := &universe.ExactQuantileSelectProcedureSpec{
+						Quantile:        tc.quantile,
+						SelectorColumns: tc.columns,
+					}
+					return universe.NewExactQuantileSelectorTransformation(d, c, spec, executetest.UnlimitedAllocator)
+				},
+			)
+		})
+	}
+}
+
+func TestQuantileSelector_TieBreak(t *testing.T) {
+	// The rows at 10, 20, and 30 share the value at the quantile.
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.Table{
+			KeyCols: []string{"t1"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "t1", Type: flux.TString},
+				{Label: "rank", Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{execute.Time(0), 1.0, "a", int64(5)},
+				{execute.Time(10), 2.0, "a", int64(3)},
+				{execute.Time(20), 2.0, "a", int64(4)},
+				{execute.Time(30), 2.0, "a", int64(1)},
+				{execute.Time(40), 3.0, "a", int64(2)},
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name     string
+		tieBreak string
+		column   string
+		want     []interface{}
+	}{
+		{
+			name: "none",
+			want: []interface{}{execute.Time(20), 2.0, "a", int64(4)},
+		},
+		{
+			name:     "first",
+			tieBreak: "first",
+			want:     []interface{}{execute.Time(10), 2.0, "a", int64(3)},
+		},
+		{
+			name:     "last",
+			tieBreak: "last",
+			want:     []interface{}{execute.Time(30), 2.0, "a", int64(1)},
+		},
+		{
+			name:     "min",
+			tieBreak: "min",
+			column:   "rank",
+			want:     []interface{}{execute.Time(30), 2.0, "a", int64(1)},
+		},
+		{
+			name:     "max",
+			tieBreak: "max",
+			column:   "rank",
+			want:     []interface{}{execute.Time(20), 2.0, "a", int64(4)},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			want := []*executetest.Table{{
+				KeyCols: []string{"t1"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t1", Type: flux.TString},
+					{Label: "rank", Type: flux.TInt},
+				},
+				Data: [][]interface{}{tc.want},
+			}}
+			executetest.ProcessTestHelper(
+				t,
+				data(),
+				want,
+				nil,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					spec := &universe.ExactQuantileSelectProcedureSpec{
+						Quantile:       0.5,
+						TieBreak:       tc.tieBreak,
+						TieBreakColumn: tc.column,
+					}
+					return universe.NewExactQuantileSelectorTransformation(d, c, spec, executetest.UnlimitedAllocator)
 				},
 			)
 		})
@@ -792,3 +2370,108 @@ func BenchmarkQuantile(b *testing.B) {
 		13.842132136909889,
 	)
 }
+
+// BenchmarkQuantileSelector_Wide selects a row from
+// many small tables with 50 columns each.
+func BenchmarkQuantileSelector_Wide(b *testing.B) {
+	const (
+		numTables = 1000
+		numRows   = 10
+		numCols   = 50
+	)
+	cols := []flux.ColMeta{
+		{Label: "t0", Type: flux.TString},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	for j := len(cols); j < numCols; j++ {
+		cols = append(cols, flux.ColMeta{Label: fmt.Sprintf("c%d", j), Type: flux.TInt})
+	}
+	executetest.ProcessBenchmarkHelper(b,
+		func(alloc *memory.Allocator) (flux.TableIterator, error) {
+			tables := make([]*executetest.Table, numTables)
+			for i := range tables {
+				data := make([][]interface{}, numRows)
+				for r := range data {
+					row := []interface{}{fmt.Sprintf("t%d", i), execute.Time(r), float64((r * 7) % numRows)}
+					for j := len(row); j < numCols; j++ {
+						row = append(row, int64(r*j))
+					}
+					data[r] = row
+				}
+				tables[i] = &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: cols,
+					Data:    data,
+				}
+			}
+			return &executetest.TableIterator{Tables: tables}, nil
+		},
+		func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+			cache := execute.NewTableBuilderCache(alloc)
+			d := execute.NewDataset(id, execute.DiscardingMode, cache)
+			spec := &universe.ExactQuantileSelectProcedureSpec{
+				Quantile:       0.9,
+				SelectorConfig: execute.SelectorConfig{Column: "_value"},
+			}
+			t := universe.NewExactQuantileSelectorTransformation(d, cache, spec, alloc)
+			return t, d
+		},
+	)
+}
+
+func TestQuantile_MarshalBinary(t *testing.T) {
+	testCases := []struct {
+		name string
+		agg  func() execute.SimpleAggregate
+		want float64
+	}{
+		{
+			name: "tdigest",
+			agg: func() execute.SimpleAggregate {
+				return universe.NewQuantileAgg(0.5, 1000.0, &memory.Allocator{}, 1)
+			},
+			want: 4.0,
+		},
+		{
+			name: "exact mean",
+			agg: func() execute.SimpleAggregate {
+				return &universe.ExactQuantileAgg{Quantile: 0.5}
+			},
+			want: 4.0,
+		},
+		{
+			// The markers have not converged to the
+			// median after only seven values.
+			name: "p2",
+			agg: func() execute.SimpleAggregate {
+				return &universe.P2QuantileAgg{Quantile: 0.5}
+			},
+			want: 3.0,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			vf := tc.agg().NewFloatAgg()
+			vf.DoFloat(arrow.NewFloat([]float64{1, 2, 3, 4, 5}, nil))
+			data, err := vf.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restored := tc.agg().NewFloatAgg()
+			if err := restored.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			restored.DoFloat(arrow.NewFloat([]float64{6, 7}, nil))
+
+			if restored.IsNull() {
+				t.Fatal("unexpected null value")
+			}
+			if got, want := restored.(execute.FloatValueFunc).ValueFloat(), tc.want; got != want {
+				t.Fatalf("unexpected value -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package universe
+
+import (
+	"math"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const SavitzkyGolayKind = "savitzkyGolay"
+
+const (
+	savitzkyGolayEdgesNull   = "null"
+	savitzkyGolayEdgesMirror = "mirror"
+)
+
+// SavitzkyGolayOpSpec smooths a column by fitting a polynomial
+// over a sliding window centered on each row of each table.
+type SavitzkyGolayOpSpec struct {
+	WindowSize int64  `json:"windowSize"`
+	PolyOrder  int64  `json:"polyOrder"`
+	Column     string `json:"column"`
+	Edges      string `json:"edges"`
+}
+
+func init() {
+	savitzkyGolaySignature := runtime.MustLookupBuiltinType("universe", "savitzkyGolay")
+
+	runtime.RegisterPackageValue("universe", SavitzkyGolayKind, flux.MustValue(flux.FunctionValue(SavitzkyGolayKind, createSavitzkyGolayOpSpec, savitzkyGolaySignature)))
+	flux.RegisterOpSpec(SavitzkyGolayKind, newSavitzkyGolayOp)
+	plan.RegisterProcedureSpec(SavitzkyGolayKind, newSavitzkyGolayProcedure, SavitzkyGolayKind)
+	execute.RegisterTransformation(SavitzkyGolayKind, createSavitzkyGolayTransformation)
+}
+
+func createSavitzkyGolayOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(SavitzkyGolayOpSpec)
+	if n, err := args.GetRequiredInt("windowSize"); err != nil {
+		return nil, err
+	} else {
+		spec.WindowSize = n
+	}
+
+	if n, err := args.GetRequiredInt("polyOrder"); err != nil {
+		return nil, err
+	} else {
+		spec.PolyOrder = n
+	}
+
+	if spec.WindowSize <= 0 || spec.WindowSize%2 == 0 {
+		return nil, errors.Newf(codes.Invalid, "windowSize must be a positive odd number, got %d", spec.WindowSize)
+	}
+	if spec.PolyOrder < 0 {
+		return nil, errors.Newf(codes.Invalid, "polyOrder must not be negative, got %d", spec.PolyOrder)
+	}
+	if spec.PolyOrder >= spec.WindowSize {
+		return nil, errors.Newf(codes.Invalid, "windowSize must be greater than polyOrder, got windowSize %d and polyOrder %d", spec.WindowSize, spec.PolyOrder)
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if edges, ok, err := args.GetString("edges"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Edges = edges
+	} else {
+		spec.Edges = savitzkyGolayEdgesNull
+	}
+
+	switch spec.Edges {
+	case savitzkyGolayEdgesNull, savitzkyGolayEdgesMirror:
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown edges mode %q, expected %q or %q", spec.Edges, savitzkyGolayEdgesNull, savitzkyGolayEdgesMirror)
+	}
+	return spec, nil
+}
+
+func newSavitzkyGolayOp() flux.OperationSpec {
+	return new(SavitzkyGolayOpSpec)
+}
+
+func (s *SavitzkyGolayOpSpec) Kind() flux.OperationKind {
+	return SavitzkyGolayKind
+}
+
+type SavitzkyGolayProcedureSpec struct {
+	plan.DefaultCost
+	WindowSize int64  `json:"windowSize"`
+	PolyOrder  int64  `json:"polyOrder"`
+	Column     string `json:"column"`
+	Edges      string `json:"edges"`
+}
+
+func newSavitzkyGolayProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SavitzkyGolayOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &SavitzkyGolayProcedureSpec{
+		WindowSize: spec.WindowSize,
+		PolyOrder:  spec.PolyOrder,
+		Column:     spec.Column,
+		Edges:      spec.Edges,
+	}, nil
+}
+
+func (s *SavitzkyGolayProcedureSpec) Kind() plan.ProcedureKind {
+	return SavitzkyGolayKind
+}
+
+func (s *SavitzkyGolayProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *SavitzkyGolayProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createSavitzkyGolayTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*SavitzkyGolayProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewSavitzkyGolayTransformation(id, s, a.Allocator())
+}
+
+type savitzkyGolayTransformation struct {
+	coeffs []float64
+	column string
+	mirror bool
+}
+
+// NewSavitzkyGolayTransformation creates a transformation that replaces the
+// value of each row with the value at the center of a polynomial of order
+// PolyOrder fitted by least squares to the WindowSize rows centered on it.
+//
+// The fit is a convolution with coefficients that only depend on the window
+// size and the polynomial order so they are computed once. The value of a
+// row depends on the rows that follow it so each table is buffered before
+// it is smoothed. Rows whose window extends past the start or end of the
+// table are null unless the edges are mirrored, in which case the window is
+// reflected about the first or last row. Rows whose window contains a null
+// value are null.
+func NewSavitzkyGolayTransformation(id execute.DatasetID, spec *SavitzkyGolayProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	coeffs, err := savitzkyGolayCoefficients(int(spec.WindowSize), int(spec.PolyOrder))
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &savitzkyGolayTransformation{
+		coeffs: coeffs,
+		column: spec.Column,
+		mirror: spec.Edges == savitzkyGolayEdgesMirror,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+// savitzkyGolayCoefficients computes the convolution coefficients that
+// evaluate, at the center of the window, the polynomial of the given
+// order fitted to the values in the window.
+//
+// For the offsets z from the center of the window, the coefficients are
+// the first row of (JᵀJ)⁻¹Jᵀ where J is the matrix with J[z][k] = zᵏ.
+// The first row is found by solving (JᵀJ)x = e₀ since JᵀJ is symmetric.
+func savitzkyGolayCoefficients(windowSize, polyOrder int) ([]float64, error) {
+	half := windowSize / 2
+	n := polyOrder + 1
+
+	// Build the augmented matrix of the normal equations.
+	// The entry at k, l is the sum of z^(k+l) over the window.
+	m := make([][]float64, n)
+	for k := range m {
+		m[k] = make([]float64, n+1)
+		for l := 0; l < n; l++ {
+			for z := -half; z <= half; z++ {
+				m[k][l] += math.Pow(float64(z), float64(k+l))
+			}
+		}
+	}
+	m[0][n] = 1
+
+	// Gaussian elimination with partial pivoting.
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if m[pivot][col] == 0 {
+			return nil, errors.New(codes.Internal, "cannot compute the Savitzky-Golay coefficients of a singular system")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			for l := col; l <= n; l++ {
+				m[row][l] -= f * m[col][l]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for k := n - 1; k >= 0; k-- {
+		sum := m[k][n]
+		for l := k + 1; l < n; l++ {
+			sum -= m[k][l] * x[l]
+		}
+		x[k] = sum / m[k][k]
+	}
+
+	coeffs := make([]float64, windowSize)
+	for i := range coeffs {
+		z := float64(i - half)
+		for k := n - 1; k >= 0; k-- {
+			coeffs[i] = coeffs[i]*z + x[k]
+		}
+	}
+	return coeffs, nil
+}
+
+type savitzkyGolayState struct {
+	chunks []table.Chunk
+
+	// values holds the value of every row and valid
+	// marks the rows that have a non-null value.
+	values []float64
+	valid  []bool
+}
+
+func (s *savitzkyGolayState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *savitzkyGolayTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *savitzkyGolayState
+	if state != nil {
+		s = state.(*savitzkyGolayState)
+	} else {
+		s = &savitzkyGolayState{}
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot smooth a column that is part of the group key")
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, vs.Value(i))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot smooth column %q of type %s", t.column, typ)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	return s, true, nil
+}
+
+func (t *savitzkyGolayTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*savitzkyGolayState)
+	offset := 0
+	for _, chunk := range s.chunks {
+		n := chunk.Len()
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for i := offset; i < offset+n; i++ {
+			if v, ok := t.smooth(s, i); ok {
+				b.Append(v)
+			} else {
+				b.AppendNull()
+			}
+		}
+		offset += n
+
+		idx := chunk.Index(t.column)
+		buffer := chunk.Buffer()
+		cols := make([]flux.ColMeta, len(buffer.Columns))
+		copy(cols, buffer.Columns)
+		cols[idx].Type = flux.TFloat
+		vs := make([]array.Array, len(buffer.Values))
+		for j := range vs {
+			if j == idx {
+				vs[j] = b.NewArray()
+				continue
+			}
+			vs[j] = buffer.Values[j]
+			vs[j].Retain()
+		}
+		buffer.Columns, buffer.Values = cols, vs
+		if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// smooth returns the smoothed value of the row at index i.
+func (t *savitzkyGolayTransformation) smooth(s *savitzkyGolayState, i int) (float64, bool) {
+	n := len(s.values)
+	half := len(t.coeffs) / 2
+
+	var sum float64
+	for j, c := range t.coeffs {
+		k := i + j - half
+		if t.mirror {
+			if k < 0 {
+				k = -k
+			} else if k >= n {
+				k = 2*(n-1) - k
+			}
+		}
+		if k < 0 || k >= n || !s.valid[k] {
+			return 0, false
+		}
+		sum += c * s.values[k]
+	}
+	return sum, true
+}
+
+// SchemaContract implements execute.SchemaContractDeclarer.
+func (t *savitzkyGolayTransformation) SchemaContract() *execute.SchemaContract {
+	return &execute.SchemaContract{
+		Columns: []flux.ColMeta{{Label: t.column, Type: flux.TFloat}},
+	}
+}
+
+func (t *savitzkyGolayTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestSavitzkyGolay_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.SavitzkyGolayProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "preserves quadratic",
+			spec: &universe.SavitzkyGolayProcedureSpec{
+				WindowSize: 5,
+				PolyOrder:  2,
+				Column:     "_value",
+				Edges:      "null",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 0.0, "a"},
+					{execute.Time(2), 1.0, "a"},
+					{execute.Time(3), 4.0, "a"},
+					{execute.Time(4), 9.0, "a"},
+					{execute.Time(5), 16.0, "a"},
+					{execute.Time(6), 25.0, "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil, "a"},
+					{execute.Time(2), nil, "a"},
+					{execute.Time(3), 4.0, "a"},
+					{execute.Time(4), 9.0, "a"},
+					{execute.Time(5), nil, "a"},
+					{execute.Time(6), nil, "a"},
+				},
+			}},
+		},
+		{
+			name: "mirror edges across chunks",
+			spec: &universe.SavitzkyGolayProcedureSpec{
+				WindowSize: 3,
+				PolyOrder:  1,
+				Column:     "_value",
+				Edges:      "mirror",
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(1)},
+						{execute.Time(2), int64(2)},
+						{execute.Time(3), int64(4)},
+						{execute.Time(4), int64(8)},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 5.0 / 3},
+					{execute.Time(2), 7.0 / 3},
+					{execute.Time(3), 14.0 / 3},
+					{execute.Time(4), 16.0 / 3},
+				},
+			}},
+		},
+		{
+			name: "null in window",
+			spec: &universe.SavitzkyGolayProcedureSpec{
+				WindowSize: 3,
+				PolyOrder:  0,
+				Column:     "_value",
+				Edges:      "null",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), uint64(1)},
+					{execute.Time(2), nil},
+					{execute.Time(3), uint64(3)},
+					{execute.Time(4), uint64(4)},
+					{execute.Time(5), uint64(5)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), nil},
+					{execute.Time(3), nil},
+					{execute.Time(4), 4.0},
+					{execute.Time(5), nil},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewSavitzkyGolayTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
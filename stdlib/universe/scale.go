@@ -0,0 +1,359 @@
+package universe
+
+import (
+	"math"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ScaleKind = "scale"
+
+const (
+	scaleMethodMinMax = "minmax"
+	scaleMethodZScore = "zscore"
+)
+
+// ScaleOpSpec rescales the values of a column within each table.
+type ScaleOpSpec struct {
+	Method string `json:"method"`
+	Column string `json:"column"`
+	As     string `json:"as"`
+}
+
+func init() {
+	scaleSignature := runtime.MustLookupBuiltinType("universe", "scale")
+
+	runtime.RegisterPackageValue("universe", ScaleKind, flux.MustValue(flux.FunctionValue(ScaleKind, createScaleOpSpec, scaleSignature)))
+	flux.RegisterOpSpec(ScaleKind, newScaleOp)
+	plan.RegisterProcedureSpec(ScaleKind, newScaleProcedure, ScaleKind)
+	execute.RegisterTransformation(ScaleKind, createScaleTransformation)
+}
+
+func createScaleOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(ScaleOpSpec)
+	if m, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Method = m
+	} else {
+		spec.Method = scaleMethodMinMax
+	}
+
+	switch spec.Method {
+	case scaleMethodMinMax, scaleMethodZScore:
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown scale method %q, expected %q or %q", spec.Method, scaleMethodMinMax, scaleMethodZScore)
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if as, ok, err := args.GetString("as"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.As = as
+	}
+	return spec, nil
+}
+
+func newScaleOp() flux.OperationSpec {
+	return new(ScaleOpSpec)
+}
+
+func (s *ScaleOpSpec) Kind() flux.OperationKind {
+	return ScaleKind
+}
+
+type ScaleProcedureSpec struct {
+	plan.DefaultCost
+	Method string `json:"method"`
+	Column string `json:"column"`
+	As     string `json:"as"`
+}
+
+func newScaleProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ScaleOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ScaleProcedureSpec{
+		Method: spec.Method,
+		Column: spec.Column,
+		As:     spec.As,
+	}, nil
+}
+
+func (s *ScaleProcedureSpec) Kind() plan.ProcedureKind {
+	return ScaleKind
+}
+
+func (s *ScaleProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ScaleProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createScaleTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ScaleProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewScaleTransformation(id, s, a.Allocator())
+}
+
+// scaler accumulates the statistics needed to rescale a column
+// in the first phase and then rescales each value in the second phase.
+type scaler interface {
+	// Add records a value during the first phase.
+	Add(v float64)
+	// Scale returns the rescaled value. If the value cannot
+	// be rescaled, such as when every value is the same,
+	// false is returned and the output is null.
+	Scale(v float64) (float64, bool)
+}
+
+// minMaxScaler rescales values to the range [0, 1].
+type minMaxScaler struct {
+	min, max float64
+	n        int
+}
+
+func (s *minMaxScaler) Add(v float64) {
+	if s.n == 0 || v < s.min {
+		s.min = v
+	}
+	if s.n == 0 || v > s.max {
+		s.max = v
+	}
+	s.n++
+}
+
+func (s *minMaxScaler) Scale(v float64) (float64, bool) {
+	if s.max == s.min {
+		return 0, false
+	}
+	return (v - s.min) / (s.max - s.min), true
+}
+
+// zScoreScaler rescales values to the number of sample
+// standard deviations they are from the mean.
+// The mean and variance are accumulated using Welford's algorithm.
+type zScoreScaler struct {
+	n        float64
+	mean, m2 float64
+}
+
+func (s *zScoreScaler) Add(v float64) {
+	s.n++
+	delta := v - s.mean
+	s.mean += delta / s.n
+	s.m2 += delta * (v - s.mean)
+}
+
+func (s *zScoreScaler) Scale(v float64) (float64, bool) {
+	if s.n < 2 || s.m2 == 0 {
+		return 0, false
+	}
+	stddev := math.Sqrt(s.m2 / (s.n - 1))
+	return (v - s.mean) / stddev, true
+}
+
+type scaleTransformation struct {
+	method string
+	column string
+	as     string
+}
+
+// NewScaleTransformation creates a transformation that rescales
+// a column per table. The first phase buffers each chunk while
+// accumulating the statistics for the column and the second phase
+// emits the buffered chunks with the rescaled values.
+func NewScaleTransformation(id execute.DatasetID, spec *ScaleProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &scaleTransformation{
+		method: spec.Method,
+		column: spec.Column,
+		as:     spec.As,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type scaleState struct {
+	scaler scaler
+	chunks []table.Chunk
+}
+
+func (s *scaleState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *scaleTransformation) newScaler() scaler {
+	if t.method == scaleMethodZScore {
+		return &zScoreScaler{}
+	}
+	return &minMaxScaler{}
+}
+
+func (t *scaleTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *scaleState
+	if state != nil {
+		s = state.(*scaleState)
+	} else {
+		s = &scaleState{scaler: t.newScaler()}
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot scale a column that is part of the group key")
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				s.scaler.Add(float64(vs.Value(i)))
+			}
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				s.scaler.Add(float64(vs.Value(i)))
+			}
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				s.scaler.Add(vs.Value(i))
+			}
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot scale column %q of type %s", t.column, typ)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	return s, true, nil
+}
+
+func (t *scaleTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*scaleState)
+	for _, chunk := range s.chunks {
+		idx := chunk.Index(t.column)
+		scaled := t.scale(s.scaler, chunk, idx, mem)
+
+		buffer := chunk.Buffer()
+		cols := make([]flux.ColMeta, len(buffer.Columns), len(buffer.Columns)+1)
+		copy(cols, buffer.Columns)
+		vs := make([]array.Array, len(buffer.Values), len(buffer.Values)+1)
+		for j := range vs {
+			vs[j] = buffer.Values[j]
+			vs[j].Retain()
+		}
+
+		if t.as == "" || t.as == t.column {
+			vs[idx].Release()
+			cols[idx].Type, vs[idx] = flux.TFloat, scaled
+		} else if j := chunk.Index(t.as); j >= 0 {
+			vs[j].Release()
+			cols[j].Type, vs[j] = flux.TFloat, scaled
+		} else {
+			cols = append(cols, flux.ColMeta{Label: t.as, Type: flux.TFloat})
+			vs = append(vs, scaled)
+		}
+
+		buffer.Columns, buffer.Values = cols, vs
+		if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *scaleTransformation) scale(s scaler, chunk table.Chunk, idx int, mem memory.Allocator) *array.Float {
+	b := arrowutil.NewFloatBuilder(mem)
+	b.Resize(chunk.Len())
+	appendScaled := func(v float64) {
+		if v, ok := s.Scale(v); ok {
+			b.Append(v)
+		} else {
+			b.AppendNull()
+		}
+	}
+
+	switch chunk.Col(idx).Type {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				appendScaled(float64(vs.Value(i)))
+			} else {
+				b.AppendNull()
+			}
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				appendScaled(float64(vs.Value(i)))
+			} else {
+				b.AppendNull()
+			}
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				appendScaled(vs.Value(i))
+			} else {
+				b.AppendNull()
+			}
+		}
+	}
+	return b.NewFloatArray()
+}
+
+// SchemaContract implements execute.SchemaContractDeclarer.
+func (t *scaleTransformation) SchemaContract() *execute.SchemaContract {
+	label := t.column
+	if t.as != "" {
+		label = t.as
+	}
+	return &execute.SchemaContract{
+		Columns: []flux.ColMeta{{Label: label, Type: flux.TFloat}},
+	}
+}
+
+func (t *scaleTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,286 @@
+package universe
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const SigmaClippedMeanKind = "sigmaClippedMean"
+
+const (
+	defaultSigmaClippedMeanSigma         = 3.0
+	defaultSigmaClippedMeanMaxIterations = 5
+)
+
+// SigmaClippedMeanOpSpec computes the mean of each table after
+// iteratively rejecting values far from the mean.
+type SigmaClippedMeanOpSpec struct {
+	Column         string  `json:"column"`
+	Sigma          float64 `json:"sigma"`
+	MaxIterations  int64   `json:"maxIterations"`
+	RejectedColumn string  `json:"rejectedColumn"`
+}
+
+func init() {
+	sigmaClippedMeanSignature := runtime.MustLookupBuiltinType("universe", "sigmaClippedMean")
+
+	runtime.RegisterPackageValue("universe", SigmaClippedMeanKind, flux.MustValue(flux.FunctionValue(SigmaClippedMeanKind, createSigmaClippedMeanOpSpec, sigmaClippedMeanSignature)))
+	flux.RegisterOpSpec(SigmaClippedMeanKind, newSigmaClippedMeanOp)
+	plan.RegisterProcedureSpec(SigmaClippedMeanKind, newSigmaClippedMeanProcedure, SigmaClippedMeanKind)
+	execute.RegisterTransformation(SigmaClippedMeanKind, createSigmaClippedMeanTransformation)
+}
+
+func createSigmaClippedMeanOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(SigmaClippedMeanOpSpec)
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if sigma, ok, err := args.GetFloat("sigma"); err != nil {
+		return nil, err
+	} else if ok {
+		if !(sigma > 0) {
+			return nil, errors.New(codes.Invalid, `parameter "sigma" must be positive`)
+		}
+		spec.Sigma = sigma
+	} else {
+		spec.Sigma = defaultSigmaClippedMeanSigma
+	}
+
+	if maxIterations, ok, err := args.GetInt("maxIterations"); err != nil {
+		return nil, err
+	} else if ok {
+		if maxIterations <= 0 {
+			return nil, errors.New(codes.Invalid, `parameter "maxIterations" must be positive`)
+		}
+		spec.MaxIterations = maxIterations
+	} else {
+		spec.MaxIterations = defaultSigmaClippedMeanMaxIterations
+	}
+
+	if col, ok, err := args.GetString("rejectedColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		if col == spec.Column {
+			return nil, errors.Newf(codes.Invalid, "rejectedColumn cannot be the same as column %q", col)
+		}
+		spec.RejectedColumn = col
+	}
+	return spec, nil
+}
+
+func newSigmaClippedMeanOp() flux.OperationSpec {
+	return new(SigmaClippedMeanOpSpec)
+}
+
+func (s *SigmaClippedMeanOpSpec) Kind() flux.OperationKind {
+	return SigmaClippedMeanKind
+}
+
+type SigmaClippedMeanProcedureSpec struct {
+	plan.DefaultCost
+	Column         string  `json:"column"`
+	Sigma          float64 `json:"sigma"`
+	MaxIterations  int64   `json:"maxIterations"`
+	RejectedColumn string  `json:"rejectedColumn"`
+}
+
+func newSigmaClippedMeanProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SigmaClippedMeanOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &SigmaClippedMeanProcedureSpec{
+		Column:         spec.Column,
+		Sigma:          spec.Sigma,
+		MaxIterations:  spec.MaxIterations,
+		RejectedColumn: spec.RejectedColumn,
+	}, nil
+}
+
+func (s *SigmaClippedMeanProcedureSpec) Kind() plan.ProcedureKind {
+	return SigmaClippedMeanKind
+}
+
+func (s *SigmaClippedMeanProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *SigmaClippedMeanProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createSigmaClippedMeanTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*SigmaClippedMeanProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewSigmaClippedMeanTransformation(id, s, a.Allocator())
+}
+
+type sigmaClippedMeanTransformation struct {
+	column         string
+	sigma          float64
+	maxIterations  int64
+	rejectedColumn string
+}
+
+// NewSigmaClippedMeanTransformation creates a transformation that computes
+// the mean of each table with outliers rejected by sigma clipping.
+//
+// All non-null values of a table are buffered. Each iteration computes the
+// mean and population standard deviation of the remaining values and rejects
+// the values that are more than sigma standard deviations from the mean.
+// The iterations stop once no value is rejected or the maximum number of
+// iterations is reached. The output is the mean of the remaining values,
+// or null if the table has no values.
+func NewSigmaClippedMeanTransformation(id execute.DatasetID, spec *SigmaClippedMeanProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &sigmaClippedMeanTransformation{
+		column:         spec.Column,
+		sigma:          spec.Sigma,
+		maxIterations:  spec.MaxIterations,
+		rejectedColumn: spec.RejectedColumn,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type sigmaClippedMeanState struct {
+	values []float64
+}
+
+func (t *sigmaClippedMeanTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	var s *sigmaClippedMeanState
+	if state != nil {
+		s = state.(*sigmaClippedMeanState)
+	} else {
+		s = &sigmaClippedMeanState{}
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				s.values = append(s.values, float64(vs.Value(i)))
+			}
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) {
+				s.values = append(s.values, float64(vs.Value(i)))
+			}
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			if vs.IsValid(i) && !math.IsNaN(vs.Value(i)) {
+				s.values = append(s.values, vs.Value(i))
+			}
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the sigma clipped mean of column %q of type %s", t.column, typ)
+	}
+	return s, true, nil
+}
+
+// clip iteratively rejects the values that are more than sigma standard
+// deviations from the mean. It returns the mean of the remaining values
+// and the number of values that were rejected.
+func (t *sigmaClippedMeanTransformation) clip(vs []float64) (mean float64, rejected int) {
+	for iter := int64(0); ; iter++ {
+		var sum float64
+		for _, v := range vs {
+			sum += v
+		}
+		mean = sum / float64(len(vs))
+		if iter >= t.maxIterations {
+			return mean, rejected
+		}
+
+		var m2 float64
+		for _, v := range vs {
+			delta := v - mean
+			m2 += delta * delta
+		}
+		limit := t.sigma * math.Sqrt(m2/float64(len(vs)))
+
+		// Keep the values within the limit in place.
+		n := 0
+		for _, v := range vs {
+			if math.Abs(v-mean) <= limit {
+				vs[n] = v
+				n++
+			}
+		}
+		if n == len(vs) || n == 0 {
+			return mean, rejected
+		}
+		rejected += len(vs) - n
+		vs = vs[:n]
+	}
+}
+
+func (t *sigmaClippedMeanTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*sigmaClippedMeanState)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+2),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), 1, mem))
+	}
+
+	b := arrowutil.NewFloatBuilder(mem)
+	rejected := 0
+	if len(s.values) == 0 {
+		b.AppendNull()
+	} else {
+		var mean float64
+		mean, rejected = t.clip(s.values)
+		b.Append(mean)
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.column, Type: flux.TFloat})
+	buffer.Values = append(buffer.Values, b.NewArray())
+
+	if t.rejectedColumn != "" {
+		rb := arrowutil.NewIntBuilder(mem)
+		rb.Append(int64(rejected))
+		buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.rejectedColumn, Type: flux.TInt})
+		buffer.Values = append(buffer.Values, rb.NewArray())
+	}
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *sigmaClippedMeanTransformation) Close() error {
+	return nil
+}
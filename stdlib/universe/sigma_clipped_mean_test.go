@@ -0,0 +1,151 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestSigmaClippedMean_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.SigmaClippedMeanProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "reject outlier",
+			spec: &universe.SigmaClippedMeanProcedureSpec{
+				Column:         "_value",
+				Sigma:          1.5,
+				MaxIterations:  5,
+				RejectedColumn: "rejected",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(1), "a"},
+					{execute.Time(2), int64(2), "a"},
+					{execute.Time(3), nil, "a"},
+					{execute.Time(4), int64(100), "a"},
+					{execute.Time(5), int64(3), "a"},
+					{execute.Time(6), int64(4), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "rejected", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{"a", 2.5, int64(1)},
+				},
+			}},
+		},
+		{
+			name: "no outliers",
+			spec: &universe.SigmaClippedMeanProcedureSpec{
+				Column:        "_value",
+				Sigma:         3,
+				MaxIterations: 5,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0},
+					{execute.Time(2), 2.0},
+					{execute.Time(3), 6.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{3.0},
+				},
+			}},
+		},
+		{
+			name: "null values",
+			spec: &universe.SigmaClippedMeanProcedureSpec{
+				Column:         "_value",
+				Sigma:          3,
+				MaxIterations:  5,
+				RejectedColumn: "rejected",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "rejected", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{nil, int64(0)},
+				},
+			}},
+		},
+		{
+			name: "unsupported type",
+			spec: &universe.SigmaClippedMeanProcedureSpec{
+				Column:        "_value",
+				Sigma:         3,
+				MaxIterations: 5,
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "a"},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `cannot compute the sigma clipped mean of column "_value" of type string`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewSigmaClippedMeanTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -173,11 +173,10 @@ func (a *SkewAgg) Type() flux.ColType {
 	return flux.TFloat
 }
 func (a *SkewAgg) ValueFloat() float64 {
-	if a.n < 2 {
-		return math.NaN()
-	}
 	return math.Sqrt(a.n) * a.m3 / math.Pow(a.m2, 1.5)
 }
+
+// IsNull reports whether there are too few values to compute the skew.
 func (a *SkewAgg) IsNull() bool {
-	return a.n == 0
+	return a.n < 3
 }
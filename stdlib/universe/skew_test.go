@@ -51,11 +51,18 @@ func TestSkew_Process(t *testing.T) {
 			want: 0.49338220021815854,
 		},
 		{
-			name: "NaN short",
+			name: "null short",
 			data: func() *array.Float {
 				return arrow.NewFloat([]float64{1}, nil)
 			},
-			want: math.NaN(),
+			want: nil,
+		},
+		{
+			name: "null two values",
+			data: func() *array.Float {
+				return arrow.NewFloat([]float64{1, 2}, nil)
+			},
+			want: nil,
 		},
 		{
 			name: "NaN divide by zero",
@@ -201,7 +201,7 @@ func (s SortLimitRule) Pattern() plan.Pattern {
 
 func (s SortLimitRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
 	limitSpec := node.ProcedureSpec().(*LimitProcedureSpec)
-	if limitSpec.Offset != 0 {
+	if limitSpec.Offset != 0 || len(limitSpec.ResetOn) > 0 || limitSpec.Global || limitSpec.Method == LimitMethodSample || limitSpec.Stride > 1 {
 		return node, false, nil
 	}
 	sortNode := node.Predecessors()[0]
@@ -0,0 +1,282 @@
+package universe
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+)
+
+const SpearmanrKind = "spearmanr"
+
+type SpearmanrOpSpec struct {
+	ValueDst string   `json:"valueDst"`
+	Columns  []string `json:"columns"`
+}
+
+func init() {
+	var spearmanrSignature = runtime.MustLookupBuiltinType("universe", "spearmanr")
+	runtime.RegisterPackageValue("universe", SpearmanrKind, flux.MustValue(flux.FunctionValue(SpearmanrKind, createSpearmanrOpSpec, spearmanrSignature)))
+	flux.RegisterOpSpec(SpearmanrKind, newSpearmanrOp)
+	plan.RegisterProcedureSpec(SpearmanrKind, newSpearmanrProcedure, SpearmanrKind)
+	execute.RegisterTransformation(SpearmanrKind, createSpearmanrTransformation)
+}
+
+func createSpearmanrOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(SpearmanrOpSpec)
+	if label, ok, err := args.GetString("valueDst"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.ValueDst = label
+	} else {
+		spec.ValueDst = execute.DefaultValueColLabel
+	}
+
+	cols, err := args.GetRequiredArray("columns", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	spec.Columns, err = interpreter.ToStringArray(cols)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Columns) != 2 {
+		return nil, errors.New(codes.Invalid, "must provide exactly two columns")
+	}
+	return spec, nil
+}
+
+func newSpearmanrOp() flux.OperationSpec {
+	return new(SpearmanrOpSpec)
+}
+
+func (s *SpearmanrOpSpec) Kind() flux.OperationKind {
+	return SpearmanrKind
+}
+
+type SpearmanrProcedureSpec struct {
+	plan.DefaultCost
+	ValueLabel string
+	Columns    []string
+}
+
+func newSpearmanrProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SpearmanrOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	ps := &SpearmanrProcedureSpec{
+		ValueLabel: spec.ValueDst,
+		Columns:    make([]string, len(spec.Columns)),
+	}
+	copy(ps.Columns, spec.Columns)
+	return ps, nil
+}
+
+func (s *SpearmanrProcedureSpec) Kind() plan.ProcedureKind {
+	return SpearmanrKind
+}
+
+func (s *SpearmanrProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(SpearmanrProcedureSpec)
+	*ns = *s
+
+	if s.Columns != nil {
+		ns.Columns = make([]string, len(s.Columns))
+		copy(ns.Columns, s.Columns)
+	}
+
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *SpearmanrProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+// SpearmanrTransformation computes the Spearman rank correlation
+// between two columns of each table. The values of each column are
+// replaced by their ranks and the result is the Pearson correlation
+// of the ranks. Equal values are given the average of their ranks.
+type SpearmanrTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+	spec  SpearmanrProcedureSpec
+
+	// xs and ys are the pairs of values of the table
+	// where neither value is null or NaN.
+	xs, ys []float64
+}
+
+func createSpearmanrTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*SpearmanrProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewSpearmanrTransformation(d, cache, s)
+	return t, d, nil
+}
+
+func NewSpearmanrTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *SpearmanrProcedureSpec) *SpearmanrTransformation {
+	return &SpearmanrTransformation{
+		d:     d,
+		cache: cache,
+		spec:  *spec,
+	}
+}
+
+func (t *SpearmanrTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *SpearmanrTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	cols := tbl.Cols()
+	builder, created := t.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "spearmanr found duplicate table with key: %v", tbl.Key())
+	}
+	if err := execute.AddTableKeyCols(tbl.Key(), builder); err != nil {
+		return err
+	}
+	valueIdx, err := builder.AddCol(flux.ColMeta{
+		Label: t.spec.ValueLabel,
+		Type:  flux.TFloat,
+	})
+	if err != nil {
+		return err
+	}
+
+	var idxs [2]int
+	for i, label := range t.spec.Columns {
+		idxs[i] = execute.ColIdx(label, cols)
+		if idxs[i] < 0 {
+			return errors.Newf(codes.FailedPrecondition, "specified column does not exist in table: %v", label)
+		}
+		switch typ := cols[idxs[i]].Type; typ {
+		case flux.TInt, flux.TUInt, flux.TFloat:
+		default:
+			return errors.Newf(codes.Invalid, "spearmanr does not support %v", typ)
+		}
+	}
+
+	t.xs, t.ys = t.xs[:0], t.ys[:0]
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		t.appendPairs(table.Values(cr, idxs[0]), table.Values(cr, idxs[1]))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := execute.AppendKeyValues(tbl.Key(), builder); err != nil {
+		return err
+	}
+	if len(t.xs) < 2 {
+		return builder.AppendNil(valueIdx)
+	}
+	return builder.AppendFloat(valueIdx, t.value())
+}
+
+// appendPairs appends the pairs of values where
+// neither value is null or NaN.
+func (t *SpearmanrTransformation) appendPairs(xs, ys array.Array) {
+	for i, l := 0, xs.Len(); i < l; i++ {
+		if xs.IsNull(i) || ys.IsNull(i) {
+			continue
+		}
+		x, y := spearmanrValue(xs, i), spearmanrValue(ys, i)
+		if math.IsNaN(x) || math.IsNaN(y) {
+			continue
+		}
+		t.xs = append(t.xs, x)
+		t.ys = append(t.ys, y)
+	}
+}
+
+// spearmanrValue returns a value of a numeric array as a float.
+// Only the order of the values matters so the conversion of
+// large integers does not need to be exact.
+func spearmanrValue(arr array.Array, i int) float64 {
+	switch arr := arr.(type) {
+	case *array.Int:
+		return float64(arr.Value(i))
+	case *array.Uint:
+		return float64(arr.Value(i))
+	default:
+		return arr.(*array.Float).Value(i)
+	}
+}
+
+// value returns the Pearson correlation of the ranks of the pairs.
+// The correlation is NaN if all of the values of a column are equal.
+func (t *SpearmanrTransformation) value() float64 {
+	rx, ry := averageRanks(t.xs), averageRanks(t.ys)
+
+	// Both columns have the same ranks, 1 through n,
+	// up to ties so they also have the same mean.
+	n := float64(len(rx))
+	mean := (n + 1) / 2
+	var sxy, sxx, syy float64
+	for i := range rx {
+		dx, dy := rx[i]-mean, ry[i]-mean
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// averageRanks returns the rank of each value starting at 1.
+// Equal values are given the average of the ranks they span.
+func averageRanks(vs []float64) []float64 {
+	idxs := make([]int, len(vs))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	sort.SliceStable(idxs, func(i, j int) bool {
+		return vs[idxs[i]] < vs[idxs[j]]
+	})
+
+	ranks := make([]float64, len(vs))
+	for i := 0; i < len(idxs); {
+		j := i + 1
+		for j < len(idxs) && vs[idxs[j]] == vs[idxs[i]] {
+			j++
+		}
+		// The values at i through j-1 have the ranks
+		// i+1 through j so their average is (i+j+1)/2.
+		rank := float64(i+j+1) / 2
+		for _, idx := range idxs[i:j] {
+			ranks[idx] = rank
+		}
+		i = j
+	}
+	return ranks
+}
+
+func (t *SpearmanrTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+
+func (t *SpearmanrTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+
+func (t *SpearmanrTransformation) Finish(id execute.DatasetID, err error) {
+	t.d.Finish(err)
+}
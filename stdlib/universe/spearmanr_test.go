@@ -0,0 +1,199 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestSpearmanr_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "simple spearmanr",
+			Raw:  `from(bucket:"mybucket") |> spearmanr(columns:["a","b"])`,
+			Want: &flux.Spec{
+				Operations: []*flux.Operation{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "spearmanr1",
+						Spec: &universe.SpearmanrOpSpec{
+							ValueDst: execute.DefaultValueColLabel,
+							Columns:  []string{"a", "b"},
+						},
+					},
+				},
+				Edges: []flux.Edge{
+					{Parent: "from0", Child: "spearmanr1"},
+				},
+			},
+		},
+		{
+			Name:    "one column",
+			Raw:     `from(bucket:"mybucket") |> spearmanr(columns:["a"])`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestSpearmanr_Process(t *testing.T) {
+	spec := &universe.SpearmanrProcedureSpec{
+		ValueLabel: execute.DefaultValueColLabel,
+		Columns:    []string{"x", "y"},
+	}
+	testCases := []struct {
+		name string
+		data []flux.Table
+		want []*executetest.Table
+	}{
+		{
+			name: "monotonic",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "x", Type: flux.TFloat},
+					{Label: "y", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), execute.Time(0), 1.0, 1.0},
+					{execute.Time(0), execute.Time(5), execute.Time(1), 2.0, 8.0},
+					{execute.Time(0), execute.Time(5), execute.Time(2), 3.0, 27.0},
+					{execute.Time(0), execute.Time(5), execute.Time(3), 4.0, 64.0},
+					{execute.Time(0), execute.Time(5), execute.Time(4), 5.0, 125.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), 1.0},
+				},
+			}},
+		},
+		{
+			name: "reversed",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "x", Type: flux.TInt},
+					{Label: "y", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), execute.Time(0), int64(-3), uint64(100)},
+					{execute.Time(0), execute.Time(5), execute.Time(1), int64(0), uint64(10)},
+					{execute.Time(0), execute.Time(5), execute.Time(2), int64(7), uint64(1)},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), -1.0},
+				},
+			}},
+		},
+		{
+			name: "ties and nulls",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "x", Type: flux.TFloat},
+					{Label: "y", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), execute.Time(0), 1.0, 1.0},
+					{execute.Time(0), execute.Time(5), execute.Time(1), 2.0, 2.0},
+					{execute.Time(0), execute.Time(5), execute.Time(2), nil, 10.0},
+					{execute.Time(0), execute.Time(5), execute.Time(3), 2.0, 3.0},
+					{execute.Time(0), execute.Time(5), execute.Time(4), 3.0, 4.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), 0.9486832980505138},
+				},
+			}},
+		},
+		{
+			name: "fewer than two pairs",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "x", Type: flux.TFloat},
+					{Label: "y", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), execute.Time(0), 1.0, 1.0},
+					{execute.Time(0), execute.Time(5), execute.Time(1), 2.0, nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), execute.Time(5), nil},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				nil,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewSpearmanrTransformation(d, c, spec)
+				},
+			)
+		})
+	}
+}
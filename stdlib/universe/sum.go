@@ -1,6 +1,9 @@
 package universe
 
 import (
+	"encoding/binary"
+	gomath "math"
+
 	"github.com/apache/arrow/go/v7/arrow/math"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/array"
@@ -137,6 +140,18 @@ func (a *SumIntAgg) IsNull() bool {
 	return !a.ok
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler
+// so the sum can be recorded in a checkpoint.
+func (a *SumIntAgg) MarshalBinary() ([]byte, error) {
+	return marshalSumState(uint64(a.sum), a.ok), nil
+}
+
+func (a *SumIntAgg) UnmarshalBinary(data []byte) error {
+	sum, ok, err := unmarshalSumState(data)
+	a.sum, a.ok = int64(sum), ok
+	return err
+}
+
 type SumUIntAgg struct {
 	sum uint64
 	ok  bool
@@ -167,6 +182,18 @@ func (a *SumUIntAgg) IsNull() bool {
 	return !a.ok
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler
+// so the sum can be recorded in a checkpoint.
+func (a *SumUIntAgg) MarshalBinary() ([]byte, error) {
+	return marshalSumState(a.sum, a.ok), nil
+}
+
+func (a *SumUIntAgg) UnmarshalBinary(data []byte) error {
+	sum, ok, err := unmarshalSumState(data)
+	a.sum, a.ok = sum, ok
+	return err
+}
+
 type SumFloatAgg struct {
 	sum float64
 	ok  bool
@@ -196,3 +223,33 @@ func (a *SumFloatAgg) ValueFloat() float64 {
 func (a *SumFloatAgg) IsNull() bool {
 	return !a.ok
 }
+
+// MarshalBinary implements encoding.BinaryMarshaler
+// so the sum can be recorded in a checkpoint.
+func (a *SumFloatAgg) MarshalBinary() ([]byte, error) {
+	return marshalSumState(gomath.Float64bits(a.sum), a.ok), nil
+}
+
+func (a *SumFloatAgg) UnmarshalBinary(data []byte) error {
+	sum, ok, err := unmarshalSumState(data)
+	a.sum, a.ok = gomath.Float64frombits(sum), ok
+	return err
+}
+
+// marshalSumState encodes the bits of a sum and
+// whether any values have been summed.
+func marshalSumState(sum uint64, ok bool) []byte {
+	data := make([]byte, 9)
+	binary.LittleEndian.PutUint64(data, sum)
+	if ok {
+		data[8] = 1
+	}
+	return data
+}
+
+func unmarshalSumState(data []byte) (sum uint64, ok bool, err error) {
+	if len(data) != 9 {
+		return 0, false, errors.Newf(codes.Internal, "invalid sum state of %d bytes", len(data))
+	}
+	return binary.LittleEndian.Uint64(data), data[8] == 1, nil
+}
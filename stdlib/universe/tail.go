@@ -132,6 +132,13 @@ func (t *tailTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 	offset := t.offset
 	readers := make([]flux.ColReader, 0)
 	numRecords := 0
+	defer func() {
+		// Release the readers that are still retained, including
+		// any that follow the offset and were never appended.
+		for _, cr := range readers {
+			cr.Release()
+		}
+	}()
 
 	var finished bool
 	if err := tbl.Do(func(cr flux.ColReader) error {
@@ -183,8 +190,6 @@ func (t *tailTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 		}
 
 		curr += cr.Len()
-
-		cr.Release()
 	}
 
 	return nil
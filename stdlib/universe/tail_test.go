@@ -165,6 +165,38 @@ func TestTail_Process(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "one table with offset multiple batches after offset",
+			spec: &universe.TailProcedureSpec{
+				N:      2,
+				Offset: 1,
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 2.0},
+						{execute.Time(2), 1.0},
+						{execute.Time(3), 0.0},
+						{execute.Time(4), 3.0},
+						{execute.Time(5), 4.0},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(3), 0.0},
+					{execute.Time(4), 3.0},
+				},
+			}},
+		},
 		{
 			name: "multiple tables",
 			spec: &universe.TailProcedureSpec{
@@ -0,0 +1,427 @@
+package universe
+
+import (
+	"sort"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interval"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/values"
+)
+
+const TimedMovingSumKind = "timedMovingSum"
+
+// TimedMovingSumOpSpec sums a column over a trailing
+// time window at a fixed frequency.
+type TimedMovingSumOpSpec struct {
+	Every           flux.Duration `json:"every"`
+	Period          flux.Duration `json:"period"`
+	Column          string        `json:"column"`
+	AllowedLateness flux.Duration `json:"allowedLateness"`
+}
+
+func init() {
+	timedMovingSumSignature := runtime.MustLookupBuiltinType("universe", "timedMovingSum")
+
+	runtime.RegisterPackageValue("universe", TimedMovingSumKind, flux.MustValue(flux.FunctionValue(TimedMovingSumKind, createTimedMovingSumOpSpec, timedMovingSumSignature)))
+	flux.RegisterOpSpec(TimedMovingSumKind, newTimedMovingSumOp)
+	plan.RegisterProcedureSpec(TimedMovingSumKind, newTimedMovingSumProcedure, TimedMovingSumKind)
+	execute.RegisterTransformation(TimedMovingSumKind, createTimedMovingSumTransformation)
+}
+
+func createTimedMovingSumOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(TimedMovingSumOpSpec)
+	if every, err := args.GetRequiredDuration("every"); err != nil {
+		return nil, err
+	} else if every.IsNegative() || every.IsZero() {
+		return nil, errors.New(codes.Invalid, `parameter "every" must be positive`)
+	} else {
+		spec.Every = every
+	}
+
+	if period, err := args.GetRequiredDuration("period"); err != nil {
+		return nil, err
+	} else if period.IsNegative() || period.IsZero() {
+		return nil, errors.New(codes.Invalid, `parameter "period" must be positive`)
+	} else {
+		spec.Period = period
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if lateness, ok, err := args.GetDuration("allowedLateness"); err != nil {
+		return nil, err
+	} else if ok {
+		if lateness.IsNegative() {
+			return nil, errors.New(codes.Invalid, `parameter "allowedLateness" must be non-negative`)
+		} else if lateness.Months() != 0 {
+			return nil, errors.New(codes.Invalid, `parameter "allowedLateness" cannot contain month units`)
+		}
+		spec.AllowedLateness = lateness
+	}
+	return spec, nil
+}
+
+func newTimedMovingSumOp() flux.OperationSpec {
+	return new(TimedMovingSumOpSpec)
+}
+
+func (s *TimedMovingSumOpSpec) Kind() flux.OperationKind {
+	return TimedMovingSumKind
+}
+
+type TimedMovingSumProcedureSpec struct {
+	plan.DefaultCost
+	Every           flux.Duration `json:"every"`
+	Period          flux.Duration `json:"period"`
+	Column          string        `json:"column"`
+	AllowedLateness flux.Duration `json:"allowedLateness"`
+}
+
+func newTimedMovingSumProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*TimedMovingSumOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &TimedMovingSumProcedureSpec{
+		Every:           spec.Every,
+		Period:          spec.Period,
+		Column:          spec.Column,
+		AllowedLateness: spec.AllowedLateness,
+	}, nil
+}
+
+func (s *TimedMovingSumProcedureSpec) Kind() plan.ProcedureKind {
+	return TimedMovingSumKind
+}
+
+func (s *TimedMovingSumProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *TimedMovingSumProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createTimedMovingSumTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*TimedMovingSumProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewTimedMovingSumTransformation(id, s, a.Allocator())
+}
+
+type timedMovingSumTransformation struct {
+	w        interval.Window
+	column   string
+	lateness values.Duration
+}
+
+// NewTimedMovingSumTransformation creates a transformation that sums
+// a column over windows of length period that start every interval.
+// Each output row contains the sum of one window with the time set
+// to the stop of the window. Windows that contain no non-null values
+// are not output.
+//
+// Rows must arrive in time order. A row may arrive out of order if it
+// is no earlier than the allowed lateness before the latest time seen
+// in the table. A window is only summed once the latest time seen is
+// past its stop by the allowed lateness, after which the rows that only
+// belong to that window are evicted. A row that arrives after its window
+// has been summed is an error.
+func NewTimedMovingSumTransformation(id execute.DatasetID, spec *TimedMovingSumProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	w, err := interval.NewWindow(spec.Every, spec.Period, values.Duration{})
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &timedMovingSumTransformation{
+		w:        w,
+		column:   spec.Column,
+		lateness: spec.AllowedLateness,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+// timedMovingSumEntry is a single non-null value
+// that may still contribute to a window.
+type timedMovingSumEntry struct {
+	t values.Time
+	i int64
+	u uint64
+	f float64
+}
+
+type timedMovingSumState struct {
+	typ flux.ColType
+
+	// entries holds the values that may still
+	// contribute to a window sorted by time.
+	entries []timedMovingSumEntry
+
+	// maxTime is the latest time that has been seen.
+	maxTime values.Time
+	seen    bool
+
+	// next is the next window to sum. It is only valid
+	// if hasNext is set.
+	next    interval.Bounds
+	hasNext bool
+
+	// The time and sum of each window that has been summed.
+	times  []int64
+	ints   []int64
+	uints  []uint64
+	floats []float64
+}
+
+func (t *timedMovingSumTransformation) Aggregate(chunk table.Chunk, state interface{}, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot sum a column that is part of the group key")
+	}
+	typ := chunk.Col(idx).Type
+	switch typ {
+	case flux.TInt, flux.TUInt, flux.TFloat:
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot sum column %q of type %s", t.column, typ)
+	}
+
+	timeIdx := chunk.Index(execute.DefaultTimeColLabel)
+	if timeIdx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", execute.DefaultTimeColLabel)
+	} else if ttyp := chunk.Col(timeIdx).Type; ttyp != flux.TTime {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q is of type %s, expected time", execute.DefaultTimeColLabel, ttyp)
+	}
+
+	var s *timedMovingSumState
+	if state != nil {
+		s = state.(*timedMovingSumState)
+		if s.typ != typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q changed type from %s to %s", t.column, s.typ, typ)
+		}
+	} else {
+		s = &timedMovingSumState{typ: typ}
+	}
+
+	ts := chunk.Ints(timeIdx)
+	for i := 0; i < ts.Len(); i++ {
+		if ts.IsNull(i) {
+			continue
+		}
+		tm := values.Time(ts.Value(i))
+		if s.seen && tm < t.watermark(s) {
+			return nil, false, errors.Newf(codes.Invalid, "row at time %v arrived after the allowed lateness of %v before time %v", tm, t.lateness, s.maxTime)
+		}
+		if !s.seen || tm > s.maxTime {
+			s.maxTime, s.seen = tm, true
+		}
+
+		e := timedMovingSumEntry{t: tm}
+		switch typ {
+		case flux.TInt:
+			vs := chunk.Ints(idx)
+			if vs.IsNull(i) {
+				continue
+			}
+			e.i = vs.Value(i)
+		case flux.TUInt:
+			vs := chunk.Uints(idx)
+			if vs.IsNull(i) {
+				continue
+			}
+			e.u = vs.Value(i)
+		case flux.TFloat:
+			vs := chunk.Floats(idx)
+			if vs.IsNull(i) {
+				continue
+			}
+			e.f = vs.Value(i)
+		}
+		t.insert(s, e)
+	}
+
+	t.sumWindows(s, t.watermark(s), false)
+	return s, true, nil
+}
+
+// watermark returns the time before which no more rows may arrive.
+func (t *timedMovingSumTransformation) watermark(s *timedMovingSumState) values.Time {
+	return s.maxTime.Add(t.lateness.Mul(-1))
+}
+
+// insert adds an entry while keeping the entries sorted by time.
+func (t *timedMovingSumTransformation) insert(s *timedMovingSumState, e timedMovingSumEntry) {
+	n := len(s.entries)
+	if n == 0 || s.entries[n-1].t <= e.t {
+		s.entries = append(s.entries, e)
+	} else {
+		i := sort.Search(n, func(i int) bool {
+			return s.entries[i].t > e.t
+		})
+		s.entries = append(s.entries, timedMovingSumEntry{})
+		copy(s.entries[i+1:], s.entries[i:])
+		s.entries[i] = e
+	}
+
+	if !s.hasNext {
+		s.next, s.hasNext = t.earliestBounds(e.t), true
+	} else if b := t.earliestBounds(e.t); b.Stop() < s.next.Stop() {
+		// A late row may belong to a window before the next window,
+		// but only if that window has not been summed yet. Rows that
+		// are late for summed windows are rejected by the watermark.
+		s.next = b
+	}
+}
+
+// earliestBounds returns the earliest window that contains the time.
+// If no window contains the time, the window after it is returned.
+func (t *timedMovingSumTransformation) earliestBounds(tm values.Time) interval.Bounds {
+	b := t.w.GetLatestBounds(tm)
+	if !b.Contains(tm) {
+		return t.w.NextBounds(b)
+	}
+	for prev := t.w.PrevBounds(b); prev.Contains(tm); prev = t.w.PrevBounds(prev) {
+		b = prev
+	}
+	return b
+}
+
+// sumWindows sums each window that stops at or before the watermark.
+// If final is set, every window with entries is summed.
+func (t *timedMovingSumTransformation) sumWindows(s *timedMovingSumState, watermark values.Time, final bool) {
+	for s.hasNext && (final || s.next.Stop() <= watermark) {
+		t.sumWindow(s, s.next)
+
+		// Evict the entries that precede the following window.
+		next := t.w.NextBounds(s.next)
+		i := sort.Search(len(s.entries), func(i int) bool {
+			return s.entries[i].t >= next.Start()
+		})
+		s.entries = s.entries[i:]
+		if len(s.entries) == 0 {
+			s.hasNext = false
+			break
+		}
+
+		// Skip over the empty windows between sparse entries.
+		if b := t.earliestBounds(s.entries[0].t); b.Stop() > next.Stop() {
+			next = b
+		}
+		s.next = next
+	}
+}
+
+// sumWindow appends the sum of the entries within the window
+// if there are any.
+func (t *timedMovingSumTransformation) sumWindow(s *timedMovingSumState, b interval.Bounds) {
+	var (
+		n int
+		i int64
+		u uint64
+		f float64
+	)
+	for _, e := range s.entries {
+		if e.t >= b.Stop() {
+			break
+		} else if e.t < b.Start() {
+			continue
+		}
+		i += e.i
+		u += e.u
+		f += e.f
+		n++
+	}
+	if n == 0 {
+		return
+	}
+
+	s.times = append(s.times, int64(b.Stop()))
+	switch s.typ {
+	case flux.TInt:
+		s.ints = append(s.ints, i)
+	case flux.TUInt:
+		s.uints = append(s.uints, u)
+	case flux.TFloat:
+		s.floats = append(s.floats, f)
+	}
+}
+
+func (t *timedMovingSumTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	s := state.(*timedMovingSumState)
+	t.sumWindows(s, s.maxTime, true)
+	n := len(s.times)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+2),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), n, mem))
+	}
+
+	tb := arrowutil.NewIntBuilder(mem)
+	tb.Resize(n)
+	for _, tm := range s.times {
+		tb.Append(tm)
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime})
+	buffer.Values = append(buffer.Values, tb.NewArray())
+
+	var vs array.Array
+	switch s.typ {
+	case flux.TInt:
+		b := arrowutil.NewIntBuilder(mem)
+		b.Resize(n)
+		for _, v := range s.ints {
+			b.Append(v)
+		}
+		vs = b.NewArray()
+	case flux.TUInt:
+		b := arrowutil.NewUintBuilder(mem)
+		b.Resize(n)
+		for _, v := range s.uints {
+			b.Append(v)
+		}
+		vs = b.NewArray()
+	case flux.TFloat:
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for _, v := range s.floats {
+			b.Append(v)
+		}
+		vs = b.NewArray()
+	}
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{Label: t.column, Type: s.typ})
+	buffer.Values = append(buffer.Values, vs)
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *timedMovingSumTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func TestTimedMovingSum_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.TimedMovingSumProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "overlapping windows",
+			spec: &universe.TimedMovingSumProcedureSpec{
+				Every:  values.ConvertDurationNsecs(2),
+				Period: values.ConvertDurationNsecs(4),
+				Column: "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(1), "a"},
+					{execute.Time(2), int64(2), "a"},
+					{execute.Time(3), int64(3), "a"},
+					{execute.Time(4), nil, "a"},
+					{execute.Time(5), int64(5), "a"},
+					{execute.Time(6), int64(6), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{"a", execute.Time(2), int64(1)},
+					{"a", execute.Time(4), int64(6)},
+					{"a", execute.Time(6), int64(10)},
+					{"a", execute.Time(8), int64(11)},
+					{"a", execute.Time(10), int64(6)},
+				},
+			}},
+		},
+		{
+			name: "sparse data across chunks",
+			spec: &universe.TimedMovingSumProcedureSpec{
+				Every:  values.ConvertDurationNsecs(2),
+				Period: values.ConvertDurationNsecs(2),
+				Column: "_value",
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(0), 1.5},
+						{execute.Time(1), 2.0},
+						{execute.Time(100), 3.0},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 3.5},
+					{execute.Time(102), 3.0},
+				},
+			}},
+		},
+		{
+			name: "out of order within allowed lateness",
+			spec: &universe.TimedMovingSumProcedureSpec{
+				Every:           values.ConvertDurationNsecs(2),
+				Period:          values.ConvertDurationNsecs(2),
+				Column:          "_value",
+				AllowedLateness: values.ConvertDurationNsecs(2),
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TUInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), uint64(1)},
+						{execute.Time(3), uint64(1)},
+						{execute.Time(2), uint64(1)},
+						{execute.Time(4), uint64(1)},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), uint64(1)},
+					{execute.Time(4), uint64(2)},
+					{execute.Time(6), uint64(1)},
+				},
+			}},
+		},
+		{
+			name: "late data",
+			spec: &universe.TimedMovingSumProcedureSpec{
+				Every:  values.ConvertDurationNsecs(2),
+				Period: values.ConvertDurationNsecs(2),
+				Column: "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(2), 1.0},
+					{execute.Time(1), 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.Invalid, "row at time 1970-01-01T00:00:00.000000001Z arrived after the allowed lateness of 0ns before time 1970-01-01T00:00:00.000000002Z"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewTimedMovingSumTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
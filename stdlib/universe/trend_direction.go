@@ -0,0 +1,299 @@
+package universe
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const TrendDirectionKind = "trendDirection"
+
+const (
+	// trendMethodEndpoints compares the first and last values of the window.
+	trendMethodEndpoints = "endpoints"
+	// trendMethodRegression uses the sign of the slope of the
+	// least squares regression line through the window.
+	trendMethodRegression = "regression"
+)
+
+// TrendDirectionOpSpec reports whether the values
+// over the trailing n rows of each row are decreasing,
+// flat, or increasing.
+type TrendDirectionOpSpec struct {
+	N      int64  `json:"n"`
+	Column string `json:"column"`
+	Method string `json:"method"`
+}
+
+func init() {
+	trendDirectionSignature := runtime.MustLookupBuiltinType("universe", "trendDirection")
+
+	runtime.RegisterPackageValue("universe", TrendDirectionKind, flux.MustValue(flux.FunctionValue(TrendDirectionKind, createTrendDirectionOpSpec, trendDirectionSignature)))
+	flux.RegisterOpSpec(TrendDirectionKind, newTrendDirectionOp)
+	plan.RegisterProcedureSpec(TrendDirectionKind, newTrendDirectionProcedure, TrendDirectionKind)
+	execute.RegisterTransformation(TrendDirectionKind, createTrendDirectionTransformation)
+}
+
+func createTrendDirectionOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(TrendDirectionOpSpec)
+	if n, err := args.GetRequiredInt("n"); err != nil {
+		return nil, err
+	} else if n < 2 {
+		return nil, errors.Newf(codes.Invalid, "cannot compute the trend direction with a period of %v (must be at least 2)", n)
+	} else {
+		spec.N = n
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if method, ok, err := args.GetString("method"); err != nil {
+		return nil, err
+	} else if ok {
+		switch method {
+		case trendMethodEndpoints, trendMethodRegression:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unknown method %q, expected %q or %q", method, trendMethodEndpoints, trendMethodRegression)
+		}
+		spec.Method = method
+	} else {
+		spec.Method = trendMethodEndpoints
+	}
+	return spec, nil
+}
+
+func newTrendDirectionOp() flux.OperationSpec {
+	return new(TrendDirectionOpSpec)
+}
+
+func (s *TrendDirectionOpSpec) Kind() flux.OperationKind {
+	return TrendDirectionKind
+}
+
+type TrendDirectionProcedureSpec struct {
+	plan.DefaultCost
+	N      int64  `json:"n"`
+	Column string `json:"column"`
+	Method string `json:"method"`
+}
+
+func newTrendDirectionProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*TrendDirectionOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &TrendDirectionProcedureSpec{
+		N:      spec.N,
+		Column: spec.Column,
+		Method: spec.Method,
+	}, nil
+}
+
+func (s *TrendDirectionProcedureSpec) Kind() plan.ProcedureKind {
+	return TrendDirectionKind
+}
+
+func (s *TrendDirectionProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *TrendDirectionProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createTrendDirectionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*TrendDirectionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewTrendDirectionTransformation(id, s, a.Allocator())
+}
+
+type trendDirectionTransformation struct {
+	n          int
+	column     string
+	regression bool
+}
+
+// NewTrendDirectionTransformation creates a transformation that replaces
+// the value of each row with -1, 0, or 1 depending on whether the values of
+// the trailing n rows, including the row itself, are decreasing, flat,
+// or increasing.
+//
+// The endpoints method compares the first and last non-null values of the
+// window. The regression method uses the sign of the slope of the least
+// squares line through the non-null values against their row position.
+// The window is kept in a ring buffer that is retained across buffers of
+// the same table. Rows before the window has filled are null, as are rows
+// whose window has fewer than two non-null values. NaN values are treated
+// as null.
+func NewTrendDirectionTransformation(id execute.DatasetID, spec *TrendDirectionProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &trendDirectionTransformation{
+		n:          int(spec.N),
+		column:     spec.Column,
+		regression: spec.Method == trendMethodRegression,
+	}
+	return execute.NewNarrowStateTransformation(id, t, mem)
+}
+
+type trendDirectionState struct {
+	typ flux.ColType
+	// values and valid are a ring buffer of the window
+	// where pos is the index of the oldest row.
+	values []float64
+	valid  []bool
+	pos    int
+	// count is the number of rows that have been read.
+	count int64
+}
+
+func (s *trendDirectionState) push(v float64, ok bool) {
+	s.values[s.pos], s.valid[s.pos] = v, ok
+	s.pos = (s.pos + 1) % len(s.values)
+	s.count++
+}
+
+// direction returns the sign of the trend of the window.
+func (t *trendDirectionTransformation) direction(s *trendDirectionState) (int64, bool) {
+	if s.count < int64(t.n) {
+		return 0, false
+	}
+
+	var (
+		first, last float64
+		n           int
+		sumX, sumY  float64
+		sumXY       float64
+	)
+	for x := 0; x < t.n; x++ {
+		i := (s.pos + x) % t.n
+		if !s.valid[i] {
+			continue
+		}
+		y := s.values[i]
+		if n == 0 {
+			first = y
+		}
+		last = y
+		n++
+		sumX += float64(x)
+		sumY += y
+		sumXY += float64(x) * y
+	}
+	if n < 2 {
+		return 0, false
+	}
+
+	// The sign of the slope is the sign of its numerator
+	// because the denominator is positive.
+	delta := last - first
+	if t.regression {
+		delta = float64(n)*sumXY - sumX*sumY
+	}
+	switch {
+	case delta > 0:
+		return 1, true
+	case delta < 0:
+		return -1, true
+	default:
+		return 0, true
+	}
+}
+
+func (t *trendDirectionTransformation) Process(chunk table.Chunk, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	typ := chunk.Col(idx).Type
+
+	var s *trendDirectionState
+	if state != nil {
+		s = state.(*trendDirectionState)
+		if s.typ != typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q changed type from %s to %s", t.column, s.typ, typ)
+		}
+	} else {
+		s = &trendDirectionState{
+			typ:    typ,
+			values: make([]float64, t.n),
+			valid:  make([]bool, t.n),
+		}
+	}
+
+	var value func(i int) (float64, bool)
+	switch typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		value = func(i int) (float64, bool) {
+			return vs.Value(i), vs.IsValid(i) && !math.IsNaN(vs.Value(i))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the trend direction of column %q of type %s", t.column, typ)
+	}
+
+	n := chunk.Len()
+	b := arrowutil.NewIntBuilder(mem)
+	b.Resize(n)
+	for i := 0; i < n; i++ {
+		s.push(value(i))
+		if dir, ok := t.direction(s); ok {
+			b.Append(dir)
+		} else {
+			b.AppendNull()
+		}
+	}
+
+	buffer := chunk.Buffer()
+	cols := make([]flux.ColMeta, len(buffer.Columns))
+	copy(cols, buffer.Columns)
+	cols[idx].Type = flux.TInt
+	vs := make([]array.Array, len(buffer.Values))
+	for j := range vs {
+		if j == idx {
+			vs[j] = b.NewArray()
+			continue
+		}
+		vs[j] = buffer.Values[j]
+		vs[j].Retain()
+	}
+	buffer.Columns, buffer.Values = cols, vs
+	if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *trendDirectionTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestTrendDirection_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.RowWiseTable{
+			Table: &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), int64(1), "a"},
+					{execute.Time(2), int64(5), "a"},
+					{execute.Time(3), int64(0), "a"},
+					{execute.Time(4), int64(2), "a"},
+					{execute.Time(5), int64(2), "a"},
+				},
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name string
+		spec *universe.TrendDirectionProcedureSpec
+		data []flux.Table
+		want []*executetest.Table
+	}{
+		{
+			name: "endpoints",
+			spec: &universe.TrendDirectionProcedureSpec{
+				N:      4,
+				Column: "_value",
+				Method: "endpoints",
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil, "a"},
+					{execute.Time(2), nil, "a"},
+					{execute.Time(3), nil, "a"},
+					{execute.Time(4), int64(1), "a"},
+					{execute.Time(5), int64(-1), "a"},
+				},
+			}},
+		},
+		{
+			name: "regression",
+			spec: &universe.TrendDirectionProcedureSpec{
+				N:      4,
+				Column: "_value",
+				Method: "regression",
+			},
+			data: data(),
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil, "a"},
+					{execute.Time(2), nil, "a"},
+					{execute.Time(3), nil, "a"},
+					{execute.Time(4), int64(-1), "a"},
+					{execute.Time(5), int64(-1), "a"},
+				},
+			}},
+		},
+		{
+			name: "nulls and NaN",
+			spec: &universe.TrendDirectionProcedureSpec{
+				N:      3,
+				Column: "_value",
+				Method: "endpoints",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 3.0},
+					{execute.Time(2), nil},
+					{execute.Time(3), 3.0},
+					{execute.Time(4), math.NaN()},
+					{execute.Time(5), 4.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), nil},
+					{execute.Time(3), int64(0)},
+					{execute.Time(4), nil},
+					{execute.Time(5), int64(1)},
+				},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewTrendDirectionTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package universe
+
+import (
+	"math"
+	"sort"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ValuePercentileKind = "valuePercentile"
+
+// ValuePercentileLabel is the column that contains the percentile of a value.
+const ValuePercentileLabel = "_percentile"
+
+const (
+	percentileTiesAverage = "average"
+	percentileTiesMin     = "min"
+	percentileTiesMax     = "max"
+)
+
+// ValuePercentileOpSpec computes the percentile of
+// the value of each row within each table.
+type ValuePercentileOpSpec struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+	Ties   string `json:"ties"`
+}
+
+func init() {
+	valuePercentileSignature := runtime.MustLookupBuiltinType("universe", "valuePercentile")
+
+	runtime.RegisterPackageValue("universe", ValuePercentileKind, flux.MustValue(flux.FunctionValue(ValuePercentileKind, createValuePercentileOpSpec, valuePercentileSignature)))
+	flux.RegisterOpSpec(ValuePercentileKind, newValuePercentileOp)
+	plan.RegisterProcedureSpec(ValuePercentileKind, newValuePercentileProcedure, ValuePercentileKind)
+	execute.RegisterTransformation(ValuePercentileKind, createValuePercentileTransformation)
+}
+
+func createValuePercentileOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(ValuePercentileOpSpec)
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+
+	if desc, ok, err := args.GetBool("desc"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Desc = desc
+	}
+
+	if ties, ok, err := args.GetString("ties"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Ties = ties
+	} else {
+		spec.Ties = percentileTiesAverage
+	}
+
+	switch spec.Ties {
+	case percentileTiesAverage, percentileTiesMin, percentileTiesMax:
+	default:
+		return nil, errors.Newf(codes.Invalid, "unknown ties method %q, expected %q, %q, or %q", spec.Ties, percentileTiesAverage, percentileTiesMin, percentileTiesMax)
+	}
+	return spec, nil
+}
+
+func newValuePercentileOp() flux.OperationSpec {
+	return new(ValuePercentileOpSpec)
+}
+
+func (s *ValuePercentileOpSpec) Kind() flux.OperationKind {
+	return ValuePercentileKind
+}
+
+type ValuePercentileProcedureSpec struct {
+	plan.DefaultCost
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+	Ties   string `json:"ties"`
+}
+
+func newValuePercentileProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ValuePercentileOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ValuePercentileProcedureSpec{
+		Column: spec.Column,
+		Desc:   spec.Desc,
+		Ties:   spec.Ties,
+	}, nil
+}
+
+func (s *ValuePercentileProcedureSpec) Kind() plan.ProcedureKind {
+	return ValuePercentileKind
+}
+
+func (s *ValuePercentileProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *ValuePercentileProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createValuePercentileTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ValuePercentileProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewValuePercentileTransformation(id, s, a.Allocator())
+}
+
+type valuePercentileTransformation struct {
+	column string
+	desc   bool
+	ties   string
+}
+
+// NewValuePercentileTransformation creates a transformation that adds the
+// percentile of the value in each row to the _percentile column.
+// Rows keep their original order.
+//
+// The non-null values of the table are ranked from 1 to n in ascending
+// order, or in descending order if desc is set. The percentile of a value
+// is 1 for the first rank and decreases linearly to 0 for the last rank,
+// so the largest value has a percentile of 1 when desc is set. Tied values
+// receive the average, lowest, or highest rank of the tie. A table with a
+// single non-null value gives it a percentile of 1. Null and NaN values
+// have a null percentile.
+func NewValuePercentileTransformation(id execute.DatasetID, spec *ValuePercentileProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &valuePercentileTransformation{
+		column: spec.Column,
+		desc:   spec.Desc,
+		ties:   spec.Ties,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+type valuePercentileState struct {
+	chunks []table.Chunk
+
+	// values holds the value of every row and valid
+	// marks the rows that have a non-null value.
+	values []float64
+	valid  []bool
+}
+
+func (s *valuePercentileState) Close() error {
+	for _, chunk := range s.chunks {
+		chunk.Release()
+	}
+	s.chunks = nil
+	return nil
+}
+
+func (t *valuePercentileTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *valuePercentileState
+	if state != nil {
+		s = state.(*valuePercentileState)
+	} else {
+		s = &valuePercentileState{}
+	}
+
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+
+	switch typ := chunk.Col(idx).Type; typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, float64(vs.Value(i)))
+			s.valid = append(s.valid, vs.IsValid(i))
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		for i := 0; i < vs.Len(); i++ {
+			s.values = append(s.values, vs.Value(i))
+			s.valid = append(s.valid, vs.IsValid(i) && !math.IsNaN(vs.Value(i)))
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the percentile of column %q of type %s", t.column, typ)
+	}
+
+	chunk.Retain()
+	s.chunks = append(s.chunks, chunk)
+	return s, true, nil
+}
+
+func (t *valuePercentileTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*valuePercentileState)
+	if len(s.values) == 0 {
+		// Empty tables are passed through unchanged.
+		for _, chunk := range s.chunks {
+			chunk.Retain()
+			if err := d.Process(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	percentiles := t.percentiles(s)
+	offset := 0
+	for _, chunk := range s.chunks {
+		n := chunk.Len()
+		b := arrowutil.NewFloatBuilder(mem)
+		b.Resize(n)
+		for i := offset; i < offset+n; i++ {
+			if s.valid[i] {
+				b.Append(percentiles[i])
+			} else {
+				b.AppendNull()
+			}
+		}
+		offset += n
+
+		buffer := chunk.Buffer()
+		cols := make([]flux.ColMeta, len(buffer.Columns), len(buffer.Columns)+1)
+		copy(cols, buffer.Columns)
+		vs := make([]array.Array, len(buffer.Values), len(buffer.Values)+1)
+		for j := range vs {
+			vs[j] = buffer.Values[j]
+			vs[j].Retain()
+		}
+
+		if j := chunk.Index(ValuePercentileLabel); j >= 0 {
+			vs[j].Release()
+			cols[j].Type, vs[j] = flux.TFloat, b.NewFloatArray()
+		} else {
+			cols = append(cols, flux.ColMeta{Label: ValuePercentileLabel, Type: flux.TFloat})
+			vs = append(vs, b.NewFloatArray())
+		}
+
+		buffer.Columns, buffer.Values = cols, vs
+		if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// percentiles computes the percentile for each of the valid values.
+func (t *valuePercentileTransformation) percentiles(s *valuePercentileState) []float64 {
+	indices := make([]int, 0, len(s.values))
+	for i, valid := range s.valid {
+		if valid {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		if t.desc {
+			return s.values[indices[i]] > s.values[indices[j]]
+		}
+		return s.values[indices[i]] < s.values[indices[j]]
+	})
+
+	n := float64(len(indices))
+	percentiles := make([]float64, len(s.values))
+	for start := 0; start < len(indices); {
+		// Find the end of the run of tied values.
+		end := start + 1
+		for end < len(indices) && s.values[indices[end]] == s.values[indices[start]] {
+			end++
+		}
+
+		var rank float64
+		switch t.ties {
+		case percentileTiesMin:
+			rank = float64(start + 1)
+		case percentileTiesMax:
+			rank = float64(end)
+		default:
+			rank = float64(start+1+end) / 2
+		}
+
+		p := 1.0
+		if n > 1 {
+			p = (n - rank) / (n - 1)
+		}
+		for _, i := range indices[start:end] {
+			percentiles[i] = p
+		}
+		start = end
+	}
+	return percentiles
+}
+
+func (t *valuePercentileTransformation) Close() error {
+	return nil
+}
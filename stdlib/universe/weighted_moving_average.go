@@ -0,0 +1,261 @@
+package universe
+
+import (
+	"math"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+)
+
+const WeightedMovingAverageKind = "weightedMovingAverage"
+
+// WeightedMovingAverageOpSpec convolves a kernel of
+// weights over the trailing rows of each table.
+type WeightedMovingAverageOpSpec struct {
+	Weights []float64 `json:"weights"`
+	Column  string    `json:"column"`
+}
+
+func init() {
+	weightedMovingAverageSignature := runtime.MustLookupBuiltinType("universe", "weightedMovingAverage")
+
+	runtime.RegisterPackageValue("universe", WeightedMovingAverageKind, flux.MustValue(flux.FunctionValue(WeightedMovingAverageKind, createWeightedMovingAverageOpSpec, weightedMovingAverageSignature)))
+	flux.RegisterOpSpec(WeightedMovingAverageKind, newWeightedMovingAverageOp)
+	plan.RegisterProcedureSpec(WeightedMovingAverageKind, newWeightedMovingAverageProcedure, WeightedMovingAverageKind)
+	execute.RegisterTransformation(WeightedMovingAverageKind, createWeightedMovingAverageTransformation)
+}
+
+func createWeightedMovingAverageOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := new(WeightedMovingAverageOpSpec)
+	weights, err := args.GetRequiredArray("weights", semantic.Float)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Weights, err = interpreter.ToFloatArray(weights); err != nil {
+		return nil, err
+	}
+	if len(spec.Weights) == 0 {
+		return nil, errors.New(codes.Invalid, "weights must not be empty")
+	}
+	var sum float64
+	for _, w := range spec.Weights {
+		sum += w
+	}
+	if sum == 0 || math.IsNaN(sum) || math.IsInf(sum, 0) {
+		return nil, errors.New(codes.Invalid, "the sum of the weights must be a non-zero finite number")
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	return spec, nil
+}
+
+func newWeightedMovingAverageOp() flux.OperationSpec {
+	return new(WeightedMovingAverageOpSpec)
+}
+
+func (s *WeightedMovingAverageOpSpec) Kind() flux.OperationKind {
+	return WeightedMovingAverageKind
+}
+
+type WeightedMovingAverageProcedureSpec struct {
+	plan.DefaultCost
+	Weights []float64 `json:"weights"`
+	Column  string    `json:"column"`
+}
+
+func newWeightedMovingAverageProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*WeightedMovingAverageOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &WeightedMovingAverageProcedureSpec{
+		Weights: spec.Weights,
+		Column:  spec.Column,
+	}, nil
+}
+
+func (s *WeightedMovingAverageProcedureSpec) Kind() plan.ProcedureKind {
+	return WeightedMovingAverageKind
+}
+
+func (s *WeightedMovingAverageProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(WeightedMovingAverageProcedureSpec)
+	*ns = *s
+	if s.Weights != nil {
+		ns.Weights = make([]float64, len(s.Weights))
+		copy(ns.Weights, s.Weights)
+	}
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *WeightedMovingAverageProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createWeightedMovingAverageTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*WeightedMovingAverageProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewWeightedMovingAverageTransformation(id, s, a.Allocator())
+}
+
+type weightedMovingAverageTransformation struct {
+	weights []float64
+	column  string
+}
+
+// NewWeightedMovingAverageTransformation creates a transformation that
+// replaces the value of each row with the weighted average of the trailing
+// len(weights) rows, including the row itself. The first weight applies to
+// the oldest row of the window and the last weight to the current row.
+//
+// The window is kept in a ring buffer that is retained across buffers of
+// the same table. Rows before the window has filled are null. Null values
+// are left out of the average and the remaining weights are normalized by
+// their sum, so the output is null if every value in the window is null or
+// the weights of the non-null values sum to zero.
+func NewWeightedMovingAverageTransformation(id execute.DatasetID, spec *WeightedMovingAverageProcedureSpec, mem arrowmem.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &weightedMovingAverageTransformation{
+		weights: spec.Weights,
+		column:  spec.Column,
+	}
+	return execute.NewNarrowStateTransformation(id, t, mem)
+}
+
+type weightedMovingAverageState struct {
+	typ flux.ColType
+	// values and valid are a ring buffer of the window
+	// where pos is the index of the oldest row.
+	values []float64
+	valid  []bool
+	pos    int
+	// count is the number of rows that have been read.
+	count int64
+}
+
+// push adds a value to the window in place of the oldest row.
+func (s *weightedMovingAverageState) push(v float64, ok bool) {
+	s.values[s.pos], s.valid[s.pos] = v, ok
+	s.pos = (s.pos + 1) % len(s.values)
+	s.count++
+}
+
+// average returns the weighted average of the window.
+func (t *weightedMovingAverageTransformation) average(s *weightedMovingAverageState) (float64, bool) {
+	n := len(t.weights)
+	if s.count < int64(n) {
+		return 0, false
+	}
+	var sum, weightSum float64
+	for j, w := range t.weights {
+		i := (s.pos + j) % n
+		if s.valid[i] {
+			sum += w * s.values[i]
+			weightSum += w
+		}
+	}
+	if weightSum == 0 {
+		return 0, false
+	}
+	return sum / weightSum, true
+}
+
+func (t *weightedMovingAverageTransformation) Process(chunk table.Chunk, state interface{}, d *execute.TransportDataset, mem arrowmem.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	typ := chunk.Col(idx).Type
+
+	var s *weightedMovingAverageState
+	if state != nil {
+		s = state.(*weightedMovingAverageState)
+		if s.typ != typ {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "column %q changed type from %s to %s", t.column, s.typ, typ)
+		}
+	} else {
+		s = &weightedMovingAverageState{
+			typ:    typ,
+			values: make([]float64, len(t.weights)),
+			valid:  make([]bool, len(t.weights)),
+		}
+	}
+
+	var value func(i int) (float64, bool)
+	switch typ {
+	case flux.TInt:
+		vs := chunk.Ints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TUInt:
+		vs := chunk.Uints(idx)
+		value = func(i int) (float64, bool) {
+			return float64(vs.Value(i)), vs.IsValid(i)
+		}
+	case flux.TFloat:
+		vs := chunk.Floats(idx)
+		value = func(i int) (float64, bool) {
+			return vs.Value(i), vs.IsValid(i)
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "cannot compute the weighted moving average of column %q of type %s", t.column, typ)
+	}
+
+	n := chunk.Len()
+	b := arrowutil.NewFloatBuilder(mem)
+	b.Resize(n)
+	for i := 0; i < n; i++ {
+		s.push(value(i))
+		if avg, ok := t.average(s); ok {
+			b.Append(avg)
+		} else {
+			b.AppendNull()
+		}
+	}
+
+	buffer := chunk.Buffer()
+	cols := make([]flux.ColMeta, len(buffer.Columns))
+	copy(cols, buffer.Columns)
+	cols[idx].Type = flux.TFloat
+	vs := make([]array.Array, len(buffer.Values))
+	for j := range vs {
+		if j == idx {
+			vs[j] = b.NewArray()
+			continue
+		}
+		vs[j] = buffer.Values[j]
+		vs[j].Retain()
+	}
+	buffer.Columns, buffer.Values = cols, vs
+	if err := d.Process(table.ChunkFromBuffer(buffer)); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *weightedMovingAverageTransformation) Close() error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestWeightedMovingAverage_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.WeightedMovingAverageProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "int",
+			spec: &universe.WeightedMovingAverageProcedureSpec{
+				Weights: []float64{1, 2, 3},
+				Column:  "_value",
+			},
+			data: []flux.Table{&executetest.RowWiseTable{
+				Table: &executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(1), "a"},
+						{execute.Time(2), int64(2), "a"},
+						{execute.Time(3), int64(3), "a"},
+						{execute.Time(4), int64(4), "a"},
+						{execute.Time(5), int64(5), "a"},
+					},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil, "a"},
+					{execute.Time(2), nil, "a"},
+					{execute.Time(3), 14.0 / 6, "a"},
+					{execute.Time(4), 20.0 / 6, "a"},
+					{execute.Time(5), 26.0 / 6, "a"},
+				},
+			}},
+		},
+		{
+			name: "nulls",
+			spec: &universe.WeightedMovingAverageProcedureSpec{
+				Weights: []float64{1, 3},
+				Column:  "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 2.0},
+					{execute.Time(2), nil},
+					{execute.Time(3), 4.0},
+					{execute.Time(4), 8.0},
+					{execute.Time(5), nil},
+					{execute.Time(6), nil},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), nil},
+					{execute.Time(2), 2.0},
+					{execute.Time(3), 4.0},
+					{execute.Time(4), 7.0},
+					{execute.Time(5), 8.0},
+					{execute.Time(6), nil},
+				},
+			}},
+		},
+		{
+			name: "unsupported type",
+			spec: &universe.WeightedMovingAverageProcedureSpec{
+				Weights: []float64{1},
+				Column:  "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "a"},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `cannot compute the weighted moving average of column "_value" of type string`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewWeightedMovingAverageTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}